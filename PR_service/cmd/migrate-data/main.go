@@ -0,0 +1,142 @@
+// Команда migrate-data переносит все данные сервиса из одной БД Postgres в другую
+// (обновление версии СУБД, переезд в другой регион) и сверяет результат.
+//
+// В режиме --follow перенос повторяется каждые --interval, пока процесс не
+// остановлен, чтобы целевая БД отставала от исходной не больше чем на интервал.
+// После остановки выполняется финальный перенос и сверка, затем сервис
+// переключается на новый DATABASE_URL.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"PR_service/internal/storage"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+type report struct {
+	Status string       `json:"status"`
+	Passes int          `json:"passes"`
+	Checks []tableCheck `json:"checks"`
+}
+
+func main() {
+	source := flag.String("source", os.Getenv("SOURCE_DATABASE_URL"), "source DSN (default $SOURCE_DATABASE_URL)")
+	target := flag.String("target", os.Getenv("TARGET_DATABASE_URL"), "target DSN (default $TARGET_DATABASE_URL)")
+	follow := flag.Bool("follow", false, "keep re-syncing the target until interrupted, then run a final sync")
+	interval := flag.Duration("interval", 30*time.Second, "delay between passes in --follow mode")
+	verifyOnly := flag.Bool("verify-only", false, "only compare row counts and checksums, do not copy")
+	flag.Parse()
+
+	if *source == "" || *target == "" {
+		log.Fatal("both --source and --target are required")
+	}
+	if *source == *target {
+		log.Fatal("--source and --target must differ")
+	}
+	if *interval <= 0 {
+		log.Fatal("--interval must be positive")
+	}
+
+	src, err := openDB(*source)
+	if err != nil {
+		log.Fatalf("source: %v", err)
+	}
+	defer src.Close()
+
+	dst, err := openDB(*target)
+	if err != nil {
+		log.Fatalf("target: %v", err)
+	}
+	defer dst.Close()
+
+	if !*verifyOnly {
+		log.Println("Applying migrations to target...")
+		if err := storage.ApplyMigrations(dst); err != nil {
+			log.Fatalf("Failed to apply migrations to target: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	rep := report{}
+	if !*verifyOnly {
+		for {
+			if err := runPass(context.Background(), src, dst, rep.Passes+1); err != nil {
+				log.Fatalf("Sync failed: %v", err)
+			}
+			rep.Passes++
+
+			if !*follow || !sleepContext(ctx, *interval) {
+				break
+			}
+		}
+		if *follow {
+			// Финальный проход после остановки записи в исходную БД
+			if err := runPass(context.Background(), src, dst, rep.Passes+1); err != nil {
+				log.Fatalf("Final sync failed: %v", err)
+			}
+			rep.Passes++
+		}
+	}
+
+	rep.Checks, err = verify(context.Background(), src, dst, storage.DataTables)
+	if err != nil {
+		log.Fatalf("Verification failed: %v", err)
+	}
+
+	rep.Status = "ok"
+	if !allMatch(rep.Checks) {
+		rep.Status = "mismatch"
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rep); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+	if rep.Status != "ok" {
+		os.Exit(1)
+	}
+}
+
+func runPass(ctx context.Context, src, dst *sql.DB, pass int) error {
+	start := time.Now()
+	copied, err := syncOnce(ctx, src, dst, storage.DataTables)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, n := range copied {
+		total += n
+	}
+	log.Printf("Pass %d: copied %d rows from %d tables in %v", pass, total, len(copied), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	return db, nil
+}
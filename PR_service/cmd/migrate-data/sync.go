@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// copyBatchSize - число строк, вставляемых в целевую БД одним запросом
+const copyBatchSize = 1000
+
+// tableCheck - результат сверки таблицы между исходной и целевой БД
+type tableCheck struct {
+	Table          string `json:"table"`
+	SourceRows     int64  `json:"source_rows"`
+	TargetRows     int64  `json:"target_rows"`
+	SourceChecksum string `json:"source_checksum"`
+	TargetChecksum string `json:"target_checksum"`
+	Match          bool   `json:"match"`
+}
+
+// syncOnce переносит снимок всех таблиц из src в dst.
+// Источник читается в одной REPEATABLE READ транзакции, поэтому снимок
+// согласован; в целевой БД данные заменяются целиком в одной транзакции.
+func syncOnce(ctx context.Context, src, dst *sql.DB, tables []string) (map[string]int64, error) {
+	srcTx, err := src.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, fmt.Errorf("begin source snapshot: %w", err)
+	}
+	defer srcTx.Rollback()
+
+	dstTx, err := dst.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin target transaction: %w", err)
+	}
+	defer dstTx.Rollback()
+
+	if _, err := dstTx.ExecContext(ctx, truncateStatement(tables)); err != nil {
+		return nil, fmt.Errorf("truncate target: %w", err)
+	}
+
+	copied := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		n, err := copyTable(ctx, srcTx, dstTx, table)
+		if err != nil {
+			return nil, fmt.Errorf("copy %s: %w", table, err)
+		}
+		copied[table] = n
+
+		if err := resetSequences(ctx, dstTx, table); err != nil {
+			return nil, fmt.Errorf("reset sequences of %s: %w", table, err)
+		}
+	}
+
+	if err := dstTx.Commit(); err != nil {
+		return nil, err
+	}
+	return copied, srcTx.Commit()
+}
+
+// copyTable переносит строки таблицы пачками через JSON, чтобы не зависеть
+// от типов колонок: json_populate_recordset восстанавливает их по схеме цели
+func copyTable(ctx context.Context, srcTx, dstTx *sql.Tx, table string) (int64, error) {
+	rows, err := srcTx.QueryContext(ctx, fmt.Sprintf(`SELECT row_to_json(t)::text FROM %s t`, quoteIdent(table)))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	insert := fmt.Sprintf(`INSERT INTO %[1]s SELECT * FROM json_populate_recordset(NULL::%[1]s, $1::json)`, quoteIdent(table))
+
+	var total int64
+	batch := make([]string, 0, copyBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := dstTx.ExecContext(ctx, insert, "["+strings.Join(batch, ",")+"]"); err != nil {
+			return err
+		}
+		total += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			return total, err
+		}
+		batch = append(batch, row)
+		if len(batch) == copyBatchSize {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return total, err
+	}
+	return total, flush()
+}
+
+// resetSequences выставляет последовательности serial-колонок после максимального значения
+func resetSequences(ctx context.Context, tx *sql.Tx, table string) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT column_name FROM information_schema.columns
+		 WHERE table_schema = current_schema() AND table_name = $1 AND column_default LIKE 'nextval(%'`, table)
+	if err != nil {
+		return err
+	}
+
+	var columns []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			rows.Close()
+			return err
+		}
+		columns = append(columns, col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, col := range columns {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(%s), 0) + 1, false) FROM %s`,
+			quoteIdent(col), quoteIdent(table)), table, col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verify сравнивает число строк и контрольные суммы таблиц в обеих БД
+func verify(ctx context.Context, src, dst *sql.DB, tables []string) ([]tableCheck, error) {
+	checks := make([]tableCheck, 0, len(tables))
+	for _, table := range tables {
+		c := tableCheck{Table: table}
+		var err error
+		if c.SourceRows, c.SourceChecksum, err = tableChecksum(ctx, src, table); err != nil {
+			return nil, fmt.Errorf("checksum source %s: %w", table, err)
+		}
+		if c.TargetRows, c.TargetChecksum, err = tableChecksum(ctx, dst, table); err != nil {
+			return nil, fmt.Errorf("checksum target %s: %w", table, err)
+		}
+		c.Match = c.SourceRows == c.TargetRows && c.SourceChecksum == c.TargetChecksum
+		checks = append(checks, c)
+	}
+	return checks, nil
+}
+
+// tableChecksum считает число строк и md5 от отсортированных хешей строк.
+// Часовой пояс фиксируется, чтобы текстовое представление timestamptz совпадало.
+func tableChecksum(ctx context.Context, db *sql.DB, table string) (int64, string, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SET LOCAL TIME ZONE 'UTC'`); err != nil {
+		return 0, "", err
+	}
+
+	var count int64
+	var checksum string
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(
+		`SELECT COUNT(*), COALESCE(md5(string_agg(h, '' ORDER BY h)), '')
+		 FROM (SELECT md5(t::text) AS h FROM %s t) s`, quoteIdent(table))).Scan(&count, &checksum)
+	if err != nil {
+		return 0, "", err
+	}
+	return count, checksum, tx.Commit()
+}
+
+// allMatch возвращает true, если все таблицы совпали
+func allMatch(checks []tableCheck) bool {
+	for _, c := range checks {
+		if !c.Match {
+			return false
+		}
+	}
+	return true
+}
+
+// truncateStatement очищает все таблицы одним запросом, чтобы не нарушать внешние ключи
+func truncateStatement(tables []string) string {
+	quoted := make([]string, len(tables))
+	for i, t := range tables {
+		quoted[i] = quoteIdent(t)
+	}
+	return "TRUNCATE TABLE " + strings.Join(quoted, ", ")
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sleepContext ждет d или отмены ctx; возвращает false при отмене
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
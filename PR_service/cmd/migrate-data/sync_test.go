@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateStatement(t *testing.T) {
+	assert.Equal(t, `TRUNCATE TABLE "teams", "users"`, truncateStatement([]string{"teams", "users"}))
+	assert.Equal(t, `"a""b"`, quoteIdent(`a"b`))
+}
+
+func TestAllMatch(t *testing.T) {
+	assert.True(t, allMatch(nil))
+	assert.True(t, allMatch([]tableCheck{{Table: "teams", Match: true}}))
+	assert.False(t, allMatch([]tableCheck{{Table: "teams", Match: true}, {Table: "users"}}))
+}
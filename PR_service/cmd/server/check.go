@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"PR_service/internal/events"
+	"PR_service/internal/storage"
+)
+
+const (
+	checkOK      = "ok"
+	checkFailed  = "failed"
+	checkSkipped = "skipped"
+)
+
+type checkResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type checkReport struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks"`
+}
+
+// runSelfTest выполняет проверки готовности к запуску и печатает отчет в stdout.
+// Возвращает код выхода процесса: 0 - все проверки пройдены, 1 - есть ошибки.
+func runSelfTest() int {
+	report := checkReport{Status: checkOK}
+	add := func(name string, start time.Time, status, message string) {
+		report.Checks = append(report.Checks, checkResult{
+			Name:       name,
+			Status:     status,
+			Message:    message,
+			DurationMs: time.Since(start).Milliseconds(),
+		})
+		if status == checkFailed {
+			report.Status = checkFailed
+		}
+	}
+
+	start := time.Now()
+	cfg, err := loadConfig()
+	if err != nil {
+		add("config", start, checkFailed, err.Error())
+		return writeReport(report)
+	}
+	add("config", start, checkOK, "")
+
+	start = time.Now()
+	db, err := openDB(cfg.DatabaseURL)
+	if err != nil {
+		add("database", start, checkFailed, err.Error())
+		add("migrations", time.Now(), checkSkipped, "database is unavailable")
+	} else {
+		defer db.Close()
+		add("database", start, checkOK, "")
+
+		start = time.Now()
+		if err := checkSchema(cfg, db); err != nil {
+			add("migrations", start, checkFailed, err.Error())
+		} else {
+			add("migrations", start, checkOK, "")
+		}
+		// Ключи подписи вебхуков хранятся в БД
+		cfg.Events.Keys = storage.NewStorage(db)
+	}
+
+	// Уведомления интеграциям уходят через канал событий: шаблоны и маршруты команд
+	// только готовят их, поэтому проверяется подключение к брокеру или приемнику вебхуков
+	start = time.Now()
+	switch {
+	case cfg.Events.Broker == "":
+		add("events", start, checkSkipped, "EVENTS_BROKER is not set")
+	case cfg.Events.Broker == events.BrokerWebhook && db == nil:
+		add("events", start, checkSkipped, "database is unavailable")
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := events.Check(ctx, cfg.Events)
+		cancel()
+		if err != nil {
+			add("events", start, checkFailed, cfg.Events.Broker+": "+err.Error())
+		} else {
+			add("events", start, checkOK, cfg.Events.Broker+" "+cfg.Events.URL)
+		}
+	}
+
+	return writeReport(report)
+}
+
+// checkSchema проверяет миграции так же, как их выполнит запуск: пробным применением
+// под ролью миграций или, без MIGRATE_ON_START, проверкой схемы под ролью приложения
+func checkSchema(cfg config, db *sql.DB) error {
+	if !cfg.MigrateOnStart {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return storage.VerifySchema(ctx, db)
+	}
+
+	if cfg.MigrationsURL != cfg.DatabaseURL {
+		migrationsDB, err := openDB(cfg.MigrationsURL)
+		if err != nil {
+			return fmt.Errorf("migrations database: %w", err)
+		}
+		defer migrationsDB.Close()
+		db = migrationsDB
+	}
+	return storage.CheckMigrations(db)
+}
+
+func writeReport(report checkReport) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+
+	if report.Status == checkFailed {
+		return 1
+	}
+	return 0
+}
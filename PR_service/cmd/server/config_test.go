@@ -0,0 +1,194 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseListenAddrs(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		expected  []string
+		wantError bool
+	}{
+		{name: "Single IPv4", raw: "0.0.0.0:8080", expected: []string{"0.0.0.0:8080"}},
+		{name: "IPv4 and IPv6", raw: "0.0.0.0:8080, [::]:8080", expected: []string{"0.0.0.0:8080", "[::]:8080"}},
+		{name: "Localhost only", raw: "127.0.0.1:9090,[::1]:9090,localhost:9091", expected: []string{"127.0.0.1:9090", "[::1]:9090", "localhost:9091"}},
+		{name: "All interfaces", raw: ":8080", expected: []string{":8080"}},
+		{name: "Missing port", raw: "127.0.0.1", wantError: true},
+		{name: "Unbracketed IPv6", raw: "::1:8080", wantError: true},
+		{name: "Invalid port", raw: "127.0.0.1:70000", wantError: true},
+		{name: "Hostname", raw: "example.com:8080", wantError: true},
+		{name: "Duplicate", raw: ":8080,:8080", wantError: true},
+		{name: "Empty", raw: " , ", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseListenAddrs(tt.raw)
+			if tt.wantError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestLoadConfigEvents(t *testing.T) {
+	t.Setenv("EVENTS_BROKER", "")
+	cfg, err := loadConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Events.Broker)
+
+	t.Setenv("EVENTS_BROKER", "nats")
+	t.Setenv("EVENTS_URL", "nats://127.0.0.1:4222")
+	cfg, err = loadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "pr_service.events", cfg.Events.Topic)
+
+	t.Setenv("EVENTS_URL", "")
+	_, err = loadConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadConfigWarmUp(t *testing.T) {
+	cfg, err := loadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cfg.WarmUpConnections)
+	assert.Equal(t, 30*time.Second, cfg.WarmUpTimeout)
+
+	t.Setenv("WARMUP_CONNECTIONS", "0")
+	_, err = loadConfig()
+	assert.Error(t, err)
+
+	t.Setenv("WARMUP_CONNECTIONS", "8")
+	t.Setenv("WARMUP_TIMEOUT", "-1s")
+	_, err = loadConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadConfigForcedReviewers(t *testing.T) {
+	cfg, err := loadConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.AllowForcedReviewers)
+
+	t.Setenv("ALLOW_FORCED_REVIEWERS", "true")
+	cfg, err = loadConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.AllowForcedReviewers)
+
+	t.Setenv("ALLOW_FORCED_REVIEWERS", "staging")
+	_, err = loadConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadConfigWebhookDedupe(t *testing.T) {
+	cfg, err := loadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 72*time.Hour, cfg.WebhookDedupeTTL)
+
+	t.Setenv("WEBHOOK_DEDUPE_TTL", "6h")
+	cfg, err = loadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 6*time.Hour, cfg.WebhookDedupeTTL)
+
+	t.Setenv("WEBHOOK_DEDUPE_TTL", "0s")
+	_, err = loadConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadConfigScopes(t *testing.T) {
+	cfg, err := loadConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.Scopes.RequireAuth)
+	assert.True(t, cfg.Scopes.Exempt["/health"])
+	assert.Empty(t, cfg.Scopes.Routes)
+
+	t.Setenv("AUTH_REQUIRED", "true")
+	t.Setenv("AUTH_EXEMPT_PATHS", "/healthz, /metrics")
+	t.Setenv("AUTH_ROUTE_SCOPES", "/pullRequest/export=reports:read")
+	cfg, err = loadConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.Scopes.RequireAuth)
+	assert.Equal(t, map[string]bool{"/healthz": true, "/metrics": true}, cfg.Scopes.Exempt)
+	assert.Equal(t, "reports:read", cfg.Scopes.RequiredScope("GET", "/pullRequest/export"))
+
+	t.Setenv("AUTH_EXEMPT_PATHS", "metrics")
+	_, err = loadConfig()
+	assert.Error(t, err)
+
+	t.Setenv("AUTH_EXEMPT_PATHS", "")
+	t.Setenv("AUTH_ROUTE_SCOPES", "/team/add=everything")
+	_, err = loadConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadConfigMigrations(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://app@db:5432/pr")
+	cfg, err := loadConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.MigrateOnStart)
+	assert.Equal(t, cfg.DatabaseURL, cfg.MigrationsURL)
+
+	t.Setenv("MIGRATIONS_DATABASE_URL", "postgres://owner@db:5432/pr")
+	t.Setenv("MIGRATE_ON_START", "false")
+	t.Setenv("DB_RUNTIME_ROLE", "app")
+	cfg, err = loadConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.MigrateOnStart)
+	assert.Equal(t, "postgres://owner@db:5432/pr", cfg.MigrationsURL)
+	assert.Equal(t, "app", cfg.RuntimeRole)
+
+	t.Setenv("MIGRATE_ON_START", "sometimes")
+	_, err = loadConfig()
+	assert.Error(t, err)
+
+	t.Setenv("MIGRATE_ON_START", "true")
+	t.Setenv("MIGRATIONS_DATABASE_URL", "mysql://owner@db/pr")
+	_, err = loadConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadConfigOutbound(t *testing.T) {
+	cfg, err := loadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cfg.Outbound.MaxRetries)
+	assert.Equal(t, 5, cfg.Outbound.BreakerThreshold)
+	assert.Equal(t, cfg.Outbound.MaxRetries, cfg.Events.HTTP.MaxRetries)
+
+	t.Setenv("OUTBOUND_HTTP_PROXY", "http://proxy.internal:3128")
+	cfg, err = loadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "http://proxy.internal:3128", cfg.Events.HTTP.ProxyURL)
+
+	t.Setenv("OUTBOUND_HTTP_PROXY", "proxy.internal")
+	_, err = loadConfig()
+	assert.Error(t, err)
+
+	t.Setenv("OUTBOUND_HTTP_PROXY", "")
+	t.Setenv("OUTBOUND_HTTP_RETRIES", "-1")
+	_, err = loadConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadConfigMetricsNamespace(t *testing.T) {
+	cfg, err := loadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "pr_service", cfg.MetricsNamespace)
+
+	t.Setenv("METRICS_NAMESPACE", "pr_service_staging")
+	cfg, err = loadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "pr_service_staging", cfg.MetricsNamespace)
+
+	for _, ns := range []string{"pr-service", "1pr"} {
+		t.Setenv("METRICS_NAMESPACE", ns)
+		_, err = loadConfig()
+		assert.Error(t, err, ns)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"PR_service/internal/storage"
+)
+
+// runMigrations применяет миграции под ролью миграций и выдает права роли приложения.
+// Вызывается при старте (MIGRATE_ON_START=true) или отдельным шагом: server -migrate.
+func runMigrations(cfg config) error {
+	db, err := openDB(cfg.MigrationsURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	log.Println("Applying database migrations...")
+	if err := storage.ApplyMigrations(db); err != nil {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+	log.Printf("Migrations applied successfully (schema version %d)", storage.SchemaVersion)
+
+	if cfg.RuntimeRole != "" {
+		if err := storage.GrantRuntimeAccess(db, cfg.RuntimeRole); err != nil {
+			return err
+		}
+		log.Printf("Granted data access to runtime role %s", cfg.RuntimeRole)
+	}
+	return nil
+}
+
+// rollbackMigration откатывает последнюю миграцию под ролью миграций: server -migrate-down
+func rollbackMigration(cfg config) error {
+	db, err := openDB(cfg.MigrationsURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	version, err := storage.RollbackMigration(db)
+	if err != nil {
+		return err
+	}
+	log.Printf("Rolled back the latest migration (schema version %d)", version)
+	return nil
+}
+
+// forceMigrationVersion записывает версию схемы и снимает флаг dirty: server -migrate-force N
+func forceMigrationVersion(cfg config, version int) error {
+	db, err := openDB(cfg.MigrationsURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := storage.ForceMigrationVersion(db, version); err != nil {
+		return err
+	}
+	log.Printf("Schema version set to %d", version)
+	return nil
+}
+
+// openDB открывает пул соединений и проверяет подключение
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	return db, nil
+}
@@ -1,11 +1,27 @@
 package api
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"PR_service/internal/authctx"
+	"PR_service/internal/domain"
 	"PR_service/internal/models"
+	"PR_service/internal/storage"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -497,3 +513,1198 @@ func TestModelInitialization(t *testing.T) {
 		assert.Equal(t, "Test error message", errorResp.Error.Message)
 	})
 }
+
+func TestValidateFocusWindows(t *testing.T) {
+	tests := []struct {
+		name        string
+		windows     []models.FocusWindow
+		shouldError bool
+	}{
+		{
+			name:    "Valid window",
+			windows: []models.FocusWindow{{Weekday: 2, StartTime: "09:00", EndTime: "12:00"}},
+		},
+		{
+			name:    "Window until end of day",
+			windows: []models.FocusWindow{{Weekday: 5, StartTime: "18:00", EndTime: "24:00"}},
+		},
+		{
+			name:    "Empty schedule clears windows",
+			windows: []models.FocusWindow{},
+		},
+		{
+			name:        "Invalid weekday",
+			windows:     []models.FocusWindow{{Weekday: 7, StartTime: "09:00", EndTime: "12:00"}},
+			shouldError: true,
+		},
+		{
+			name:        "End before start",
+			windows:     []models.FocusWindow{{Weekday: 1, StartTime: "12:00", EndTime: "09:00"}},
+			shouldError: true,
+		},
+		{
+			name:        "Invalid time format",
+			windows:     []models.FocusWindow{{Weekday: 1, StartTime: "9am", EndTime: "12:00"}},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateFocusWindows(tt.windows)
+			if tt.shouldError {
+				assert.NotEmpty(t, result)
+			} else {
+				assert.Empty(t, result)
+			}
+		})
+	}
+}
+
+func TestAuthContextMiddleware(t *testing.T) {
+	var got authctx.Principal
+	var gotOK bool
+	var gotRequestID string
+	handler := AuthContextMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, gotOK = authctx.PrincipalFrom(r.Context())
+		gotRequestID = authctx.RequestIDFrom(r.Context())
+	}))
+
+	t.Run("Principal from proxy headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderRequestID, "req-1")
+		req.Header.Set(HeaderAuthUser, "u1")
+		req.Header.Set(HeaderAuthRoles, "admin, user")
+		req.Header.Set(HeaderAuthOrg, "acme")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.True(t, gotOK)
+		assert.Equal(t, "u1", got.ID)
+		assert.Equal(t, []string{"admin", "user"}, got.Roles)
+		assert.Equal(t, "acme", got.Org)
+		assert.Equal(t, "req-1", gotRequestID)
+		assert.Equal(t, "req-1", rec.Header().Get(HeaderRequestID))
+	})
+
+	t.Run("Anonymous request gets generated request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.False(t, gotOK)
+		assert.NotEmpty(t, gotRequestID)
+		assert.Equal(t, gotRequestID, rec.Header().Get(HeaderRequestID))
+	})
+}
+
+func TestValidateBorrowLenders(t *testing.T) {
+	tests := []struct {
+		name        string
+		lenders     []models.BorrowLender
+		shouldError bool
+	}{
+		{
+			name:    "Valid pool",
+			lenders: []models.BorrowLender{{TeamName: "frontend", MaxNetBorrowed: 3}, {TeamName: "mobile"}},
+		},
+		{
+			name:    "Empty pool disables borrowing",
+			lenders: []models.BorrowLender{},
+		},
+		{
+			name:        "Borrow from itself",
+			lenders:     []models.BorrowLender{{TeamName: "backend"}},
+			shouldError: true,
+		},
+		{
+			name:        "Duplicate lender",
+			lenders:     []models.BorrowLender{{TeamName: "frontend"}, {TeamName: "frontend"}},
+			shouldError: true,
+		},
+		{
+			name:        "Negative cap",
+			lenders:     []models.BorrowLender{{TeamName: "frontend", MaxNetBorrowed: -1}},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateBorrowLenders("backend", tt.lenders)
+			if tt.shouldError {
+				assert.NotEmpty(t, result)
+			} else {
+				assert.Empty(t, result)
+			}
+		})
+	}
+}
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	h := &Handler{maintenance: &maintenanceMode{}}
+	handler := h.MaintenanceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	t.Run("Mutations allowed when disabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/pullRequest/create").Code)
+	})
+
+	h.maintenance.set(models.MaintenanceState{Enabled: true, Message: "schema migration"})
+
+	t.Run("Mutations rejected when enabled", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/pullRequest/create")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"MAINTENANCE"`)
+		assert.Contains(t, rec.Body.String(), "schema migration")
+	})
+
+	t.Run("Reads allowed when enabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/team/get").Code)
+	})
+
+	t.Run("Maintenance toggle allowed when enabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, maintenancePath).Code)
+	})
+}
+
+func TestValidateTeamSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    models.TeamSettings
+		shouldError bool
+	}{
+		{name: "Default settings", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random"}},
+		{name: "Least loaded", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 3, Strategy: "least_loaded"}},
+		{name: "No reviewers", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 0, Strategy: "random"}},
+		{name: "Negative count", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: -1, Strategy: "random"}, shouldError: true},
+		{name: "Too many reviewers", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 11, Strategy: "random"}, shouldError: true},
+		{name: "Unknown strategy", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "round_robin"}, shouldError: true},
+		{name: "All approve policy", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", MergePolicy: "all"}},
+		{name: "Quorum policy", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 3, Strategy: "random", MergePolicy: "quorum", RequiredApprovals: 2}},
+		{name: "Quorum without count", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 3, Strategy: "random", MergePolicy: "quorum"}, shouldError: true},
+		{name: "Approvals without quorum", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 3, Strategy: "random", MergePolicy: "all", RequiredApprovals: 2}, shouldError: true},
+		{name: "Unknown merge policy", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", MergePolicy: "majority"}, shouldError: true},
+		{name: "Capacity limit", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", MaxOpenReviews: 5}},
+		{name: "Negative capacity", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", MaxOpenReviews: -1}, shouldError: true},
+		{name: "Capacity too large", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", MaxOpenReviews: 101}, shouldError: true},
+		{name: "Adaptive count", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", AdaptiveBacklogThreshold: 10, AdaptiveReviewerCount: 1}},
+		{name: "Adaptive count not lower", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", AdaptiveBacklogThreshold: 10, AdaptiveReviewerCount: 2}, shouldError: true},
+		{name: "New member holdback", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", NewMemberHoldbackDays: 14}},
+		{name: "Negative holdback", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", NewMemberHoldbackDays: -1}, shouldError: true},
+		{name: "Holdback too long", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", NewMemberHoldbackDays: 91}, shouldError: true},
+		{name: "Adaptive count without threshold", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", AdaptiveReviewerCount: 1}, shouldError: true},
+		{name: "PR policies", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", PRNamePattern: `^(feat|fix): `, RequireTicketReference: true, MinTags: 1}},
+		{name: "Invalid name pattern", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", PRNamePattern: `([a-z`}, shouldError: true},
+		{name: "Too many required tags", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", MinTags: 21}, shouldError: true},
+		{name: "Peer then lead", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", ApprovalStages: []models.ApprovalStage{{Kind: "peer", Approvals: 1}, {Kind: "lead", Approvals: 1}}}},
+		{name: "Lead first", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", ApprovalStages: []models.ApprovalStage{{Kind: "lead", Approvals: 1}}}, shouldError: true},
+		{name: "Unknown stage kind", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", ApprovalStages: []models.ApprovalStage{{Kind: "peer", Approvals: 1}, {Kind: "qa", Approvals: 1}}}, shouldError: true},
+		{name: "Stage without approvals", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", ApprovalStages: []models.ApprovalStage{{Kind: "peer", Approvals: 0}}}, shouldError: true},
+		{name: "Candidate filter", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", CandidateFilter: `!in_focus && (load < 5 || "db" in tags)`}},
+		{name: "Candidate rank", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "least_loaded", CandidateRank: "tenure_days >= 90 ? 1 + shared_tags : 0.5"}},
+		{name: "Candidate filter syntax error", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", CandidateFilter: "load <"}, shouldError: true},
+		{name: "Candidate filter not bool", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", CandidateFilter: "load + 1"}, shouldError: true},
+		{name: "Candidate filter unknown variable", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", CandidateFilter: "salary > 3"}, shouldError: true},
+		{name: "Candidate rank not number", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "least_loaded", CandidateRank: "is_lead"}, shouldError: true},
+		{name: "Candidate rank with random strategy", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", CandidateRank: "1"}, shouldError: true},
+		{name: "Team locale and timezone", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", Locale: "ru", Timezone: "Europe/Moscow"}},
+		{name: "Invalid locale", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", Locale: "russian"}, shouldError: true},
+		{name: "Unknown timezone", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", Timezone: "Moscow"}, shouldError: true},
+		{name: "Server local timezone", settings: models.TeamSettings{TeamName: "backend", ReviewerCount: 2, Strategy: "random", Timezone: "Local"}, shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateTeamSettings(tt.settings)
+			if tt.shouldError {
+				assert.NotEmpty(t, result)
+			} else {
+				assert.Empty(t, result)
+			}
+		})
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	handler := AuthContextMiddleware(RecoveryMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/team/get", nil)
+	req.Header.Set(HeaderRequestID, "req-panic")
+	rec := httptest.NewRecorder()
+
+	assert.NotPanics(t, func() {
+		handler.ServeHTTP(rec, req)
+	})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"INTERNAL_ERROR"`)
+	assert.Equal(t, "req-panic", rec.Header().Get(HeaderRequestID))
+}
+
+func TestParseAuditFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		shouldError bool
+	}{
+		{name: "Empty filter", query: ""},
+		{name: "All filters", query: "actor=alice&entity_type=pull_request&action=merge&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&limit=50"},
+		{name: "Invalid from", query: "from=2024-01-01", shouldError: true},
+		{name: "Reversed range", query: "from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z", shouldError: true},
+		{name: "Zero limit", query: "limit=0", shouldError: true},
+		{name: "Limit too large", query: "limit=10001", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			assert.NoError(t, err)
+
+			_, errMsg := parseAuditFilter(query)
+			if tt.shouldError {
+				assert.NotEmpty(t, errMsg)
+			} else {
+				assert.Empty(t, errMsg)
+			}
+		})
+	}
+
+	query, _ := url.ParseQuery("actor=alice&from=2024-01-01T00:00:00Z&limit=5")
+	filter, _ := parseAuditFilter(query)
+	assert.Equal(t, "alice", filter.Actor)
+	assert.Equal(t, 5, filter.Limit)
+	if assert.NotNil(t, filter.From) {
+		assert.Equal(t, 2024, filter.From.Year())
+	}
+	assert.Nil(t, filter.To)
+}
+
+func TestAuditCSVStream(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream, err := startCSVStream(rec, "audit_log.csv", auditCSVHeader)
+	assert.NoError(t, err)
+
+	assert.NoError(t, stream.Write(auditCSVRecord(models.AuditEvent{
+		ID:         7,
+		Actor:      "alice",
+		EntityType: "pull_request",
+		EntityID:   "pr-1",
+		Action:     "merge",
+		Details:    json.RawMessage(`{"a":"b,c"}`),
+		CreatedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})))
+	assert.NoError(t, stream.Flush())
+
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.True(t, rec.Flushed)
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "id,created_at,actor,request_id,entity_type,entity_id,action,details", lines[0])
+	assert.Equal(t, `7,2024-01-02T03:04:05Z,alice,,pull_request,pr-1,merge,"{""a"":""b,c""}"`, lines[1])
+}
+
+func TestCSVStreamFlushesInChunks(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream, err := startCSVStream(rec, "pull_requests.csv", prCSVHeader)
+	assert.NoError(t, err)
+
+	merged := "2024-01-03T00:00:00Z"
+	pr := models.PullRequest{
+		PullRequestID: "pr-1",
+		AuthorID:      "u1",
+		Status:        "MERGED",
+		Reviewers:     []string{"u2", "u3"},
+		CreatedAt:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		MergedAt:      &merged,
+	}
+	for i := 0; i < exportFlushRows-1; i++ {
+		assert.NoError(t, stream.Write(prCSVRecord(pr)))
+	}
+	assert.False(t, rec.Flushed)
+
+	assert.NoError(t, stream.Write(prCSVRecord(pr)))
+	assert.True(t, rec.Flushed)
+	assert.Contains(t, rec.Body.String(), "pr-1,,u1,MERGED,2024-01-02T00:00:00Z,2024-01-03T00:00:00Z,u2;u3\n")
+}
+
+func TestParsePRExportFilter(t *testing.T) {
+	filter, errMsg := parsePRExportFilter(url.Values{
+		"status":    {"OPEN"},
+		"team_name": {"backend"},
+		"from":      {"2024-01-01T00:00:00Z"},
+	})
+	assert.Empty(t, errMsg)
+	assert.Equal(t, "OPEN", filter.Status)
+	assert.Equal(t, "backend", filter.TeamName)
+	assert.NotNil(t, filter.From)
+	assert.Nil(t, filter.To)
+
+	_, errMsg = parsePRExportFilter(url.Values{"status": {"CLOSED"}})
+	assert.Equal(t, "status must be OPEN, MERGED or DECLINED", errMsg)
+
+	_, errMsg = parsePRExportFilter(url.Values{"from": {"2024-02-01T00:00:00Z"}, "to": {"2024-01-01T00:00:00Z"}})
+	assert.Equal(t, "from must be before to", errMsg)
+}
+
+func TestRecoveryCoversMiddlewaresBelowTimeout(t *testing.T) {
+	// Паника в middleware между TimeoutMiddleware и хендлером (как ForceReviewers
+	// или Maintenance в newRouter) выполняется в горутине таймаута
+	panicking := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Panic") != "" {
+				panic("middleware boom")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	handler := AuthContextMiddleware(TimeoutMiddleware(RecoveryMiddleware(nil)(FieldsMiddleware(panicking(ok)))))
+	req := httptest.NewRequest(http.MethodGet, "/team/get?fields=status", nil)
+	req.Header.Set("X-Panic", "1")
+	rec := httptest.NewRecorder()
+	assert.NotPanics(t, func() {
+		handler.ServeHTTP(rec, req)
+	})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"INTERNAL_ERROR"`)
+
+	// Без Recovery паника не завершает процесс из горутины, а передается в горутину
+	// запроса, где ее перехватывает net/http
+	bare := TimeoutMiddleware(panicking(ok))
+	assert.PanicsWithValue(t, "middleware boom", func() {
+		bare.ServeHTTP(httptest.NewRecorder(), req)
+	})
+}
+
+func TestTimeoutMiddlewareSkipsExports(t *testing.T) {
+	handler := TimeoutMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		assert.Equal(t, r.URL.Path != "/audit/export", hasDeadline)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, path := range []string{"/audit/export", "/audit/search"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+}
+
+func TestValidateNotificationTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    models.NotificationTemplate
+		shouldError bool
+	}{
+		{name: "Valid template", template: models.NotificationTemplate{TeamName: "backend", Kind: "pr_merged", Locale: "ru", Body: "{{.PullRequestName}} смерджен"}},
+		{name: "Delete template", template: models.NotificationTemplate{TeamName: "backend", Kind: "pr_merged", Locale: "ru"}},
+		{name: "Missing locale", template: models.NotificationTemplate{TeamName: "backend", Kind: "pr_merged", Body: "x"}, shouldError: true},
+		{name: "Unknown kind", template: models.NotificationTemplate{TeamName: "backend", Kind: "pr_closed", Locale: "en", Body: "x"}, shouldError: true},
+		{name: "Invalid locale", template: models.NotificationTemplate{TeamName: "backend", Kind: "pr_merged", Locale: "english", Body: "x"}, shouldError: true},
+		{name: "Unknown field", template: models.NotificationTemplate{TeamName: "backend", Kind: "pr_merged", Locale: "en", Body: "{{.Title}}"}, shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateNotificationTemplate(&tt.template)
+			if tt.shouldError {
+				assert.NotEmpty(t, result)
+			} else {
+				assert.Empty(t, result)
+			}
+		})
+	}
+
+	tmpl := models.NotificationTemplate{TeamName: "backend", Kind: "pr_merged", Locale: "pt_BR"}
+	assert.Empty(t, validateNotificationTemplate(&tmpl))
+	assert.Equal(t, "pt-br", tmpl.Locale)
+}
+
+func TestValidateExcludedReviewers(t *testing.T) {
+	assert.Empty(t, validateExcludedReviewers("u1", nil))
+	assert.Empty(t, validateExcludedReviewers("u1", []string{"u2", "u3"}))
+	assert.NotEmpty(t, validateExcludedReviewers("u1", []string{"u2", ""}))
+	assert.NotEmpty(t, validateExcludedReviewers("u1", []string{"u1"}))
+	assert.NotEmpty(t, validateExcludedReviewers("u1", []string{"u2", "u2"}))
+	assert.NotEmpty(t, validateExcludedReviewers("u1", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}))
+}
+
+func TestValidatePRTags(t *testing.T) {
+	assert.Empty(t, validatePRTags(nil))
+	assert.Empty(t, validatePRTags([]string{"go", "postgres"}))
+	assert.NotEmpty(t, validatePRTags([]string{"go", " "}))
+	assert.NotEmpty(t, validatePRTags([]string{"Go", "go"}))
+	assert.NotEmpty(t, validatePRTags([]string{strings.Repeat("x", storage.MaxTagLength+1)}))
+
+	tooMany := make([]string, storage.MaxPRTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag%d", i)
+	}
+	assert.NotEmpty(t, validatePRTags(tooMany))
+}
+
+func TestHandleAddReviewerError(t *testing.T) {
+	h := &Handler{}
+	tests := []struct {
+		err    string
+		status string
+		code   string
+	}{
+		{err: "pr not found", status: "404", code: "NOT_FOUND"},
+		{err: "reviewer excluded by author", status: "409", code: "REVIEWER_EXCLUDED"},
+		{err: "reviewer is a bot", status: "409", code: "REVIEWER_BOT"},
+		{err: "no active candidate available", status: "409", code: "NO_CANDIDATE"},
+		{err: "connection refused", status: "500", code: "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			status := h.handleAddReviewerError(rec, errors.New(tt.err))
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.status, strconv.Itoa(rec.Code))
+			assert.Contains(t, rec.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
+
+func TestGetAuditDiffInvalidID(t *testing.T) {
+	h := &Handler{}
+	for _, query := range []string{"", "?id=abc", "?id=0"} {
+		rec := httptest.NewRecorder()
+		h.GetAuditDiff(rec, httptest.NewRequest(http.MethodGet, "/audit/diff"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestHandleTransferAuthorError(t *testing.T) {
+	h := &Handler{}
+	tests := []struct {
+		err    string
+		status string
+		code   string
+	}{
+		{err: "author not found", status: "404", code: "NOT_FOUND"},
+		{err: "author is still active", status: "409", code: "AUTHOR_ACTIVE"},
+		{err: "no successor available", status: "409", code: "NO_SUCCESSOR"},
+		{err: storage.ErrTeamLeadRequired.Error(), status: "403", code: "TEAM_LEAD_REQUIRED"},
+		{err: "connection refused", status: "500", code: "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			status := h.handleTransferAuthorError(rec, errors.New(tt.err))
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.status, strconv.Itoa(rec.Code))
+			assert.Contains(t, rec.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
+
+func TestHandleTeamLeadError(t *testing.T) {
+	h := &Handler{}
+	tests := []struct {
+		err    error
+		status string
+		code   string
+	}{
+		{err: errors.New("team not found"), status: "404", code: "NOT_FOUND"},
+		{err: errors.New("user is not a team member"), status: "409", code: "INVALID_TEAM_LEAD"},
+		{err: storage.ErrTeamLeadRequired, status: "403", code: "TEAM_LEAD_REQUIRED"},
+		{err: errors.New("connection refused"), status: "500", code: "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			status := h.handleTeamLeadError(rec, tt.err, "AddTeamLead")
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.status, strconv.Itoa(rec.Code))
+			assert.Contains(t, rec.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
+
+func TestHandleReassignErrorTeamLead(t *testing.T) {
+	h := &Handler{}
+
+	rec := httptest.NewRecorder()
+	h.handleReassignError(rec, storage.ErrTeamLeadRequired)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"TEAM_LEAD_REQUIRED"`)
+
+	rec = httptest.NewRecorder()
+	h.handleReassignError(rec, errors.New("reviewer excluded by author"))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"REVIEWER_EXCLUDED"`)
+}
+
+func TestConflictResponsesCarryPR(t *testing.T) {
+	h := &Handler{}
+	pr := &models.PullRequest{
+		PullRequestID: "pr-1",
+		AuthorID:      "u1",
+		Status:        "MERGED",
+		Reviewers:     []string{"u2", "u3"},
+	}
+
+	rec := httptest.NewRecorder()
+	h.handleReassignError(rec, &storage.PRConflictError{Reason: "cannot modify reviewers after merge", PR: pr})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	var body struct {
+		Data struct {
+			PR models.PullRequest `json:"pr"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "pr-1", body.Data.PR.PullRequestID)
+	assert.Equal(t, "MERGED", body.Data.PR.Status)
+	assert.Equal(t, []string{"u2", "u3"}, body.Data.PR.Reviewers)
+
+	blocked := mergeBlockedResponse(&domain.ApprovalsMissingError{
+		Policy: "all", Required: 2, Approved: 1, Pending: []string{"u3"}, PR: pr,
+	})
+	data, err := json.Marshal(blocked)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"data":{"pr":{"pull_request_id":"pr-1"`)
+
+	// Ошибки без состояния ресурса не содержат поля data
+	data, err = json.Marshal(createErrorResponse("NOT_FOUND", "pr not found"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), `"data"`)
+}
+
+func TestReadinessDuringWarmUp(t *testing.T) {
+	h := &Handler{maintenance: &maintenanceMode{}}
+	h.BeginWarmUp()
+
+	rec := httptest.NewRecorder()
+	h.ReadinessCheck(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"warming_up"`)
+}
+
+func TestHealthzCachedState(t *testing.T) {
+	h := &Handler{}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	server := h.WithHealthz(next)
+
+	probe := func(method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(method, "/healthz", nil))
+		return rec
+	}
+
+	// До первой фоновой проверки реплика не готова
+	assert.Equal(t, http.StatusServiceUnavailable, probe(http.MethodGet).Code)
+
+	h.setHealth(true, time.Now())
+	rec := probe(http.MethodGet)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok\n", rec.Body.String())
+	assert.Equal(t, http.StatusOK, probe(http.MethodHead).Code)
+
+	h.setHealth(false, time.Now())
+	assert.Equal(t, http.StatusServiceUnavailable, probe(http.MethodGet).Code)
+
+	// Устаревший результат не держит реплику в балансировке
+	h.setHealth(true, time.Now().Add(-2*healthzStaleAfter))
+	assert.Equal(t, http.StatusServiceUnavailable, probe(http.MethodGet).Code)
+
+	// Остальные запросы уходят в роутер
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+}
+
+func TestCollectTeamMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	created := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: "test", Name: "pr_created_total"}, []string{"team"})
+	merged := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: "test", Name: "pr_merged_total"}, []string{"team"})
+	reviewers := prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: "test", Name: "pr_reviewers_assigned_count"}, []string{"team"})
+	teamErrors := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: "test", Name: "team_errors_total"}, []string{"team", "error_type"})
+	registry.MustRegister(created, merged, reviewers, teamErrors)
+
+	created.WithLabelValues("backend").Add(4)
+	merged.WithLabelValues("backend").Add(3)
+	reviewers.WithLabelValues("backend").Observe(2)
+	reviewers.WithLabelValues("backend").Observe(1)
+	teamErrors.WithLabelValues("backend", "NOT_FOUND").Inc()
+	teamErrors.WithLabelValues("backend", "PR_MERGED").Add(2)
+	created.WithLabelValues("alpha").Inc()
+
+	teams, err := collectTeamMetrics(registry, "test")
+	assert.NoError(t, err)
+	assert.Len(t, teams, 2)
+
+	// Команды идут по алфавиту
+	assert.Equal(t, "alpha", teams[0].TeamName)
+	assert.Equal(t, 1.0, teams[0].PRCreated)
+	assert.Equal(t, 0.0, teams[0].MergeRate)
+
+	backend := teams[1]
+	assert.Equal(t, 4.0, backend.PRCreated)
+	assert.Equal(t, 3.0, backend.PRMerged)
+	assert.Equal(t, 75.0, backend.MergeRate)
+	assert.Equal(t, 2.0, backend.ReviewerAssignments)
+	assert.Equal(t, 1.5, backend.AvgReviewersAssigned)
+	assert.Equal(t, 3.0, backend.Errors)
+	assert.Equal(t, map[string]float64{"NOT_FOUND": 1, "PR_MERGED": 2}, backend.ErrorsByType)
+
+	// Метрики с чужим префиксом не учитываются
+	teams, err = collectTeamMetrics(registry, DefaultMetricsNamespace)
+	assert.NoError(t, err)
+	assert.Empty(t, teams)
+}
+
+func TestValidateVeto(t *testing.T) {
+	assert.Empty(t, validateVeto(models.VetoRequest{Reason: storage.VetoOverloaded}))
+	assert.Empty(t, validateVeto(models.VetoRequest{Reason: storage.VetoConflictOfInterest, Comment: "мой проект"}))
+	assert.Contains(t, validateVeto(models.VetoRequest{Reason: "busy"}), "reason must be one of")
+	assert.Contains(t, validateVeto(models.VetoRequest{
+		Reason:  storage.VetoUnfamiliarArea,
+		Comment: strings.Repeat("a", storage.MaxVetoCommentLength+1),
+	}), "comment must be at most")
+}
+
+func TestHandleVetoError(t *testing.T) {
+	h := &Handler{}
+
+	rec := httptest.NewRecorder()
+	assert.Equal(t, "403", h.handleVetoError(rec, storage.ErrVetoNotAllowed))
+	assert.Contains(t, rec.Body.String(), `"code":"VETO_NOT_ALLOWED"`)
+
+	rec = httptest.NewRecorder()
+	pr := &models.PullRequest{PullRequestID: "pr-1", Status: "OPEN", Reviewers: []string{"u2"}}
+	assert.Equal(t, "409", h.handleVetoError(rec, &storage.PRConflictError{Reason: "reviewer is not assigned to this PR", PR: pr}))
+	assert.Contains(t, rec.Body.String(), `"code":"NOT_ASSIGNED"`)
+	assert.Contains(t, rec.Body.String(), `"pull_request_id":"pr-1"`)
+
+	rec = httptest.NewRecorder()
+	assert.Equal(t, "404", h.handleVetoError(rec, errors.New("pr not found")))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestWritePolicyViolations(t *testing.T) {
+	violations := domain.CheckPRPolicies(models.TeamSettings{
+		TeamName:               "backend",
+		PRNamePattern:          `^(feat|fix): `,
+		RequireTicketReference: true,
+	}, models.CreatePRRequest{PullRequestName: "Refunds"})
+
+	rec := httptest.NewRecorder()
+	writePolicyViolations(rec, "backend", violations)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"POLICY_VIOLATION"`)
+	assert.Contains(t, rec.Body.String(), `"violations":[{"field":"pull_request_name"`)
+
+	err := &domain.PolicyViolationError{TeamName: "backend", Violations: violations}
+	assert.Equal(t, "pull request violates 2 policies of team backend", err.Error())
+}
+
+func TestParseRotationWeeks(t *testing.T) {
+	weeks, errMsg := parseRotationWeeks("")
+	assert.Empty(t, errMsg)
+	assert.Equal(t, defaultRotationWeeks, weeks)
+
+	weeks, errMsg = parseRotationWeeks("8")
+	assert.Empty(t, errMsg)
+	assert.Equal(t, 8, weeks)
+
+	for _, raw := range []string{"0", "-1", "27", "many"} {
+		_, errMsg = parseRotationWeeks(raw)
+		assert.NotEmpty(t, errMsg, raw)
+	}
+}
+
+func TestWriteJSONPooledBuffer(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		WriteJSON(rec, http.StatusCreated, map[string]int{"n": i})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, fmt.Sprintf("{\"n\":%d}\n", i), rec.Body.String())
+		assert.Equal(t, strconv.Itoa(rec.Body.Len()), rec.Header().Get("Content-Length"))
+	}
+
+	// Ошибка кодирования отдается как 500, а не как обрезанный ответ с исходным статусом
+	rec := httptest.NewRecorder()
+	WriteJSON(rec, http.StatusOK, map[string]interface{}{"bad": make(chan int)})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	WriteJSON(rec, http.StatusNoContent, nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestProjectFields(t *testing.T) {
+	tree := parseFields(" pr.pull_request_id, pr.reviewers ,,team")
+	assert.Equal(t, fieldTree{
+		"pr":   fieldTree{"pull_request_id": fieldTree{}, "reviewers": fieldTree{}},
+		"team": fieldTree{},
+	}, tree)
+
+	value := map[string]interface{}{
+		"pr": map[string]interface{}{
+			"pull_request_id": "pr-1",
+			"status":          "OPEN",
+			"reviewers":       []interface{}{"u2", "u3"},
+		},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"pr": map[string]interface{}{
+			"pull_request_id": "pr-1",
+			"reviewers":       []interface{}{"u2", "u3"},
+		},
+	}, projectFields(value, tree))
+
+	// Выбор применяется к каждому элементу массива
+	list := map[string]interface{}{
+		"user_id": "u1",
+		"pull_requests": []interface{}{
+			map[string]interface{}{"pull_request_id": "pr-1", "status": "OPEN"},
+			map[string]interface{}{"pull_request_id": "pr-2", "status": "MERGED"},
+		},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"pull_requests": []interface{}{
+			map[string]interface{}{"pull_request_id": "pr-1"},
+			map[string]interface{}{"pull_request_id": "pr-2"},
+		},
+	}, projectFields(list, parseFields("pull_requests.pull_request_id")))
+}
+
+func TestValidateAssignmentQuota(t *testing.T) {
+	quota := models.AssignmentQuota{UserID: "u1", MaxAssignments: 3}
+	assert.Empty(t, validateAssignmentQuota(&quota))
+	assert.Equal(t, "day", quota.Period, "period defaults to day")
+
+	assert.Empty(t, validateAssignmentQuota(&models.AssignmentQuota{UserID: "u1", MaxAssignments: 10, Period: "week"}))
+	assert.Empty(t, validateAssignmentQuota(&models.AssignmentQuota{UserID: "u1", MaxAssignments: 0}), "zero removes the quota")
+	assert.NotEmpty(t, validateAssignmentQuota(&models.AssignmentQuota{UserID: "u1", MaxAssignments: -1}))
+	assert.NotEmpty(t, validateAssignmentQuota(&models.AssignmentQuota{UserID: "u1", MaxAssignments: 101}))
+	assert.NotEmpty(t, validateAssignmentQuota(&models.AssignmentQuota{UserID: "u1", MaxAssignments: 3, Period: "month"}))
+}
+
+func TestForceReviewersMiddleware(t *testing.T) {
+	h := &Handler{}
+	var forced []string
+	handler := h.ForceReviewersMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forced = storage.ForcedReviewersFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(header string) *httptest.ResponseRecorder {
+		forced = nil
+		req := httptest.NewRequest(http.MethodPost, "/pullRequest/create", nil)
+		req.Header.Set(HeaderForceReviewers, header)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Ignored when disabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("u2,u3").Code)
+		assert.Nil(t, forced)
+	})
+
+	h.EnableForcedReviewers()
+
+	t.Run("Order kept, duplicates dropped", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(" u3, u2,,u3 ").Code)
+		assert.Equal(t, []string{"u3", "u2"}, forced)
+	})
+
+	t.Run("Too many reviewers", func(t *testing.T) {
+		ids := make([]string, storage.MaxReviewerCount+1)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("u%d", i)
+		}
+		rec := serve(strings.Join(ids, ","))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Nil(t, forced)
+	})
+}
+
+func TestFieldsMiddleware(t *testing.T) {
+	handler := FieldsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			WriteJSON(w, http.StatusNotFound, createErrorResponse("NOT_FOUND", "pr not found"))
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"pr": models.PullRequest{PullRequestID: "pr-1", Status: "OPEN", Reviewers: []string{"u2"}},
+		})
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/pullRequest/get?fields=pr.assigned_reviewers", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "{\"pr\":{\"assigned_reviewers\":[\"u2\"]}}\n", rec.Body.String())
+	assert.Equal(t, strconv.Itoa(rec.Body.Len()), rec.Header().Get("Content-Length"))
+
+	// Ошибки не проецируются
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/pullRequest/get?fields=pr.reviewers&fail=1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"NOT_FOUND"`)
+
+	// Без fields ответ не меняется
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/pullRequest/get", nil))
+	assert.Contains(t, rec.Body.String(), `"status":"OPEN"`)
+}
+
+func TestFieldsMiddlewareExportLiftsWriteDeadline(t *testing.T) {
+	handler := FieldsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream, err := startCSVStream(w, "pull_requests.csv", []string{"pull_request_id"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		// Выгрузка идет дольше WriteTimeout сервера
+		time.Sleep(300 * time.Millisecond)
+		assert.NoError(t, stream.Write([]string{"pr-1"}))
+		assert.Equal(t, "200", finishExport(r, stream, "export", nil))
+	}))
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/pullRequest/export?fields=pull_request_id")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "pull_request_id\npr-1\n", string(body))
+}
+
+func TestVerifyBitbucketSignature(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"eventKey":"pr:opened"}`)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	valid := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, verifyBitbucketSignature(secret, body, valid))
+	assert.False(t, verifyBitbucketSignature([]byte("other"), body, valid))
+	assert.False(t, verifyBitbucketSignature(secret, []byte(`{"eventKey":"pr:merged"}`), valid))
+	assert.False(t, verifyBitbucketSignature(secret, body, strings.TrimPrefix(valid, "sha256=")))
+	assert.False(t, verifyBitbucketSignature(secret, body, "sha256=zz"))
+	assert.False(t, verifyBitbucketSignature(secret, body, ""))
+}
+
+func TestBitbucketWebhookRequest(t *testing.T) {
+	sign := func(secret, body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	send := func(h *Handler, eventKey, signature, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/bitbucket", strings.NewReader(body))
+		req.Header.Set(HeaderBitbucketEvent, eventKey)
+		req.Header.Set(HeaderBitbucketSignature, signature)
+		rec := httptest.NewRecorder()
+		h.BitbucketWebhook(rec, req)
+		return rec
+	}
+
+	// Без секрета прием выключен
+	rec := send(&Handler{}, "diagnostics:ping", sign("s3cret", "{}"), "{}")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	h := &Handler{}
+	h.SetBitbucketWebhookSecret("s3cret")
+
+	rec = send(h, "diagnostics:ping", sign("wrong", "{}"), "{}")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"INVALID_SIGNATURE"`)
+
+	rec = send(h, "diagnostics:ping", sign("s3cret", "{}"), "{}")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	body := `{"eventKey":"pr:opened","pullRequest":{"title":"no id"}}`
+	rec = send(h, "pr:opened", sign("s3cret", body), body)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Идентификатор доставки проверяется до обращения к таблице дедупликации
+	body = `{"eventKey":"pr:merged","pullRequest":{"id":1,"toRef":{"repository":{"slug":"api"}}}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/bitbucket", strings.NewReader(body))
+	req.Header.Set(HeaderBitbucketSignature, sign("s3cret", body))
+	req.Header.Set(HeaderBitbucketDelivery, strings.Repeat("d", storage.MaxDeliveryIDLength+1))
+	rec = httptest.NewRecorder()
+	h.BitbucketWebhook(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), HeaderBitbucketDelivery)
+}
+
+func TestBitbucketEventParsing(t *testing.T) {
+	body := `{
+		"eventKey": "pr:reviewer:updated",
+		"actor": {"name": "Jane Doe", "slug": "jdoe"},
+		"pullRequest": {
+			"id": 42,
+			"title": "PROJ-1 add cache",
+			"author": {"user": {"name": "alice", "slug": "alice"}, "role": "AUTHOR"},
+			"reviewers": [{"user": {"name": "bob", "slug": "bob"}, "role": "REVIEWER"}],
+			"toRef": {"repository": {"slug": "backend", "project": {"key": "PROJ"}}}
+		},
+		"addedReviewers": [{"name": "carol"}],
+		"removedReviewers": [{"name": "bob", "slug": "bob"}]
+	}`
+
+	var event bitbucketEvent
+	assert.NoError(t, json.Unmarshal([]byte(body), &event))
+	assert.Equal(t, "bitbucket:PROJ/backend#42", event.PullRequest.serviceID())
+	assert.Equal(t, "jdoe", event.Actor.externalID())
+	assert.Equal(t, "alice", event.PullRequest.Author.User.externalID())
+	assert.Equal(t, "bob", event.PullRequest.Reviewers[0].User.externalID())
+	// Без slug пользователь сопоставляется по имени
+	assert.Equal(t, "carol", event.AddedReviewers[0].externalID())
+	assert.Equal(t, "bob", event.RemovedReviewers[0].externalID())
+}
+
+func TestValidateExternalIdentity(t *testing.T) {
+	assert.Empty(t, validateExternalIdentity(models.ExternalIdentity{Provider: "bitbucket", ExternalID: "jdoe", UserID: "u1"}))
+	assert.Empty(t, validateExternalIdentity(models.ExternalIdentity{Provider: "bitbucket", ExternalID: "jdoe"}))
+	assert.Equal(t, "provider must be bitbucket", validateExternalIdentity(models.ExternalIdentity{Provider: "gitlab", ExternalID: "jdoe"}))
+	assert.Equal(t, "external_id is required", validateExternalIdentity(models.ExternalIdentity{Provider: "bitbucket"}))
+	assert.Equal(t, "external_id must be at most 255 characters",
+		validateExternalIdentity(models.ExternalIdentity{Provider: "bitbucket", ExternalID: strings.Repeat("x", 256)}))
+}
+
+func TestScopeMiddleware(t *testing.T) {
+	h := &Handler{scopes: DefaultScopePolicy()}
+	handler := AuthContextMiddleware(h.ScopeMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	serve := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	bridge := map[string]string{HeaderAuthUser: "github-bridge", HeaderAuthScopes: "prs:read, prs:write"}
+
+	unscoped := map[string]string{HeaderAuthUser: "u1"}
+	unrestricted := map[string]string{HeaderAuthUser: "u1", HeaderAuthScopes: "*"}
+
+	t.Run("Anonymous and unscoped alike by default", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/team/add", nil).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/team/add", unscoped).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/admin/maintenance", unscoped).Code)
+	})
+
+	t.Run("Explicit unrestricted grant", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/team/add", unrestricted).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/admin/maintenance", unrestricted).Code)
+	})
+
+	t.Run("Token limited to its scopes", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/pullRequest/create", bridge).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/pullRequest/get", bridge).Code)
+
+		rec := serve(http.MethodPost, "/team/add", bridge)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"INSUFFICIENT_SCOPE"`)
+		assert.Contains(t, rec.Body.String(), "teams:write")
+
+		// Предпросмотр настроек ничего не меняет и требует только чтения
+		reader := map[string]string{HeaderAuthUser: "dashboard", HeaderAuthScopes: "teams:read"}
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/team/settings/preview", reader).Code)
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/team/settings/apply", reader).Code)
+
+		// Маршруты без правила доступны только с admin
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/unknown", bridge).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/health", bridge).Code)
+	})
+
+	t.Run("Route override", func(t *testing.T) {
+		h.scopes.Routes = map[string]string{"/team/get": "prs:read"}
+		defer func() { h.scopes.Routes = nil }()
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/team/get", bridge).Code)
+	})
+
+	t.Run("Authentication required", func(t *testing.T) {
+		h.scopes.RequireAuth = true
+		defer func() { h.scopes.RequireAuth = false }()
+
+		rec := serve(http.MethodGet, "/team/get", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"UNAUTHENTICATED"`)
+
+		// Принципалу без scope нужен явный доступ, scope токена по-прежнему ограничивают
+		rec = serve(http.MethodGet, "/team/get", unscoped)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"INSUFFICIENT_SCOPE"`)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/team/get", unrestricted).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/pullRequest/create", bridge).Code)
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/team/add", bridge).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/health/ready", nil).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/webhooks/bitbucket", nil).Code)
+	})
+}
+
+func TestParseRouteScopes(t *testing.T) {
+	routes, err := ParseRouteScopes(" /pullRequest/export=reports:read, /team/get = prs:read ")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"/pullRequest/export": "reports:read", "/team/get": "prs:read"}, routes)
+
+	_, err = ParseRouteScopes("/team/get")
+	assert.Error(t, err)
+	_, err = ParseRouteScopes("team/get=teams:read")
+	assert.Error(t, err)
+	_, err = ParseRouteScopes("/team/get=teams:delete")
+	assert.Error(t, err)
+	_, err = ParseRouteScopes("/team/get=teams:read,/team/get=teams:write")
+	assert.Error(t, err)
+}
+
+func TestRequestWindow(t *testing.T) {
+	var w requestWindow
+	now := time.Date(2024, 3, 4, 9, 10, 30, 0, time.UTC)
+
+	w.record(now.Add(-10*time.Minute), true) // Вне окна
+	w.record(now.Add(-4*time.Minute), false)
+	w.record(now.Add(-time.Minute), true)
+	w.record(now, false)
+
+	total, errors := w.counts(now)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 1, errors)
+
+	total, _ = w.counts(now.Add(10 * time.Minute))
+	assert.Equal(t, 0, total)
+}
+
+func TestStatus(t *testing.T) {
+	h := &Handler{maintenance: &maintenanceMode{}}
+	now := time.Now()
+
+	t.Run("Stale health check", func(t *testing.T) {
+		status := h.serviceStatus(now)
+		assert.Equal(t, "unavailable", status.Status)
+		assert.Equal(t, "unknown", status.Database)
+		assert.Nil(t, status.Scheduler)
+	})
+
+	h.setHealth(true, now)
+	depths := map[string]int{"backend": 3, "frontend": 1}
+	h.queueDepths.Store(&depths)
+
+	t.Run("Healthy", func(t *testing.T) {
+		status := h.serviceStatus(now)
+		assert.Equal(t, "ok", status.Status)
+		assert.Equal(t, "ok", status.Database)
+		assert.Empty(t, status.Problems)
+		assert.Equal(t, depths, status.QueueDepths)
+	})
+
+	t.Run("Maintenance degrades", func(t *testing.T) {
+		h.maintenance.set(models.MaintenanceState{Enabled: true, Message: "migration"})
+		defer h.maintenance.set(models.MaintenanceState{})
+
+		status := h.serviceStatus(now)
+		assert.Equal(t, "degraded", status.Status)
+		assert.Equal(t, []string{"maintenance mode is enabled"}, status.Problems)
+	})
+
+	t.Run("HTML and JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		rec := httptest.NewRecorder()
+		h.Status(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, rec.Body.String(), "<td>backend</td><td>3</td>")
+
+		rec = httptest.NewRecorder()
+		h.Status(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		assert.Contains(t, rec.Header().Get("Content-Type"), "application/json")
+		assert.Contains(t, rec.Body.String(), `"queue_depths":{"backend":3,"frontend":1}`)
+	})
+}
+
+func TestWebhookKeyGrace(t *testing.T) {
+	hours := func(h int) *int { return &h }
+
+	grace, errMsg := webhookKeyGrace(models.RotateWebhookKeyRequest{})
+	assert.Empty(t, errMsg)
+	assert.Equal(t, storage.DefaultWebhookKeyGrace, grace)
+
+	grace, errMsg = webhookKeyGrace(models.RotateWebhookKeyRequest{GraceHours: hours(0)})
+	assert.Empty(t, errMsg)
+	assert.Zero(t, grace)
+
+	_, errMsg = webhookKeyGrace(models.RotateWebhookKeyRequest{GraceHours: hours(-1)})
+	assert.NotEmpty(t, errMsg)
+	_, errMsg = webhookKeyGrace(models.RotateWebhookKeyRequest{GraceHours: hours(169)})
+	assert.NotEmpty(t, errMsg)
+}
+
+func TestValidateBulkLabelRequest(t *testing.T) {
+	valid := models.BulkLabelRequest{Action: "add", Label: " Security ", PullRequestIDs: []string{"pr-1", "pr-2"}}
+	assert.Empty(t, validateBulkLabelRequest(valid))
+
+	tests := []struct {
+		name string
+		req  models.BulkLabelRequest
+		want string
+	}{
+		{"unknown action", models.BulkLabelRequest{Action: "toggle", Label: "a", PullRequestIDs: []string{"pr-1"}}, "action must be add or remove"},
+		{"empty label", models.BulkLabelRequest{Action: "remove", Label: "  ", PullRequestIDs: []string{"pr-1"}}, "label is required"},
+		{"long label", models.BulkLabelRequest{Action: "add", Label: strings.Repeat("x", 65), PullRequestIDs: []string{"pr-1"}}, "label must be at most 64 characters"},
+		{"no PRs", models.BulkLabelRequest{Action: "add", Label: "a"}, "pull_request_ids must not be empty"},
+		{"too many PRs", models.BulkLabelRequest{Action: "add", Label: "a", PullRequestIDs: make([]string, 501)}, "pull_request_ids must contain at most 500 pull requests"},
+		{"empty id", models.BulkLabelRequest{Action: "add", Label: "a", PullRequestIDs: []string{"pr-1", ""}}, "pull_request_ids[1] must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validateBulkLabelRequest(tt.req))
+		})
+	}
+}
+
+func TestValidateNotificationRoute(t *testing.T) {
+	route := models.NotificationRoute{TeamName: "backend", Name: "security", Condition: `"security" in labels`, Channel: "slack:#security-guild"}
+	assert.Empty(t, validateNotificationRoute(route))
+
+	// Пустой канал удаляет маршрут, условие не нужно
+	assert.Empty(t, validateNotificationRoute(models.NotificationRoute{TeamName: "backend", Name: "security"}))
+
+	assert.NotEmpty(t, validateNotificationRoute(models.NotificationRoute{TeamName: "backend", Channel: "slack:#x", Condition: "true"}))
+	assert.Equal(t, "condition is required", validateNotificationRoute(models.NotificationRoute{TeamName: "backend", Name: "x", Channel: "slack:#x"}))
+	assert.Contains(t, validateNotificationRoute(models.NotificationRoute{TeamName: "backend", Name: "x", Channel: "slack:#x", Condition: "labels"}), "condition:")
+	assert.Contains(t, validateNotificationRoute(models.NotificationRoute{TeamName: "backend", Name: "x", Channel: "slack:#x", Condition: `load > 1`}), "condition:")
+}
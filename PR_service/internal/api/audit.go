@@ -0,0 +1,165 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// SearchAuditLog ищет записи журнала аудита по автору, типу сущности, действию и периоду
+func (h *Handler) SearchAuditLog(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	filter, errMsg := parseAuditFilter(r.URL.Query())
+	if errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_AUDIT_FILTER")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	events, err := h.store.SearchAuditLog(r.Context(), filter)
+	if err != nil {
+		status = "500"
+		log.Printf("SearchAuditLog error: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"events": events,
+	})
+}
+
+// ExportAuditLog выгружает записи журнала аудита в CSV с теми же фильтрами, что и поиск.
+// Без limit выгружаются все подходящие записи; они стримятся клиенту по мере чтения из БД.
+func (h *Handler) ExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	filter, errMsg := parseAuditFilter(r.URL.Query())
+	if errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_AUDIT_FILTER")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	stream, err := startCSVStream(w, "audit_log.csv", auditCSVHeader)
+	if err != nil {
+		status = "500"
+		log.Printf("ExportAuditLog error: %v", err)
+		return
+	}
+
+	err = h.store.StreamAuditLog(r.Context(), filter, func(e models.AuditEvent) error {
+		return stream.Write(auditCSVRecord(e))
+	})
+	status = finishExport(r, stream, "ExportAuditLog", err)
+}
+
+// GetAuditDiff показывает, что изменила запись аудита: изменения полей сущности
+// между снимками до и после
+func (h *Handler) GetAuditDiff(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id < 1 {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_AUDIT_ID")
+		}
+		writeError(w, http.StatusBadRequest, "id query parameter must be a positive integer")
+		return
+	}
+
+	diff, err := h.store.GetAuditDiff(r.Context(), id)
+	if err != nil {
+		switch err.Error() {
+		case "audit entry not found":
+			status = "404"
+			if h.metrics != nil {
+				h.metrics.IncBusinessError("NOT_FOUND")
+			}
+			WriteJSON(w, http.StatusNotFound, createErrorResponse("NOT_FOUND", err.Error()))
+		case "audit entry has no snapshots":
+			status = "409"
+			if h.metrics != nil {
+				h.metrics.IncBusinessError("NO_AUDIT_SNAPSHOTS")
+			}
+			WriteJSON(w, http.StatusConflict, createErrorResponse("NO_AUDIT_SNAPSHOTS", err.Error()))
+		default:
+			status = "500"
+			log.Printf("GetAuditDiff error: %v", err)
+			writeError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, diff)
+}
+
+// parseAuditFilter разбирает параметры поиска по журналу аудита.
+// Границы периода from/to задаются в RFC3339.
+func parseAuditFilter(query url.Values) (models.AuditFilter, string) {
+	filter := models.AuditFilter{
+		Actor:      query.Get("actor"),
+		EntityType: query.Get("entity_type"),
+		Action:     query.Get("action"),
+	}
+
+	var errMsg string
+	if filter.From, filter.To, errMsg = parseTimeRange(query); errMsg != "" {
+		return filter, errMsg
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > storage.MaxAuditSearchLimit {
+			return filter, fmt.Sprintf("limit must be between 1 and %d", storage.MaxAuditSearchLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, ""
+}
+
+var auditCSVHeader = []string{"id", "created_at", "actor", "request_id", "entity_type", "entity_id", "action", "details"}
+
+// auditCSVRecord - строка выгрузки журнала аудита
+func auditCSVRecord(e models.AuditEvent) []string {
+	return []string{
+		strconv.FormatInt(e.ID, 10),
+		e.CreatedAt.UTC().Format(time.RFC3339),
+		e.Actor,
+		e.RequestID,
+		e.EntityType,
+		e.EntityID,
+		e.Action,
+		string(e.Details),
+	}
+}
@@ -0,0 +1,420 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// maxWebhookBodyBytes - предел размера тела вебхука
+const maxWebhookBodyBytes = 1 << 20
+
+// Заголовки вебхуков Bitbucket Server
+const (
+	HeaderBitbucketEvent     = "X-Event-Key"
+	HeaderBitbucketSignature = "X-Hub-Signature"
+	HeaderBitbucketDelivery  = "X-Request-Id" // Идентификатор доставки, одинаковый у повторов
+)
+
+// duplicateDeliveryReason - причина пропуска повторной доставки вебхука
+const duplicateDeliveryReason = "duplicate ignored"
+
+// bitbucketUser - пользователь Bitbucket Server; в сервисе сопоставляется по slug
+type bitbucketUser struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// externalID возвращает идентификатор пользователя для external_identities
+func (u bitbucketUser) externalID() string {
+	if u.Slug != "" {
+		return u.Slug
+	}
+	return u.Name
+}
+
+type bitbucketParticipant struct {
+	User bitbucketUser `json:"user"`
+}
+
+type bitbucketPullRequest struct {
+	ID        int64                  `json:"id"`
+	Title     string                 `json:"title"`
+	Author    bitbucketParticipant   `json:"author"`
+	Reviewers []bitbucketParticipant `json:"reviewers"`
+	ToRef     struct {
+		Repository struct {
+			Slug    string `json:"slug"`
+			Project struct {
+				Key string `json:"key"`
+			} `json:"project"`
+		} `json:"repository"`
+	} `json:"toRef"`
+}
+
+// bitbucketEvent - тело вебхука Bitbucket Server о pull request
+type bitbucketEvent struct {
+	EventKey         string               `json:"eventKey"`
+	Actor            bitbucketUser        `json:"actor"`
+	PullRequest      bitbucketPullRequest `json:"pullRequest"`
+	AddedReviewers   []bitbucketUser      `json:"addedReviewers"`
+	RemovedReviewers []bitbucketUser      `json:"removedReviewers"`
+}
+
+// serviceID возвращает идентификатор PR в сервисе: PR Bitbucket уникален в репозитории
+func (pr bitbucketPullRequest) serviceID() string {
+	repo := pr.ToRef.Repository
+	return fmt.Sprintf("bitbucket:%s/%s#%d", repo.Project.Key, repo.Slug, pr.ID)
+}
+
+// SetWebhookDedupeTTL задает, сколько помнить идентификаторы доставок вебхуков
+func (h *Handler) SetWebhookDedupeTTL(ttl time.Duration) {
+	h.webhookDedupeTTL = ttl
+}
+
+// SetBitbucketWebhookSecret включает прием вебхуков Bitbucket Server с подписью этим секретом
+func (h *Handler) SetBitbucketWebhookSecret(secret string) {
+	h.bitbucketSecret = []byte(secret)
+}
+
+// verifyBitbucketSignature проверяет подпись тела вебхука: "sha256=" + hex(HMAC-SHA256(secret, body))
+func verifyBitbucketSignature(secret, body []byte, header string) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// BitbucketWebhook принимает вебхуки Bitbucket Server и повторяет события PR в сервисе:
+// pr:opened создает PR, pr:merged и pr:declined закрывают его, pr:reviewer:updated
+// добавляет и снимает ревьюеров. Пользователи Bitbucket сопоставляются через
+// external_identities; PR, созданные до подключения вебхука, пропускаются.
+func (h *Handler) BitbucketWebhook(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	if len(h.bitbucketSecret) == 0 {
+		status = "404"
+		WriteJSON(w, http.StatusNotFound, createErrorResponse("NOT_FOUND", "bitbucket webhooks are disabled"))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if !verifyBitbucketSignature(h.bitbucketSecret, body, r.Header.Get(HeaderBitbucketSignature)) {
+		status = "401"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_SIGNATURE")
+		}
+		WriteJSON(w, http.StatusUnauthorized, createErrorResponse("INVALID_SIGNATURE", "webhook signature is invalid"))
+		return
+	}
+
+	eventKey := r.Header.Get(HeaderBitbucketEvent)
+	if eventKey == "diagnostics:ping" {
+		WriteJSON(w, http.StatusOK, map[string]string{"event": eventKey, "status": "ok"})
+		return
+	}
+
+	var event bitbucketEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if eventKey == "" {
+		eventKey = event.EventKey
+	}
+	if event.PullRequest.ID == 0 || event.PullRequest.ToRef.Repository.Slug == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		writeError(w, http.StatusBadRequest, "pullRequest.id and pullRequest.toRef.repository are required")
+		return
+	}
+
+	deliveryID := r.Header.Get(HeaderBitbucketDelivery)
+	if len(deliveryID) > storage.MaxDeliveryIDLength {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", HeaderBitbucketDelivery, storage.MaxDeliveryIDLength))
+		return
+	}
+
+	ctx := r.Context()
+	if _, ok := authctx.PrincipalFrom(ctx); !ok {
+		ctx = authctx.WithPrincipal(ctx, authctx.Principal{ID: storage.ProviderBitbucket + ":" + event.Actor.externalID()})
+	}
+
+	// Повторная доставка не должна второй раз создать или смержить PR.
+	// Доставки без идентификатора обрабатываются всегда.
+	if deliveryID != "" {
+		claimed, err := h.store.ClaimWebhookDelivery(ctx, storage.ProviderBitbucket, deliveryID, eventKey, h.webhookDedupeTTL)
+		if err != nil {
+			status = h.handleBitbucketError(w, err)
+			return
+		}
+		if !claimed {
+			WriteJSON(w, http.StatusOK, &bitbucketResult{
+				Event:         eventKey,
+				PullRequestID: event.PullRequest.serviceID(),
+				Status:        "ignored",
+				Reason:        duplicateDeliveryReason,
+			})
+			return
+		}
+	}
+
+	result, err := h.applyBitbucketEvent(ctx, eventKey, event)
+	if err != nil {
+		// Событие не применено - повтор Bitbucket должен обработать его заново.
+		// Отметка снимается и после таймаута запроса.
+		if deliveryID != "" {
+			if err := h.store.ReleaseWebhookDelivery(context.WithoutCancel(ctx), storage.ProviderBitbucket, deliveryID); err != nil {
+				log.Printf("BitbucketWebhook: delivery %s not released: %v", deliveryID, err)
+			}
+		}
+		status = h.handleBitbucketError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, result)
+}
+
+// bitbucketResult - итог обработки вебхука
+type bitbucketResult struct {
+	Event         string   `json:"event"`
+	PullRequestID string   `json:"pull_request_id"`
+	Status        string   `json:"status"` // applied|ignored
+	Reason        string   `json:"reason,omitempty"`
+	Unmapped      []string `json:"unmapped_users,omitempty"` // Пользователи Bitbucket без сопоставления
+}
+
+// applyBitbucketEvent выполняет операцию сервиса, соответствующую событию
+func (h *Handler) applyBitbucketEvent(ctx context.Context, eventKey string, event bitbucketEvent) (*bitbucketResult, error) {
+	prID := event.PullRequest.serviceID()
+	result := &bitbucketResult{Event: eventKey, PullRequestID: prID, Status: "applied"}
+
+	switch eventKey {
+	case "pr:opened":
+		return h.syncOpenedPR(ctx, event, result)
+
+	case "pr:merged":
+		pr, err := h.store.SyncMergedPR(ctx, prID)
+		if err != nil {
+			return ignoreMissingPR(result, err)
+		}
+		if h.metrics != nil {
+			h.metrics.IncPRMerged(h.metricsTeam(ctx, pr.AuthorID))
+		}
+
+	case "pr:declined":
+		if _, err := h.store.DeclinePR(ctx, prID); err != nil {
+			return ignoreMissingPR(result, err)
+		}
+
+	case "pr:reviewer:updated":
+		ids := make([]string, 0, len(event.AddedReviewers)+len(event.RemovedReviewers))
+		for _, u := range event.AddedReviewers {
+			ids = append(ids, u.externalID())
+		}
+		for _, u := range event.RemovedReviewers {
+			ids = append(ids, u.externalID())
+		}
+		users, err := h.store.ResolveExternalIdentities(ctx, storage.ProviderBitbucket, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range event.RemovedReviewers {
+			userID, ok := users[u.externalID()]
+			if !ok {
+				result.Unmapped = append(result.Unmapped, u.externalID())
+				continue
+			}
+			if _, err := h.store.RemoveReviewer(ctx, prID, userID); err != nil {
+				var conflict *storage.PRConflictError
+				if errors.As(err, &conflict) {
+					log.Printf("BitbucketWebhook: reviewer %s not removed from %s: %v", userID, prID, err)
+					continue
+				}
+				return ignoreMissingPR(result, err)
+			}
+		}
+		if err := h.syncAddedReviewers(ctx, prID, event.AddedReviewers, users, result); err != nil {
+			return ignoreMissingPR(result, err)
+		}
+
+	default:
+		result.Status = "ignored"
+		result.Reason = "unsupported event"
+	}
+	return result, nil
+}
+
+// syncOpenedPR создает PR от сопоставленного автора; ревьюеры, уже выбранные в Bitbucket,
+// добавляются к назначенным сервисом. Повторная доставка события ничего не меняет.
+func (h *Handler) syncOpenedPR(ctx context.Context, event bitbucketEvent, result *bitbucketResult) (*bitbucketResult, error) {
+	ids := []string{event.PullRequest.Author.User.externalID()}
+	for _, p := range event.PullRequest.Reviewers {
+		ids = append(ids, p.User.externalID())
+	}
+	users, err := h.store.ResolveExternalIdentities(ctx, storage.ProviderBitbucket, ids)
+	if err != nil {
+		return nil, err
+	}
+	authorID, ok := users[ids[0]]
+	if !ok {
+		return nil, &unmappedIdentityError{ExternalID: ids[0]}
+	}
+
+	name := event.PullRequest.Title
+	if name == "" {
+		name = result.PullRequestID
+	}
+	pr, err := h.store.CreatePR(ctx, models.CreatePRRequest{
+		PullRequestID:   result.PullRequestID,
+		PullRequestName: name,
+		AuthorID:        authorID,
+		SkipPolicies:    true,
+	})
+	if err != nil {
+		if err.Error() == "pr already exists" {
+			result.Status = "ignored"
+			result.Reason = err.Error()
+			return result, nil
+		}
+		return nil, err
+	}
+	if h.metrics != nil {
+		teamName := h.metricsTeam(ctx, authorID)
+		h.metrics.IncPRCreated(teamName)
+		h.metrics.ObserveReviewersAssigned(teamName, len(pr.Reviewers))
+	}
+
+	reviewers := make([]bitbucketUser, 0, len(event.PullRequest.Reviewers))
+	for _, p := range event.PullRequest.Reviewers {
+		reviewers = append(reviewers, p.User)
+	}
+	if err := h.syncAddedReviewers(ctx, result.PullRequestID, reviewers, users, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// syncAddedReviewers добавляет PR ревьюеров, выбранных в Bitbucket. Ревьюер, которого
+// сервис не может назначить (неактивен, уже назначен, лимит), пропускается с записью в лог:
+// в Bitbucket он уже стоит на PR, и откатывать событие из-за него не нужно.
+func (h *Handler) syncAddedReviewers(ctx context.Context, prID string, reviewers []bitbucketUser, users map[string]string, result *bitbucketResult) error {
+	for _, u := range reviewers {
+		userID, ok := users[u.externalID()]
+		if !ok {
+			result.Unmapped = append(result.Unmapped, u.externalID())
+			continue
+		}
+		if _, _, err := h.store.AddReviewer(ctx, prID, userID); err != nil {
+			if _, known := addReviewerErrors[err.Error()]; known && err.Error() != "pr not found" {
+				log.Printf("BitbucketWebhook: reviewer %s not added to %s: %v", userID, prID, err)
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ignoreMissingPR пропускает события о PR, которых нет в сервисе: они открыты до подключения вебхука
+func ignoreMissingPR(result *bitbucketResult, err error) (*bitbucketResult, error) {
+	if err.Error() != "pr not found" {
+		return nil, err
+	}
+	result.Status = "ignored"
+	result.Reason = err.Error()
+	return result, nil
+}
+
+// metricsTeam возвращает команду автора для метрик
+func (h *Handler) metricsTeam(ctx context.Context, authorID string) string {
+	if teamName := h.getAuthorTeam(ctx, authorID); teamName != "" {
+		return teamName
+	}
+	return "unknown"
+}
+
+// unmappedIdentityError - автор события Bitbucket не сопоставлен пользователю сервиса
+type unmappedIdentityError struct {
+	ExternalID string
+}
+
+func (e *unmappedIdentityError) Error() string {
+	return fmt.Sprintf("bitbucket user %s is not mapped to a service user", e.ExternalID)
+}
+
+// handleBitbucketError пишет ответ об ошибке и возвращает HTTP статус для метрик
+func (h *Handler) handleBitbucketError(w http.ResponseWriter, err error) string {
+	log.Printf("BitbucketWebhook error: %v", err)
+
+	var unmapped *unmappedIdentityError
+	var conflict *storage.PRConflictError
+	switch {
+	case errors.As(err, &unmapped):
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("IDENTITY_NOT_MAPPED")
+		}
+		WriteJSON(w, http.StatusUnprocessableEntity, createErrorResponse("IDENTITY_NOT_MAPPED", err.Error()))
+		return "422"
+	case errors.As(err, &conflict):
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("PR_CONFLICT")
+		}
+		errorResp := createErrorResponse("PR_CONFLICT", err.Error())
+		errorResp.Data = createPRResponse(*conflict.PR)
+		WriteJSON(w, http.StatusConflict, errorResp)
+		return "409"
+	case err.Error() == "author not found" || err.Error() == "author is not in any team":
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("AUTHOR_NOT_FOUND")
+		}
+		WriteJSON(w, http.StatusUnprocessableEntity, createErrorResponse("AUTHOR_NOT_FOUND", err.Error()))
+		return "422"
+	default:
+		h.handleStorageError(w, err, "BitbucketWebhook")
+		return "500"
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+// SetBorrowPool задает команды, у которых команда может занимать ревьюеров
+func (h *Handler) SetBorrowPool(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.SetBorrowPoolRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateRequiredFields(map[string]string{
+		"team_name": req.TeamName,
+	}); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if errMsg := validateBorrowLenders(req.TeamName, req.Lenders); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_BORROW_POOL")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if err := h.store.SetBorrowPool(r.Context(), req.TeamName, req.Lenders); err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "SetBorrowPool")
+		return
+	}
+
+	lenders, err := h.store.GetBorrowPool(r.Context(), req.TeamName)
+	if err != nil {
+		status = "500"
+		h.handleStorageError(w, err, "SetBorrowPool")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"team_name": req.TeamName,
+		"lenders":   lenders,
+	})
+}
+
+// BorrowingReport возвращает баланс заимствований ревьюеров между командами
+func (h *Handler) BorrowingReport(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	teamName := r.URL.Query().Get("team_name")
+
+	balances, err := h.store.GetBorrowingReport(r.Context(), teamName)
+	if err != nil {
+		status = "500"
+		log.Printf("BorrowingReport error: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"balances": balances,
+	})
+}
+
+// validateBorrowLenders проверяет список команд-доноров
+func validateBorrowLenders(teamName string, lenders []models.BorrowLender) string {
+	seen := make(map[string]bool, len(lenders))
+	for i, l := range lenders {
+		if l.TeamName == "" {
+			return fmt.Sprintf("lenders[%d].team_name is required", i)
+		}
+		if l.TeamName == teamName {
+			return fmt.Sprintf("lenders[%d]: team cannot borrow from itself", i)
+		}
+		if seen[l.TeamName] {
+			return fmt.Sprintf("lenders[%d]: duplicate team %s", i, l.TeamName)
+		}
+		if l.MaxNetBorrowed < 0 {
+			return fmt.Sprintf("lenders[%d].max_net_borrowed must not be negative", i)
+		}
+		seen[l.TeamName] = true
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// PreviewCandidates показывает, кого из участников команды можно назначить ревьюером
+// автоматически, и причину, если нельзя
+func (h *Handler) PreviewCandidates(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_TEAM_NAME")
+		}
+		writeError(w, http.StatusBadRequest, "team_name query parameter is required")
+		return
+	}
+
+	candidates, err := h.store.PreviewCandidates(r.Context(), teamName)
+	if err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "PreviewCandidates")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"team_name":  teamName,
+		"candidates": candidates,
+	})
+}
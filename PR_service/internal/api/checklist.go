@@ -0,0 +1,317 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+// GetChecklist возвращает шаблон чек-листа ревью команды
+func (h *Handler) GetChecklist(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_TEAM_NAME")
+		}
+		writeError(w, http.StatusBadRequest, "team_name query parameter is required")
+		return
+	}
+
+	items, err := h.store.GetChecklist(r.Context(), teamName)
+	if err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "GetChecklist")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"team_name": teamName,
+		"items":     items,
+	})
+}
+
+// AddChecklistItem добавляет пункт в чек-лист команды
+func (h *Handler) AddChecklistItem(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "201"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req struct {
+		TeamName string `json:"team_name"`
+		Text     string `json:"text"`
+	}
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateRequiredFields(map[string]string{
+		"team_name": req.TeamName,
+		"text":      req.Text,
+	}); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	item, err := h.store.AddChecklistItem(r.Context(), req.TeamName, req.Text)
+	if err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "AddChecklistItem")
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, map[string]interface{}{
+		"item": item,
+	})
+}
+
+// UpdateChecklistItem меняет текст пункта чек-листа
+func (h *Handler) UpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req struct {
+		ItemID int64  `json:"item_id"`
+		Text   string `json:"text"`
+	}
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if req.ItemID <= 0 || req.Text == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, "item_id and text are required")
+		return
+	}
+
+	item, err := h.store.UpdateChecklistItem(r.Context(), req.ItemID, req.Text)
+	if err != nil {
+		status = "500"
+		if err.Error() == "checklist item not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "UpdateChecklistItem")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"item": item,
+	})
+}
+
+// DeleteChecklistItem убирает пункт из чек-листа команды
+func (h *Handler) DeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req struct {
+		ItemID int64 `json:"item_id"`
+	}
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if req.ItemID <= 0 {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, "item_id is required")
+		return
+	}
+
+	if err := h.store.DeleteChecklistItem(r.Context(), req.ItemID); err != nil {
+		status = "500"
+		if err.Error() == "checklist item not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "DeleteChecklistItem")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, "checklist item deleted")
+}
+
+// ApprovePR фиксирует одобрение PR ревьюером с отметками чек-листа
+func (h *Handler) ApprovePR(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.ApprovePRRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateRequiredFields(map[string]string{
+		"pull_request_id": req.PullRequestID,
+		"user_id":         req.UserID,
+	}); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	detail, err := h.store.ApprovePR(r.Context(), req.PullRequestID, req.UserID, req.CheckedItems)
+	if err != nil {
+		status = "500"
+		h.handleApproveError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pr": detail,
+	})
+}
+
+// GetPR возвращает PR с одобрениями и чек-листом
+func (h *Handler) GetPR(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_PR_ID")
+		}
+		writeError(w, http.StatusBadRequest, "pull_request_id query parameter is required")
+		return
+	}
+
+	detail, err := h.store.GetPRDetail(r.Context(), prID)
+	if err != nil {
+		status = "500"
+		if err.Error() == "pr not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "GetPR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pr": detail,
+	})
+}
+
+// ChecklistReport возвращает процент выполнения пунктов чек-листа команды
+func (h *Handler) ChecklistReport(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_TEAM_NAME")
+		}
+		writeError(w, http.StatusBadRequest, "team_name query parameter is required")
+		return
+	}
+
+	report, err := h.store.GetChecklistReport(r.Context(), teamName)
+	if err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "ChecklistReport")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, report)
+}
+
+func (h *Handler) handleApproveError(w http.ResponseWriter, err error) {
+	log.Printf("ApprovePR error: %v", err)
+
+	errorResp := models.ErrorResponse{}
+	errorResp.Error.Message = err.Error()
+
+	switch err.Error() {
+	case "pr not found", "checklist item not found":
+		errorResp.Error.Code = "NOT_FOUND"
+		WriteJSON(w, http.StatusNotFound, errorResp)
+	case "pr already merged":
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("PR_ALREADY_MERGED")
+		}
+		errorResp.Error.Code = "PR_MERGED"
+		WriteJSON(w, http.StatusConflict, errorResp)
+	case "reviewer is not assigned to this PR":
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("REVIEWER_NOT_ASSIGNED")
+		}
+		errorResp.Error.Code = "NOT_ASSIGNED"
+		WriteJSON(w, http.StatusConflict, errorResp)
+	default:
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("APPROVE_ERROR")
+		}
+		errorResp.Error.Code = "INTERNAL_ERROR"
+		WriteJSON(w, http.StatusInternalServerError, errorResp)
+	}
+}
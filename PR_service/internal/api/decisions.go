@@ -0,0 +1,73 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetAssignmentDecisions возвращает журнал решений о назначении ревьюеров для PR
+func (h *Handler) GetAssignmentDecisions(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_PR_ID")
+		}
+		writeError(w, http.StatusBadRequest, "pull_request_id query parameter is required")
+		return
+	}
+
+	decisions, err := h.store.GetAssignmentDecisions(r.Context(), prID)
+	if err != nil {
+		status = "500"
+		log.Printf("GetAssignmentDecisions error: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pull_request_id": prID,
+		"decisions":       decisions,
+	})
+}
+
+// ReplayAssignmentDecision воспроизводит выбор ревьюеров по сохраненным входным данным решения
+func (h *Handler) ReplayAssignmentDecision(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	decisionID, err := strconv.ParseInt(r.URL.Query().Get("decision_id"), 10, 64)
+	if err != nil || decisionID <= 0 {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_DECISION_ID")
+		}
+		writeError(w, http.StatusBadRequest, "decision_id query parameter must be a positive integer")
+		return
+	}
+
+	replay, err := h.store.ReplayAssignmentDecision(r.Context(), decisionID)
+	if err != nil {
+		status = "500"
+		if err.Error() == "decision not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "ReplayAssignmentDecision")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, replay)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+// exportFlushRows - через сколько строк выгрузки накопленный CSV отправляется клиенту
+const exportFlushRows = 500
+
+// exportPaths - выгрузки стримятся дольше RequestTimeout и прерываются только отключением клиента
+var exportPaths = map[string]bool{
+	"/audit/export":       true,
+	"/pullRequest/export": true,
+}
+
+// csvStream пишет CSV в ответ частями, сбрасывая буфер клиенту каждые exportFlushRows строк
+type csvStream struct {
+	cw   *csv.Writer
+	rc   *http.ResponseController
+	rows int
+}
+
+// startCSVStream отправляет заголовки ответа и строку заголовка CSV.
+// После этого статус ответа изменить уже нельзя.
+func startCSVStream(w http.ResponseWriter, filename string, header []string) (*csvStream, error) {
+	rc := http.NewResponseController(w)
+	// Выгрузка может идти дольше WriteTimeout сервера
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, err
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	s := &csvStream{cw: csv.NewWriter(w), rc: rc}
+	if err := s.cw.Write(header); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *csvStream) Write(record []string) error {
+	if err := s.cw.Write(record); err != nil {
+		return err
+	}
+	s.rows++
+	if s.rows%exportFlushRows == 0 {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Flush отправляет накопленные строки клиенту
+func (s *csvStream) Flush() error {
+	s.cw.Flush()
+	if err := s.cw.Error(); err != nil {
+		return err
+	}
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// finishExport завершает выгрузку. Если чтение оборвалось после отправки заголовков,
+// соединение разрывается, чтобы клиент не принял неполный файл за целый.
+func finishExport(r *http.Request, s *csvStream, name string, err error) string {
+	if err == nil {
+		err = s.Flush()
+	}
+	if err == nil {
+		log.Printf("%s: streamed %d rows", name, s.rows)
+		return "200"
+	}
+
+	if errors.Is(r.Context().Err(), context.Canceled) {
+		log.Printf("%s: client disconnected after %d rows", name, s.rows)
+		return "499"
+	}
+	log.Printf("%s error after %d rows: %v", name, s.rows, err)
+	panic(http.ErrAbortHandler)
+}
+
+// ExportPullRequests выгружает PR в CSV с фильтрами по статусу, команде автора и периоду создания
+func (h *Handler) ExportPullRequests(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	filter, errMsg := parsePRExportFilter(r.URL.Query())
+	if errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_EXPORT_FILTER")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	stream, err := startCSVStream(w, "pull_requests.csv", prCSVHeader)
+	if err != nil {
+		status = "500"
+		log.Printf("ExportPullRequests error: %v", err)
+		return
+	}
+
+	err = h.store.StreamPullRequests(r.Context(), filter, func(pr models.PullRequest) error {
+		return stream.Write(prCSVRecord(pr))
+	})
+	status = finishExport(r, stream, "ExportPullRequests", err)
+}
+
+var prCSVHeader = []string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "merged_at", "reviewers"}
+
+// prCSVRecord - строка выгрузки PR; ревьюеры разделены ";"
+func prCSVRecord(pr models.PullRequest) []string {
+	mergedAt := ""
+	if pr.MergedAt != nil {
+		mergedAt = *pr.MergedAt
+	}
+	return []string{
+		pr.PullRequestID,
+		pr.PullRequestName,
+		pr.AuthorID,
+		pr.Status,
+		pr.CreatedAt.UTC().Format(time.RFC3339),
+		mergedAt,
+		strings.Join(pr.Reviewers, ";"),
+	}
+}
+
+// parsePRExportFilter разбирает параметры выгрузки PR.
+// Границы периода from/to задаются в RFC3339.
+func parsePRExportFilter(query url.Values) (models.PullRequestExportFilter, string) {
+	filter := models.PullRequestExportFilter{
+		Status:   query.Get("status"),
+		TeamName: query.Get("team_name"),
+	}
+	switch filter.Status {
+	case "", "OPEN", "MERGED", "DECLINED":
+	default:
+		return filter, "status must be OPEN, MERGED or DECLINED"
+	}
+
+	var errMsg string
+	filter.From, filter.To, errMsg = parseTimeRange(query)
+	return filter, errMsg
+}
+
+// parseTimeRange разбирает границы периода from/to в RFC3339
+func parseTimeRange(query url.Values) (from, to *time.Time, errMsg string) {
+	for _, p := range []struct {
+		name string
+		dest **time.Time
+	}{
+		{"from", &from},
+		{"to", &to},
+	} {
+		raw := query.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, nil, fmt.Sprintf("%s must be an RFC3339 timestamp", p.name)
+		}
+		*p.dest = &t
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, "from must be before to"
+	}
+	return from, to, ""
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// fieldTree - выбранные поля ответа: ключ - имя поля, значение - выбранные вложенные поля
+// (пустое дерево - поле целиком)
+type fieldTree map[string]fieldTree
+
+// parseFields разбирает параметр fields: поля через запятую, вложенные через точку,
+// например "pr.pull_request_id,pr.assigned_reviewers"
+func parseFields(raw string) fieldTree {
+	tree := fieldTree{}
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		node := tree
+		for _, name := range strings.Split(field, ".") {
+			if name == "" {
+				break
+			}
+			child, ok := node[name]
+			if !ok {
+				child = fieldTree{}
+				node[name] = child
+			}
+			node = child
+		}
+	}
+	return tree
+}
+
+// projectFields оставляет в значении ответа только выбранные поля. Выбор применяется
+// к каждому элементу массива; поля, которых нет в ответе, пропускаются.
+func projectFields(value interface{}, tree fieldTree) interface{} {
+	if len(tree) == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		projected := make(map[string]interface{}, len(tree))
+		for name, sub := range tree {
+			if field, ok := v[name]; ok {
+				projected[name] = projectFields(field, sub)
+			}
+		}
+		return projected
+	case []interface{}:
+		projected := make([]interface{}, len(v))
+		for i, item := range v {
+			projected[i] = projectFields(item, tree)
+		}
+		return projected
+	default:
+		return value
+	}
+}
+
+// FieldsMiddleware поддерживает частичные ответы GET-запросов (?fields=pr.assigned_reviewers):
+// успешный JSON-ответ хендлера проецируется на выбранные поля. Ошибки и ответы
+// в других форматах (выгрузки CSV) передаются без изменений.
+func FieldsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := r.URL.Query().Get("fields")
+		if r.Method != http.MethodGet || raw == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tree := parseFields(raw)
+		if len(tree) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fw := &fieldsWriter{ResponseWriter: w}
+		next.ServeHTTP(fw, r)
+		if fw.buffering {
+			fw.flushProjected(tree)
+		}
+	})
+}
+
+// fieldsWriter буферизует успешный JSON-ответ, чтобы спроецировать его после хендлера
+type fieldsWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	buffering   bool
+	buf         bytes.Buffer
+}
+
+func (fw *fieldsWriter) WriteHeader(status int) {
+	if fw.wroteHeader {
+		return
+	}
+	fw.wroteHeader = true
+	fw.status = status
+
+	contentType := fw.Header().Get("Content-Type")
+	if status >= 200 && status < 300 && strings.HasPrefix(contentType, "application/json") {
+		fw.buffering = true
+		return
+	}
+	fw.ResponseWriter.WriteHeader(status)
+}
+
+func (fw *fieldsWriter) Write(p []byte) (int, error) {
+	if !fw.wroteHeader {
+		fw.WriteHeader(http.StatusOK)
+	}
+	if fw.buffering {
+		return fw.buf.Write(p)
+	}
+	return fw.ResponseWriter.Write(p)
+}
+
+// Flush нужен выгрузкам; буферизованный JSON отдается целиком в конце запроса
+func (fw *fieldsWriter) Flush() {
+	if fw.buffering {
+		return
+	}
+	if f, ok := fw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap дает http.ResponseController доступ к исходному ответу, например
+// чтобы выгрузка сняла WriteTimeout сервера через SetWriteDeadline
+func (fw *fieldsWriter) Unwrap() http.ResponseWriter {
+	return fw.ResponseWriter
+}
+
+func (fw *fieldsWriter) flushProjected(tree fieldTree) {
+	body := fw.buf.Bytes()
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // Без потери точности больших чисел
+	if err := decoder.Decode(&value); err == nil {
+		if projected, err := json.Marshal(projectFields(value, tree)); err == nil {
+			body = append(projected, '\n')
+		} else {
+			log.Printf("fields projection error: %v", err)
+		}
+	}
+
+	fw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	fw.ResponseWriter.WriteHeader(fw.status)
+	if _, err := fw.ResponseWriter.Write(body); err != nil {
+		log.Printf("JSON write error: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// SetFocusWindows задает еженедельное расписание окон фокуса пользователя
+func (h *Handler) SetFocusWindows(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.SetFocusWindowsRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateRequiredFields(map[string]string{
+		"user_id": req.UserID,
+	}); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if errMsg := validateFocusWindows(req.Windows); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_FOCUS_WINDOW")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if err := h.store.SetFocusWindows(r.Context(), req.UserID, req.Windows); err != nil {
+		status = "500"
+		if err.Error() == "user not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "SetFocusWindows")
+		return
+	}
+
+	windows, err := h.store.GetFocusWindows(r.Context(), req.UserID)
+	if err != nil {
+		status = "500"
+		h.handleStorageError(w, err, "SetFocusWindows")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id": req.UserID,
+		"windows": windows,
+	})
+}
+
+// GetFocusWindows возвращает расписание окон фокуса пользователя
+func (h *Handler) GetFocusWindows(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	uid := r.URL.Query().Get("user_id")
+	if uid == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_USER_ID")
+		}
+		writeError(w, http.StatusBadRequest, "user_id query parameter is required")
+		return
+	}
+
+	windows, err := h.store.GetFocusWindows(r.Context(), uid)
+	if err != nil {
+		status = "500"
+		log.Printf("GetFocusWindows error: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id": uid,
+		"windows": windows,
+	})
+}
+
+// validateFocusWindows проверяет корректность окон фокуса
+func validateFocusWindows(windows []models.FocusWindow) string {
+	for i, fw := range windows {
+		if fw.Weekday < 0 || fw.Weekday > 6 {
+			return fmt.Sprintf("windows[%d].weekday must be between 0 and 6", i)
+		}
+
+		startMinute, err := storage.ParseMinuteOfDay(fw.StartTime)
+		if err != nil {
+			return fmt.Sprintf("windows[%d].start_time: %v", i, err)
+		}
+
+		endMinute, err := storage.ParseMinuteOfDay(fw.EndTime)
+		if err != nil {
+			return fmt.Sprintf("windows[%d].end_time: %v", i, err)
+		}
+
+		if endMinute <= startMinute {
+			return fmt.Sprintf("windows[%d].end_time must be after start_time", i)
+		}
+	}
+	return ""
+}
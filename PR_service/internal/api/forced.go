@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"PR_service/internal/storage"
+)
+
+// HeaderForceReviewers - ревьюеры через запятую, которых автоматические назначения
+// запроса выбирают вместо стратегии команды. Учитывается, только если режим включен.
+const HeaderForceReviewers = "X-Force-Reviewers"
+
+// EnableForcedReviewers включает заголовок X-Force-Reviewers. Только для тестовых стендов:
+// QA-автоматизация получает предсказуемые назначения без правки БД между шагами.
+func (h *Handler) EnableForcedReviewers() {
+	h.forceReviewers = true
+}
+
+// parseForcedReviewers разбирает заголовок X-Force-Reviewers, сохраняя порядок и убирая повторы
+func parseForcedReviewers(header string) ([]string, error) {
+	var ids []string
+	seen := map[string]bool{}
+	for _, id := range strings.Split(header, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) > storage.MaxReviewerCount {
+		return nil, fmt.Errorf("%s must list at most %d users", HeaderForceReviewers, storage.MaxReviewerCount)
+	}
+	return ids, nil
+}
+
+// ForceReviewersMiddleware передает в storage ревьюеров из X-Force-Reviewers.
+// Если режим выключен, заголовок игнорируется.
+func (h *Handler) ForceReviewersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(HeaderForceReviewers)
+		if !h.forceReviewers || header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ids, err := parseForcedReviewers(header)
+		if err != nil {
+			if h.metrics != nil {
+				h.metrics.IncBusinessError("INVALID_FORCED_REVIEWERS")
+			}
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(storage.WithForcedReviewers(r.Context(), ids)))
+	})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"PR_service/internal/storage"
+)
+
+// ReviewerGrowthReport возвращает теги, которые пользователь ревьюил за период from/to,
+// и теги PR его команды, которых он еще не видел
+func (h *Handler) ReviewerGrowthReport(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	query := r.URL.Query()
+	userID := query.Get("user_id")
+	if userID == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_USER_ID")
+		}
+		writeError(w, http.StatusBadRequest, "user_id query parameter is required")
+		return
+	}
+
+	from, to, errMsg := parseTimeRange(query)
+	if errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_TIME_RANGE")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	report, err := h.store.GetReviewerGrowth(r.Context(), userID, from, to)
+	if err != nil {
+		if err.Error() == "user not found" {
+			status = "404"
+		} else {
+			status = "500"
+		}
+		h.handleStorageError(w, err, "ReviewerGrowthReport")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, report)
+}
+
+// validatePRTags проверяет теги PR. Теги сравниваются без учета регистра.
+func validatePRTags(tags []string) string {
+	if len(tags) > storage.MaxPRTags {
+		return fmt.Sprintf("tags must contain at most %d tags", storage.MaxPRTags)
+	}
+	seen := make(map[string]bool, len(tags))
+	for i, raw := range tags {
+		tag := storage.NormalizeTag(raw)
+		if tag == "" {
+			return fmt.Sprintf("tags[%d] must not be empty", i)
+		}
+		if len(tag) > storage.MaxTagLength {
+			return fmt.Sprintf("tags[%d] must be at most %d characters", i, storage.MaxTagLength)
+		}
+		if seen[tag] {
+			return fmt.Sprintf("tags[%d]: duplicate tag %s", i, tag)
+		}
+		seen[tag] = true
+	}
+	return ""
+}
@@ -2,20 +2,41 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"runtime"
+	"sync/atomic"
 	"time"
 
+	"PR_service/internal/authctx"
+	"PR_service/internal/domain"
 	"PR_service/internal/models"
+	"PR_service/internal/notify"
+	"PR_service/internal/scheduler"
 	"PR_service/internal/storage"
 )
 
 type Handler struct {
-	store   *storage.StorageData
-	metrics *Metrics
+	store       *storage.StorageData
+	metrics     *Metrics
+	maintenance *maintenanceMode
+	notifier    *notify.Renderer
+	warmingUp   atomic.Bool // Реплика прогревает кэши и еще не готова
+
+	healthy         atomic.Bool  // Результат последней фоновой проверки для /healthz
+	healthCheckedAt atomic.Int64 // Время последней фоновой проверки, UnixNano
+
+	queueDepths atomic.Pointer[map[string]int] // Глубины очередей ревью из фонового обновления
+	scheduler   *scheduler.Scheduler           // Фоновые задачи для /status; nil - не подключен
+
+	bitbucketSecret  []byte        // Секрет подписи вебхуков Bitbucket; пусто - прием выключен
+	webhookDedupeTTL time.Duration // Сколько помнить идентификаторы доставок вебхуков
+	forceReviewers   bool          // Учитывать X-Force-Reviewers (только тестовые стенды)
+
+	scopes ScopePolicy // Исключения аутентификации и scope маршрутов
 }
 
 func NewHandler(s *storage.StorageData, m *Metrics) *Handler {
@@ -24,8 +45,13 @@ func NewHandler(s *storage.StorageData, m *Metrics) *Handler {
 	}
 
 	return &Handler{
-		store:   s,
-		metrics: m,
+		store:       s,
+		metrics:     m,
+		maintenance: &maintenanceMode{},
+		notifier:    notify.NewRenderer(),
+		scopes:      DefaultScopePolicy(),
+
+		webhookDedupeTTL: storage.DefaultWebhookDedupeTTL,
 	}
 }
 
@@ -204,22 +230,60 @@ func (h *Handler) CreatePR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if errMsg := validateExcludedReviewers(req.AuthorID, req.ExcludedReviewers); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_EXCLUDED_REVIEWERS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if errMsg := validatePRTags(req.Tags); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_TAGS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if req.Priority != "" && !domain.IsKnownPriority(req.Priority) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_PRIORITY")
+		}
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown priority %q", req.Priority))
+		return
+	}
+
+	// Команда автора, ее политики оформления и занятость идентификатора проверяются
+	// в транзакции создания PR: отдельные запросы до нее могли бы устареть
 	createdPR, err := h.store.CreatePR(r.Context(), req)
 	if err != nil {
+		var violation *domain.PolicyViolationError
+		if errors.As(err, &violation) {
+			status = "422"
+			if h.metrics != nil {
+				h.metrics.IncBusinessError("POLICY_VIOLATION")
+				h.metrics.IncTeamError(violation.TeamName, "POLICY_VIOLATION")
+			}
+			writePolicyViolations(w, violation.TeamName, violation.Violations)
+			return
+		}
 		status = "500"
-		h.handleCreatePRError(w, err)
+		code := h.handleCreatePRError(w, err)
+		h.recordTeamError(r.Context(), req.AuthorID, code)
 		return
 	}
 
 	// Бизнес-метрики
 	if h.metrics != nil {
-		h.metrics.IncPRCreated()
-
-		// Получаем реальное имя команды автора
-		teamName := h.getAuthorTeam(r.Context(), req.AuthorID)
+		teamName := createdPR.TeamName
 		if teamName == "" {
 			teamName = "unknown"
 		}
+		h.metrics.IncPRCreated(teamName)
 		h.metrics.ObserveReviewersAssigned(teamName, len(createdPR.Reviewers))
 	}
 
@@ -260,6 +324,18 @@ func (h *Handler) MergePR(w http.ResponseWriter, r *http.Request) {
 
 	mergedPR, err := h.store.MergePR(r.Context(), req.PullRequestID)
 	if err != nil {
+		var missing *domain.ApprovalsMissingError
+		if errors.As(err, &missing) {
+			status = "409"
+			if h.metrics != nil {
+				h.metrics.IncBusinessError("APPROVALS_MISSING")
+			}
+			if missing.PR != nil {
+				h.recordTeamError(r.Context(), missing.PR.AuthorID, "APPROVALS_MISSING")
+			}
+			WriteJSON(w, http.StatusConflict, mergeBlockedResponse(missing))
+			return
+		}
 		status = "500"
 		h.handleStorageError(w, err, "MergePR")
 		return
@@ -267,7 +343,11 @@ func (h *Handler) MergePR(w http.ResponseWriter, r *http.Request) {
 
 	// Бизнес-метрики
 	if h.metrics != nil {
-		h.metrics.IncPRMerged()
+		teamName := h.getAuthorTeam(r.Context(), mergedPR.AuthorID)
+		if teamName == "" {
+			teamName = "unknown"
+		}
+		h.metrics.IncPRMerged(teamName)
 	}
 
 	// Возвращаем PR в соответствии со спецификацией
@@ -284,11 +364,7 @@ func (h *Handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 		h.recordHandlerDuration(r, start, status)
 	}()
 
-	var req struct {
-		PullRequestID string `json:"pull_request_id"`
-		OldUserID     string `json:"old_user_id"`
-	}
-
+	var req models.ReassignRequest
 	if !h.bindJSON(w, r, &req) {
 		status = "400"
 		if h.metrics != nil {
@@ -309,10 +385,14 @@ func (h *Handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedPR, replacedBy, err := h.store.ReassignReviewer(r.Context(), req.PullRequestID, req.OldUserID)
+	updatedPR, replacedBy, err := h.store.ReassignReviewer(r.Context(), req.PullRequestID, req.OldUserID, req.NewUserID)
 	if err != nil {
 		status = "500"
-		h.handleReassignError(w, err)
+		code := h.handleReassignError(w, err)
+		var conflict *storage.PRConflictError
+		if errors.As(err, &conflict) {
+			h.recordTeamError(r.Context(), conflict.PR.AuthorID, code)
+		}
 		return
 	}
 
@@ -361,7 +441,7 @@ func (h *Handler) GetPRsForUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Возвращаем в соответствии со спецификацией - PullRequestShort
+	// Поля PullRequestShort из спецификации дополнены приоритетом и сроком ревью
 	WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"user_id":       uid,
 		"pull_requests": prs,
@@ -425,7 +505,8 @@ func (h *Handler) recordHandlerDuration(r *http.Request, start time.Time, status
 	if h.metrics != nil {
 		duration := time.Since(start)
 		h.metrics.RecordHTTPRequest(r.Method, r.URL.Path, status, duration)
-		log.Printf("HANDLER DURATION: %s %s %s - %.6fs", r.Method, r.URL.Path, status, duration.Seconds())
+		log.Printf("HANDLER DURATION: %s %s %s - %.6fs [request_id=%s actor=%s]", r.Method, r.URL.Path, status,
+			duration.Seconds(), authctx.RequestIDFrom(r.Context()), authctx.ActorID(r.Context()))
 	}
 }
 
@@ -443,16 +524,24 @@ func (h *Handler) handleStorageError(w http.ResponseWriter, err error, handlerNa
 
 	switch err.Error() {
 	case "pr not found", "team not found", "user not found", "author not found",
-		"author is not in any team", "old reviewer not in any team":
+		"author is not in any team", "old reviewer not in any team", "decision not found",
+		"checklist item not found", "external identity not found":
 		errorResp.Error.Code = "NOT_FOUND"
 		WriteJSON(w, http.StatusNotFound, errorResp)
+	case "pr is declined":
+		errorResp.Error.Code = "PR_DECLINED"
+		WriteJSON(w, http.StatusConflict, errorResp)
+	case storage.ErrTeamLeadRequired.Error():
+		errorResp.Error.Code = "TEAM_LEAD_REQUIRED"
+		WriteJSON(w, http.StatusForbidden, errorResp)
 	default:
 		errorResp.Error.Code = "INTERNAL_ERROR"
 		WriteJSON(w, http.StatusInternalServerError, errorResp)
 	}
 }
 
-func (h *Handler) handleCreatePRError(w http.ResponseWriter, err error) {
+// handleCreatePRError пишет ответ об ошибке и возвращает код ошибки API
+func (h *Handler) handleCreatePRError(w http.ResponseWriter, err error) string {
 	log.Printf("CreatePR error: %v", err)
 
 	// Создаем ErrorResponse в соответствии со спецификацией
@@ -470,6 +559,9 @@ func (h *Handler) handleCreatePRError(w http.ResponseWriter, err error) {
 		case "author is not in any team":
 			h.metrics.IncBusinessError("AUTHOR_NO_TEAM")
 			errorResp.Error.Code = "NOT_FOUND"
+		case domain.ErrTooManyExclusions.Error():
+			h.metrics.IncBusinessError("TOO_MANY_EXCLUSIONS")
+			errorResp.Error.Code = "TOO_MANY_EXCLUSIONS"
 		default:
 			h.metrics.IncBusinessError("PR_CREATION_ERROR")
 			errorResp.Error.Code = "INTERNAL_ERROR"
@@ -477,16 +569,18 @@ func (h *Handler) handleCreatePRError(w http.ResponseWriter, err error) {
 	}
 
 	switch err.Error() {
-	case "pr already exists":
+	case "pr already exists", domain.ErrTooManyExclusions.Error():
 		WriteJSON(w, http.StatusConflict, errorResp)
 	case "author not found", "author is not in any team":
 		WriteJSON(w, http.StatusNotFound, errorResp)
 	default:
 		WriteJSON(w, http.StatusInternalServerError, errorResp)
 	}
+	return errorResp.Error.Code
 }
 
-func (h *Handler) handleReassignError(w http.ResponseWriter, err error) {
+// handleReassignError пишет ответ об ошибке и возвращает код ошибки API
+func (h *Handler) handleReassignError(w http.ResponseWriter, err error) string {
 	log.Printf("ReassignReviewer error: %v", err)
 
 	// Создаем ErrorResponse в соответствии со спецификацией
@@ -504,6 +598,8 @@ func (h *Handler) handleReassignError(w http.ResponseWriter, err error) {
 		case "no active replacement candidate in team":
 			h.metrics.IncBusinessError("NO_REPLACEMENT_CANDIDATE")
 			errorResp.Error.Code = "NO_CANDIDATE"
+		case storage.ErrTeamLeadRequired.Error():
+			h.metrics.IncBusinessError("TEAM_LEAD_REQUIRED")
 		default:
 			h.metrics.IncBusinessError("REASSIGN_ERROR")
 			errorResp.Error.Code = "INTERNAL_ERROR"
@@ -516,21 +612,44 @@ func (h *Handler) handleReassignError(w http.ResponseWriter, err error) {
 		WriteJSON(w, http.StatusNotFound, errorResp)
 	case "cannot modify reviewers after merge", "reviewer is not assigned to this PR",
 		"no active replacement candidate in team":
+		var conflict *storage.PRConflictError
+		if errors.As(err, &conflict) {
+			errorResp.Data = createPRResponse(*conflict.PR)
+		}
+		WriteJSON(w, http.StatusConflict, errorResp)
+	case storage.ErrTeamLeadRequired.Error():
+		errorResp.Error.Code = "TEAM_LEAD_REQUIRED"
+		WriteJSON(w, http.StatusForbidden, errorResp)
+	case "author cannot review own PR", "reviewer already assigned", "reviewer excluded by author",
+		"reviewer is inactive", "reviewer is a bot":
+		errorResp.Error.Code = addReviewerErrors[err.Error()]
 		WriteJSON(w, http.StatusConflict, errorResp)
 	default:
 		errorResp.Error.Code = "INTERNAL_ERROR"
 		WriteJSON(w, http.StatusInternalServerError, errorResp)
 	}
+	return errorResp.Error.Code
+}
+
+// recordTeamError учитывает ошибку операции с PR в метриках команды автора.
+// Ошибки, для которых команду не определить (автор не найден), не учитываются.
+func (h *Handler) recordTeamError(ctx context.Context, authorID, code string) {
+	if h.metrics == nil || code == "" {
+		return
+	}
+	if teamName := h.getAuthorTeam(ctx, authorID); teamName != "" {
+		h.metrics.IncTeamError(teamName, code)
+	}
 }
 
 // Вспомогательная функция для получения команды автора
 func (h *Handler) getAuthorTeam(ctx context.Context, authorID string) string {
-	// Получаем команду пользователя через существующий метод storage
-	team, err := h.store.GetTeamByUserID(ctx, authorID)
+	// Только имя команды: участники здесь не нужны, а функция вызывается на горячем пути
+	teamName, err := h.store.GetUserTeamName(ctx, authorID)
 	if err != nil {
 		return ""
 	}
-	return team.TeamName
+	return teamName
 }
 
 // Вспомогательная функция для получения пользователя с информацией о команде
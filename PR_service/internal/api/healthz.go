@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// healthzPath - проверка для балансировщиков: без запросов к БД, логов и цепочки middleware
+const healthzPath = "/healthz"
+
+// healthzStaleAfter - через сколько без обновления кэшированное состояние считается
+// недействительным: зависшая фоновая проверка не должна держать реплику в балансировке
+const healthzStaleAfter = 15 * time.Second
+
+// WatchHealth периодически проверяет готовность реплики (прогрев завершен, БД отвечает)
+// и кэширует результат для /healthz. interval должен быть заметно меньше healthzStaleAfter.
+func (h *Handler) WatchHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.refreshHealth(ctx, interval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) refreshHealth(ctx context.Context, timeout time.Duration) {
+	healthy := false
+	if !h.warmingUp.Load() {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		healthy = h.store.HealthCheck(checkCtx) == nil
+		cancel()
+	}
+	h.setHealth(healthy, time.Now())
+}
+
+// setHealth сохраняет результат фоновой проверки
+func (h *Handler) setHealth(healthy bool, at time.Time) {
+	h.healthy.Store(healthy)
+	h.healthCheckedAt.Store(at.UnixNano())
+}
+
+// Healthz отвечает 200 или 503 по кэшированному результату фоновой проверки.
+// Частые пробы балансировщиков не создают нагрузки на БД, в отличие от /health.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	checkedAt := h.healthCheckedAt.Load()
+	if !h.healthy.Load() || checkedAt == 0 || time.Since(time.Unix(0, checkedAt)) > healthzStaleAfter {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("unavailable\n"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok\n"))
+}
+
+// WithHealthz отвечает на GET и HEAD /healthz до роутера и его middleware,
+// остальные запросы передает next
+func (h *Handler) WithHealthz(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == healthzPath && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			h.Healthz(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// validateExternalIdentity проверяет сопоставление учетной записи внешней системы
+func validateExternalIdentity(identity models.ExternalIdentity) string {
+	if identity.Provider != storage.ProviderBitbucket {
+		return fmt.Sprintf("provider must be %s", storage.ProviderBitbucket)
+	}
+	if identity.ExternalID == "" {
+		return "external_id is required"
+	}
+	if len(identity.ExternalID) > storage.MaxExternalIDLength {
+		return fmt.Sprintf("external_id must be at most %d characters", storage.MaxExternalIDLength)
+	}
+	return ""
+}
+
+// GetExternalIdentities возвращает сопоставления пользователей внешней системы (?provider=bitbucket)
+func (h *Handler) GetExternalIdentities(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	provider := r.URL.Query().Get("provider")
+	if provider == "" {
+		provider = storage.ProviderBitbucket
+	}
+	if provider != storage.ProviderBitbucket {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_PROVIDER")
+		}
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("provider must be %s", storage.ProviderBitbucket))
+		return
+	}
+
+	identities, err := h.store.ListExternalIdentities(r.Context(), provider)
+	if err != nil {
+		status = "500"
+		h.handleStorageError(w, err, "GetExternalIdentities")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"provider":   provider,
+		"identities": identities,
+	})
+}
+
+// SetExternalIdentity сопоставляет пользователя внешней системы пользователю сервиса;
+// пустой user_id удаляет сопоставление
+func (h *Handler) SetExternalIdentity(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.ExternalIdentity
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateExternalIdentity(req); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_EXTERNAL_IDENTITY")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if err := h.store.SetExternalIdentity(r.Context(), req); err != nil {
+		if err.Error() == "user not found" || err.Error() == "external identity not found" {
+			status = "404"
+		} else {
+			status = "500"
+		}
+		h.handleStorageError(w, err, "SetExternalIdentity")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"identity": req,
+	})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// BulkLabelPRs добавляет метку нескольким PR или снимает ее
+func (h *Handler) BulkLabelPRs(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.BulkLabelRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateBulkLabelRequest(req); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_BULK_LABEL")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	result, err := h.store.BulkLabelPRs(r.Context(), req.Action, req.Label, req.PullRequestIDs)
+	if err != nil {
+		status = "500"
+		h.handleStorageError(w, err, "BulkLabelPRs")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, result)
+}
+
+// validateBulkLabelRequest проверяет действие, метку по правилам тегов и список PR
+func validateBulkLabelRequest(req models.BulkLabelRequest) string {
+	if !storage.IsKnownLabelAction(req.Action) {
+		return fmt.Sprintf("action must be %s or %s", storage.LabelActionAdd, storage.LabelActionRemove)
+	}
+	label := storage.NormalizeTag(req.Label)
+	if label == "" {
+		return "label is required"
+	}
+	if len(label) > storage.MaxTagLength {
+		return fmt.Sprintf("label must be at most %d characters", storage.MaxTagLength)
+	}
+	if len(req.PullRequestIDs) == 0 {
+		return "pull_request_ids must not be empty"
+	}
+	if len(req.PullRequestIDs) > storage.MaxBulkLabelPRs {
+		return fmt.Sprintf("pull_request_ids must contain at most %d pull requests", storage.MaxBulkLabelPRs)
+	}
+	for i, id := range req.PullRequestIDs {
+		if id == "" {
+			return fmt.Sprintf("pull_request_ids[%d] must not be empty", i)
+		}
+	}
+	return ""
+}
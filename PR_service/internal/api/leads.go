@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// GetTeamLeads возвращает лидов команды
+func (h *Handler) GetTeamLeads(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_TEAM_NAME")
+		}
+		writeError(w, http.StatusBadRequest, "team_name query parameter is required")
+		return
+	}
+
+	leads, err := h.store.GetTeamLeads(r.Context(), teamName)
+	if err != nil {
+		status = h.handleTeamLeadError(w, err, "GetTeamLeads")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"team_name": teamName,
+		"leads":     leads,
+	})
+}
+
+// AddTeamLead назначает участника команды лидом.
+// Если у команды уже есть лиды, назначать новых могут только они.
+func (h *Handler) AddTeamLead(w http.ResponseWriter, r *http.Request) {
+	h.changeTeamLead(w, r, "AddTeamLead", h.store.AddTeamLead)
+}
+
+// RemoveTeamLead снимает с пользователя роль лида команды
+func (h *Handler) RemoveTeamLead(w http.ResponseWriter, r *http.Request) {
+	h.changeTeamLead(w, r, "RemoveTeamLead", h.store.RemoveTeamLead)
+}
+
+func (h *Handler) changeTeamLead(w http.ResponseWriter, r *http.Request, name string,
+	change func(ctx context.Context, teamName, userID string) ([]string, error)) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.TeamLeadRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateRequiredFields(map[string]string{
+		"team_name": req.TeamName,
+		"user_id":   req.UserID,
+	}); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	leads, err := change(r.Context(), req.TeamName, req.UserID)
+	if err != nil {
+		status = h.handleTeamLeadError(w, err, name)
+		return
+	}
+
+	log.Printf("%s: team %s leads are now %v", name, req.TeamName, leads)
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"team_name": req.TeamName,
+		"leads":     leads,
+	})
+}
+
+// handleTeamLeadError пишет ответ об ошибке и возвращает HTTP статус для метрик
+func (h *Handler) handleTeamLeadError(w http.ResponseWriter, err error, handlerName string) string {
+	switch err.Error() {
+	case "user is not a team member", "user is not a team lead":
+		log.Printf("%s error: %v", handlerName, err)
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_TEAM_LEAD")
+		}
+		WriteJSON(w, http.StatusConflict, createErrorResponse("INVALID_TEAM_LEAD", err.Error()))
+		return "409"
+	case "team not found":
+		h.handleStorageError(w, err, handlerName)
+		return "404"
+	case storage.ErrTeamLeadRequired.Error():
+		h.handleStorageError(w, err, handlerName)
+		return "403"
+	default:
+		h.handleStorageError(w, err, handlerName)
+		return "500"
+	}
+}
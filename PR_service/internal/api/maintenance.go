@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+const maintenancePath = "/admin/maintenance"
+
+// maintenanceMode - локальная копия состояния режима обслуживания
+type maintenanceMode struct {
+	mu    sync.RWMutex
+	state models.MaintenanceState
+}
+
+func (m *maintenanceMode) get() models.MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+func (m *maintenanceMode) set(state models.MaintenanceState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+}
+
+// MaintenanceMiddleware в режиме обслуживания отклоняет изменяющие запросы с 503
+func (h *Handler) MaintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := h.maintenance.get()
+		if !state.Enabled || isReadOnlyMethod(r.Method) || r.URL.Path == maintenancePath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MAINTENANCE_MODE")
+		}
+
+		message := state.Message
+		if message == "" {
+			message = "service is in maintenance mode"
+		}
+		WriteJSON(w, http.StatusServiceUnavailable, createErrorResponse("MAINTENANCE", message))
+	})
+}
+
+// WatchMaintenance периодически подтягивает состояние режима обслуживания из БД,
+// чтобы переключение на одной реплике применялось на всех
+func (h *Handler) WatchMaintenance(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.refreshMaintenance(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) refreshMaintenance(ctx context.Context) {
+	state, err := h.store.GetMaintenance(ctx)
+	if err != nil {
+		log.Printf("Maintenance refresh error: %v", err)
+		return
+	}
+	h.maintenance.set(state)
+}
+
+// GetMaintenance возвращает текущее состояние режима обслуживания
+func (h *Handler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	defer h.recordHandlerDuration(r, start, "200")
+
+	WriteJSON(w, http.StatusOK, h.maintenance.get())
+}
+
+// SetMaintenance включает или выключает режим только для чтения без перезапуска
+func (h *Handler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.SetMaintenanceRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	state, err := h.store.SetMaintenance(r.Context(), req.Enabled, req.Message)
+	if err != nil {
+		status = "500"
+		h.handleStorageError(w, err, "SetMaintenance")
+		return
+	}
+	h.maintenance.set(state)
+
+	log.Printf("Maintenance mode set to %t by %s: %s", state.Enabled, state.UpdatedBy, state.Message)
+	WriteJSON(w, http.StatusOK, state)
+}
+
+// ReadinessCheck сообщает, готова ли реплика принимать трафик.
+// Пока идет прогрев кэшей, реплика не готова. В режиме обслуживания реплика
+// остается готовой (чтение работает), но статус и состояние режима отражаются в ответе.
+func (h *Handler) ReadinessCheck(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	resp := struct {
+		Status      string                  `json:"status"`
+		Database    string                  `json:"database"`
+		Maintenance models.MaintenanceState `json:"maintenance"`
+	}{
+		Status:      "ready",
+		Database:    "OK",
+		Maintenance: h.maintenance.get(),
+	}
+
+	if h.warmingUp.Load() {
+		status = "503"
+		resp.Status = "warming_up"
+		resp.Database = "not checked"
+		WriteJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := h.store.HealthCheck(ctx); err != nil {
+		status = "503"
+		resp.Status = "not_ready"
+		resp.Database = "ERROR: " + err.Error()
+		WriteJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+
+	if resp.Maintenance.Enabled {
+		resp.Status = "maintenance"
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
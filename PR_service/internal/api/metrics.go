@@ -6,9 +6,13 @@ import (
 	"runtime"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -16,22 +20,45 @@ import (
 type Metrics struct {
 	httpRequestsTotal   *prometheus.CounterVec
 	httpRequestDuration *prometheus.HistogramVec
-	prCreatedTotal      prometheus.Counter
-	prMergedTotal       prometheus.Counter
+	prCreatedTotal      *prometheus.CounterVec
+	prMergedTotal       *prometheus.CounterVec
 	prReviewersAssigned *prometheus.HistogramVec
 	teamMembersCount    *prometheus.GaugeVec
 	dbQueryDuration     *prometheus.HistogramVec
 	businessErrors      *prometheus.CounterVec
+	teamErrors          *prometheus.CounterVec
+	panicsTotal         *prometheus.CounterVec
+	warmUpDuration      prometheus.Gauge
+	reviewQueueDepth    *prometheus.GaugeVec
+	reviewBacklog       *prometheus.GaugeVec
+	adaptiveReviewers   *prometheus.GaugeVec
+	reviewersReduced    *prometheus.GaugeVec
+	outboundRequests    *prometheus.CounterVec
+	outboundDuration    *prometheus.HistogramVec
+	outboundCircuitOpen *prometheus.GaugeVec
+	quotaDeferred       *prometheus.CounterVec
+	selectionDuration   *prometheus.HistogramVec
+	selectionConsidered *prometheus.HistogramVec
+	selectionsTotal     *prometheus.CounterVec
+	selectionFiltered   *prometheus.CounterVec
+	selectionFallbacks  *prometheus.CounterVec
+	namespace           string
 	mu                  sync.RWMutex
+
+	recent requestWindow // Запросы за последние минуты для /status
 }
 
+// DefaultMetricsNamespace - префикс имен метрик, если он не задан в конфигурации
+const DefaultMetricsNamespace = "pr_service"
+
 // Глобальная переменная для времени старта
 var appStartTime = time.Now()
 
-func NewMetrics() *Metrics {
-	const namespace = "pr_service"
-
+// NewMetrics создает и регистрирует метрики сервиса с префиксом namespace.
+// Разные префиксы нужны, когда несколько инсталляций пишут в один Prometheus.
+func NewMetrics(namespace string) *Metrics {
 	m := &Metrics{
+		namespace: namespace,
 		httpRequestsTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: namespace,
@@ -51,20 +78,22 @@ func NewMetrics() *Metrics {
 			[]string{"method", "path", "status"},
 		),
 
-		prCreatedTotal: prometheus.NewCounter(
+		prCreatedTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: namespace,
 				Name:      "pr_created_total",
 				Help:      "Total number of created pull requests",
 			},
+			[]string{"team"},
 		),
 
-		prMergedTotal: prometheus.NewCounter(
+		prMergedTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: namespace,
 				Name:      "pr_merged_total",
 				Help:      "Total number of merged pull requests",
 			},
+			[]string{"team"},
 		),
 
 		prReviewersAssigned: prometheus.NewHistogramVec(
@@ -104,6 +133,152 @@ func NewMetrics() *Metrics {
 			},
 			[]string{"error_type"},
 		),
+
+		teamErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "team_errors_total",
+				Help:      "Pull request operation errors by author team and type",
+			},
+			[]string{"team", "error_type"},
+		),
+
+		panicsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "panics_total",
+				Help:      "Recovered handler panics by path",
+			},
+			[]string{"path"},
+		),
+
+		warmUpDuration: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "cache_warmup_duration_seconds",
+				Help:      "Duration of the startup cache warm-up in seconds",
+			},
+		),
+
+		reviewQueueDepth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "review_queue_depth",
+				Help:      "Pull requests waiting for reviewer capacity by team",
+			},
+			[]string{"team_name"},
+		),
+
+		reviewBacklog: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "review_backlog",
+				Help:      "Open pull requests awaiting review for teams with adaptive reviewer count",
+			},
+			[]string{"team_name"},
+		),
+
+		adaptiveReviewers: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "effective_reviewer_count",
+				Help:      "Reviewers assigned to new pull requests after backlog adaptation",
+			},
+			[]string{"team_name"},
+		),
+
+		reviewersReduced: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "reviewer_count_reduced",
+				Help:      "Whether the team reviewer count is currently reduced because of backlog (1 or 0)",
+			},
+			[]string{"team_name"},
+		),
+
+		outboundRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "outbound_requests_total",
+				Help:      "Outbound HTTP request attempts by integration, method and status",
+			},
+			[]string{"client", "method", "status"},
+		),
+
+		outboundDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "outbound_request_duration_seconds",
+				Help:      "Outbound HTTP request attempt duration in seconds",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"client"},
+		),
+
+		outboundCircuitOpen: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "outbound_circuit_open",
+				Help:      "Whether the circuit breaker of an outbound integration is open (1 or 0)",
+			},
+			[]string{"client"},
+		),
+
+		quotaDeferred: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "assignment_quota_deferred_total",
+				Help:      "Reviewer slots left unfilled in a selection because candidates exhausted their assignment quota",
+			},
+			[]string{"team_name"},
+		),
+
+		selectionDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "assignment_selection_duration_seconds",
+				Help:      "Reviewer selection latency including input collection, by strategy",
+				Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5},
+			},
+			[]string{"strategy"},
+		),
+
+		selectionConsidered: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "assignment_candidates_considered",
+				Help:      "Candidates considered by a reviewer selection before filtering rules, by strategy",
+				Buckets:   []float64{0, 1, 2, 3, 5, 8, 13, 21, 34},
+			},
+			[]string{"strategy"},
+		),
+
+		selectionsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "assignment_selections_total",
+				Help:      "Reviewer selections by strategy and result (full, partial, empty)",
+			},
+			[]string{"strategy", "result"},
+		),
+
+		selectionFiltered: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "assignment_candidates_filtered_total",
+				Help:      "Candidates removed from reviewer selections by strategy and rule",
+			},
+			[]string{"strategy", "rule"},
+		),
+
+		selectionFallbacks: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "assignment_fallback_total",
+				Help:      "Reviewer selections that used a fallback, by strategy and fallback",
+			},
+			[]string{"strategy", "fallback"},
+		),
 	}
 
 	// Регистрируем все метрики
@@ -116,22 +291,38 @@ func NewMetrics() *Metrics {
 		m.teamMembersCount,
 		m.dbQueryDuration,
 		m.businessErrors,
+		m.teamErrors,
+		m.panicsTotal,
+		m.warmUpDuration,
+		m.reviewQueueDepth,
+		m.reviewBacklog,
+		m.adaptiveReviewers,
+		m.reviewersReduced,
+		m.outboundRequests,
+		m.outboundDuration,
+		m.outboundCircuitOpen,
+		m.quotaDeferred,
+		m.selectionDuration,
+		m.selectionConsidered,
+		m.selectionsTotal,
+		m.selectionFiltered,
+		m.selectionFallbacks,
 	)
 
 	return m
 }
 
 // Thread-safe методы
-func (m *Metrics) IncPRCreated() {
+func (m *Metrics) IncPRCreated(team string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.prCreatedTotal.Inc()
+	m.prCreatedTotal.WithLabelValues(team).Inc()
 }
 
-func (m *Metrics) IncPRMerged() {
+func (m *Metrics) IncPRMerged(team string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.prMergedTotal.Inc()
+	m.prMergedTotal.WithLabelValues(team).Inc()
 }
 
 func (m *Metrics) ObserveReviewersAssigned(team string, reviewers int) {
@@ -158,6 +349,102 @@ func (m *Metrics) IncBusinessError(errorType string) {
 	m.businessErrors.WithLabelValues(errorType).Inc()
 }
 
+// IncTeamError учитывает ошибку операции с PR команды team
+func (m *Metrics) IncTeamError(team, errorType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.teamErrors.WithLabelValues(team, errorType).Inc()
+}
+
+// IncAssignmentQuotaDeferred учитывает места ревьюеров, не заполненные из-за квот назначений
+func (m *Metrics) IncAssignmentQuotaDeferred(team string, slots int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotaDeferred.WithLabelValues(team).Add(float64(slots))
+}
+
+// ObserveAssignmentSelection учитывает автоматический выбор ревьюеров по стратегии
+func (m *Metrics) ObserveAssignmentSelection(sel storage.AssignmentSelection) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.selectionDuration.WithLabelValues(sel.Strategy).Observe(sel.Duration.Seconds())
+	m.selectionConsidered.WithLabelValues(sel.Strategy).Observe(float64(sel.Considered))
+	m.selectionsTotal.WithLabelValues(sel.Strategy, selectionResult(sel)).Inc()
+	for rule, n := range sel.Filtered {
+		m.selectionFiltered.WithLabelValues(sel.Strategy, rule).Add(float64(n))
+	}
+	for _, fallback := range sel.Fallbacks {
+		m.selectionFallbacks.WithLabelValues(sel.Strategy, fallback).Inc()
+	}
+}
+
+// selectionResult - набраны ли все запрошенные ревьюеры
+func selectionResult(sel storage.AssignmentSelection) string {
+	switch {
+	case sel.Selected >= sel.Requested:
+		return "full"
+	case sel.Selected > 0:
+		return "partial"
+	default:
+		return "empty"
+	}
+}
+
+func (m *Metrics) IncPanic(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.panicsTotal.WithLabelValues(path).Inc()
+}
+
+func (m *Metrics) SetWarmUpDuration(duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.warmUpDuration.Set(duration.Seconds())
+}
+
+// SetReviewQueueDepths заменяет глубины очередей; команды без очереди пропадают из метрики
+func (m *Metrics) SetReviewQueueDepths(depths map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reviewQueueDepth.Reset()
+	for team, depth := range depths {
+		m.reviewQueueDepth.WithLabelValues(team).Set(float64(depth))
+	}
+}
+
+// SetAdaptiveReviewerStates заменяет бэклог и действующее число ревьюеров команд
+func (m *Metrics) SetAdaptiveReviewerStates(states []models.AdaptiveReviewerState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reviewBacklog.Reset()
+	m.adaptiveReviewers.Reset()
+	m.reviewersReduced.Reset()
+	for _, st := range states {
+		m.reviewBacklog.WithLabelValues(st.TeamName).Set(float64(st.Backlog))
+		m.adaptiveReviewers.WithLabelValues(st.TeamName).Set(float64(st.ReviewerCount))
+		reduced := 0.0
+		if st.Reduced {
+			reduced = 1
+		}
+		m.reviewersReduced.WithLabelValues(st.TeamName).Set(reduced)
+	}
+}
+
+// ObserveOutboundRequest учитывает попытку исходящего HTTP-запроса (httpclient.Observer)
+func (m *Metrics) ObserveOutboundRequest(client, method, status string, duration time.Duration) {
+	m.outboundRequests.WithLabelValues(client, method, status).Inc()
+	m.outboundDuration.WithLabelValues(client).Observe(duration.Seconds())
+}
+
+// SetCircuitOpen отражает состояние автомата отключения интеграции (httpclient.Observer)
+func (m *Metrics) SetCircuitOpen(client string, open bool) {
+	value := 0.0
+	if open {
+		value = 1
+	}
+	m.outboundCircuitOpen.WithLabelValues(client).Set(value)
+}
+
 // Метод для middleware - должен быть безопасным
 func (m *Metrics) RecordHTTPRequest(method, path, status string, duration time.Duration) {
 	m.mu.Lock()
@@ -165,6 +452,7 @@ func (m *Metrics) RecordHTTPRequest(method, path, status string, duration time.D
 
 	m.httpRequestsTotal.WithLabelValues(method, path, status).Inc()
 	m.httpRequestDuration.WithLabelValues(method, path, status).Observe(duration.Seconds())
+	m.recent.record(time.Now(), strings.HasPrefix(status, "5"))
 }
 
 func (m *Metrics) MetricsMiddleware(next http.Handler) http.Handler {
@@ -201,6 +489,11 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return size, err
 }
 
+// Unwrap дает http.ResponseController доступ к Flush исходного ResponseWriter
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func (m *Metrics) InstrumentedHandler() http.Handler {
 	return promhttp.Handler()
 }
@@ -230,6 +523,7 @@ func (h *Handler) MetricsData(w http.ResponseWriter, r *http.Request) {
 		Goroutines     int              `json:"goroutines"`
 		Handlers       []HandlerMetric  `json:"handlers"`
 		BusinessErrors []BusinessMetric `json:"business_errors"`
+		Teams          []teamMetric     `json:"teams"`
 		Totals         struct {
 			TotalRequests  float64 `json:"total_requests"`
 			TotalPRCreated float64 `json:"total_pr_created"`
@@ -237,6 +531,19 @@ func (h *Handler) MetricsData(w http.ResponseWriter, r *http.Request) {
 		} `json:"totals"`
 	}
 
+	// Имена метрик зависят от настроенного префикса
+	namespace := DefaultMetricsNamespace
+	if h.metrics != nil {
+		namespace = h.metrics.namespace
+	}
+	var (
+		requestsTotalName   = prometheus.BuildFQName(namespace, "", "http_requests_total")
+		requestDurationName = prometheus.BuildFQName(namespace, "", "http_request_duration_seconds")
+		businessErrorsName  = prometheus.BuildFQName(namespace, "", "business_errors_total")
+		prCreatedName       = prometheus.BuildFQName(namespace, "", "pr_created_total")
+		prMergedName        = prometheus.BuildFQName(namespace, "", "pr_merged_total")
+	)
+
 	// Собираем метрики из Prometheus
 	metrics, err := prometheus.DefaultGatherer.Gather()
 	if err != nil {
@@ -253,7 +560,7 @@ func (h *Handler) MetricsData(w http.ResponseWriter, r *http.Request) {
 		name := metric.GetName()
 
 		// HTTP requests - счетчики запросов
-		if name == "pr_service_http_requests_total" {
+		if name == requestsTotalName {
 			for _, m := range metric.GetMetric() {
 				var path, method, status string
 				for _, label := range m.GetLabel() {
@@ -294,7 +601,7 @@ func (h *Handler) MetricsData(w http.ResponseWriter, r *http.Request) {
 		name := metric.GetName()
 
 		// HTTP durations - длительности запросов
-		if name == "pr_service_http_request_duration_seconds" {
+		if name == requestDurationName {
 			for _, m := range metric.GetMetric() {
 				var path, method, status string
 				for _, label := range m.GetLabel() {
@@ -348,7 +655,7 @@ func (h *Handler) MetricsData(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Business errors
-		if name == "pr_service_business_errors_total" {
+		if name == businessErrorsName {
 			for _, m := range metric.GetMetric() {
 				var errorType string
 				for _, label := range m.GetLabel() {
@@ -364,14 +671,14 @@ func (h *Handler) MetricsData(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// PR created
-		if name == "pr_service_pr_created_total" {
+		if name == prCreatedName {
 			for _, m := range metric.GetMetric() {
 				totalPRCreated += m.GetCounter().GetValue()
 			}
 		}
 
 		// PR merged
-		if name == "pr_service_pr_merged_total" {
+		if name == prMergedName {
 			for _, m := range metric.GetMetric() {
 				totalPRMerged += m.GetCounter().GetValue()
 			}
@@ -416,6 +723,12 @@ func (h *Handler) MetricsData(w http.ResponseWriter, r *http.Request) {
 		return businessErrorsSlice[i].Count > businessErrorsSlice[j].Count
 	})
 
+	teams, err := collectTeamMetrics(prometheus.DefaultGatherer, namespace)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
 	// Формируем ответ
 	response := MetricsResponse{
 		Timestamp:      time.Now().UTC(),
@@ -423,6 +736,7 @@ func (h *Handler) MetricsData(w http.ResponseWriter, r *http.Request) {
 		Goroutines:     runtime.NumGoroutine(),
 		Handlers:       handlers,
 		BusinessErrors: businessErrorsSlice,
+		Teams:          teams,
 	}
 
 	response.Totals.TotalRequests = totalRequests
@@ -431,3 +745,91 @@ func (h *Handler) MetricsData(w http.ResponseWriter, r *http.Request) {
 
 	WriteJSON(w, http.StatusOK, response)
 }
+
+// teamMetric - сводка по PR команды из метрик с меткой team
+type teamMetric struct {
+	TeamName             string             `json:"team_name"`
+	PRCreated            float64            `json:"pr_created"`
+	PRMerged             float64            `json:"pr_merged"`
+	MergeRate            float64            `json:"merge_rate"` // Доля смерженных от созданных, %
+	ReviewerAssignments  float64            `json:"reviewer_assignments"`
+	AvgReviewersAssigned float64            `json:"avg_reviewers_assigned"`
+	Errors               float64            `json:"errors"`
+	ErrorsByType         map[string]float64 `json:"errors_by_type"`
+}
+
+// collectTeamMetrics собирает сводку по командам из счетчиков созданных и смерженных PR,
+// гистограммы назначенных ревьюеров и ошибок операций с PR
+func collectTeamMetrics(gatherer prometheus.Gatherer, namespace string) ([]teamMetric, error) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		createdName   = prometheus.BuildFQName(namespace, "", "pr_created_total")
+		mergedName    = prometheus.BuildFQName(namespace, "", "pr_merged_total")
+		reviewersName = prometheus.BuildFQName(namespace, "", "pr_reviewers_assigned_count")
+		errorsName    = prometheus.BuildFQName(namespace, "", "team_errors_total")
+	)
+
+	teams := make(map[string]*teamMetric)
+	team := func(name string) *teamMetric {
+		if teams[name] == nil {
+			teams[name] = &teamMetric{TeamName: name, ErrorsByType: map[string]float64{}}
+		}
+		return teams[name]
+	}
+	var reviewerSums = make(map[string]float64)
+
+	for _, family := range families {
+		name := family.GetName()
+		if name != createdName && name != mergedName && name != reviewersName && name != errorsName {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			var teamName, errorType string
+			for _, label := range m.GetLabel() {
+				switch label.GetName() {
+				case "team":
+					teamName = label.GetValue()
+				case "error_type":
+					errorType = label.GetValue()
+				}
+			}
+			if teamName == "" {
+				continue
+			}
+
+			t := team(teamName)
+			switch name {
+			case createdName:
+				t.PRCreated += m.GetCounter().GetValue()
+			case mergedName:
+				t.PRMerged += m.GetCounter().GetValue()
+			case reviewersName:
+				t.ReviewerAssignments += float64(m.GetHistogram().GetSampleCount())
+				reviewerSums[teamName] += m.GetHistogram().GetSampleSum()
+			case errorsName:
+				value := m.GetCounter().GetValue()
+				t.Errors += value
+				t.ErrorsByType[errorType] += value
+			}
+		}
+	}
+
+	result := make([]teamMetric, 0, len(teams))
+	for name, t := range teams {
+		if t.PRCreated > 0 {
+			t.MergeRate = t.PRMerged / t.PRCreated * 100
+		}
+		if t.ReviewerAssignments > 0 {
+			t.AvgReviewersAssigned = reviewerSums[name] / t.ReviewerAssignments
+		}
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TeamName < result[j].TeamName
+	})
+	return result, nil
+}
@@ -2,15 +2,25 @@ package api
 
 import (
 	"context"
+	"log"
 	"net/http"
+	"runtime/debug"
+	"strings"
 	"time"
+
+	"PR_service/internal/authctx"
 )
 
 const RequestTimeout = 300 * time.Millisecond
 
-// TimeoutMiddleware добавляет таймаут ко всем HTTP-запросам
+// TimeoutMiddleware добавляет таймаут ко всем HTTP-запросам, кроме выгрузок
 func TimeoutMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Выгрузка ограничена только отключением клиента
+		if exportPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
 
 		// Создаём контекст с таймаутом
 		ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout)
@@ -21,8 +31,16 @@ func TimeoutMiddleware(next http.Handler) http.Handler {
 
 		// Канал, который закроется, если запрос завершён
 		done := make(chan struct{})
+		// Паника в горутине завершила бы процесс: она передается в горутину запроса,
+		// где ее перехватит net/http. Ответ 500 отдает RecoveryMiddleware под этим middleware.
+		panicked := make(chan interface{}, 1)
 
 		go func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					panicked <- rec
+				}
+			}()
 			next.ServeHTTP(w, r)
 			close(done)
 		}()
@@ -32,8 +50,86 @@ func TimeoutMiddleware(next http.Handler) http.Handler {
 			// Таймаут или отмена клиента
 			http.Error(w, "request timed out", http.StatusGatewayTimeout)
 			return
+		case rec := <-panicked:
+			panic(rec)
 		case <-done:
 			return
 		}
 	})
 }
+
+// Заголовки, которые выставляет аутентифицирующий прокси перед сервисом
+const (
+	HeaderRequestID = "X-Request-ID"
+	HeaderAuthUser  = "X-Auth-User"
+	HeaderAuthRoles = "X-Auth-Roles"
+	HeaderAuthOrg   = "X-Auth-Org"
+	// HeaderAuthScopes - scope принципала через запятую или пробел. Без заголовка
+	// принципал не ограничен, только пока аутентификация не обязательна (AUTH_REQUIRED)
+	HeaderAuthScopes = "X-Auth-Scopes"
+)
+
+// AuthContextMiddleware кладет в контекст идентификатор запроса и принципала
+func AuthContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(HeaderRequestID)
+		if requestID == "" {
+			requestID = authctx.NewRequestID()
+		}
+		w.Header().Set(HeaderRequestID, requestID)
+
+		ctx := authctx.WithRequestID(r.Context(), requestID)
+
+		if userID := r.Header.Get(HeaderAuthUser); userID != "" {
+			var roles []string
+			for _, role := range strings.Split(r.Header.Get(HeaderAuthRoles), ",") {
+				if role = strings.TrimSpace(role); role != "" {
+					roles = append(roles, role)
+				}
+			}
+			principal := authctx.Principal{
+				ID:    userID,
+				Roles: roles,
+				Org:   r.Header.Get(HeaderAuthOrg),
+			}
+			if raw, ok := r.Header[http.CanonicalHeaderKey(HeaderAuthScopes)]; ok {
+				principal.Scopes = parseScopes(strings.Join(raw, ","))
+			}
+			ctx = authctx.WithPrincipal(ctx, principal)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RecoveryMiddleware перехватывает панику в хендлере, логирует стек с request ID
+// и отвечает 500 вместо обрыва соединения. Регистрируется сразу после TimeoutMiddleware:
+// тот выполняет остальную цепочку в отдельной горутине, и Recovery должен охватывать
+// все middleware в ней.
+func RecoveryMiddleware(metrics *Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				log.Printf("PANIC: %s %s [request_id=%s]: %v\n%s", r.Method, r.URL.Path,
+					authctx.RequestIDFrom(r.Context()), rec, debug.Stack())
+
+				if metrics != nil {
+					metrics.IncPanic(r.URL.Path)
+				}
+
+				WriteJSON(w, http.StatusInternalServerError,
+					createErrorResponse("INTERNAL_ERROR", "internal server error"))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
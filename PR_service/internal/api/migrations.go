@@ -0,0 +1,28 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// GetMigrationStatus возвращает версию схемы, флаг незавершенной миграции
+// и состояние каждой миграции сборки - для диагностики неудачных деплоев
+func (h *Handler) GetMigrationStatus(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	migrations, err := h.store.GetMigrationStatus(r.Context())
+	if err != nil {
+		status = "500"
+		log.Printf("GetMigrationStatus error: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, migrations)
+}
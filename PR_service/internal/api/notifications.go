@@ -0,0 +1,362 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+	"PR_service/internal/notify"
+	"PR_service/internal/storage"
+)
+
+// SetUserLocale задает локаль, на которой пользователь получает уведомления
+func (h *Handler) SetUserLocale(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.SetUserLocaleRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if req.UserID == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_USER_ID")
+		}
+		writeError(w, http.StatusBadRequest, "user_id is required")
+		return
+	}
+
+	locale, err := notify.NormalizeLocale(req.Locale)
+	if err != nil {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_LOCALE")
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.store.SetUserLocale(r.Context(), req.UserID, locale); err != nil {
+		status = "500"
+		if err.Error() == "user not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "SetUserLocale")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id": req.UserID,
+		"locale":  locale,
+	})
+}
+
+// GetNotificationTemplates возвращает шаблоны уведомлений команды и доступные встроенные локали
+func (h *Handler) GetNotificationTemplates(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_TEAM_NAME")
+		}
+		writeError(w, http.StatusBadRequest, "team_name query parameter is required")
+		return
+	}
+
+	templates, err := h.store.GetNotificationTemplates(r.Context(), teamName)
+	if err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "GetNotificationTemplates")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"team_name":       teamName,
+		"templates":       templates,
+		"default_locales": h.notifier.Locales(),
+	})
+}
+
+// SetNotificationTemplate сохраняет или удаляет шаблон уведомления команды
+func (h *Handler) SetNotificationTemplate(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.NotificationTemplate
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateNotificationTemplate(&req); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_NOTIFICATION_TEMPLATE")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if err := h.store.SetNotificationTemplate(r.Context(), req); err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "SetNotificationTemplate")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"template": req,
+	})
+}
+
+// PreviewNotification показывает уведомление так, как его получит пользователь
+func (h *Handler) PreviewNotification(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.NotificationPreviewRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateRequiredFields(map[string]string{
+		"kind":            req.Kind,
+		"user_id":         req.UserID,
+		"pull_request_id": req.PullRequestID,
+	}); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+	if !notify.IsKnownKind(req.Kind) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_NOTIFICATION_KIND")
+		}
+		writeError(w, http.StatusBadRequest, "unknown notification kind")
+		return
+	}
+
+	recipient, err := h.store.GetNotificationRecipient(r.Context(), req.UserID, req.PullRequestID, req.Kind)
+	if err != nil {
+		status = "500"
+		if err.Error() == "user not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "PreviewNotification")
+		return
+	}
+
+	pr, err := h.store.GetPRDetail(r.Context(), req.PullRequestID)
+	if err != nil {
+		status = "500"
+		if err.Error() == "pr not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "PreviewNotification")
+		return
+	}
+
+	var dueAt string
+	if recipient.DueAt != nil {
+		dueAt = notify.FormatTime(*recipient.DueAt, recipient.Locale, recipient.Location)
+	}
+
+	rendered, err := h.notifier.Render(req.Kind, recipient.Locale, recipient.Overrides, notify.Data{
+		RecipientID:     recipient.UserID,
+		RecipientName:   recipient.Username,
+		TeamName:        recipient.TeamName,
+		PullRequestID:   pr.PullRequestID,
+		PullRequestName: pr.PullRequestName,
+		AuthorID:        pr.AuthorID,
+		ReplacedUserID:  req.ReplacedUserID,
+		DueAt:           dueAt,
+	})
+	if err != nil {
+		status = "500"
+		h.handleStorageError(w, err, "PreviewNotification")
+		return
+	}
+
+	channels, routeErrors, err := h.store.MatchNotificationRoutes(r.Context(), req.PullRequestID, req.Kind)
+	if err != nil {
+		status = "500"
+		h.handleStorageError(w, err, "PreviewNotification")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, models.NotificationPreview{
+		Kind:        req.Kind,
+		UserID:      req.UserID,
+		Locale:      rendered.Locale,
+		Source:      rendered.Source,
+		Text:        rendered.Text,
+		Channels:    channels,
+		RouteErrors: routeErrors,
+	})
+}
+
+// GetNotificationRoutes возвращает маршруты уведомлений команды
+func (h *Handler) GetNotificationRoutes(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_TEAM_NAME")
+		}
+		writeError(w, http.StatusBadRequest, "team_name query parameter is required")
+		return
+	}
+
+	routes, err := h.store.GetNotificationRoutes(r.Context(), teamName)
+	if err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "GetNotificationRoutes")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"team_name": teamName,
+		"routes":    routes,
+	})
+}
+
+// SetNotificationRoute сохраняет или удаляет маршрут уведомлений команды
+func (h *Handler) SetNotificationRoute(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.NotificationRoute
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateNotificationRoute(req); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_NOTIFICATION_ROUTE")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if err := h.store.SetNotificationRoute(r.Context(), req); err != nil {
+		status = "500"
+		if err.Error() == "team not found" || err.Error() == "notification route not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "SetNotificationRoute")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"route": req,
+	})
+}
+
+// validateNotificationRoute проверяет маршрут уведомлений; условие компилируется,
+// чтобы ошибки возвращались при сохранении, а не при отправке
+func validateNotificationRoute(route models.NotificationRoute) string {
+	if route.TeamName == "" || route.Name == "" {
+		return "team_name and name are required"
+	}
+	if len(route.Name) > storage.MaxNotificationRouteNameLength {
+		return fmt.Sprintf("name must be at most %d characters", storage.MaxNotificationRouteNameLength)
+	}
+	if route.Channel == "" {
+		return ""
+	}
+	if len(route.Channel) > storage.MaxNotificationRouteChannelLength {
+		return fmt.Sprintf("channel must be at most %d characters", storage.MaxNotificationRouteChannelLength)
+	}
+	if route.Condition == "" {
+		return "condition is required"
+	}
+	if _, err := domain.CompileNotificationRoute(route.Condition); err != nil {
+		return fmt.Sprintf("condition: %v", err)
+	}
+	return ""
+}
+
+// validateNotificationTemplate проверяет шаблон команды и нормализует его локаль
+func validateNotificationTemplate(t *models.NotificationTemplate) string {
+	if t.TeamName == "" || t.Kind == "" || t.Locale == "" {
+		return "team_name, kind and locale are required"
+	}
+	if !notify.IsKnownKind(t.Kind) {
+		return "unknown notification kind"
+	}
+
+	locale, err := notify.NormalizeLocale(t.Locale)
+	if err != nil {
+		return err.Error()
+	}
+	t.Locale = locale
+
+	if t.Body != "" {
+		if err := notify.Validate(t.Kind, t.Body); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
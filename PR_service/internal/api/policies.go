@@ -0,0 +1,36 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"PR_service/internal/models"
+)
+
+// maxPRNamePatternLength - предел длины регулярного выражения для имени PR
+const maxPRNamePatternLength = 200
+
+// validatePRNamePattern проверяет регулярное выражение политики имени PR из настроек команды
+func validatePRNamePattern(pattern string) string {
+	if pattern == "" {
+		return ""
+	}
+	if len(pattern) > maxPRNamePatternLength {
+		return fmt.Sprintf("pr_name_pattern must be at most %d characters", maxPRNamePatternLength)
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Sprintf("pr_name_pattern is not a valid regular expression: %v", err)
+	}
+	return ""
+}
+
+// writePolicyViolations отвечает 422 со списком нарушений политик команды
+func writePolicyViolations(w http.ResponseWriter, teamName string, violations []models.PolicyViolation) {
+	errorResp := createErrorResponse("POLICY_VIOLATION",
+		fmt.Sprintf("pull request violates %d policies of team %s", len(violations), teamName))
+	errorResp.Data = map[string]interface{}{
+		"violations": violations,
+	}
+	WriteJSON(w, http.StatusUnprocessableEntity, errorResp)
+}
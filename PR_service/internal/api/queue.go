@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+// GetReviewQueue возвращает PR команды, ждущие освобождения ревьюеров, в порядке очереди
+func (h *Handler) GetReviewQueue(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_TEAM_NAME")
+		}
+		writeError(w, http.StatusBadRequest, "team_name query parameter is required")
+		return
+	}
+
+	queue, err := h.store.GetReviewQueue(r.Context(), teamName)
+	if err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "GetReviewQueue")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"team_name": teamName,
+		"depth":     len(queue),
+		"queue":     queue,
+	})
+}
+
+// WatchReviewQueues периодически обновляет метрики очередей и бэклога ревью
+func (h *Handler) WatchReviewQueues(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.refreshReviewQueueDepths(ctx)
+		h.refreshAdaptiveReviewerStates(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) refreshReviewQueueDepths(ctx context.Context) {
+	depths, err := h.store.ReviewQueueDepths(ctx)
+	if err != nil {
+		log.Printf("Review queue refresh error: %v", err)
+		return
+	}
+	// Последние глубины очередей показывает /status
+	h.queueDepths.Store(&depths)
+	if h.metrics != nil {
+		h.metrics.SetReviewQueueDepths(depths)
+	}
+}
+
+func (h *Handler) refreshAdaptiveReviewerStates(ctx context.Context) {
+	if h.metrics == nil {
+		return
+	}
+	states, err := h.store.AdaptiveReviewerStates(ctx)
+	if err != nil {
+		log.Printf("Adaptive reviewer count refresh error: %v", err)
+		return
+	}
+	h.metrics.SetAdaptiveReviewerStates(states)
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// validateAssignmentQuota проверяет квоту назначений; пустой период - сутки
+func validateAssignmentQuota(quota *models.AssignmentQuota) string {
+	if quota.MaxAssignments < 0 || quota.MaxAssignments > storage.MaxAssignmentQuota {
+		return fmt.Sprintf("max_assignments must be between 0 and %d", storage.MaxAssignmentQuota)
+	}
+	if quota.Period == "" {
+		quota.Period = storage.QuotaPeriodDay
+	}
+	if !storage.IsKnownQuotaPeriod(quota.Period) {
+		return fmt.Sprintf("period must be %s or %s", storage.QuotaPeriodDay, storage.QuotaPeriodWeek)
+	}
+	return ""
+}
+
+// SetAssignmentQuota задает квоту новых назначений пользователя ревьюером за скользящее окно
+func (h *Handler) SetAssignmentQuota(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.AssignmentQuota
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateRequiredFields(map[string]string{
+		"user_id": req.UserID,
+	}); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if errMsg := validateAssignmentQuota(&req); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_ASSIGNMENT_QUOTA")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if err := h.store.SetAssignmentQuota(r.Context(), req); err != nil {
+		status = "500"
+		if err.Error() == "user not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "SetAssignmentQuota")
+		return
+	}
+
+	quota, err := h.store.GetAssignmentQuota(r.Context(), req.UserID)
+	if err != nil {
+		status = "500"
+		h.handleStorageError(w, err, "SetAssignmentQuota")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"quota": quota,
+	})
+}
+
+// GetAssignmentQuota возвращает квоту назначений пользователя и ее использование
+func (h *Handler) GetAssignmentQuota(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	uid := r.URL.Query().Get("user_id")
+	if uid == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_USER_ID")
+		}
+		writeError(w, http.StatusBadRequest, "user_id query parameter is required")
+		return
+	}
+
+	quota, err := h.store.GetAssignmentQuota(r.Context(), uid)
+	if err != nil {
+		status = "500"
+		if err.Error() == "user not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "GetAssignmentQuota")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"quota": quota,
+	})
+}
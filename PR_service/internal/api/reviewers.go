@@ -0,0 +1,100 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// AddReviewer добавляет PR ревьюера "второго мнения" сверх обычного числа
+func (h *Handler) AddReviewer(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.AddReviewerRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if req.PullRequestID == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_PR_ID")
+		}
+		writeError(w, http.StatusBadRequest, "pull_request_id is required")
+		return
+	}
+
+	updatedPR, added, err := h.store.AddReviewer(r.Context(), req.PullRequestID, req.UserID)
+	if err != nil {
+		status = h.handleAddReviewerError(w, err)
+		return
+	}
+
+	if h.metrics != nil {
+		teamName := h.getAuthorTeam(r.Context(), updatedPR.AuthorID)
+		if teamName == "" {
+			teamName = "unknown"
+		}
+		h.metrics.ObserveReviewersAssigned(teamName, len(updatedPR.Reviewers))
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pr":          updatedPR,
+		"added":       added,
+		"source":      storage.ReviewerSourceManual,
+		"auto_picked": req.UserID == "",
+	})
+}
+
+// addReviewerErrors - код ошибки API для известных ошибок AddReviewer
+var addReviewerErrors = map[string]string{
+	"pr not found":                        "NOT_FOUND",
+	"user not found":                      "NOT_FOUND",
+	"author is not in any team":           "NOT_FOUND",
+	"cannot modify reviewers after merge": "PR_MERGED",
+	"reviewer already assigned":           "ALREADY_ASSIGNED",
+	"reviewer excluded by author":         "REVIEWER_EXCLUDED",
+	"reviewer is inactive":                "REVIEWER_INACTIVE",
+	"reviewer is a bot":                   "REVIEWER_BOT",
+	"author cannot review own PR":         "AUTHOR_REVIEWER",
+	"reviewer limit reached":              "REVIEWER_LIMIT",
+	"no active candidate available":       "NO_CANDIDATE",
+}
+
+// handleAddReviewerError пишет ответ об ошибке и возвращает HTTP статус для метрик
+func (h *Handler) handleAddReviewerError(w http.ResponseWriter, err error) string {
+	log.Printf("AddReviewer error: %v", err)
+
+	code, known := addReviewerErrors[err.Error()]
+	if !known {
+		code = "INTERNAL_ERROR"
+	}
+	if h.metrics != nil {
+		h.metrics.IncBusinessError(code)
+	}
+
+	errorResp := createErrorResponse(code, err.Error())
+	switch {
+	case !known:
+		WriteJSON(w, http.StatusInternalServerError, errorResp)
+		return "500"
+	case code == "NOT_FOUND":
+		WriteJSON(w, http.StatusNotFound, errorResp)
+		return "404"
+	default:
+		WriteJSON(w, http.StatusConflict, errorResp)
+		return "409"
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// defaultRotationWeeks - сколько недель расписания дежурств показывать по умолчанию
+const defaultRotationWeeks = 4
+
+// GetTeamRotation возвращает дежурных ревьюеров команды на ближайшие weeks недель
+func (h *Handler) GetTeamRotation(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	query := r.URL.Query()
+	teamName := query.Get("team_name")
+	if teamName == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_TEAM_NAME")
+		}
+		writeError(w, http.StatusBadRequest, "team_name query parameter is required")
+		return
+	}
+
+	weeks, errMsg := parseRotationWeeks(query.Get("weeks"))
+	if errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_ROTATION")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	rotation, err := h.store.GetTeamRotation(r.Context(), teamName, weeks)
+	if err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "GetTeamRotation")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, rotation)
+}
+
+// SetTeamRotation назначает дежурного на неделю вместо очереди или возвращает неделю очереди
+func (h *Handler) SetTeamRotation(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.SetRotationRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateRequiredFields(map[string]string{
+		"team_name":  req.TeamName,
+		"week_start": req.WeekStart,
+	}); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	week, err := time.Parse("2006-01-02", req.WeekStart)
+	if err != nil {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_ROTATION")
+		}
+		writeError(w, http.StatusBadRequest, "week_start must be a date in YYYY-MM-DD format")
+		return
+	}
+
+	rotation, err := h.store.SetRotationOverride(r.Context(), req.TeamName, week, req.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "user is not a member of the team", "cannot change rotation for past weeks",
+			fmt.Sprintf("week_start must be within %d weeks", storage.MaxRotationWeeks):
+			status = "400"
+			if h.metrics != nil {
+				h.metrics.IncBusinessError("INVALID_ROTATION")
+			}
+			WriteJSON(w, http.StatusBadRequest, createErrorResponse("INVALID_ROTATION", err.Error()))
+		default:
+			status = "500"
+			h.handleStorageError(w, err, "SetTeamRotation")
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, rotation)
+}
+
+// parseRotationWeeks разбирает число недель расписания дежурств
+func parseRotationWeeks(raw string) (int, string) {
+	if raw == "" {
+		return defaultRotationWeeks, ""
+	}
+	weeks, err := strconv.Atoi(raw)
+	if err != nil || weeks < 1 || weeks > storage.MaxRotationWeeks {
+		return 0, fmt.Sprintf("weeks must be between 1 and %d", storage.MaxRotationWeeks)
+	}
+	return weeks, ""
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"PR_service/internal/authctx"
+)
+
+// ScopePolicy - требования аутентификации по маршрутам
+type ScopePolicy struct {
+	Routes      map[string]string // Путь -> требуемый scope; перекрывает правило по префиксу
+	Exempt      map[string]bool   // Пути без аутентификации и проверки scope
+	RequireAuth bool              // Запросы без принципала к остальным путям отклоняются с 401
+}
+
+// DefaultExemptPaths - пути, доступные без аутентификации: пробы, метрики
+// и вебхуки, которые проверяются подписью
+var DefaultExemptPaths = []string{"/", "/health", "/health/ready", healthzPath, "/metrics", "/webhooks/bitbucket"}
+
+// DefaultScopePolicy возвращает политику без обязательной аутентификации
+// с исключениями по умолчанию
+func DefaultScopePolicy() ScopePolicy {
+	exempt := make(map[string]bool, len(DefaultExemptPaths))
+	for _, path := range DefaultExemptPaths {
+		exempt[path] = true
+	}
+	return ScopePolicy{Routes: map[string]string{}, Exempt: exempt}
+}
+
+// routeScopePrefixes - scope на чтение и изменение по префиксу пути
+var routeScopePrefixes = []struct {
+	prefix      string
+	read, write string
+}{
+	{"/team/", authctx.ScopeTeamsRead, authctx.ScopeTeamsWrite},
+	{"/users/", authctx.ScopeUsersRead, authctx.ScopeUsersWrite},
+	{"/pullRequest/", authctx.ScopePRsRead, authctx.ScopePRsWrite},
+	{"/reports/", authctx.ScopeReportsRead, authctx.ScopeReportsRead},
+	{"/metrics/", authctx.ScopeReportsRead, authctx.ScopeReportsRead},
+	{"/notifications/", authctx.ScopeTeamsRead, authctx.ScopeTeamsRead}, // Предпросмотр ничего не меняет
+	{"/admin/", authctx.ScopeAdmin, authctx.ScopeAdmin},
+	{"/audit/", authctx.ScopeAdmin, authctx.ScopeAdmin},
+}
+
+// readOnlyRoutes - маршруты, которые принимают POST, но ничего не меняют
+var readOnlyRoutes = map[string]bool{
+	"/team/settings/preview": true,
+}
+
+// RequiredScope возвращает scope, нужный для запроса.
+// Пути без правила требуют admin, чтобы новый маршрут не оказался открыт для интеграций.
+func (p ScopePolicy) RequiredScope(method, path string) string {
+	if scope, ok := p.Routes[path]; ok {
+		return scope
+	}
+	if readOnlyRoutes[path] {
+		method = http.MethodGet
+	}
+	for _, rule := range routeScopePrefixes {
+		if strings.HasPrefix(path, rule.prefix) {
+			if isReadOnlyMethod(method) {
+				return rule.read
+			}
+			return rule.write
+		}
+	}
+	return authctx.ScopeAdmin
+}
+
+// ParseRouteScopes разбирает переопределения вида "/pullRequest/export=reports:read,/team/get=teams:write"
+func ParseRouteScopes(raw string) (map[string]string, error) {
+	routes := make(map[string]string)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		path, scope, ok := strings.Cut(item, "=")
+		path, scope = strings.TrimSpace(path), strings.TrimSpace(scope)
+		if !ok || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid route scope %q, expected /path=scope", item)
+		}
+		if !authctx.IsKnownScope(scope) {
+			return nil, fmt.Errorf("unknown scope %q for %s", scope, path)
+		}
+		if _, dup := routes[path]; dup {
+			return nil, fmt.Errorf("duplicate route %s", path)
+		}
+		routes[path] = scope
+	}
+	return routes, nil
+}
+
+// parseScopes разбирает список scope через запятую или пробел. Неизвестные scope
+// сохраняются: они не дают прав, но видны в логах прокси.
+func parseScopes(raw string) []string {
+	scopes := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' })
+	if scopes == nil {
+		return []string{}
+	}
+	return scopes
+}
+
+// SetScopePolicy задает требования аутентификации по маршрутам
+func (h *Handler) SetScopePolicy(policy ScopePolicy) {
+	h.scopes = policy
+}
+
+// ScopeMiddleware проверяет, что принципал запроса может вызвать маршрут: принципал
+// с X-Auth-Scopes ограничен своими scope. При RequireAuth анонимные запросы отклоняются,
+// а принципалу без scope нужен явный доступ "*".
+func (h *Handler) ScopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.scopes.Exempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Без обязательной аутентификации анонимный запрос и принципал без X-Auth-Scopes
+		// не ограничены одинаково; scope, переданные прокси, ограничивают всегда
+		principal, ok := authctx.PrincipalFrom(r.Context())
+		if !h.scopes.RequireAuth && (!ok || principal.Scopes == nil) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !ok {
+			if h.metrics != nil {
+				h.metrics.IncBusinessError("UNAUTHENTICATED")
+			}
+			WriteJSON(w, http.StatusUnauthorized, createErrorResponse("UNAUTHENTICATED", "authentication required"))
+			return
+		}
+
+		if scope := h.scopes.RequiredScope(r.Method, r.URL.Path); !principal.HasScope(scope) {
+			if h.metrics != nil {
+				h.metrics.IncBusinessError("INSUFFICIENT_SCOPE")
+			}
+			WriteJSON(w, http.StatusForbidden, createErrorResponse("INSUFFICIENT_SCOPE",
+				fmt.Sprintf("token lacks scope %s", scope)))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
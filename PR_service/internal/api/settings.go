@@ -0,0 +1,245 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+	"PR_service/internal/notify"
+	"PR_service/internal/storage"
+)
+
+// GetTeamSettings возвращает настройки назначения ревьюеров команды
+func (h *Handler) GetTeamSettings(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_TEAM_NAME")
+		}
+		writeError(w, http.StatusBadRequest, "team_name query parameter is required")
+		return
+	}
+
+	settings, err := h.store.GetTeamSettings(r.Context(), teamName)
+	if err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "GetTeamSettings")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, settings)
+}
+
+// PreviewTeamSettings показывает влияние новых настроек на открытые PR без их сохранения
+func (h *Handler) PreviewTeamSettings(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	settings, ok := h.bindTeamSettings(w, r)
+	if !ok {
+		status = "400"
+		return
+	}
+
+	impact, err := h.store.PreviewTeamSettings(r.Context(), settings)
+	if err != nil {
+		status = "500"
+		if err.Error() == "team not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "PreviewTeamSettings")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, impact)
+}
+
+// ApplyTeamSettings сохраняет настройки и приводит открытые PR в соответствие с ними
+func (h *Handler) ApplyTeamSettings(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	settings, ok := h.bindTeamSettings(w, r)
+	if !ok {
+		status = "400"
+		return
+	}
+
+	result, err := h.store.ApplyTeamSettings(r.Context(), settings)
+	if err != nil {
+		status = "500"
+		switch err.Error() {
+		case "team not found":
+			status = "404"
+		case storage.ErrTeamLeadRequired.Error():
+			status = "403"
+		}
+		h.handleStorageError(w, err, "ApplyTeamSettings")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, result)
+}
+
+// bindTeamSettings разбирает и валидирует настройки команды из тела запроса
+func (h *Handler) bindTeamSettings(w http.ResponseWriter, r *http.Request) (models.TeamSettings, bool) {
+	var settings models.TeamSettings
+	if !h.bindJSON(w, r, &settings) {
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return settings, false
+	}
+
+	if errMsg := validateRequiredFields(map[string]string{
+		"team_name": settings.TeamName,
+		"strategy":  settings.Strategy,
+	}); errMsg != "" {
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return settings, false
+	}
+
+	if settings.MergePolicy == "" {
+		settings.MergePolicy = domain.MergePolicyNone
+	}
+	if locale, err := notify.NormalizeLocale(settings.Locale); err == nil {
+		settings.Locale = locale
+	}
+
+	if errMsg := validateTeamSettings(settings); errMsg != "" {
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_TEAM_SETTINGS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return settings, false
+	}
+
+	return settings, true
+}
+
+// validateTeamSettings проверяет значения настроек команды
+func validateTeamSettings(settings models.TeamSettings) string {
+	if settings.ReviewerCount < 0 || settings.ReviewerCount > storage.MaxReviewerCount {
+		return fmt.Sprintf("reviewer_count must be between 0 and %d", storage.MaxReviewerCount)
+	}
+	if !domain.IsKnownStrategy(settings.Strategy) {
+		return fmt.Sprintf("unknown strategy %q", settings.Strategy)
+	}
+	if settings.MergePolicy != "" && !domain.IsKnownMergePolicy(settings.MergePolicy) {
+		return fmt.Sprintf("unknown merge_policy %q", settings.MergePolicy)
+	}
+	if settings.MergePolicy == domain.MergePolicyQuorum {
+		if settings.RequiredApprovals < 1 || settings.RequiredApprovals > storage.MaxReviewerCount {
+			return fmt.Sprintf("required_approvals must be between 1 and %d for quorum policy", storage.MaxReviewerCount)
+		}
+	} else if settings.RequiredApprovals != 0 {
+		return "required_approvals is only allowed for quorum policy"
+	}
+	if settings.MaxOpenReviews < 0 || settings.MaxOpenReviews > storage.MaxOpenReviewsLimit {
+		return fmt.Sprintf("max_open_reviews must be between 0 and %d", storage.MaxOpenReviewsLimit)
+	}
+	if settings.AdaptiveBacklogThreshold < 0 {
+		return "adaptive_backlog_threshold must not be negative"
+	}
+	if settings.AdaptiveBacklogThreshold > 0 {
+		if settings.AdaptiveReviewerCount < 1 || settings.AdaptiveReviewerCount >= settings.ReviewerCount {
+			return "adaptive_reviewer_count must be at least 1 and less than reviewer_count"
+		}
+	} else if settings.AdaptiveReviewerCount != 0 {
+		return "adaptive_reviewer_count is only allowed with adaptive_backlog_threshold"
+	}
+	if settings.NewMemberHoldbackDays < 0 || settings.NewMemberHoldbackDays > storage.MaxHoldbackDays {
+		return fmt.Sprintf("new_member_holdback_days must be between 0 and %d", storage.MaxHoldbackDays)
+	}
+	if errMsg := validatePRNamePattern(settings.PRNamePattern); errMsg != "" {
+		return errMsg
+	}
+	if settings.MinTags < 0 || settings.MinTags > storage.MaxPRTags {
+		return fmt.Sprintf("min_tags must be between 0 and %d", storage.MaxPRTags)
+	}
+	if errMsg := validateCandidateExpressions(settings); errMsg != "" {
+		return errMsg
+	}
+	if settings.ReviewSLAHours < 0 || settings.ReviewSLAHours > domain.MaxReviewSLAHours {
+		return fmt.Sprintf("review_sla_hours must be between 0 and %d", domain.MaxReviewSLAHours)
+	}
+	if errMsg := validateTeamLocale(settings); errMsg != "" {
+		return errMsg
+	}
+	return validateApprovalStages(settings.ApprovalStages)
+}
+
+// validateTeamLocale проверяет локаль и пояс IANA команды
+func validateTeamLocale(settings models.TeamSettings) string {
+	if _, err := notify.NormalizeLocale(settings.Locale); err != nil {
+		return fmt.Sprintf("locale: %v", err)
+	}
+	if _, err := storage.LoadTimezone(settings.Timezone); err != nil {
+		return fmt.Sprintf("timezone: %v", err)
+	}
+	return ""
+}
+
+// validateCandidateExpressions компилирует фильтр и ранг кандидатов команды, чтобы
+// синтаксические ошибки и ошибки типов возвращались при сохранении, а не при назначении.
+// Ранг меняет веса кандидатов, а стратегия random их не учитывает.
+func validateCandidateExpressions(settings models.TeamSettings) string {
+	if settings.CandidateFilter != "" {
+		if _, err := domain.CompileCandidateFilter(settings.CandidateFilter); err != nil {
+			return fmt.Sprintf("candidate_filter: %v", err)
+		}
+	}
+	if settings.CandidateRank != "" {
+		if settings.Strategy == domain.StrategyRandom {
+			return fmt.Sprintf("candidate_rank requires %s or %s strategy", domain.StrategyLeastLoaded, domain.StrategyKnowledgeSpread)
+		}
+		if _, err := domain.CompileCandidateRank(settings.CandidateRank); err != nil {
+			return fmt.Sprintf("candidate_rank: %v", err)
+		}
+	}
+	return ""
+}
+
+// validateApprovalStages проверяет цепочку одобрений. Первый этап - ревьюеры, назначенные
+// при создании PR, поэтому он может быть только peer.
+func validateApprovalStages(stages []models.ApprovalStage) string {
+	if len(stages) > domain.MaxApprovalStages {
+		return fmt.Sprintf("approval_stages must contain at most %d stages", domain.MaxApprovalStages)
+	}
+	for i, stage := range stages {
+		if !domain.IsKnownApprovalStage(stage.Kind) {
+			return fmt.Sprintf("approval_stages[%d]: unknown kind %q", i, stage.Kind)
+		}
+		if i == 0 && stage.Kind != domain.ApprovalStagePeer {
+			return fmt.Sprintf("approval_stages[0]: first stage must be %s", domain.ApprovalStagePeer)
+		}
+		if stage.Approvals < 1 || stage.Approvals > storage.MaxReviewerCount {
+			return fmt.Sprintf("approval_stages[%d]: approvals must be between 1 and %d", i, storage.MaxReviewerCount)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/scheduler"
+)
+
+// statusWindowMinutes - окно доли ошибок на странице /status
+const statusWindowMinutes = 5
+
+// statusErrorRateDegraded - доля ответов 5xx, при которой реплика считается деградировавшей
+const statusErrorRateDegraded = 0.05
+
+// requestWindow - счетчики запросов и ответов 5xx по минутам за последние statusWindowMinutes
+type requestWindow struct {
+	mu      sync.Mutex
+	buckets [statusWindowMinutes]requestBucket
+}
+
+type requestBucket struct {
+	minute        int64 // Номер минуты Unix; бакет другой минуты считается пустым
+	total, errors int
+}
+
+func (w *requestWindow) record(at time.Time, serverError bool) {
+	minute := at.Unix() / 60
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b := &w.buckets[minute%statusWindowMinutes]
+	if b.minute != minute {
+		*b = requestBucket{minute: minute}
+	}
+	b.total++
+	if serverError {
+		b.errors++
+	}
+}
+
+// counts возвращает число запросов и ответов 5xx за окно, заканчивающееся в now
+func (w *requestWindow) counts(now time.Time) (total, errors int) {
+	minute := now.Unix() / 60
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, b := range w.buckets {
+		if b.minute > minute-statusWindowMinutes && b.minute <= minute {
+			total += b.total
+			errors += b.errors
+		}
+	}
+	return total, errors
+}
+
+// SetScheduler показывает фоновые задачи реплики на странице /status
+func (h *Handler) SetScheduler(s *scheduler.Scheduler) {
+	h.scheduler = s
+}
+
+// Status отдает сводку состояния реплики из внутреннего состояния без запросов к БД:
+// HTML для браузера, JSON для остальных клиентов или с ?format=json
+func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	defer h.recordHandlerDuration(r, start, "200")
+
+	status := h.serviceStatus(time.Now())
+
+	if !wantsHTML(r) {
+		WriteJSON(w, http.StatusOK, status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := statusPage.Execute(w, status); err != nil {
+		http.Error(w, "failed to render status page", http.StatusInternalServerError)
+	}
+}
+
+// wantsHTML выбирает формат ответа /status: параметр format, затем заголовок Accept
+func wantsHTML(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "json":
+		return false
+	case "html":
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// serviceStatus собирает сводку: кэш /healthz, прогрев, режим обслуживания,
+// окно запросов метрик, глубины очередей и запуски планировщика
+func (h *Handler) serviceStatus(now time.Time) models.ServiceStatus {
+	status := models.ServiceStatus{
+		Status:        "ok",
+		Version:       getVersion(),
+		Timestamp:     now.UTC(),
+		StartedAt:     appStartTime.UTC(),
+		UptimeSeconds: now.Sub(appStartTime).Seconds(),
+		Database:      "unknown",
+		WarmingUp:     h.warmingUp.Load(),
+		QueueDepths:   map[string]int{},
+	}
+	if h.maintenance != nil {
+		status.Maintenance = h.maintenance.get()
+	}
+
+	degrade := func(problem string) {
+		if status.Status == "ok" {
+			status.Status = "degraded"
+		}
+		status.Problems = append(status.Problems, problem)
+	}
+
+	// Результат проверки БД берется из фоновой проверки /healthz; во время прогрева она не выполняется
+	checkedAt := h.healthCheckedAt.Load()
+	switch {
+	case status.WarmingUp:
+		degrade("replica is warming up")
+	case checkedAt == 0 || now.Sub(time.Unix(0, checkedAt)) > healthzStaleAfter:
+		status.Status = "unavailable"
+		status.Problems = append(status.Problems, "database health check is stale")
+	case h.healthy.Load():
+		status.Database = "ok"
+	default:
+		status.Database = "unavailable"
+		status.Status = "unavailable"
+		status.Problems = append(status.Problems, "database health check is failing")
+	}
+	if status.Maintenance.Enabled {
+		degrade("maintenance mode is enabled")
+	}
+
+	if h.metrics != nil {
+		total, errors := h.metrics.recent.counts(now)
+		requests := &models.RecentRequests{WindowSeconds: statusWindowMinutes * 60, Total: total, ServerErrors: errors}
+		if total > 0 {
+			requests.ErrorRate = float64(errors) / float64(total)
+		}
+		if requests.ErrorRate >= statusErrorRateDegraded {
+			degrade("server error rate is above 5%")
+		}
+		status.Requests = requests
+	}
+
+	if depths := h.queueDepths.Load(); depths != nil {
+		for team, depth := range *depths {
+			status.QueueDepths[team] = depth
+		}
+	}
+
+	if h.scheduler != nil {
+		sched := &models.SchedulerStatus{Leader: h.scheduler.IsLeader(), Jobs: []models.JobRun{}}
+		for _, job := range h.scheduler.JobStatuses() {
+			run := models.JobRun{
+				Name:            job.Name,
+				IntervalSeconds: job.Interval.Seconds(),
+				LastDurationMs:  float64(job.Duration.Microseconds()) / 1000,
+				LastError:       job.Err,
+			}
+			if !job.LastRun.IsZero() {
+				lastRun := job.LastRun.UTC()
+				run.LastRunAt = &lastRun
+			}
+			if job.Err != "" {
+				degrade("background job " + job.Name + " failed")
+			}
+			sched.Jobs = append(sched.Jobs, run)
+		}
+		status.Scheduler = sched
+	}
+
+	return status
+}
+
+// queueDepthRow - строка таблицы очередей на HTML-странице
+type queueDepthRow struct {
+	Team  string
+	Depth int
+}
+
+// sortedDepths возвращает глубины очередей по убыванию для HTML-страницы
+func sortedDepths(depths map[string]int) []queueDepthRow {
+	rows := make([]queueDepthRow, 0, len(depths))
+	for team, depth := range depths {
+		rows = append(rows, queueDepthRow{team, depth})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Depth != rows[j].Depth {
+			return rows[i].Depth > rows[j].Depth
+		}
+		return rows[i].Team < rows[j].Team
+	})
+	return rows
+}
+
+var statusPage = template.Must(template.New("status").Funcs(template.FuncMap{
+	"depths":   sortedDepths,
+	"duration": func(seconds float64) string { return (time.Duration(seconds) * time.Second).String() },
+	"percent":  func(rate float64) string { return strconv.FormatFloat(rate*100, 'f', 1, 64) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="15">
+<title>PR Reviewer Service - {{.Status}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+.ok { color: #1a7f37; } .degraded { color: #9a6700; } .unavailable { color: #cf222e; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { border: 1px solid #ddd; padding: 4px 10px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>PR Reviewer Service: <span class="{{.Status}}">{{.Status}}</span></h1>
+{{if .Problems}}<ul>{{range .Problems}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<table>
+<tr><th>Version</th><td>{{.Version}}</td></tr>
+<tr><th>Uptime</th><td>{{duration .UptimeSeconds}} (since {{.StartedAt.Format "2006-01-02 15:04:05 MST"}})</td></tr>
+<tr><th>Database</th><td class="{{if eq .Database "ok"}}ok{{else}}unavailable{{end}}">{{.Database}}</td></tr>
+<tr><th>Warming up</th><td>{{.WarmingUp}}</td></tr>
+<tr><th>Maintenance</th><td>{{if .Maintenance.Enabled}}enabled{{with .Maintenance.Message}}: {{.}}{{end}}{{else}}disabled{{end}}</td></tr>
+{{with .Requests}}<tr><th>Requests (last {{.WindowSeconds}}s)</th><td>{{.Total}} total, {{.ServerErrors}} server errors ({{percent .ErrorRate}}%)</td></tr>{{end}}
+</table>
+<h2>Review queues</h2>
+{{with depths .QueueDepths}}<table><tr><th>Team</th><th>Queued PRs</th></tr>
+{{range .}}<tr><td>{{.Team}}</td><td>{{.Depth}}</td></tr>
+{{end}}</table>{{else}}<p>No queued pull requests.</p>{{end}}
+{{with .Scheduler}}<h2>Background jobs{{if not .Leader}} (this replica is not the leader){{end}}</h2>
+<table><tr><th>Job</th><th>Interval</th><th>Last run</th><th>Duration, ms</th><th>Error</th></tr>
+{{range .Jobs}}<tr><td>{{.Name}}</td><td>{{duration .IntervalSeconds}}</td><td>{{with .LastRunAt}}{{.Format "2006-01-02 15:04:05 MST"}}{{else}}never{{end}}</td><td>{{.LastDurationMs}}</td><td class="{{if .LastError}}unavailable{{end}}">{{.LastError}}</td></tr>
+{{end}}</table>{{end}}
+<p>Generated {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}. JSON: <a href="?format=json">?format=json</a></p>
+</body>
+</html>
+`))
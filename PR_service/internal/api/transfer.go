@@ -0,0 +1,92 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// TransferAuthor передает открытые PR ушедшего автора преемнику или лиду его команды
+func (h *Handler) TransferAuthor(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.TransferAuthorRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if req.AuthorID == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_AUTHOR_ID")
+		}
+		writeError(w, http.StatusBadRequest, "author_id is required")
+		return
+	}
+
+	successor, prs, err := h.store.TransferAuthor(r.Context(), req.AuthorID, req.SuccessorID)
+	if err != nil {
+		status = h.handleTransferAuthorError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"author_id":    req.AuthorID,
+		"successor_id": successor,
+		"transferred":  prs,
+	})
+}
+
+// transferAuthorErrors - код ошибки API для известных ошибок TransferAuthor
+var transferAuthorErrors = map[string]string{
+	"author not found":                  "NOT_FOUND",
+	"author is not in any team":         "NOT_FOUND",
+	"successor not found":               "NOT_FOUND",
+	"successor is not in any team":      "NOT_FOUND",
+	"author is still active":            "AUTHOR_ACTIVE",
+	"successor is inactive":             "SUCCESSOR_INACTIVE",
+	"successor is a bot":                "SUCCESSOR_BOT",
+	"no successor available":            "NO_SUCCESSOR",
+	storage.ErrTeamLeadRequired.Error(): "TEAM_LEAD_REQUIRED",
+}
+
+// handleTransferAuthorError пишет ответ об ошибке и возвращает HTTP статус для метрик
+func (h *Handler) handleTransferAuthorError(w http.ResponseWriter, err error) string {
+	log.Printf("TransferAuthor error: %v", err)
+
+	code, known := transferAuthorErrors[err.Error()]
+	if !known {
+		code = "INTERNAL_ERROR"
+	}
+	if h.metrics != nil {
+		h.metrics.IncBusinessError(code)
+	}
+
+	errorResp := createErrorResponse(code, err.Error())
+	switch {
+	case !known:
+		WriteJSON(w, http.StatusInternalServerError, errorResp)
+		return "500"
+	case code == "NOT_FOUND":
+		WriteJSON(w, http.StatusNotFound, errorResp)
+		return "404"
+	case code == "TEAM_LEAD_REQUIRED":
+		WriteJSON(w, http.StatusForbidden, errorResp)
+		return "403"
+	default:
+		WriteJSON(w, http.StatusConflict, errorResp)
+		return "409"
+	}
+}
@@ -1,23 +1,65 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"PR_service/internal/domain"
 	"PR_service/internal/models"
+	"PR_service/internal/storage"
 )
 
-// WriteJSON универсальная функция для JSON ответов (теперь экспортирована)
+// jsonBuffer - буфер ответа вместе с привязанным к нему энкодером
+type jsonBuffer struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// maxPooledJSONBuffer - буферы крупнее (выгрузки, большие отчеты) не возвращаются в пул,
+// чтобы редкий большой ответ не удерживал память
+const maxPooledJSONBuffer = 64 << 10
+
+var jsonBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := &jsonBuffer{}
+		b.enc = json.NewEncoder(&b.buf)
+		return b
+	},
+}
+
+// WriteJSON универсальная функция для JSON ответов (теперь экспортирована).
+// Ответ кодируется в буфер из пула: энкодер и буфер не создаются на каждый запрос,
+// а ошибка кодирования превращается в 500 до того, как отправлен статус.
 func WriteJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+	if data == nil {
+		w.WriteHeader(statusCode)
+		return
+	}
 
-	if data != nil {
-		if err := json.NewEncoder(w).Encode(data); err != nil {
-			log.Printf("JSON encode error: %v", err)
+	b := jsonBufferPool.Get().(*jsonBuffer)
+	defer func() {
+		if b.buf.Cap() <= maxPooledJSONBuffer {
+			b.buf.Reset()
+			jsonBufferPool.Put(b)
 		}
+	}()
+
+	if err := b.enc.Encode(data); err != nil {
+		log.Printf("JSON encode error: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(b.buf.Len()))
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(b.buf.Bytes()); err != nil {
+		log.Printf("JSON write error: %v", err)
 	}
 }
 
@@ -67,6 +109,27 @@ func validateRequiredFields(fields map[string]string) string {
 	return ""
 }
 
+// validateExcludedReviewers проверяет список ревьюеров, исключенных автором PR
+func validateExcludedReviewers(authorID string, excluded []string) string {
+	if len(excluded) > storage.MaxReviewerCount {
+		return fmt.Sprintf("excluded_reviewers must contain at most %d users", storage.MaxReviewerCount)
+	}
+	seen := make(map[string]bool, len(excluded))
+	for i, uid := range excluded {
+		if uid == "" {
+			return fmt.Sprintf("excluded_reviewers[%d] must not be empty", i)
+		}
+		if uid == authorID {
+			return "author cannot be excluded: authors are never assigned to their own PRs"
+		}
+		if seen[uid] {
+			return fmt.Sprintf("excluded_reviewers[%d]: duplicate user %s", i, uid)
+		}
+		seen[uid] = true
+	}
+	return ""
+}
+
 // formatDateTime форматирует время в строку RFC3339 (для JSON ответов)
 func formatDateTime(t time.Time) string {
 	return t.Format(time.RFC3339)
@@ -126,3 +189,19 @@ func createReassignResponse(pr models.PullRequest, replacedBy string) map[string
 		"replaced_by": replacedBy,
 	}
 }
+
+// mergeBlockedResponse формирует тело ответа 409 с недостающими одобрениями
+func mergeBlockedResponse(missing *domain.ApprovalsMissingError) models.MergeBlockedResponse {
+	resp := models.MergeBlockedResponse{
+		ErrorResponse:    createErrorResponse("APPROVALS_MISSING", missing.Error()),
+		MergePolicy:      missing.Policy,
+		Required:         missing.Required,
+		Approved:         missing.Approved,
+		MissingApprovals: missing.Pending,
+		ApprovalStage:    missing.Stage,
+	}
+	if missing.PR != nil {
+		resp.Data = createPRResponse(*missing.PR)
+	}
+	return resp
+}
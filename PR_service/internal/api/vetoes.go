@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// vetoErrors - код ошибки API для известных ошибок VetoAssignment
+var vetoErrors = map[string]string{
+	"pr not found":                        "NOT_FOUND",
+	"author is not in any team":           "NOT_FOUND",
+	"cannot modify reviewers after merge": "PR_MERGED",
+	"reviewer is not assigned to this PR": "NOT_ASSIGNED",
+	storage.ErrVetoNotAllowed.Error():     "VETO_NOT_ALLOWED",
+}
+
+// VetoAssignment снимает ревьюера с PR по его отказу с причиной и назначает замену
+func (h *Handler) VetoAssignment(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.VetoRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if errMsg := validateRequiredFields(map[string]string{
+		"pull_request_id": req.PullRequestID,
+		"user_id":         req.UserID,
+		"reason":          req.Reason,
+	}); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_REQUIRED_FIELDS")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if errMsg := validateVeto(req); errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_VETO")
+		}
+		WriteJSON(w, http.StatusBadRequest, createErrorResponse("INVALID_VETO", errMsg))
+		return
+	}
+
+	updatedPR, replacedBy, err := h.store.VetoAssignment(r.Context(), req)
+	if err != nil {
+		status = h.handleVetoError(w, err)
+		return
+	}
+
+	if h.metrics != nil {
+		teamName := h.getAuthorTeam(r.Context(), updatedPR.AuthorID)
+		if teamName == "" {
+			teamName = "unknown"
+		}
+		h.metrics.ObserveReviewersAssigned(teamName, len(updatedPR.Reviewers))
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pr":          updatedPR,
+		"replaced_by": replacedBy,
+	})
+}
+
+// validateVeto проверяет причину и комментарий отказа
+func validateVeto(req models.VetoRequest) string {
+	if !storage.IsKnownVetoReason(req.Reason) {
+		return fmt.Sprintf("reason must be one of %s, %s, %s",
+			storage.VetoConflictOfInterest, storage.VetoUnfamiliarArea, storage.VetoOverloaded)
+	}
+	if len(req.Comment) > storage.MaxVetoCommentLength {
+		return fmt.Sprintf("comment must be at most %d characters", storage.MaxVetoCommentLength)
+	}
+	return ""
+}
+
+// handleVetoError пишет ответ об ошибке и возвращает HTTP статус для метрик
+func (h *Handler) handleVetoError(w http.ResponseWriter, err error) string {
+	log.Printf("VetoAssignment error: %v", err)
+
+	code, known := vetoErrors[err.Error()]
+	if !known {
+		code = "INTERNAL_ERROR"
+	}
+	if h.metrics != nil {
+		h.metrics.IncBusinessError(code)
+	}
+
+	errorResp := createErrorResponse(code, err.Error())
+	var conflict *storage.PRConflictError
+	if errors.As(err, &conflict) {
+		errorResp.Data = createPRResponse(*conflict.PR)
+	}
+	switch {
+	case !known:
+		WriteJSON(w, http.StatusInternalServerError, errorResp)
+		return "500"
+	case code == "NOT_FOUND":
+		WriteJSON(w, http.StatusNotFound, errorResp)
+		return "404"
+	case code == "VETO_NOT_ALLOWED":
+		WriteJSON(w, http.StatusForbidden, errorResp)
+		return "403"
+	default:
+		WriteJSON(w, http.StatusConflict, errorResp)
+		return "409"
+	}
+}
+
+// VetoReport возвращает отказы ревьюеров команды от назначений за период from/to по причинам
+func (h *Handler) VetoReport(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	query := r.URL.Query()
+	teamName := query.Get("team_name")
+	if teamName == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_TEAM_NAME")
+		}
+		writeError(w, http.StatusBadRequest, "team_name query parameter is required")
+		return
+	}
+
+	from, to, errMsg := parseTimeRange(query)
+	if errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_TIME_RANGE")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	report, err := h.store.GetVetoReport(r.Context(), teamName, from, to)
+	if err != nil {
+		if err.Error() == "team not found" {
+			status = "404"
+		} else {
+			status = "500"
+		}
+		h.handleStorageError(w, err, "VetoReport")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// BeginWarmUp переводит реплику в состояние прогрева: /health/ready отвечает 503,
+// пока WarmUp не завершится
+func (h *Handler) BeginWarmUp() {
+	h.warmingUp.Store(true)
+}
+
+// WarmUp прогревает кэши хранилища и снимает состояние прогрева.
+// Ошибка прогрева не фатальна: реплика становится готовой, просто первые
+// запросы после деплоя будут медленнее.
+func (h *Handler) WarmUp(ctx context.Context, conns int) {
+	defer h.warmingUp.Store(false)
+
+	start := time.Now()
+	stats, err := h.store.WarmUp(ctx, conns)
+	duration := time.Since(start)
+
+	if h.metrics != nil {
+		h.metrics.SetWarmUpDuration(duration)
+	}
+	if err != nil {
+		log.Printf("Cache warm-up failed after %v: %v", duration.Round(time.Millisecond), err)
+		return
+	}
+	log.Printf("Cache warm-up finished in %v: %d connections, %d prepared statements, %d teams, %d active candidates",
+		duration.Round(time.Millisecond), stats.Connections, stats.Statements, stats.Teams, stats.Candidates)
+}
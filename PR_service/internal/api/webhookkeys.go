@@ -0,0 +1,126 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"PR_service/internal/models"
+	"PR_service/internal/storage"
+)
+
+// GetWebhookKeys возвращает ключи подписи исходящих вебхуков без секретов
+func (h *Handler) GetWebhookKeys(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	keys, err := h.store.ListWebhookSigningKeys(r.Context())
+	if err != nil {
+		status = "500"
+		h.handleStorageError(w, err, "GetWebhookKeys")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": keys,
+	})
+}
+
+// RotateWebhookKey выпускает новый ключ подписи; прежние ключи действуют еще grace_hours.
+// Секрет нового ключа возвращается только в этом ответе.
+func (h *Handler) RotateWebhookKey(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.RotateWebhookKeyRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	grace, errMsg := webhookKeyGrace(req)
+	if errMsg != "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_GRACE_PERIOD")
+		}
+		writeError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	key, err := h.store.RotateWebhookSigningKey(r.Context(), grace)
+	if err != nil {
+		status = "500"
+		h.handleStorageError(w, err, "RotateWebhookKey")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"key": key,
+	})
+}
+
+// RevokeWebhookKey немедленно отзывает ключ подписи, например при утечке секрета
+func (h *Handler) RevokeWebhookKey(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := "200"
+
+	defer func() {
+		h.recordHandlerDuration(r, start, status)
+	}()
+
+	var req models.RevokeWebhookKeyRequest
+	if !h.bindJSON(w, r, &req) {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("INVALID_REQUEST")
+		}
+		return
+	}
+
+	if req.KeyID == "" {
+		status = "400"
+		if h.metrics != nil {
+			h.metrics.IncBusinessError("MISSING_KEY_ID")
+		}
+		writeError(w, http.StatusBadRequest, "key_id is required")
+		return
+	}
+
+	if err := h.store.RevokeWebhookSigningKey(r.Context(), req.KeyID); err != nil {
+		status = "500"
+		if err.Error() == "signing key not found" {
+			status = "404"
+		}
+		h.handleStorageError(w, err, "RevokeWebhookKey")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"key_id":  req.KeyID,
+		"revoked": true,
+	})
+}
+
+// webhookKeyGrace возвращает период действия прежних ключей после ротации
+func webhookKeyGrace(req models.RotateWebhookKeyRequest) (time.Duration, string) {
+	if req.GraceHours == nil {
+		return storage.DefaultWebhookKeyGrace, ""
+	}
+	maxHours := int(storage.MaxWebhookKeyGrace / time.Hour)
+	if *req.GraceHours < 0 || *req.GraceHours > maxHours {
+		return 0, fmt.Sprintf("grace_hours must be between 0 and %d", maxHours)
+	}
+	return time.Duration(*req.GraceHours) * time.Hour, ""
+}
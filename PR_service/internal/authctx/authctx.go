@@ -0,0 +1,117 @@
+// Package authctx переносит через context данные аутентифицированного запроса:
+// принципала (пользователь, роли, организация) и идентификатор запроса.
+package authctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Роли принципала
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Scope токенов: ограничивают операции, доступные интеграции
+const (
+	ScopeTeamsRead   = "teams:read"
+	ScopeTeamsWrite  = "teams:write"
+	ScopeUsersRead   = "users:read"
+	ScopeUsersWrite  = "users:write"
+	ScopePRsRead     = "prs:read"
+	ScopePRsWrite    = "prs:write"
+	ScopeReportsRead = "reports:read"
+	ScopeAdmin       = "admin"
+
+	// ScopeAll - явный доступ без ограничений (сессия пользователя); маршрутам не назначается
+	ScopeAll = "*"
+)
+
+// IsKnownScope проверяет, поддерживается ли scope
+func IsKnownScope(scope string) bool {
+	switch scope {
+	case ScopeTeamsRead, ScopeTeamsWrite, ScopeUsersRead, ScopeUsersWrite,
+		ScopePRsRead, ScopePRsWrite, ScopeReportsRead, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// Principal - аутентифицированный участник запроса
+type Principal struct {
+	ID    string
+	Roles []string
+	Org   string
+
+	// Scopes - разрешенные операции; nil - прокси не передал scope. HasScope дает права
+	// только по списку, доступ без ограничений дается явно через ScopeAll.
+	Scopes []string
+}
+
+// HasRole проверяет наличие роли у принципала
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope проверяет, разрешен ли принципалу scope
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAll {
+			return true
+		}
+	}
+	return false
+}
+
+type ctxKey int
+
+const (
+	principalKey ctxKey = iota
+	requestIDKey
+)
+
+// WithPrincipal возвращает контекст с принципалом
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// PrincipalFrom возвращает принципала из контекста
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey).(Principal)
+	return p, ok
+}
+
+// ActorID возвращает идентификатор принципала или "anonymous"
+func ActorID(ctx context.Context) string {
+	if p, ok := PrincipalFrom(ctx); ok && p.ID != "" {
+		return p.ID
+	}
+	return "anonymous"
+}
+
+// WithRequestID возвращает контекст с идентификатором запроса
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFrom возвращает идентификатор запроса или пустую строку
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// NewRequestID генерирует случайный идентификатор запроса
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,54 @@
+package authctx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrincipalPropagation(t *testing.T) {
+	ctx := context.Background()
+
+	_, ok := PrincipalFrom(ctx)
+	assert.False(t, ok)
+	assert.Equal(t, "anonymous", ActorID(ctx))
+
+	ctx = WithPrincipal(ctx, Principal{ID: "u1", Roles: []string{RoleAdmin}, Org: "acme"})
+	p, ok := PrincipalFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "u1", p.ID)
+	assert.Equal(t, "acme", p.Org)
+	assert.True(t, p.HasRole(RoleAdmin))
+	assert.False(t, p.HasRole(RoleUser))
+	assert.Equal(t, "u1", ActorID(ctx))
+}
+
+func TestPrincipalScopes(t *testing.T) {
+	// Без scope прав нет, доступ без ограничений выдается явно
+	assert.False(t, Principal{ID: "u1"}.HasScope(ScopeTeamsWrite))
+	assert.True(t, Principal{ID: "u1", Scopes: []string{ScopeAll}}.HasScope(ScopeTeamsWrite))
+	assert.True(t, Principal{ID: "u1", Scopes: []string{ScopeAll}}.HasScope(ScopeAdmin))
+	assert.False(t, IsKnownScope(ScopeAll))
+
+	bridge := Principal{ID: "github-bridge", Scopes: []string{ScopePRsRead, ScopePRsWrite}}
+	assert.True(t, bridge.HasScope(ScopePRsWrite))
+	assert.False(t, bridge.HasScope(ScopeTeamsWrite))
+
+	assert.False(t, Principal{ID: "t1", Scopes: []string{}}.HasScope(ScopePRsRead))
+
+	assert.True(t, IsKnownScope(ScopeReportsRead))
+	assert.False(t, IsKnownScope("prs:delete"))
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, RequestIDFrom(ctx))
+
+	id := NewRequestID()
+	assert.Len(t, id, 16)
+	assert.NotEqual(t, id, NewRequestID())
+
+	ctx = WithRequestID(ctx, id)
+	assert.Equal(t, id, RequestIDFrom(ctx))
+}
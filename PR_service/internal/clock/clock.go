@@ -0,0 +1,54 @@
+// Package clock - источник текущего времени для storage и фоновых задач.
+// В проде используется системное время, в тестах - Fake, который можно перематывать,
+// чтобы детерминированно проверять окна фокуса, сроки хранения и ожидание в очереди.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock возвращает текущее время
+type Clock interface {
+	Now() time.Time
+}
+
+// System - системные часы
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Fake - часы, которые идут только по команде теста. Безопасны для конкурентного использования.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake создает часы, остановленные на моменте t
+func NewFake(t time.Time) *Fake {
+	return &Fake{now: t}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance перематывает часы вперед на d
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Set переставляет часы на момент t
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
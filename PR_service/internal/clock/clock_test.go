@@ -0,0 +1,28 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemClock(t *testing.T) {
+	before := time.Now()
+	now := System.Now()
+	assert.False(t, now.Before(before))
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	assert.Equal(t, start, c.Now())
+	assert.Equal(t, start, c.Now(), "fake clock must not move by itself")
+
+	c.Advance(90 * time.Minute)
+	assert.Equal(t, start.Add(90*time.Minute), c.Now())
+
+	later := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	c.Set(later)
+	assert.Equal(t, later, c.Now())
+}
@@ -0,0 +1,96 @@
+package domain
+
+import "PR_service/internal/models"
+
+// ReviewQueueEnabled проверяет, работает ли у команды очередь ревью: PR ждут в ней
+// ревьюеров, только когда у команды есть предел неодобренных ревью на человека
+func ReviewQueueEnabled(settings models.TeamSettings) bool {
+	return settings.MaxOpenReviews > 0
+}
+
+// FilterByCapacity убирает кандидатов, у которых уже maxOpen неодобренных ревью открытых PR.
+// Второе значение сообщает, был ли кто-то отсеян. maxOpen 0 - без предела.
+func FilterByCapacity(candidates []string, loads map[string]int, maxOpen int) ([]string, bool) {
+	if maxOpen <= 0 || len(candidates) == 0 {
+		return candidates, false
+	}
+
+	free := make([]string, 0, len(candidates))
+	for _, uid := range candidates {
+		if loads[uid] < maxOpen {
+			free = append(free, uid)
+		}
+	}
+	return free, len(free) < len(candidates)
+}
+
+// CreatePRState - данные команды автора, от которых зависит назначение ревьюеров нового PR
+type CreatePRState struct {
+	Settings      models.TeamSettings
+	ReviewerCount int            // Число ревьюеров с учетом снижения при бэклоге
+	Candidates    []string       // Активные участники команды без автора
+	Excluded      []string       // Ревьюеры, исключенные автором
+	QueueDepth    int            // PR, оставшиеся в очереди команды после ее разбора
+	Loads         map[string]int // Неодобренные ревью кандидатов; нужны, только если работает очередь
+}
+
+// CreatePRPlan - план назначения ревьюеров нового PR. Storage собирает для него данные
+// и сохраняет результат, а решения - назначать или ставить в очередь, занимать ли
+// ревьюеров у других команд, переходить ли к следующему этапу одобрений - принимает план.
+type CreatePRPlan struct {
+	ReviewerCount int      // Сколько ревьюеров нужно PR
+	Candidates    []string // Кандидаты команды для стратегии; пусто, если PR встает за очередью
+	AtCapacity    bool     // Предел ревью на человека отсеял кандидатов
+	QueueWaiting  bool     // В очереди команды ждут PR: новый PR встает за ними
+}
+
+// PlanCreatePR строит план назначения по данным команды. Исключения автора применяются
+// первыми и не могут лишить PR ревьюеров (ErrTooManyExclusions). При работающей очереди
+// кандидаты с исчерпанной емкостью отсеиваются, а пока в очереди ждут PR, новый PR не
+// забирает освободившихся ревьюеров и встает за ними.
+func PlanCreatePR(state CreatePRState) (CreatePRPlan, error) {
+	plan := CreatePRPlan{ReviewerCount: state.ReviewerCount}
+
+	candidates, err := ApplyReviewerExclusions(state.Candidates, state.Excluded, state.ReviewerCount)
+	if err != nil {
+		return plan, err
+	}
+
+	if ReviewQueueEnabled(state.Settings) {
+		candidates, plan.AtCapacity = FilterByCapacity(candidates, state.Loads, state.Settings.MaxOpenReviews)
+		if state.QueueDepth > 0 {
+			plan.QueueWaiting = true
+			candidates = nil
+		}
+	}
+	plan.Candidates = candidates
+	return plan, nil
+}
+
+// BorrowSlots возвращает, сколько ревьюеров занять у команд из пула, если команда
+// предложила только selected. PR, вставший за очередью, ревьюеров не занимает.
+func (p CreatePRPlan) BorrowSlots(selected int) int {
+	if p.QueueWaiting || selected >= p.ReviewerCount {
+		return 0
+	}
+	return p.ReviewerCount - selected
+}
+
+// CreatePROutcome - что делать с PR после назначения ревьюеров
+type CreatePROutcome struct {
+	Enqueue       bool // PR ждет ревьюеров в очереди команды
+	AdvanceStages bool // Первый этап одобрений без ревьюеров завершен - назначить следующий
+}
+
+// Outcome решает судьбу PR, получившего assigned ревьюеров. PR без ревьюеров встает
+// в очередь, если ревьюеры ему нужны, но все кандидаты заняты, исчерпали квоту назначений
+// или очередь уже не пуста. Иначе первый этап цепочки одобрений считается завершенным.
+func (p CreatePRPlan) Outcome(assigned int, quotaDeferred bool) CreatePROutcome {
+	if assigned > 0 {
+		return CreatePROutcome{}
+	}
+	if p.ReviewerCount > 0 && (p.AtCapacity || p.QueueWaiting || quotaDeferred) {
+		return CreatePROutcome{Enqueue: true}
+	}
+	return CreatePROutcome{AdvanceStages: true}
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"testing"
+
+	"PR_service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterByCapacity(t *testing.T) {
+	loads := map[string]int{"u1": 3, "u2": 1}
+
+	free, filtered := FilterByCapacity([]string{"u1", "u2", "u3"}, loads, 2)
+	assert.Equal(t, []string{"u2", "u3"}, free)
+	assert.True(t, filtered)
+
+	free, filtered = FilterByCapacity([]string{"u1", "u2"}, loads, 0)
+	assert.Equal(t, []string{"u1", "u2"}, free)
+	assert.False(t, filtered)
+}
+
+func TestPlanCreatePR(t *testing.T) {
+	candidates := []string{"u1", "u2", "u3"}
+	queued := models.TeamSettings{ReviewerCount: 2, MaxOpenReviews: 2}
+
+	t.Run("Assigns from team", func(t *testing.T) {
+		plan, err := PlanCreatePR(CreatePRState{
+			Settings:      models.TeamSettings{ReviewerCount: 2},
+			ReviewerCount: 2,
+			Candidates:    candidates,
+			Excluded:      []string{"u3"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"u1", "u2"}, plan.Candidates)
+		assert.Equal(t, 0, plan.BorrowSlots(2))
+		assert.Equal(t, 1, plan.BorrowSlots(1))
+		assert.Equal(t, CreatePROutcome{}, plan.Outcome(2, false))
+	})
+
+	t.Run("Exclusions cannot leave PR without reviewers", func(t *testing.T) {
+		_, err := PlanCreatePR(CreatePRState{
+			Settings:      models.TeamSettings{ReviewerCount: 2},
+			ReviewerCount: 2,
+			Candidates:    candidates,
+			Excluded:      []string{"u1", "u2"},
+		})
+		assert.ErrorIs(t, err, ErrTooManyExclusions)
+	})
+
+	t.Run("Capacity filter only with queue", func(t *testing.T) {
+		loads := map[string]int{"u1": 2, "u2": 2, "u3": 2}
+		plan, err := PlanCreatePR(CreatePRState{Settings: queued, ReviewerCount: 2, Candidates: candidates, Loads: loads})
+		assert.NoError(t, err)
+		assert.Empty(t, plan.Candidates)
+		assert.True(t, plan.AtCapacity)
+		assert.Equal(t, CreatePROutcome{Enqueue: true}, plan.Outcome(0, false))
+
+		plan, err = PlanCreatePR(CreatePRState{Settings: models.TeamSettings{ReviewerCount: 2}, ReviewerCount: 2, Candidates: candidates, Loads: loads})
+		assert.NoError(t, err)
+		assert.Equal(t, candidates, plan.Candidates)
+	})
+
+	t.Run("New PR waits behind queue", func(t *testing.T) {
+		plan, err := PlanCreatePR(CreatePRState{Settings: queued, ReviewerCount: 2, Candidates: candidates, QueueDepth: 1})
+		assert.NoError(t, err)
+		assert.Empty(t, plan.Candidates)
+		assert.True(t, plan.QueueWaiting)
+		assert.Equal(t, 0, plan.BorrowSlots(0), "PR behind the queue does not borrow reviewers")
+		assert.Equal(t, CreatePROutcome{Enqueue: true}, plan.Outcome(0, false))
+	})
+
+	t.Run("Quota deferral enqueues", func(t *testing.T) {
+		plan, err := PlanCreatePR(CreatePRState{Settings: models.TeamSettings{ReviewerCount: 2}, ReviewerCount: 2, Candidates: candidates})
+		assert.NoError(t, err)
+		assert.Equal(t, CreatePROutcome{Enqueue: true}, plan.Outcome(0, true))
+		assert.Equal(t, CreatePROutcome{AdvanceStages: true}, plan.Outcome(0, false))
+	})
+
+	t.Run("Effective reviewer count decides enqueue", func(t *testing.T) {
+		// Очередь решает действующее число ревьюеров, а не reviewer_count из настроек
+		settings := models.TeamSettings{ReviewerCount: 2, AdaptiveReviewerCount: 0, ReviewerCountReduced: true, MaxOpenReviews: 2}
+		plan, err := PlanCreatePR(CreatePRState{Settings: settings, ReviewerCount: 0, Candidates: candidates, QueueDepth: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, CreatePROutcome{AdvanceStages: true}, plan.Outcome(0, true))
+	})
+}
@@ -0,0 +1,270 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+// Политики мерджа PR
+const (
+	// MergePolicyNone - мердж без проверки одобрений
+	MergePolicyNone = "none"
+	// MergePolicyAll - все назначенные ревьюеры должны одобрить PR
+	MergePolicyAll = "all"
+	// MergePolicyQuorum - достаточно required_approvals одобрений из назначенных
+	MergePolicyQuorum = "quorum"
+)
+
+// IsKnownMergePolicy проверяет, поддерживается ли политика мерджа
+func IsKnownMergePolicy(policy string) bool {
+	return policy == MergePolicyNone || policy == MergePolicyAll || policy == MergePolicyQuorum
+}
+
+// ApprovalsMissingError возвращается при мердже, если политика команды не выполнена
+type ApprovalsMissingError struct {
+	Policy   string
+	Required int
+	Approved int
+	Pending  []string            // Назначенные ревьюеры, которые еще не одобрили PR
+	Stage    int                 // Незавершенный этап цепочки одобрений; 0 - не выполнена политика мерджа
+	PR       *models.PullRequest // PR на момент отказа
+}
+
+func (e *ApprovalsMissingError) Error() string {
+	if e.Stage > 0 {
+		return fmt.Sprintf("approval stage %d is not complete: %d of %d required, pending: %s",
+			e.Stage, e.Approved, e.Required, strings.Join(e.Pending, ", "))
+	}
+	return fmt.Sprintf("not enough approvals: %d of %d required, pending: %s",
+		e.Approved, e.Required, strings.Join(e.Pending, ", "))
+}
+
+// EvaluateMergePolicy проверяет одобрения назначенных ревьюеров по политике.
+// Кворум не может превышать число назначенных ревьюеров, иначе PR в маленькой
+// команде нельзя было бы смерджить никогда.
+func EvaluateMergePolicy(policy string, requiredApprovals int, reviewers []string, approved map[string]bool) *ApprovalsMissingError {
+	required := 0
+	switch policy {
+	case MergePolicyAll:
+		required = len(reviewers)
+	case MergePolicyQuorum:
+		required = requiredApprovals
+		if required > len(reviewers) {
+			required = len(reviewers)
+		}
+	default:
+		return nil
+	}
+
+	count := 0
+	pending := []string{}
+	for _, uid := range reviewers {
+		if approved[uid] {
+			count++
+		} else {
+			pending = append(pending, uid)
+		}
+	}
+
+	if count >= required {
+		return nil
+	}
+	return &ApprovalsMissingError{
+		Policy:   policy,
+		Required: required,
+		Approved: count,
+		Pending:  pending,
+	}
+}
+
+// Виды этапов цепочки одобрений
+const (
+	// ApprovalStagePeer - одобрения любых ревьюеров этапа
+	ApprovalStagePeer = "peer"
+	// ApprovalStageLead - одобрения лидов команды
+	ApprovalStageLead = "lead"
+)
+
+// MaxApprovalStages - предел числа этапов цепочки одобрений команды
+const MaxApprovalStages = 5
+
+// MergePolicyApprovalStages - политика в ApprovalsMissingError, если не завершена цепочка одобрений
+const MergePolicyApprovalStages = "approval_stages"
+
+// IsKnownApprovalStage проверяет, поддерживается ли вид этапа
+func IsKnownApprovalStage(kind string) bool {
+	return kind == ApprovalStagePeer || kind == ApprovalStageLead
+}
+
+// IsLeadStage проверяет, что этап stage цепочки команды требует одобрения лидов
+func IsLeadStage(settings models.TeamSettings, stage int) bool {
+	return stage >= 1 && stage <= len(settings.ApprovalStages) && settings.ApprovalStages[stage-1].Kind == ApprovalStageLead
+}
+
+// StageReviewer - ревьюер PR с этапом, на котором он назначен
+type StageReviewer struct {
+	UserID   string
+	Stage    int
+	Approved bool
+	Lead     bool
+}
+
+// EvaluateApprovalStages считает прогресс PR по этапам. Пройденные этапы (до current)
+// считаются завершенными, следующие за текущим - нет. Как и кворум, требование этапа
+// не превышает числа назначенных на него ревьюеров: этап без подходящих ревьюеров
+// не блокирует мердж навсегда. На этапе lead засчитываются только одобрения лидов.
+func EvaluateApprovalStages(stages []models.ApprovalStage, current int, reviewers []StageReviewer) []models.ApprovalStageProgress {
+	progress := make([]models.ApprovalStageProgress, 0, len(stages))
+	for i, stage := range stages {
+		number := i + 1
+		p := models.ApprovalStageProgress{
+			Stage:     number,
+			Kind:      stage.Kind,
+			Reviewers: []string{},
+			Pending:   []string{},
+		}
+		eligible := 0
+		for _, r := range reviewers {
+			if r.Stage != number {
+				continue
+			}
+			p.Reviewers = append(p.Reviewers, r.UserID)
+			if stage.Kind == ApprovalStageLead && !r.Lead {
+				continue
+			}
+			eligible++
+			if r.Approved {
+				p.Approved++
+			} else {
+				p.Pending = append(p.Pending, r.UserID)
+			}
+		}
+
+		p.Required = stage.Approvals
+		if p.Required > eligible {
+			p.Required = eligible
+		}
+		switch {
+		case number < current:
+			p.Completed = true
+		case number == current:
+			p.Completed = p.Approved >= p.Required
+		}
+		progress = append(progress, p)
+	}
+	return progress
+}
+
+// StagesMissing возвращает ошибку по первому незавершенному этапу или nil
+func StagesMissing(progress []models.ApprovalStageProgress) *ApprovalsMissingError {
+	for _, p := range progress {
+		if p.Completed {
+			continue
+		}
+		return &ApprovalsMissingError{
+			Policy:   MergePolicyApprovalStages,
+			Stage:    p.Stage,
+			Required: p.Required,
+			Approved: p.Approved,
+			Pending:  p.Pending,
+		}
+	}
+	return nil
+}
+
+// ReviewerCountReduced решает, должно ли число ревьюеров команды быть снижено при данном бэклоге.
+// Снижение включается, когда бэклог превышает порог, и снимается, только когда он опустится
+// до половины порога: иначе на границе порога число ревьюеров менялось бы с каждым PR.
+func ReviewerCountReduced(settings models.TeamSettings, backlog int) bool {
+	if settings.AdaptiveBacklogThreshold <= 0 {
+		return false
+	}
+	if settings.ReviewerCountReduced {
+		return backlog > settings.AdaptiveBacklogThreshold/2
+	}
+	return backlog > settings.AdaptiveBacklogThreshold
+}
+
+// EffectiveReviewerCount возвращает число ревьюеров для новых PR с учетом снижения
+func EffectiveReviewerCount(settings models.TeamSettings, reduced bool) int {
+	if reduced && settings.AdaptiveReviewerCount < settings.ReviewerCount {
+		return settings.AdaptiveReviewerCount
+	}
+	return settings.ReviewerCount
+}
+
+// Приоритеты PR
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// MaxReviewSLAHours - верхняя граница срока ревью команды (четыре недели)
+const MaxReviewSLAHours = 24 * 28
+
+// priorityRank - вес приоритета при сортировке списка ревью, больше - срочнее
+var priorityRank = map[string]int{
+	PriorityLow:    0,
+	PriorityNormal: 1,
+	PriorityHigh:   2,
+	PriorityUrgent: 3,
+}
+
+// IsKnownPriority проверяет, поддерживается ли приоритет PR
+func IsKnownPriority(priority string) bool {
+	_, ok := priorityRank[priority]
+	return ok
+}
+
+// ReviewDueAt возвращает срок ревью, назначенного в assignedAt, или nil, если у команды нет SLA
+func ReviewDueAt(assignedAt time.Time, slaHours int) *time.Time {
+	if slaHours <= 0 {
+		return nil
+	}
+	due := assignedAt.Add(time.Duration(slaHours) * time.Hour).UTC()
+	return &due
+}
+
+// ReviewItem - строка списка ревью с полями, которые нужны только для сортировки
+type ReviewItem struct {
+	models.ReviewAssignment
+	CreatedAt time.Time
+	Pending   bool // PR открыт и ревьюер еще не одобрил его
+}
+
+// SortReviewAssignments упорядочивает список ревью по срочности: сначала ожидающие ревью,
+// среди них - по приоритету, затем по оставшемуся сроку (без срока - в конце),
+// затем по возрасту PR (старые раньше). Сортировка устойчива для одинаковых PR.
+func SortReviewAssignments(items []ReviewItem) []models.ReviewAssignment {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Pending != b.Pending {
+			return a.Pending
+		}
+		if ra, rb := priorityRank[a.Priority], priorityRank[b.Priority]; ra != rb {
+			return ra > rb
+		}
+		if (a.DueAt == nil) != (b.DueAt == nil) {
+			return a.DueAt != nil
+		}
+		if a.DueAt != nil && !a.DueAt.Equal(*b.DueAt) {
+			return a.DueAt.Before(*b.DueAt)
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.PullRequestID < b.PullRequestID
+	})
+
+	res := make([]models.ReviewAssignment, 0, len(items))
+	for _, item := range items {
+		res = append(res, item.ReviewAssignment)
+	}
+	return res
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"PR_service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateMergePolicy(t *testing.T) {
+	reviewers := []string{"u1", "u2", "u3"}
+	approved := map[string]bool{"u2": true}
+
+	assert.Nil(t, EvaluateMergePolicy(MergePolicyNone, 0, reviewers, approved))
+	assert.Nil(t, EvaluateMergePolicy(MergePolicyQuorum, 1, reviewers, approved))
+
+	missing := EvaluateMergePolicy(MergePolicyQuorum, 2, reviewers, approved)
+	if assert.NotNil(t, missing) {
+		assert.Equal(t, 2, missing.Required)
+		assert.Equal(t, 1, missing.Approved)
+		assert.Equal(t, []string{"u1", "u3"}, missing.Pending)
+	}
+
+	missing = EvaluateMergePolicy(MergePolicyAll, 0, reviewers, approved)
+	if assert.NotNil(t, missing) {
+		assert.Equal(t, 3, missing.Required)
+	}
+
+	// Кворум больше числа ревьюеров ограничивается назначенными
+	all := map[string]bool{"u1": true, "u2": true, "u3": true}
+	assert.Nil(t, EvaluateMergePolicy(MergePolicyQuorum, 5, reviewers, all))
+
+	// PR без ревьюеров мерджится при любой политике
+	assert.Nil(t, EvaluateMergePolicy(MergePolicyAll, 0, nil, nil))
+}
+
+func TestEvaluateApprovalStages(t *testing.T) {
+	stages := []models.ApprovalStage{{Kind: ApprovalStagePeer, Approvals: 1}, {Kind: ApprovalStageLead, Approvals: 1}}
+	reviewers := []StageReviewer{
+		{UserID: "u1", Stage: 1},
+		{UserID: "u2", Stage: 1},
+	}
+
+	// Первый этап не завершен, второй еще не начат
+	progress := EvaluateApprovalStages(stages, 1, reviewers)
+	assert.False(t, progress[0].Completed)
+	assert.False(t, progress[1].Completed)
+	missing := StagesMissing(progress)
+	if assert.NotNil(t, missing) {
+		assert.Equal(t, 1, missing.Stage)
+		assert.Equal(t, MergePolicyApprovalStages, missing.Policy)
+		assert.Equal(t, []string{"u1", "u2"}, missing.Pending)
+	}
+
+	// Одобрение любого ревьюера завершает этап peer; этап lead без ревьюеров еще не пройден
+	reviewers[1].Approved = true
+	progress = EvaluateApprovalStages(stages, 1, reviewers)
+	assert.True(t, progress[0].Completed)
+	assert.Equal(t, 2, StagesMissing(progress).Stage)
+
+	// На этапе lead засчитываются только одобрения лидов
+	reviewers = append(reviewers, StageReviewer{UserID: "u3", Stage: 2, Approved: true}, StageReviewer{UserID: "lead", Stage: 2, Lead: true})
+	progress = EvaluateApprovalStages(stages, 2, reviewers)
+	assert.Equal(t, []string{"u3", "lead"}, progress[1].Reviewers)
+	assert.Equal(t, 0, progress[1].Approved)
+	assert.Equal(t, []string{"lead"}, progress[1].Pending)
+	assert.False(t, progress[1].Completed)
+
+	reviewers[3].Approved = true
+	assert.Nil(t, StagesMissing(EvaluateApprovalStages(stages, 2, reviewers)))
+
+	// Этап без подходящих ревьюеров не блокирует мердж
+	assert.Nil(t, StagesMissing(EvaluateApprovalStages(stages, 2, []StageReviewer{{UserID: "u1", Stage: 1, Approved: true}})))
+}
+
+func TestAdaptiveReviewerCount(t *testing.T) {
+	settings := models.TeamSettings{
+		TeamName:                 "backend",
+		ReviewerCount:            2,
+		AdaptiveBacklogThreshold: 10,
+		AdaptiveReviewerCount:    1,
+	}
+
+	// Снижение включается только при превышении порога
+	assert.False(t, ReviewerCountReduced(settings, 10))
+	assert.True(t, ReviewerCountReduced(settings, 11))
+
+	// и снимается, когда бэклог опустится до половины порога
+	settings.ReviewerCountReduced = true
+	assert.True(t, ReviewerCountReduced(settings, 8))
+	assert.False(t, ReviewerCountReduced(settings, 5))
+
+	assert.Equal(t, 1, EffectiveReviewerCount(settings, true))
+	assert.Equal(t, 2, EffectiveReviewerCount(settings, false))
+
+	// Выключенная политика никогда не снижает число ревьюеров
+	settings.AdaptiveBacklogThreshold = 0
+	assert.False(t, ReviewerCountReduced(settings, 100))
+}
+
+func TestSortReviewAssignments(t *testing.T) {
+	base := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	item := func(id, status, priority string, created time.Time, pending bool, slaHours int) ReviewItem {
+		it := ReviewItem{CreatedAt: created, Pending: pending}
+		it.PullRequestID, it.Status, it.Priority, it.AssignedAt = id, status, priority, created
+		if pending {
+			it.DueAt = ReviewDueAt(created, slaHours)
+		}
+		return it
+	}
+
+	sorted := SortReviewAssignments([]ReviewItem{
+		item("merged", "MERGED", PriorityUrgent, base, false, 24),
+		item("old-normal", "OPEN", PriorityNormal, base, true, 0),
+		item("normal-due-late", "OPEN", PriorityNormal, base.Add(2*time.Hour), true, 24),
+		item("normal-due-soon", "OPEN", PriorityNormal, base.Add(time.Hour), true, 8),
+		item("high", "OPEN", PriorityHigh, base.Add(3*time.Hour), true, 0),
+		item("approved", "OPEN", PriorityUrgent, base, false, 24),
+	})
+
+	ids := make([]string, 0, len(sorted))
+	for _, a := range sorted {
+		ids = append(ids, a.PullRequestID)
+	}
+	// Ожидающие ревью - по приоритету и сроку, завершенные - в конце
+	assert.Equal(t, []string{"high", "normal-due-soon", "normal-due-late", "old-normal", "approved", "merged"}, ids)
+	assert.Equal(t, base.Add(9*time.Hour), *sorted[1].DueAt)
+	assert.Nil(t, sorted[0].DueAt)
+	assert.Nil(t, sorted[4].DueAt)
+
+	assert.True(t, IsKnownPriority(PriorityUrgent))
+	assert.False(t, IsKnownPriority("critical"))
+}
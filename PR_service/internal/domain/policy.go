@@ -0,0 +1,212 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+
+	"PR_service/internal/expr"
+	"PR_service/internal/models"
+)
+
+// candidateExprEnv - переменные выражений candidate_filter и candidate_rank команды
+var candidateExprEnv = expr.Env{
+	"load":        expr.Number, // Открытые PR на ревью у кандидата
+	"tenure_days": expr.Number, // Полных дней в команде, -1 - дата вступления неизвестна
+	"is_lead":     expr.Bool,   // Кандидат - лид команды
+	"in_focus":    expr.Bool,   // У кандидата сейчас окно фокуса
+	"tags":        expr.List,   // Теги PR, отревьюенных кандидатом за окно knowledge_spread
+	"pr_tags":     expr.List,   // Теги назначаемого PR
+	"shared_tags": expr.Number, // Сколько тегов назначаемого PR есть в tags
+}
+
+// CompileCandidateFilter компилирует фильтр кандидатов команды: выражение типа bool,
+// кандидаты со значением false не назначаются автоматически
+func CompileCandidateFilter(src string) (*expr.Program, error) {
+	return compileTyped(src, candidateExprEnv, expr.Bool)
+}
+
+// CompileCandidateRank компилирует ранг кандидатов команды: выражение типа number,
+// на которое умножается вес кандидата в стратегии
+func CompileCandidateRank(src string) (*expr.Program, error) {
+	return compileTyped(src, candidateExprEnv, expr.Number)
+}
+
+func compileTyped(src string, env expr.Env, want expr.Type) (*expr.Program, error) {
+	program, err := expr.Compile(src, env)
+	if err != nil {
+		return nil, err
+	}
+	if program.Type() != want {
+		return nil, fmt.Errorf("expression must be %s, got %s", want, program.Type())
+	}
+	return program, nil
+}
+
+// CandidateExpressions - скомпилированные выражения кандидатов команды
+type CandidateExpressions struct {
+	Filter *expr.Program
+	Rank   *expr.Program
+}
+
+// CompileCandidateExpressions компилирует выражения из настроек команды. Выражения
+// проверяются при сохранении настроек, поэтому ошибка здесь означает данные в обход API.
+func CompileCandidateExpressions(settings models.TeamSettings) (CandidateExpressions, error) {
+	var exprs CandidateExpressions
+	var err error
+	if settings.CandidateFilter != "" {
+		if exprs.Filter, err = CompileCandidateFilter(settings.CandidateFilter); err != nil {
+			return exprs, fmt.Errorf("team %s candidate_filter: %w", settings.TeamName, err)
+		}
+	}
+	if settings.CandidateRank != "" {
+		if exprs.Rank, err = CompileCandidateRank(settings.CandidateRank); err != nil {
+			return exprs, fmt.Errorf("team %s candidate_rank: %w", settings.TeamName, err)
+		}
+	}
+	return exprs, nil
+}
+
+// Empty проверяет, что у команды нет выражений кандидатов
+func (e CandidateExpressions) Empty() bool {
+	return e.Filter == nil && e.Rank == nil
+}
+
+// Apply применяет выражения к входным данным решения. Как и отказы, фильтр не оставляет
+// PR без нужного числа ревьюеров: если после него кандидатов меньше Count, пул не
+// фильтруется. Ошибка вычисления (например, деление на ноль) не отсеивает кандидата
+// и не меняет его вес, а сохраняется в решении; бесконечный ранг или NaN - тоже ошибка
+// вычисления. Отрицательный ранг считается нулевым.
+func (e CandidateExpressions) Apply(in AssignmentInput, vars map[string]expr.Vars) AssignmentInput {
+	if e.Filter != nil {
+		remaining := make([]string, 0, len(in.Candidates))
+		var filtered []string
+		for _, uid := range in.Candidates {
+			keep, err := e.Filter.EvalBool(vars[uid])
+			if err != nil {
+				in.ExpressionErrors = append(in.ExpressionErrors, fmt.Sprintf("%s: candidate_filter: %v", uid, err))
+				keep = true
+			}
+			if keep {
+				remaining = append(remaining, uid)
+			} else {
+				filtered = append(filtered, uid)
+			}
+		}
+		if len(filtered) > 0 && len(remaining) >= in.Count {
+			weights := make(map[string]float64, len(remaining))
+			for _, uid := range remaining {
+				weights[uid] = in.Weights[uid]
+			}
+			in.Stats.Filter(SelectionRuleCandidateFilter, len(filtered))
+			in.Candidates = remaining
+			in.Weights = weights
+			in.FilteredOut = filtered
+		} else if len(filtered) > 0 {
+			in.Stats.Fallback(SelectionFallbackFilterIgnored)
+		}
+	}
+
+	if e.Rank != nil {
+		for _, uid := range in.Candidates {
+			rank, err := e.Rank.EvalNumber(vars[uid])
+			if err == nil && (math.IsInf(rank, 0) || math.IsNaN(rank)) {
+				err = fmt.Errorf("non-finite result %v", rank)
+			}
+			if err != nil {
+				in.ExpressionErrors = append(in.ExpressionErrors, fmt.Sprintf("%s: candidate_rank: %v", uid, err))
+				continue
+			}
+			if rank < 0 {
+				rank = 0
+			}
+			in.Weights[uid] *= rank
+		}
+	}
+	return in
+}
+
+// notificationRouteEnv - переменные условия маршрута уведомлений
+var notificationRouteEnv = expr.Env{
+	"labels":   expr.List,   // Метки (теги) PR
+	"kind":     expr.String, // Вид уведомления, например review_requested
+	"priority": expr.String, // Приоритет PR
+	"status":   expr.String, // OPEN|MERGED|DECLINED
+}
+
+// CompileNotificationRoute компилирует условие маршрута уведомлений: выражение типа bool
+// над метками PR, например "security" in labels && kind == "review_requested"
+func CompileNotificationRoute(src string) (*expr.Program, error) {
+	return compileTyped(src, notificationRouteEnv, expr.Bool)
+}
+
+// MatchNotificationRoutes вычисляет условия маршрутов. Каналы не повторяются и идут в порядке
+// маршрутов. Условия проверены при сохранении, поэтому ошибка компиляции или вычисления
+// означает сломанный маршрут: он пропускается и не мешает остальным, а ошибка возвращается.
+func MatchNotificationRoutes(routes []models.NotificationRoute, vars expr.Vars) ([]string, []string) {
+	channels := []string{}
+	var routeErrors []string
+	seen := make(map[string]bool)
+	for _, route := range routes {
+		program, err := CompileNotificationRoute(route.Condition)
+		if err != nil {
+			routeErrors = append(routeErrors, fmt.Sprintf("%s: %v", route.Name, err))
+			continue
+		}
+		matched, err := program.EvalBool(vars)
+		if err != nil {
+			routeErrors = append(routeErrors, fmt.Sprintf("%s: %v", route.Name, err))
+			continue
+		}
+		if matched && !seen[route.Channel] {
+			seen[route.Channel] = true
+			channels = append(channels, route.Channel)
+		}
+	}
+	return channels, routeErrors
+}
+
+// ticketReferenceRe - ссылка на задачу в имени PR: ключ трекера (ABC-123) или номер issue (#123)
+var ticketReferenceRe = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-[0-9]+\b|#[0-9]+\b`)
+
+// PolicyViolationError возвращается при создании PR, нарушающего политики оформления команды автора
+type PolicyViolationError struct {
+	TeamName   string
+	Violations []models.PolicyViolation
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("pull request violates %d policies of team %s", len(e.Violations), e.TeamName)
+}
+
+// CheckPRPolicies проверяет PR по политикам оформления команды и возвращает все нарушения.
+// Выражение из настроек проверено при сохранении; если оно все же не компилируется,
+// политика имени пропускается, чтобы не блокировать создание PR.
+func CheckPRPolicies(settings models.TeamSettings, req models.CreatePRRequest) []models.PolicyViolation {
+	var violations []models.PolicyViolation
+
+	if settings.PRNamePattern != "" {
+		if re, err := regexp.Compile(settings.PRNamePattern); err == nil && !re.MatchString(req.PullRequestName) {
+			violations = append(violations, models.PolicyViolation{
+				Field:   "pull_request_name",
+				Policy:  "pr_name_pattern",
+				Message: fmt.Sprintf("pull_request_name must match %s", settings.PRNamePattern),
+			})
+		}
+	}
+	if settings.RequireTicketReference && !ticketReferenceRe.MatchString(req.PullRequestName) {
+		violations = append(violations, models.PolicyViolation{
+			Field:   "pull_request_name",
+			Policy:  "require_ticket_reference",
+			Message: "pull_request_name must reference a ticket (ABC-123 or #123)",
+		})
+	}
+	if settings.MinTags > 0 && len(req.Tags) < settings.MinTags {
+		violations = append(violations, models.PolicyViolation{
+			Field:   "tags",
+			Policy:  "min_tags",
+			Message: fmt.Sprintf("tags must contain at least %d tags", settings.MinTags),
+		})
+	}
+	return violations
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"PR_service/internal/expr"
+	"PR_service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCandidateExpressionsApply(t *testing.T) {
+	exprs, err := CompileCandidateExpressions(models.TeamSettings{
+		TeamName:        "backend",
+		CandidateFilter: "load < 5",
+		CandidateRank:   "10 / tenure_days",
+	})
+	assert.NoError(t, err)
+
+	vars := map[string]expr.Vars{}
+	for uid, v := range map[string][2]float64{"u1": {7, 30}, "u2": {1, 10}, "u3": {2, 0}} {
+		vars[uid] = expr.Vars{"load": v[0], "tenure_days": v[1]}
+	}
+
+	in := NewAssignmentInput(StrategyLeastLoaded, time.Now().UnixNano(), []string{"u1", "u2", "u3"}, nil, nil, 1)
+	out := exprs.Apply(in, vars)
+	assert.Equal(t, []string{"u2", "u3"}, out.Candidates)
+	assert.Equal(t, []string{"u1"}, out.FilteredOut)
+	// Деление на ноль не меняет вес u3 и сохраняется в решении
+	assert.Equal(t, map[string]float64{"u2": 1, "u3": 1}, out.Weights)
+	assert.Equal(t, []string{"u3: candidate_rank: division by zero"}, out.ExpressionErrors)
+
+	// Фильтр не оставляет PR без нужного числа ревьюеров
+	in = NewAssignmentInput(StrategyLeastLoaded, time.Now().UnixNano(), []string{"u1", "u2", "u3"}, nil, nil, 3)
+	out = exprs.Apply(in, vars)
+	assert.Equal(t, []string{"u1", "u2", "u3"}, out.Candidates)
+	assert.Empty(t, out.FilteredOut)
+
+	_, err = CompileCandidateExpressions(models.TeamSettings{TeamName: "backend", CandidateFilter: "load"})
+	assert.EqualError(t, err, "team backend candidate_filter: expression must be bool, got number")
+}
+
+func TestCandidateRankNonFinite(t *testing.T) {
+	huge := "1" + strings.Repeat("0", 200)
+	exprs, err := CompileCandidateExpressions(models.TeamSettings{CandidateRank: "tenure_days > 0 ? load * " + huge + " * " + huge + " : load"})
+	assert.NoError(t, err)
+
+	vars := map[string]expr.Vars{
+		"u1": {"load": 2.0, "tenure_days": 5.0},         // Переполнение при вычислении
+		"u2": {"load": math.Inf(1), "tenure_days": 0.0}, // Бесконечность из значения переменной
+		"u3": {"load": math.NaN(), "tenure_days": 0.0},
+		"u4": {"load": 3.0, "tenure_days": 0.0},
+	}
+	in := NewAssignmentInput(StrategyLeastLoaded, time.Now().UnixNano(), []string{"u1", "u2", "u3", "u4"}, nil, nil, 1)
+	out := exprs.Apply(in, vars)
+
+	// Вес остается прежним, а ошибка сохраняется в решении
+	assert.Equal(t, map[string]float64{"u1": 1, "u2": 1, "u3": 1, "u4": 3}, out.Weights)
+	assert.Equal(t, []string{
+		"u1: candidate_rank: numeric overflow",
+		"u2: candidate_rank: non-finite result +Inf",
+		"u3: candidate_rank: non-finite result NaN",
+	}, out.ExpressionErrors)
+	_, err = json.Marshal(out.Weights)
+	assert.NoError(t, err)
+
+	// Переполнение в константной части отклоняется при сохранении
+	_, err = CompileCandidateRank("load * (" + huge + " * " + huge + ")")
+	assert.ErrorContains(t, err, "numeric overflow")
+}
+
+func TestMatchNotificationRoutes(t *testing.T) {
+	routes := []models.NotificationRoute{
+		{Name: "broken", Condition: `size(labels) / 0 > 1 && "x" in labels`, Channel: "slack:#broken"},
+		{Name: "db", Condition: `"db" in labels`, Channel: "slack:#dba"},
+		{Name: "security", Condition: `"security" in labels && kind == "review_requested"`, Channel: "slack:#security-guild"},
+		{Name: "security-urgent", Condition: `"security" in labels && priority == "urgent"`, Channel: "slack:#security-guild"},
+	}
+
+	channels, routeErrors := MatchNotificationRoutes(routes, expr.Vars{
+		"labels": []string{"security"}, "kind": "review_requested", "priority": "urgent", "status": "OPEN",
+	})
+	assert.Equal(t, []string{"slack:#security-guild"}, channels)
+	assert.Equal(t, []string{"broken: division by zero"}, routeErrors)
+
+	channels, _ = MatchNotificationRoutes(routes, expr.Vars{
+		"labels": []string{"db", "security"}, "kind": "pr_merged", "priority": "normal", "status": "MERGED",
+	})
+	assert.Equal(t, []string{"slack:#dba"}, channels)
+
+	// Маршрут с условием в обход проверки пропускается, остальные вычисляются
+	routes[0].Condition = `labels`
+	channels, routeErrors = MatchNotificationRoutes(routes, expr.Vars{
+		"labels": []string{"db"}, "kind": "pr_merged", "priority": "normal", "status": "OPEN",
+	})
+	assert.Equal(t, []string{"slack:#dba"}, channels)
+	assert.Len(t, routeErrors, 1)
+}
+func TestCheckPRPolicies(t *testing.T) {
+	settings := models.TeamSettings{
+		TeamName:               "backend",
+		PRNamePattern:          `^(feat|fix): `,
+		RequireTicketReference: true,
+		MinTags:                1,
+	}
+
+	valid := models.CreatePRRequest{PullRequestName: "feat: PAY-42 refunds", Tags: []string{"payments"}}
+	assert.Empty(t, CheckPRPolicies(settings, valid))
+	assert.Empty(t, CheckPRPolicies(settings, models.CreatePRRequest{PullRequestName: "fix: crash (#17)", Tags: []string{"go"}}))
+
+	violations := CheckPRPolicies(settings, models.CreatePRRequest{PullRequestName: "Refunds"})
+	assert.Len(t, violations, 3)
+	assert.Equal(t, "pull_request_name", violations[0].Field)
+	assert.Equal(t, "pr_name_pattern", violations[0].Policy)
+	assert.Equal(t, "require_ticket_reference", violations[1].Policy)
+	assert.Equal(t, "tags", violations[2].Field)
+
+	// Без политик подходит любой PR
+	assert.Empty(t, CheckPRPolicies(models.TeamSettings{TeamName: "backend"}, models.CreatePRRequest{PullRequestName: "x"}))
+
+}
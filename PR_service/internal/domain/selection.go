@@ -0,0 +1,361 @@
+// Package domain - бизнес-правила сервиса без доступа к БД: выбор ревьюеров, жизненный
+// цикл PR (политики мерджа, цепочки одобрений, адаптивное число ревьюеров, срочность)
+// и вычисление политик команды. Функции пакета чистые: все входные данные, включая
+// время и источник случайности, передаются явно, а storage только собирает их из БД
+// и сохраняет результат.
+package domain
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Стратегии выбора ревьюеров
+const (
+	// StrategyRandom - равновероятный выбор среди кандидатов
+	StrategyRandom = "random"
+	// StrategyLeastLoaded - предпочтение кандидатам с наименьшим числом открытых ревью
+	StrategyLeastLoaded = "least_loaded"
+	// StrategyKnowledgeSpread - предпочтение кандидатам, которые давно не ревьюили PR с теми же тегами
+	StrategyKnowledgeSpread = "knowledge_spread"
+)
+
+// IsKnownStrategy проверяет, поддерживается ли стратегия выбора
+func IsKnownStrategy(strategy string) bool {
+	return strategy == StrategyRandom || strategy == StrategyLeastLoaded || strategy == StrategyKnowledgeSpread
+}
+
+// Правила, отсеивающие кандидатов при выборе ревьюеров
+const (
+	SelectionRuleQuota           = "quota"
+	SelectionRuleVeto            = "veto"
+	SelectionRuleCandidateFilter = "candidate_filter"
+)
+
+// Запасные варианты выбора: правило не удалось соблюсти или ревьюеров добрали иначе
+const (
+	SelectionFallbackVetoIgnored   = "veto_ignored"
+	SelectionFallbackFilterIgnored = "filter_ignored"
+	SelectionFallbackFocused       = "focused"
+	SelectionFallbackBorrow        = "borrow"
+)
+
+// AssignmentInput содержит все входные данные решения о назначении.
+// По этим данным выбор ревьюеров воспроизводится детерминированно.
+type AssignmentInput struct {
+	Strategy   string
+	Seed       int64
+	Candidates []string
+	Weights    map[string]float64
+	Focused    map[string]bool
+	Count      int
+	OnDuty     string // Дежурный недели получает первый слот, если он среди кандидатов и не в фокусе
+
+	FilteredOut      []string // Кандидаты, отсеянные фильтром команды
+	ExpressionErrors []string // Ошибки вычисления выражений кандидатов команды
+	Forced           []string // Принудительный выбор тестового стенда
+	QuotaDeferred    []string // Кандидаты, исчерпавшие квоту назначений
+
+	Stats SelectionStats // Наблюдения для метрик стратегий, в решении не сохраняются
+}
+
+// NewAssignmentInput готовит входные данные для выбора count ревьюеров из candidates.
+// Вес кандидата тем больше, чем предпочтительнее он для стратегии. Для least_loaded
+// loads - открытые ревью кандидата, для knowledge_spread - недавние ревью PR с теми же тегами.
+func NewAssignmentInput(strategy string, seed int64, candidates []string, focused map[string]bool, loads map[string]int, count int) AssignmentInput {
+	sorted := make([]string, len(candidates))
+	copy(sorted, candidates)
+	sort.Strings(sorted)
+
+	weights := make(map[string]float64, len(sorted))
+	for _, c := range sorted {
+		switch strategy {
+		case StrategyLeastLoaded, StrategyKnowledgeSpread:
+			weights[c] = 1 / float64(1+loads[c])
+		default:
+			weights[c] = 1
+		}
+	}
+
+	return AssignmentInput{
+		Strategy:   strategy,
+		Seed:       seed,
+		Candidates: sorted,
+		Weights:    weights,
+		Focused:    focused,
+		Count:      count,
+	}
+}
+
+// SelectionStats копит наблюдения за выбором, пока собираются входные данные.
+// В решении о назначении не сохраняется и на повтор решения не влияет.
+type SelectionStats struct {
+	Started    time.Time      // Начало сбора входных данных; нулевое - выбор не для метрик
+	Considered int            // Кандидаты до правил отбора
+	Filtered   map[string]int // Кандидаты, отсеянные каждым правилом
+	Fallbacks  []string       // Использованные запасные варианты
+}
+
+// NewSelectionStats начинает наблюдение за выбором из considered кандидатов
+func NewSelectionStats(considered int, started time.Time) SelectionStats {
+	return SelectionStats{Started: started, Considered: considered, Filtered: map[string]int{}}
+}
+
+// Filter учитывает n кандидатов, отсеянных правилом rule
+func (st *SelectionStats) Filter(rule string, n int) {
+	if n > 0 && st.Filtered != nil {
+		st.Filtered[rule] += n
+	}
+}
+
+// Fallback учитывает использованный запасной вариант
+func (st *SelectionStats) Fallback(name string) {
+	st.Fallbacks = append(st.Fallbacks, name)
+}
+
+// ErrTooManyExclusions - исключения автора оставляют PR без нужного числа ревьюеров
+var ErrTooManyExclusions = errors.New("excluded reviewers leave too few candidates")
+
+// ApplyReviewerExclusions убирает из кандидатов исключенных автором ревьюеров.
+// Исключать можно, только пока оставшихся кандидатов хватает на required
+// ревьюеров (или на всех, кого команда могла предложить без исключений).
+func ApplyReviewerExclusions(candidates, excluded []string, required int) ([]string, error) {
+	if len(excluded) == 0 {
+		return candidates, nil
+	}
+
+	skip := make(map[string]bool, len(excluded))
+	for _, uid := range excluded {
+		skip[uid] = true
+	}
+
+	remaining := make([]string, 0, len(candidates))
+	for _, uid := range candidates {
+		if !skip[uid] {
+			remaining = append(remaining, uid)
+		}
+	}
+
+	need := required
+	if len(candidates) < need {
+		need = len(candidates)
+	}
+	if len(remaining) < need {
+		return nil, ErrTooManyExclusions
+	}
+	return remaining, nil
+}
+
+// SelectReviewers выбирает ревьюеров по входным данным решения.
+// Одинаковые входные данные всегда дают одинаковый результат.
+func SelectReviewers(in AssignmentInput) []string {
+	if len(in.Forced) > 0 {
+		return PickForced(in)
+	}
+	if first, rest, ok := TakeOnDuty(in); ok {
+		return append([]string{first}, SelectReviewers(rest)...)
+	}
+
+	rng := rand.New(rand.NewSource(in.Seed))
+
+	switch in.Strategy {
+	case StrategyLeastLoaded, StrategyKnowledgeSpread:
+		// Случайная перестановка разбивает ничьи, затем сортируем по фокусу и весу
+		ordered := make([]string, len(in.Candidates))
+		copy(ordered, in.Candidates)
+		rng.Shuffle(len(ordered), func(i, j int) {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		})
+		sort.SliceStable(ordered, func(i, j int) bool {
+			fi, fj := in.Focused[ordered[i]], in.Focused[ordered[j]]
+			if fi != fj {
+				return !fi
+			}
+			return in.Weights[ordered[i]] > in.Weights[ordered[j]]
+		})
+		if in.Count <= 0 {
+			return []string{}
+		}
+		if len(ordered) > in.Count {
+			ordered = ordered[:in.Count]
+		}
+		return ordered
+	default:
+		// Веса случайной стратегии меняют только штрафы за отказы; без них выбор равновероятный
+		if uniformWeights(in.Candidates, in.Weights) {
+			return PickAvoidingFocus(rng.Intn, in.Candidates, in.Focused, in.Count)
+		}
+		return PickWeightedAvoidingFocus(rng.Float64, in.Candidates, in.Focused, in.Weights, in.Count)
+	}
+}
+
+// uniformWeights проверяет, что у всех кандидатов одинаковый вес
+func uniformWeights(candidates []string, weights map[string]float64) bool {
+	for _, uid := range candidates {
+		if weights[uid] != weights[candidates[0]] {
+			return false
+		}
+	}
+	return true
+}
+
+// PickForced выбирает принудительно заданных ревьюеров в порядке списка. Пропускаются
+// те, кто не входит в кандидаты (автор, неактивные, чужая команда, исчерпан лимит
+// открытых ревью): принудительный выбор не обходит правила назначения, а незаполненные
+// слоты остаются пустыми, чтобы результат не зависел от случайности.
+func PickForced(in AssignmentInput) []string {
+	isCandidate := make(map[string]bool, len(in.Candidates))
+	for _, uid := range in.Candidates {
+		isCandidate[uid] = true
+	}
+
+	selected := []string{}
+	for _, uid := range in.Forced {
+		if len(selected) >= in.Count {
+			break
+		}
+		if isCandidate[uid] {
+			selected = append(selected, uid)
+			delete(isCandidate, uid)
+		}
+	}
+	return selected
+}
+
+// PickAvoidingFocus выбирает до n ревьюеров, отдавая предпочтение тем, у кого нет окна фокуса.
+// Пользователи в фокусе назначаются только если свободных кандидатов не хватает.
+func PickAvoidingFocus(intn func(int) int, candidates []string, focused map[string]bool, n int) []string {
+	var free, busy []string
+	for _, c := range candidates {
+		if focused[c] {
+			busy = append(busy, c)
+		} else {
+			free = append(free, c)
+		}
+	}
+
+	selected := PickDistinct(intn, free, n)
+	if len(selected) < n {
+		selected = append(selected, PickDistinct(intn, busy, n-len(selected))...)
+	}
+	return selected
+}
+
+// PickWeightedAvoidingFocus выбирает до n ревьюеров без повторов с вероятностью,
+// пропорциональной весу, и, как PickAvoidingFocus, берет кандидатов в фокусе, только
+// если свободных не хватает. Кандидаты с нулевым весом выбираются последними.
+func PickWeightedAvoidingFocus(float func() float64, candidates []string, focused map[string]bool, weights map[string]float64, n int) []string {
+	var free, busy []string
+	for _, c := range candidates {
+		if focused[c] {
+			busy = append(busy, c)
+		} else {
+			free = append(free, c)
+		}
+	}
+
+	selected := pickWeighted(float, free, weights, n)
+	if len(selected) < n {
+		selected = append(selected, pickWeighted(float, busy, weights, n-len(selected))...)
+	}
+	return selected
+}
+
+// pickWeighted последовательно выбирает n кандидатов: каждый следующий - с вероятностью,
+// пропорциональной весу среди еще не выбранных
+func pickWeighted(float func() float64, candidates []string, weights map[string]float64, n int) []string {
+	selected := []string{}
+	rest := make([]string, len(candidates))
+	copy(rest, candidates)
+	for len(selected) < n && len(rest) > 0 {
+		total := 0.0
+		for _, uid := range rest {
+			total += weights[uid]
+		}
+
+		i := 0
+		if total > 0 {
+			r := float() * total
+			for i < len(rest)-1 && (weights[rest[i]] <= 0 || r >= weights[rest[i]]) {
+				r -= weights[rest[i]]
+				i++
+			}
+		}
+		selected = append(selected, rest[i])
+		rest = append(rest[:i], rest[i+1:]...)
+	}
+	return selected
+}
+
+// PickDistinct выбирает уникальные элементы, используя переданный источник случайности
+func PickDistinct(intn func(int) int, arr []string, n int) []string {
+	if arr == nil || n <= 0 {
+		return []string{}
+	}
+
+	if len(arr) <= n {
+		res := make([]string, len(arr))
+		copy(res, arr)
+		return res
+	}
+
+	res := make([]string, len(arr))
+	copy(res, arr)
+	for i := len(res) - 1; i > 0; i-- {
+		j := intn(i + 1)
+		res[i], res[j] = res[j], res[i]
+	}
+	return res[:n]
+}
+
+// rotationEpoch - понедельник, от которого считаются недели очереди дежурств
+var rotationEpoch = time.Date(1970, time.January, 5, 0, 0, 0, 0, time.UTC)
+
+// WeekStart возвращает понедельник (00:00 UTC) недели, в которую попадает t
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7 // Понедельник - 0
+	return day.AddDate(0, 0, -offset)
+}
+
+// RotationPick возвращает дежурного недели week по очереди: участники по user_id,
+// каждую неделю дежурит следующий. При изменении состава очередь сдвигается.
+func RotationPick(members []string, week time.Time) string {
+	if len(members) == 0 {
+		return ""
+	}
+	sorted := make([]string, len(members))
+	copy(sorted, members)
+	sort.Strings(sorted)
+
+	index := int(WeekStart(week).Sub(rotationEpoch).Hours()/24) / 7
+	return sorted[index%len(sorted)]
+}
+
+// TakeOnDuty отделяет дежурного в первый слот. Дежурный в окне фокуса не получает
+// приоритета. Третье значение false, если дежурного некуда или некого назначить.
+func TakeOnDuty(in AssignmentInput) (string, AssignmentInput, bool) {
+	if in.OnDuty == "" || in.Count <= 0 || in.Focused[in.OnDuty] {
+		return "", in, false
+	}
+
+	rest := in
+	rest.OnDuty = ""
+	rest.Count = in.Count - 1
+	rest.Candidates = make([]string, 0, len(in.Candidates))
+	found := false
+	for _, uid := range in.Candidates {
+		if uid == in.OnDuty {
+			found = true
+			continue
+		}
+		rest.Candidates = append(rest.Candidates, uid)
+	}
+	if !found {
+		return "", in, false
+	}
+	return in.OnDuty, rest, true
+}
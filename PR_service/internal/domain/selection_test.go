@@ -0,0 +1,211 @@
+package domain
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickAvoidingFocus(t *testing.T) {
+	candidates := []string{"a", "b", "c", "d"}
+
+	t.Run("Prefers candidates outside focus window", func(t *testing.T) {
+		focused := map[string]bool{"a": true, "b": true}
+		for i := 0; i < 20; i++ {
+			result := PickAvoidingFocus(rand.Intn, candidates, focused, 2)
+			assert.ElementsMatch(t, []string{"c", "d"}, result)
+		}
+	})
+
+	t.Run("Falls back to focused candidates when needed", func(t *testing.T) {
+		focused := map[string]bool{"a": true, "b": true, "c": true}
+		result := PickAvoidingFocus(rand.Intn, candidates, focused, 2)
+		assert.Len(t, result, 2)
+		assert.Contains(t, result, "d")
+		assert.Equal(t, len(result), len(uniqueStrings(result)))
+	})
+
+	t.Run("No focus windows", func(t *testing.T) {
+		result := PickAvoidingFocus(rand.Intn, candidates, map[string]bool{}, 2)
+		assert.Len(t, result, 2)
+	})
+}
+
+func TestSelectReviewersDeterministic(t *testing.T) {
+	candidates := []string{"u1", "u2", "u3", "u4", "u5", "u6"}
+	focused := map[string]bool{"u2": true}
+
+	input := NewAssignmentInput(StrategyRandom, time.Now().UnixNano(), candidates, focused, nil, 2)
+	first := SelectReviewers(input)
+	assert.Len(t, first, 2)
+	assert.NotContains(t, first, "u2")
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first, SelectReviewers(input))
+	}
+
+	t.Run("Candidates are normalized", func(t *testing.T) {
+		shuffled := NewAssignmentInput(StrategyRandom, time.Now().UnixNano(), []string{"u6", "u1", "u5", "u3", "u2", "u4"}, focused, nil, 2)
+		shuffled.Seed = input.Seed
+		assert.Equal(t, input.Candidates, shuffled.Candidates)
+		assert.Equal(t, first, SelectReviewers(shuffled))
+	})
+
+	t.Run("Uniform weights", func(t *testing.T) {
+		assert.Equal(t, StrategyRandom, input.Strategy)
+		assert.Len(t, input.Weights, len(candidates))
+		for _, w := range input.Weights {
+			assert.Equal(t, 1.0, w)
+		}
+	})
+}
+
+func TestSelectReviewersRandomWeighted(t *testing.T) {
+	candidates := []string{"u1", "u2", "u3"}
+
+	t.Run("Lower weight is picked less often", func(t *testing.T) {
+		picks := map[string]int{}
+		for seed := int64(0); seed < 3000; seed++ {
+			input := NewAssignmentInput(StrategyRandom, seed, candidates, nil, nil, 1)
+			input.Weights["u1"] = 0.25
+			picks[SelectReviewers(input)[0]]++
+		}
+		// Ожидаемая доля u1 - 0.25 / 2.25, около 11%
+		assert.Less(t, picks["u1"], 600)
+		assert.Greater(t, picks["u2"], picks["u1"]*2)
+		assert.Greater(t, picks["u3"], picks["u1"]*2)
+	})
+
+	t.Run("Distinct, deterministic and avoids focus", func(t *testing.T) {
+		input := NewAssignmentInput(StrategyRandom, time.Now().UnixNano(), candidates, map[string]bool{"u3": true}, nil, 2)
+		input.Weights["u2"] = 0.5
+		first := SelectReviewers(input)
+		assert.ElementsMatch(t, []string{"u1", "u2"}, first)
+		assert.Equal(t, first, SelectReviewers(input))
+
+		input.Count = 3
+		assert.ElementsMatch(t, candidates, SelectReviewers(input))
+	})
+
+	t.Run("Zero weights are picked last", func(t *testing.T) {
+		result := PickWeightedAvoidingFocus(rand.Float64, candidates, nil, map[string]float64{"u1": 0, "u2": 1, "u3": 0}, 2)
+		assert.Len(t, result, 2)
+		assert.Equal(t, "u2", result[0])
+	})
+}
+
+func TestSelectReviewersLeastLoaded(t *testing.T) {
+	candidates := []string{"u1", "u2", "u3", "u4"}
+	loads := map[string]int{"u1": 5, "u2": 0, "u3": 1, "u4": 0}
+
+	t.Run("Prefers least loaded", func(t *testing.T) {
+		input := NewAssignmentInput(StrategyLeastLoaded, time.Now().UnixNano(), candidates, map[string]bool{}, loads, 2)
+		assert.ElementsMatch(t, []string{"u2", "u4"}, SelectReviewers(input))
+	})
+
+	t.Run("Focus window outweighs load", func(t *testing.T) {
+		input := NewAssignmentInput(StrategyLeastLoaded, time.Now().UnixNano(), candidates, map[string]bool{"u2": true}, loads, 2)
+		assert.ElementsMatch(t, []string{"u3", "u4"}, SelectReviewers(input))
+	})
+
+	t.Run("Zero count", func(t *testing.T) {
+		input := NewAssignmentInput(StrategyLeastLoaded, time.Now().UnixNano(), candidates, nil, loads, 0)
+		assert.Empty(t, SelectReviewers(input))
+	})
+}
+
+func TestSelectReviewersKnowledgeSpread(t *testing.T) {
+	candidates := []string{"u1", "u2", "u3"}
+	recent := map[string]int{"u1": 4, "u3": 1}
+
+	input := NewAssignmentInput(StrategyKnowledgeSpread, time.Now().UnixNano(), candidates, nil, recent, 2)
+	assert.Equal(t, []string{"u2", "u3"}, SelectReviewers(input))
+	assert.True(t, IsKnownStrategy(StrategyKnowledgeSpread))
+}
+
+func TestApplyReviewerExclusions(t *testing.T) {
+	candidates := []string{"u2", "u3", "u4"}
+
+	remaining, err := ApplyReviewerExclusions(candidates, nil, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, candidates, remaining)
+
+	remaining, err = ApplyReviewerExclusions(candidates, []string{"u3", "u9"}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u2", "u4"}, remaining)
+
+	_, err = ApplyReviewerExclusions(candidates, []string{"u2", "u3"}, 2)
+	assert.ErrorIs(t, err, ErrTooManyExclusions)
+
+	// В маленькой команде нельзя исключить тех, без кого ревьюеров не хватит
+	_, err = ApplyReviewerExclusions([]string{"u2"}, []string{"u2"}, 2)
+	assert.ErrorIs(t, err, ErrTooManyExclusions)
+
+	// Без обязательных ревьюеров исключать можно всех
+	remaining, err = ApplyReviewerExclusions(candidates, candidates, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+}
+
+func TestWeekStartAndRotationPick(t *testing.T) {
+	monday := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, monday, WeekStart(monday))
+	assert.Equal(t, monday, WeekStart(time.Date(2024, time.March, 10, 23, 59, 0, 0, time.UTC)))
+	assert.Equal(t, monday.AddDate(0, 0, 7), WeekStart(time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC)))
+
+	// Каждую неделю дежурит следующий участник, порядок не зависит от порядка входа
+	members := []string{"u3", "u1", "u2"}
+	first := RotationPick(members, monday)
+	second := RotationPick(members, monday.AddDate(0, 0, 7))
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, first, RotationPick([]string{"u1", "u2", "u3"}, monday.AddDate(0, 0, 3)))
+	assert.Equal(t, first, RotationPick(members, monday.AddDate(0, 0, 21)))
+	assert.Empty(t, RotationPick(nil, monday))
+}
+
+func TestSelectReviewersOnDuty(t *testing.T) {
+	in := NewAssignmentInput(StrategyRandom, time.Now().UnixNano(), []string{"u1", "u2", "u3", "u4"}, nil, nil, 2)
+	in.OnDuty = "u3"
+	selected := SelectReviewers(in)
+	assert.Len(t, selected, 2)
+	assert.Equal(t, "u3", selected[0])
+	assert.Equal(t, selected, SelectReviewers(in))
+
+	// Дежурный не из кандидатов или в окне фокуса не получает первый слот
+	in.OnDuty = "u9"
+	assert.NotContains(t, SelectReviewers(in), "u9")
+	in.OnDuty = "u3"
+	in.Focused = map[string]bool{"u3": true}
+	assert.NotContains(t, SelectReviewers(in), "u3")
+}
+
+func TestSelectReviewersForced(t *testing.T) {
+	in := NewAssignmentInput(StrategyLeastLoaded, time.Now().UnixNano(), []string{"u1", "u2", "u3", "u4"}, nil, nil, 2)
+	in.OnDuty = "u1"
+	in.Forced = []string{"u9", "u4", "u2", "u3"}
+
+	// Стратегия и дежурный не учитываются, не кандидаты пропускаются
+	assert.Equal(t, []string{"u4", "u2"}, SelectReviewers(in))
+
+	// Незаполненные слоты остаются пустыми
+	in.Forced = []string{"u3", "u9"}
+	assert.Equal(t, []string{"u3"}, SelectReviewers(in))
+
+	in.Count = 0
+	assert.Empty(t, SelectReviewers(in))
+}
+
+// Вспомогательная функция для проверки уникальности
+func uniqueStrings(arr []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, item := range arr {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
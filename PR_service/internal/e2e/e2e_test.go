@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"PR_service/internal/api"
+	"PR_service/internal/clock"
 	"PR_service/internal/models"
 	"PR_service/internal/storage"
 
@@ -31,8 +32,12 @@ type TestServer struct {
 	Store   *storage.StorageData
 	DB      *sql.DB
 	Metrics *api.Metrics
+	Clock   *clock.Fake
 }
 
+// e2eStartTime - момент, на котором стоят часы тестового сервера
+var e2eStartTime = time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
 // getTestDSN возвращает DSN для тестовой БД
 func getTestDSN() string {
 	if dsn := os.Getenv("TEST_DATABASE_URL"); dsn != "" {
@@ -87,32 +92,93 @@ func setupTestServer(t *testing.T) *TestServer {
 	require.NoError(t, err)
 
 	// Создаем storage и handler
+	// Часы стоят, пока тест их не перемотает
+	clk := clock.NewFake(e2eStartTime)
 	store := storage.NewStorage(db)
-	metrics := api.NewMetrics()
+	store.SetClock(clk)
+	metrics := api.NewMetrics(api.DefaultMetricsNamespace)
 	handler := api.NewHandler(store, metrics)
 
 	// Создаем router с ТОЧНО ТАКИМИ ЖЕ настройками как в main.go
 	router := mux.NewRouter()
 
 	// Middleware (как в main.go)
+	router.Use(api.AuthContextMiddleware)
 	router.Use(metrics.MetricsMiddleware)
+	router.Use(handler.ScopeMiddleware)
 	router.Use(api.TimeoutMiddleware)
+	router.Use(api.RecoveryMiddleware(metrics))
+	router.Use(api.FieldsMiddleware)
+	router.Use(handler.MaintenanceMiddleware)
+	router.Use(handler.ForceReviewersMiddleware)
 
 	// API routes (ТОЧНО КАК В main.go)
 	router.HandleFunc("/", handler.Root).Methods("GET")
 	router.HandleFunc("/team/add", handler.AddTeam).Methods("POST")
 	router.HandleFunc("/team/get", handler.GetTeam).Methods("GET")
+	router.HandleFunc("/team/setBorrowPool", handler.SetBorrowPool).Methods("POST")
+	router.HandleFunc("/team/leads", handler.GetTeamLeads).Methods("GET")
+	router.HandleFunc("/team/leads/add", handler.AddTeamLead).Methods("POST")
+	router.HandleFunc("/team/leads/remove", handler.RemoveTeamLead).Methods("POST")
+	router.HandleFunc("/team/queue", handler.GetReviewQueue).Methods("GET")
+	router.HandleFunc("/team/candidates", handler.PreviewCandidates).Methods("GET")
+	router.HandleFunc("/team/rotation", handler.GetTeamRotation).Methods("GET")
+	router.HandleFunc("/team/rotation", handler.SetTeamRotation).Methods("POST")
+	router.HandleFunc("/team/settings", handler.GetTeamSettings).Methods("GET")
+	router.HandleFunc("/team/settings/preview", handler.PreviewTeamSettings).Methods("POST")
+	router.HandleFunc("/team/settings/apply", handler.ApplyTeamSettings).Methods("POST")
+	router.HandleFunc("/team/checklist", handler.GetChecklist).Methods("GET")
+	router.HandleFunc("/team/checklist/add", handler.AddChecklistItem).Methods("POST")
+	router.HandleFunc("/team/checklist/update", handler.UpdateChecklistItem).Methods("POST")
+	router.HandleFunc("/team/checklist/delete", handler.DeleteChecklistItem).Methods("POST")
+	router.HandleFunc("/team/notificationTemplates", handler.GetNotificationTemplates).Methods("GET")
+	router.HandleFunc("/team/notificationTemplates/set", handler.SetNotificationTemplate).Methods("POST")
+	router.HandleFunc("/team/notificationRoutes", handler.GetNotificationRoutes).Methods("GET")
+	router.HandleFunc("/team/notificationRoutes/set", handler.SetNotificationRoute).Methods("POST")
 	router.HandleFunc("/users/setIsActive", handler.SetIsActive).Methods("POST")
 	router.HandleFunc("/users/getReview", handler.GetPRsForUser).Methods("GET")
+	router.HandleFunc("/users/setFocusWindows", handler.SetFocusWindows).Methods("POST")
+	router.HandleFunc("/users/getFocusWindows", handler.GetFocusWindows).Methods("GET")
+	router.HandleFunc("/users/setLocale", handler.SetUserLocale).Methods("POST")
+	router.HandleFunc("/users/setAssignmentQuota", handler.SetAssignmentQuota).Methods("POST")
+	router.HandleFunc("/users/getAssignmentQuota", handler.GetAssignmentQuota).Methods("GET")
+	router.HandleFunc("/notifications/preview", handler.PreviewNotification).Methods("POST")
 	router.HandleFunc("/pullRequest/create", handler.CreatePR).Methods("POST") // ПРАВИЛЬНЫЙ адрес
 	router.HandleFunc("/pullRequest/merge", handler.MergePR).Methods("POST")
 	router.HandleFunc("/pullRequest/reassign", handler.ReassignReviewer).Methods("POST")
+	router.HandleFunc("/pullRequest/transferAuthor", handler.TransferAuthor).Methods("POST")
+	router.HandleFunc("/pullRequest/addReviewer", handler.AddReviewer).Methods("POST")
+	router.HandleFunc("/pullRequest/veto", handler.VetoAssignment).Methods("POST")
+	router.HandleFunc("/pullRequest/approve", handler.ApprovePR).Methods("POST")
+	router.HandleFunc("/pullRequest/get", handler.GetPR).Methods("GET")
+	router.HandleFunc("/pullRequest/export", handler.ExportPullRequests).Methods("GET")
+	router.HandleFunc("/pullRequest/labels/bulk", handler.BulkLabelPRs).Methods("POST")
+	router.HandleFunc("/reports/borrowing", handler.BorrowingReport).Methods("GET")
+	router.HandleFunc("/reports/checklist", handler.ChecklistReport).Methods("GET")
+	router.HandleFunc("/reports/reviewerGrowth", handler.ReviewerGrowthReport).Methods("GET")
+	router.HandleFunc("/reports/vetoes", handler.VetoReport).Methods("GET")
+	router.HandleFunc("/webhooks/bitbucket", handler.BitbucketWebhook).Methods("POST")
+	router.HandleFunc("/admin/maintenance", handler.GetMaintenance).Methods("GET")
+	router.HandleFunc("/admin/maintenance", handler.SetMaintenance).Methods("POST")
+	router.HandleFunc("/admin/assignmentDecisions", handler.GetAssignmentDecisions).Methods("GET")
+	router.HandleFunc("/admin/assignmentDecisions/replay", handler.ReplayAssignmentDecision).Methods("GET")
+	router.HandleFunc("/admin/migrations", handler.GetMigrationStatus).Methods("GET")
+	router.HandleFunc("/admin/externalIdentities", handler.GetExternalIdentities).Methods("GET")
+	router.HandleFunc("/admin/externalIdentities/set", handler.SetExternalIdentity).Methods("POST")
+	router.HandleFunc("/admin/webhookKeys", handler.GetWebhookKeys).Methods("GET")
+	router.HandleFunc("/admin/webhookKeys/rotate", handler.RotateWebhookKey).Methods("POST")
+	router.HandleFunc("/admin/webhookKeys/revoke", handler.RevokeWebhookKey).Methods("POST")
+	router.HandleFunc("/audit/search", handler.SearchAuditLog).Methods("GET")
+	router.HandleFunc("/audit/export", handler.ExportAuditLog).Methods("GET")
+	router.HandleFunc("/audit/diff", handler.GetAuditDiff).Methods("GET")
+	router.HandleFunc("/status", handler.Status).Methods("GET")
 	router.HandleFunc("/health", handler.HealthCheck).Methods("GET")
+	router.HandleFunc("/health/ready", handler.ReadinessCheck).Methods("GET")
 	router.Handle("/metrics", metrics.InstrumentedHandler()).Methods("GET")
 	router.HandleFunc("/metrics/data", handler.MetricsData).Methods("GET")
 
 	// Создаем тестовый сервер
-	server := httptest.NewServer(router)
+	server := httptest.NewServer(handler.WithHealthz(router))
 
 	return &TestServer{
 		Router:  router,
@@ -120,6 +186,7 @@ func setupTestServer(t *testing.T) *TestServer {
 		Store:   store,
 		DB:      db,
 		Metrics: metrics,
+		Clock:   clk,
 	}
 }
 
@@ -135,7 +202,7 @@ func (ts *TestServer) teardownTestServer(t *testing.T) {
 
 // cleanTestDB очищает тестовую БД
 func cleanTestDB(t *testing.T, db *sql.DB) {
-	tables := []string{"pr_reviewers", "pull_requests", "team_members", "users", "teams"}
+	tables := []string{"notification_routes", "webhook_signing_keys", "webhook_deliveries", "user_assignment_quotas", "external_identities", "team_rotation_overrides", "pr_vetoes", "schema_migrations", "schema_version", "pr_tags", "event_outbox", "notification_templates", "audit_log", "pr_checklist_marks", "checklist_items", "review_queue", "team_settings", "service_maintenance", "review_borrows", "team_borrow_pools", "assignment_decisions", "user_focus_windows", "pr_excluded_reviewers", "pr_reviewers", "pull_requests", "team_leads", "team_members", "users", "teams"}
 	for _, table := range tables {
 		_, err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", table))
 		if err != nil {
@@ -469,6 +536,272 @@ func TestE2EErrorScenarios(t *testing.T) {
 	t.Log("=== ТЕСТИРОВАНИЕ ОШИБОК ЗАВЕРШЕНО ===")
 }
 
+// TestE2EClockFastForward проверяет, что время PR и ожидания в очереди берется из часов сервера
+func TestE2EClockFastForward(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Пропускаем E2E тесты в short mode")
+	}
+
+	ts := setupTestServer(t)
+	defer ts.teardownTestServer(t)
+
+	client := ts.Server.Client()
+	post := func(path string, body interface{}) *http.Response {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		resp, err := client.Post(ts.Server.URL+path, "application/json", bytes.NewBuffer(data))
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Один ревьюер с пределом в одно открытое ревью: второй PR встает в очередь
+	resp := post("/team/add", models.Team{
+		TeamName: "clock-team",
+		Members: []models.User{
+			{UserID: "clock-author", Username: "Автор", IsActive: true},
+			{UserID: "clock-reviewer", Username: "Ревьюер", IsActive: true},
+		},
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = post("/team/settings/apply", models.TeamSettings{
+		TeamName: "clock-team", ReviewerCount: 1, Strategy: "random", MaxOpenReviews: 1,
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	var created struct {
+		PR models.PullRequest `json:"pr"`
+	}
+	resp = post("/pullRequest/create", models.CreatePRRequest{
+		PullRequestID: "clock-pr-1", PullRequestName: "Первый", AuthorID: "clock-author",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	assert.True(t, created.PR.CreatedAt.Equal(e2eStartTime), "createdAt должен совпадать с часами сервера")
+
+	resp = post("/pullRequest/create", models.CreatePRRequest{
+		PullRequestID: "clock-pr-2", PullRequestName: "Второй", AuthorID: "clock-author",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	ts.Clock.Advance(2 * time.Hour)
+
+	resp, err := client.Get(ts.Server.URL + "/team/queue?team_name=clock-team")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var queue struct {
+		Queue []models.QueuedPR `json:"queue"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&queue))
+	resp.Body.Close()
+	require.Len(t, queue.Queue, 1)
+	assert.Equal(t, "clock-pr-2", queue.Queue[0].PullRequestID)
+	assert.Equal(t, int64(2*time.Hour/time.Second), queue.Queue[0].WaitSeconds)
+
+	var merged struct {
+		PR models.PullRequest `json:"pr"`
+	}
+	resp = post("/pullRequest/merge", map[string]string{"pull_request_id": "clock-pr-1"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&merged))
+	resp.Body.Close()
+	require.NotNil(t, merged.PR.MergedAt)
+	mergedAt, err := time.Parse(time.RFC3339, *merged.PR.MergedAt)
+	require.NoError(t, err)
+	assert.True(t, mergedAt.Equal(e2eStartTime.Add(2*time.Hour)), "mergedAt должен учитывать перемотку часов")
+}
+
+func TestE2EBorrowRespectsLenderCapacity(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Пропускаем E2E тесты в short mode")
+	}
+
+	ts := setupTestServer(t)
+	defer ts.teardownTestServer(t)
+
+	client := ts.Server.Client()
+	post := func(path string, body interface{}) *http.Response {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		resp, err := client.Post(ts.Server.URL+path, "application/json", bytes.NewBuffer(data))
+		require.NoError(t, err)
+		return resp
+	}
+	expect := func(resp *http.Response, status int) {
+		t.Helper()
+		require.Equal(t, status, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	// В команде-доноре единственный ревьюер с пределом в одно открытое ревью
+	expect(post("/team/add", models.Team{
+		TeamName: "lender-team",
+		Members: []models.User{
+			{UserID: "lender-author", Username: "Автор донора", IsActive: true},
+			{UserID: "lender-busy", Username: "Занятый", IsActive: true},
+		},
+	}), http.StatusCreated)
+	expect(post("/team/settings/apply", models.TeamSettings{
+		TeamName: "lender-team", ReviewerCount: 1, Strategy: "random", MaxOpenReviews: 1,
+	}), http.StatusOK)
+	expect(post("/pullRequest/create", models.CreatePRRequest{
+		PullRequestID: "lender-pr", PullRequestName: "Свой PR", AuthorID: "lender-author",
+	}), http.StatusCreated)
+	expect(post("/users/setIsActive", models.SetActiveRequest{UserID: "lender-author", Active: false}), http.StatusOK)
+
+	expect(post("/team/add", models.Team{
+		TeamName: "borrower-team",
+		Members:  []models.User{{UserID: "borrower-author", Username: "Заемщик", IsActive: true}},
+	}), http.StatusCreated)
+	expect(post("/team/settings/apply", models.TeamSettings{
+		TeamName: "borrower-team", ReviewerCount: 1, Strategy: "random",
+	}), http.StatusOK)
+	expect(post("/team/setBorrowPool", models.SetBorrowPoolRequest{
+		TeamName: "borrower-team", Lenders: []models.BorrowLender{{TeamName: "lender-team"}},
+	}), http.StatusOK)
+
+	// Ревьюер донора исчерпал предел своей команды и не занимается
+	resp := post("/pullRequest/create", models.CreatePRRequest{
+		PullRequestID: "borrower-pr", PullRequestName: "Чужой PR", AuthorID: "borrower-author",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created struct {
+		PR models.PullRequest `json:"pr"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	assert.NotContains(t, created.PR.Reviewers, "lender-busy")
+}
+
+func TestE2EPRPoliciesCheckedInCreateTransaction(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Пропускаем E2E тесты в short mode")
+	}
+
+	ts := setupTestServer(t)
+	defer ts.teardownTestServer(t)
+
+	client := ts.Server.Client()
+	post := func(path string, body interface{}) *http.Response {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		resp, err := client.Post(ts.Server.URL+path, "application/json", bytes.NewBuffer(data))
+		require.NoError(t, err)
+		return resp
+	}
+	expect := func(resp *http.Response, status int) {
+		t.Helper()
+		require.Equal(t, status, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	expect(post("/team/add", models.Team{
+		TeamName: "policy-team",
+		Members: []models.User{
+			{UserID: "policy-author", Username: "Автор", IsActive: true},
+			{UserID: "policy-reviewer", Username: "Ревьюер", IsActive: true},
+		},
+	}), http.StatusCreated)
+	expect(post("/team/settings/apply", models.TeamSettings{
+		TeamName: "policy-team", ReviewerCount: 1, Strategy: "random", RequireTicketReference: true,
+	}), http.StatusOK)
+
+	// Нарушение политики откатывает транзакцию: PR не создается
+	resp := post("/pullRequest/create", models.CreatePRRequest{
+		PullRequestID: "policy-pr", PullRequestName: "Без задачи", AuthorID: "policy-author",
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorResp))
+	resp.Body.Close()
+	assert.Equal(t, "POLICY_VIOLATION", errorResp.Error.Code)
+
+	_, err := ts.Store.GetPRDetail(context.Background(), "policy-pr")
+	require.Error(t, err)
+
+	// PR, уже открытый во внешней системе, политикам не подлежит
+	pr, err := ts.Store.CreatePR(context.Background(), models.CreatePRRequest{
+		PullRequestID: "policy-pr", PullRequestName: "Без задачи", AuthorID: "policy-author", SkipPolicies: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "policy-team", pr.TeamName)
+	assert.Equal(t, []string{"policy-reviewer"}, pr.Reviewers)
+}
+
+func TestE2EWarmUp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Пропускаем E2E тесты в short mode")
+	}
+
+	ts := setupTestServer(t)
+	defer ts.teardownTestServer(t)
+
+	data, _ := json.Marshal(models.Team{
+		TeamName: "warm-team",
+		Members: []models.User{
+			{UserID: "warm-1", Username: "Первый", IsActive: true},
+			{UserID: "warm-2", Username: "Второй", IsActive: true},
+			{UserID: "warm-3", Username: "Неактивный", IsActive: false},
+		},
+	})
+	resp, err := ts.Server.Client().Post(ts.Server.URL+"/team/add", "application/json", bytes.NewBuffer(data))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	// Прогрев готовит запросы горячего пути и загружает настройки и кандидатов
+	// теми же функциями, что и CreatePR
+	stats, err := ts.Store.WarmUp(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, storage.WarmUpStats{Connections: 2, Teams: 1, Candidates: 2, Statements: 10}, stats)
+}
+
+func TestE2ECheckMigrations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Пропускаем E2E тесты в short mode")
+	}
+
+	ts := setupTestServer(t)
+	defer ts.teardownTestServer(t)
+
+	// Проверка ничего не оставляет: временная схема исчезает при откате
+	require.NoError(t, storage.CheckMigrations(ts.DB))
+	var leftover bool
+	require.NoError(t, ts.DB.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM pg_namespace WHERE nspname = 'pr_service_migration_check')`).Scan(&leftover))
+	assert.False(t, leftover)
+
+	// Проверка ждет advisory lock миграций, как и их применение
+	ctx := context.Background()
+	conn, err := ts.DB.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, int64(0x50525f6d696772))
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- storage.CheckMigrations(ts.DB) }()
+	select {
+	case <-done:
+		t.Fatal("CheckMigrations не дождался блокировки миграций")
+	case <-time.After(200 * time.Millisecond):
+	}
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, int64(0x50525f6d696772))
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+
+	// Незавершенная миграция не проверяется поверх
+	_, err = ts.DB.Exec(`UPDATE schema_version SET dirty = true`)
+	require.NoError(t, err)
+	var dirty *storage.DirtySchemaError
+	assert.ErrorAs(t, storage.CheckMigrations(ts.DB), &dirty)
+	_, err = ts.DB.Exec(`UPDATE schema_version SET dirty = false`)
+	require.NoError(t, err)
+}
+
 // CheckUserActiveStatus проверяет активность пользователя
 func CheckUserActiveStatus(t *testing.T, client *http.Client, serverURL, userID string, expectedActive bool) {
 	t.Helper()
@@ -0,0 +1,303 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"PR_service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvents() []models.BusinessEvent {
+	return []models.BusinessEvent{
+		{ID: 1, Type: "pr.created", AggregateID: "pr-1", Actor: "u1", Payload: json.RawMessage(`{"a":1}`), OccurredAt: time.Unix(0, 0).UTC()},
+		{ID: 2, Type: "pr.merged", AggregateID: "pr-1", Actor: "u1", Payload: json.RawMessage(`{}`), OccurredAt: time.Unix(0, 0).UTC()},
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Broker: "nats", URL: "nats://localhost:4222", Topic: "events"})
+	assert.NoError(t, err)
+	_, err = New(Config{Broker: "kafka", URL: "http://localhost:8082", Topic: "events"})
+	assert.NoError(t, err)
+
+	_, err = New(Config{Broker: "nats", URL: "http://localhost:4222", Topic: "events"})
+	assert.Error(t, err)
+	_, err = New(Config{Broker: "kafka", URL: "kafka://localhost:9092", Topic: "events"})
+	assert.Error(t, err)
+	_, err = New(Config{Broker: "webhook", URL: "https://hooks.example.com/pr", Topic: "events"})
+	assert.NoError(t, err)
+	_, err = New(Config{Broker: "webhook", URL: "hooks.example.com/pr", Topic: "events"})
+	assert.Error(t, err)
+	_, err = New(Config{Broker: "rabbitmq", URL: "amqp://localhost", Topic: "events"})
+	assert.Error(t, err)
+	_, err = New(Config{Broker: "nats", URL: "nats://localhost:4222"})
+	assert.Error(t, err)
+}
+
+func TestNATSPublisher(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	subjects := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "PUB "):
+				parts := strings.Fields(line)
+				subjects <- parts[1]
+				r.ReadString('\n') // payload
+			case strings.HasPrefix(line, "PING"):
+				conn.Write([]byte("PONG\r\n"))
+			}
+		}
+	}()
+
+	p, err := New(Config{Broker: BrokerNATS, URL: "nats://" + ln.Addr().String(), Topic: "pr_service.events"})
+	assert.NoError(t, err)
+	defer p.Close()
+
+	assert.NoError(t, p.Publish(context.Background(), testEvents()))
+	assert.Equal(t, "pr_service.events.pr.created", <-subjects)
+	assert.Equal(t, "pr_service.events.pr.merged", <-subjects)
+}
+
+func TestKafkaRESTPublisher(t *testing.T) {
+	var gotPath, gotType string
+	var gotBody struct {
+		Records []struct {
+			Key   string               `json:"key"`
+			Value models.BusinessEvent `json:"value"`
+		} `json:"records"`
+	}
+	reject := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		if reject {
+			w.Write([]byte(`{"offsets":[{"partition":0,"offset":1},{"error_code":50002,"error":"boom"}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":1},{"partition":0,"offset":2}]}`))
+	}))
+	defer srv.Close()
+
+	p, err := New(Config{Broker: BrokerKafka, URL: srv.URL + "/", Topic: "pr_service.events"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.Publish(context.Background(), testEvents()))
+	assert.Equal(t, "/topics/pr_service.events", gotPath)
+	assert.Equal(t, "application/vnd.kafka.json.v2+json", gotType)
+	if assert.Len(t, gotBody.Records, 2) {
+		assert.Equal(t, "pr-1", gotBody.Records[0].Key)
+		assert.Equal(t, "pr.created", gotBody.Records[0].Value.Type)
+	}
+
+	reject = true
+	assert.Error(t, p.Publish(context.Background(), testEvents()))
+}
+
+type fakeStore struct {
+	pending   []models.BusinessEvent
+	published []int64
+}
+
+func (s *fakeStore) PendingEvents(ctx context.Context, limit int) ([]models.BusinessEvent, error) {
+	if limit > len(s.pending) {
+		limit = len(s.pending)
+	}
+	return s.pending[:limit], nil
+}
+
+func (s *fakeStore) MarkEventsPublished(ctx context.Context, ids []int64) error {
+	s.published = append(s.published, ids...)
+	s.pending = s.pending[len(ids):]
+	return nil
+}
+
+type fakePublisher struct {
+	batches int
+	fail    bool
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, events []models.BusinessEvent) error {
+	if p.fail {
+		return io.ErrClosedPipe
+	}
+	p.batches++
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func TestRelay(t *testing.T) {
+	store := &fakeStore{}
+	for i := 1; i <= 5; i++ {
+		store.pending = append(store.pending, models.BusinessEvent{ID: int64(i)})
+	}
+	pub := &fakePublisher{}
+
+	n, err := NewRelay(store, pub, 2).Run(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 3, pub.batches)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, store.published)
+
+	// При ошибке брокера события остаются в outbox
+	store.pending = []models.BusinessEvent{{ID: 6}}
+	pub.fail = true
+	n, err = NewRelay(store, pub, 2).Run(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+	assert.Len(t, store.pending, 1)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	sent := time.Unix(1700000000, 0)
+	header := http.Header{}
+	header.Set(HeaderWebhookTimestamp, "1700000000")
+	header.Set(HeaderWebhookSignature, signatureHeader([]string{"new", "old"}, sent.Unix(), body))
+
+	// Во время ротации подходит любой из действующих секретов
+	assert.NoError(t, Verify(header, body, []string{"old"}, sent, DefaultSignatureTolerance))
+	assert.NoError(t, Verify(header, body, []string{"other", "new"}, sent, DefaultSignatureTolerance))
+	assert.Error(t, Verify(header, body, []string{"other"}, sent, DefaultSignatureTolerance))
+
+	// Часы потребителя могут отставать или спешить в пределах окна
+	assert.NoError(t, Verify(header, body, []string{"new"}, sent.Add(4*time.Minute), DefaultSignatureTolerance))
+	assert.NoError(t, Verify(header, body, []string{"new"}, sent.Add(-4*time.Minute), DefaultSignatureTolerance))
+	assert.Error(t, Verify(header, body, []string{"new"}, sent.Add(6*time.Minute), DefaultSignatureTolerance))
+	assert.Error(t, Verify(header, body, []string{"new"}, sent.Add(-6*time.Minute), DefaultSignatureTolerance))
+
+	// Метка времени и тело входят в подпись
+	assert.Error(t, Verify(header, []byte(`{"id":2}`), []string{"new"}, sent, DefaultSignatureTolerance))
+	header.Set(HeaderWebhookTimestamp, "1700000060")
+	assert.Error(t, Verify(header, body, []string{"new"}, sent, DefaultSignatureTolerance))
+	header.Del(HeaderWebhookTimestamp)
+	assert.Error(t, Verify(header, body, []string{"new"}, sent, DefaultSignatureTolerance))
+}
+
+type staticKeys []models.WebhookSigningKey
+
+func (k staticKeys) ActiveWebhookSigningKeys(ctx context.Context) ([]models.WebhookSigningKey, error) {
+	return k, nil
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	sent := time.Unix(1700000000, 0)
+	var deliveries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(r.Header, body, []string{"secret-2"}, sent, DefaultSignatureTolerance); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		var e models.BusinessEvent
+		assert.NoError(t, json.Unmarshal(body, &e))
+		assert.Equal(t, e.Type, r.Header.Get(HeaderWebhookEvent))
+		deliveries = append(deliveries, r.Header.Get(HeaderWebhookDelivery))
+	}))
+	defer srv.Close()
+
+	p, err := New(Config{Broker: BrokerWebhook, URL: srv.URL, Topic: "pr_service.events"})
+	assert.NoError(t, err)
+	defer p.Close()
+	wp := p.(*webhookPublisher)
+	wp.now = func() time.Time { return sent }
+
+	// Без ключей события не отправляются
+	assert.Error(t, p.Publish(context.Background(), testEvents()))
+	wp.keys = staticKeys{}
+	assert.Error(t, p.Publish(context.Background(), testEvents()))
+	assert.Empty(t, deliveries)
+
+	wp.keys = staticKeys{{KeyID: "whk_2", Secret: "secret-2"}, {KeyID: "whk_1", Secret: "secret-1"}}
+	assert.NoError(t, p.Publish(context.Background(), testEvents()))
+	assert.Equal(t, []string{"1", "2"}, deliveries)
+
+	// Потребитель с отозванным ключом отклоняет запрос
+	wp.keys = staticKeys{{KeyID: "whk_1", Secret: "secret-1"}}
+	assert.Error(t, p.Publish(context.Background(), testEvents()))
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Kafka topic metadata", func(t *testing.T) {
+		var gotMethod, gotPath string
+		status := http.StatusOK
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotMethod, gotPath = r.Method, r.URL.Path
+			w.WriteHeader(status)
+		}))
+		defer srv.Close()
+
+		cfg := Config{Broker: BrokerKafka, URL: srv.URL, Topic: "pr_service.events"}
+		assert.NoError(t, Check(ctx, cfg))
+		assert.Equal(t, http.MethodGet, gotMethod)
+		assert.Equal(t, "/topics/pr_service.events", gotPath)
+
+		status = http.StatusUnauthorized
+		assert.Error(t, Check(ctx, cfg))
+	})
+
+	t.Run("Webhook requires signing key and reachable receiver", func(t *testing.T) {
+		var posts int
+		status := http.StatusMethodNotAllowed
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodHead {
+				posts++
+			}
+			w.WriteHeader(status)
+		}))
+		defer srv.Close()
+
+		cfg := Config{Broker: BrokerWebhook, URL: srv.URL, Topic: "events", Keys: staticKeys{{KeyID: "k1", Secret: "s1"}}}
+		assert.NoError(t, Check(ctx, cfg))
+		assert.Zero(t, posts, "check must not deliver events")
+
+		status = http.StatusNotImplemented
+		assert.Error(t, Check(ctx, cfg))
+
+		cfg.Keys = staticKeys{}
+		assert.Error(t, Check(ctx, cfg))
+	})
+
+	t.Run("Unreachable NATS", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Skipf("cannot listen: %v", err)
+		}
+		addr := ln.Addr().String()
+		ln.Close()
+
+		assert.Error(t, Check(ctx, Config{Broker: BrokerNATS, URL: "nats://" + addr, Topic: "events", Timeout: time.Second}))
+	})
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"PR_service/internal/httpclient"
+	"PR_service/internal/models"
+)
+
+// kafkaRESTPublisher публикует события в Kafka через REST Proxy (API v2).
+// Ключ записи - aggregate_id, поэтому события одного PR или пользователя
+// попадают в одну партицию и читаются по порядку.
+type kafkaRESTPublisher struct {
+	endpoint string
+	client   *httpclient.Client
+}
+
+type kafkaRecord struct {
+	Key   string               `json:"key"`
+	Value models.BusinessEvent `json:"value"`
+}
+
+func newKafkaRESTPublisher(cfg Config) (*kafkaRESTPublisher, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("events URL must be an http(s) Kafka REST Proxy URL for Kafka")
+	}
+
+	// Повтор пачки безопасен: доставка и так "как минимум один раз"
+	httpCfg := cfg.HTTP
+	httpCfg.Name = BrokerKafka
+	httpCfg.Timeout = cfg.Timeout
+	httpCfg.RetryUnsafe = true
+	client, err := httpclient.New(httpCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafkaRESTPublisher{
+		endpoint: strings.TrimSuffix(cfg.URL, "/") + "/topics/" + url.PathEscape(cfg.Topic),
+		client:   client,
+	}, nil
+}
+
+func (p *kafkaRESTPublisher) Publish(ctx context.Context, events []models.BusinessEvent) error {
+	records := make([]kafkaRecord, len(events))
+	for i, e := range events {
+		records[i] = kafkaRecord{Key: e.AggregateID, Value: e}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka rest proxy: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	// Прокси отвечает 200 и при частичном отказе, ошибки приходят по записям
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("kafka rest proxy: decode response: %v", err)
+	}
+	for _, o := range result.Offsets {
+		if o.ErrorCode != nil || o.Error != "" {
+			return fmt.Errorf("kafka rest proxy: record rejected: %s", o.Error)
+		}
+	}
+	return nil
+}
+
+// Check запрашивает у REST Proxy метаданные топика: так проверяются адрес,
+// учетные данные прокси и существование топика
+func (p *kafkaRESTPublisher) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka rest proxy: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (p *kafkaRESTPublisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
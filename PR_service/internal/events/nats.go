@@ -0,0 +1,157 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+// natsPublisher публикует события по текстовому протоколу NATS.
+// Событие уходит в subject "<topic>.<type>", например "pr_service.events.pr.created".
+type natsPublisher struct {
+	addr    string
+	topic   string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newNATSPublisher(cfg Config) (*natsPublisher, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Scheme != "nats" || u.Host == "" {
+		return nil, fmt.Errorf("events URL must be nats://host:port for NATS")
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &natsPublisher{addr: addr, topic: cfg.Topic, timeout: cfg.Timeout}, nil
+}
+
+// Publish отправляет события и ждет PONG, подтверждающий, что сервер их обработал
+func (p *natsPublisher) Publish(ctx context.Context, events []models.BusinessEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.publish(ctx, events); err != nil {
+		// Соединение в неизвестном состоянии, переподключимся при следующей отправке
+		p.closeConn()
+		return err
+	}
+	return nil
+}
+
+func (p *natsPublisher) publish(ctx context.Context, events []models.BusinessEvent) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(p.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := p.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(p.conn)
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "PUB %s %d\r\n", p.topic+"."+e.Type, len(data))
+		w.Write(data)
+		w.WriteString("\r\n")
+	}
+	w.WriteString("PING\r\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return p.awaitPong()
+}
+
+// Check подключается к серверу и дожидается PONG на пустую пачку
+func (p *natsPublisher) Check(ctx context.Context) error {
+	return p.Publish(ctx, nil)
+}
+
+func (p *natsPublisher) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(p.timeout))
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("nats: unexpected greeting %q", strings.TrimSpace(line))
+	}
+
+	if _, err := conn.Write([]byte(`CONNECT {"verbose":false,"pedantic":false,"name":"pr_service"}` + "\r\n")); err != nil {
+		conn.Close()
+		return err
+	}
+
+	p.conn = conn
+	p.reader = reader
+	return nil
+}
+
+// awaitPong читает ответы сервера до PONG; -ERR означает отказ в публикации
+func (p *natsPublisher) awaitPong() error {
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", line)
+		}
+		// +OK и INFO пропускаем
+	}
+}
+
+func (p *natsPublisher) closeConn() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+		p.reader = nil
+	}
+}
+
+func (p *natsPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeConn()
+	return nil
+}
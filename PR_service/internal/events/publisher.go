@@ -0,0 +1,135 @@
+// Package events публикует бизнес-события из event_outbox во внешние
+// брокеры (NATS или Kafka) или подписанными вебхуками, чтобы аналитика
+// и интеграции могли читать поток событий.
+// Доставка "как минимум один раз": потребители дедуплицируют события по id.
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"PR_service/internal/httpclient"
+	"PR_service/internal/models"
+)
+
+// Поддерживаемые брокеры
+const (
+	BrokerNATS    = "nats"
+	BrokerKafka   = "kafka"
+	BrokerWebhook = "webhook"
+)
+
+// Publisher отправляет пачку событий в брокер.
+// Ошибка означает, что ни одно событие пачки нельзя считать доставленным.
+type Publisher interface {
+	Publish(ctx context.Context, events []models.BusinessEvent) error
+	Close() error
+}
+
+// Config - настройки публикатора
+type Config struct {
+	Broker  string        // nats|kafka|webhook
+	URL     string        // nats://host:4222, http://kafka-rest:8082 или URL приемника вебхуков
+	Topic   string        // Префикс subject в NATS или топик Kafka
+	Timeout time.Duration // Таймаут отправки пачки
+
+	HTTP httpclient.Config // Повторы, автомат отключения и прокси HTTP-брокеров (Kafka REST Proxy, вебхуки)
+	Keys KeySource         // Ключи подписи вебхуков
+}
+
+// New создает публикатор для настроенного брокера
+func New(cfg Config) (Publisher, error) {
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("events topic is required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	switch strings.ToLower(cfg.Broker) {
+	case BrokerNATS:
+		return newNATSPublisher(cfg)
+	case BrokerKafka:
+		return newKafkaRESTPublisher(cfg)
+	case BrokerWebhook:
+		return newWebhookPublisher(cfg)
+	default:
+		return nil, fmt.Errorf("unknown events broker %q", cfg.Broker)
+	}
+}
+
+// checker - публикатор, умеющий проверить подключение к брокеру без отправки событий
+type checker interface {
+	Check(ctx context.Context) error
+}
+
+// Check подключается к настроенному брокеру и проверяет доступ, не публикуя событий.
+// Используется самопроверкой --check: ошибка означает, что события (и уведомления
+// интеграций, которые их читают) не будут доставлены.
+func Check(ctx context.Context, cfg Config) error {
+	p, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	c, ok := p.(checker)
+	if !ok {
+		return fmt.Errorf("events broker %q does not support checks", cfg.Broker)
+	}
+	return c.Check(ctx)
+}
+
+// Store - часть хранилища, нужная для переноса событий из outbox
+type Store interface {
+	PendingEvents(ctx context.Context, limit int) ([]models.BusinessEvent, error)
+	MarkEventsPublished(ctx context.Context, ids []int64) error
+}
+
+// Relay переносит неопубликованные события из outbox в брокер
+type Relay struct {
+	store     Store
+	publisher Publisher
+	batchSize int
+}
+
+func NewRelay(store Store, publisher Publisher, batchSize int) *Relay {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &Relay{store: store, publisher: publisher, batchSize: batchSize}
+}
+
+// Run публикует события пачками, пока outbox не опустеет.
+// Возвращает число опубликованных событий.
+func (r *Relay) Run(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		pending, err := r.store.PendingEvents(ctx, r.batchSize)
+		if err != nil {
+			return published, err
+		}
+		if len(pending) == 0 {
+			return published, nil
+		}
+
+		if err := r.publisher.Publish(ctx, pending); err != nil {
+			return published, err
+		}
+
+		ids := make([]int64, len(pending))
+		for i, e := range pending {
+			ids[i] = e.ID
+		}
+		if err := r.store.MarkEventsPublished(ctx, ids); err != nil {
+			return published, err
+		}
+		published += len(pending)
+
+		if len(pending) < r.batchSize {
+			return published, nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки исходящих вебхуков. Имена входят в контракт с потребителями и не меняются.
+const (
+	HeaderWebhookEvent     = "X-PR-Service-Event"         // Тип события, например pr.merged
+	HeaderWebhookDelivery  = "X-PR-Service-Delivery"      // id события; повторная доставка приходит с тем же id
+	HeaderWebhookTimestamp = "X-PR-Service-Timestamp"     // Время отправки, unix-секунды
+	HeaderWebhookSignature = "X-PR-Service-Signature-256" // sha256=<hex> по каждому действующему ключу через запятую
+)
+
+// signaturePrefix - префикс подписи, как в X-Hub-Signature-256 GitHub
+const signaturePrefix = "sha256="
+
+// DefaultSignatureTolerance - допустимое расхождение часов отправителя и потребителя.
+// Это же окно защиты от повтора: запрос старше окна отклоняется, а внутри окна
+// потребитель отбрасывает повторы по HeaderWebhookDelivery.
+const DefaultSignatureTolerance = 5 * time.Minute
+
+// Sign подписывает тело запроса: HMAC-SHA256 секрета над "<timestamp>.<body>".
+// Метка времени входит в подпись, поэтому перехваченный запрос нельзя отправить
+// позже окна с новой меткой.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// signatureHeader подписывает тело каждым секретом. Во время ротации потребитель
+// может проверять и старым, и новым секретом.
+func signatureHeader(secrets []string, timestamp int64, body []byte) string {
+	signatures := make([]string, len(secrets))
+	for i, secret := range secrets {
+		signatures[i] = Sign(secret, timestamp, body)
+	}
+	return strings.Join(signatures, ",")
+}
+
+// Verify проверяет подпись вебхука на стороне потребителя: метка времени должна
+// отличаться от now не больше чем на tolerance в любую сторону, а одна из подписей
+// заголовка - совпасть с подписью одним из secrets.
+func Verify(header http.Header, body []byte, secrets []string, now time.Time, tolerance time.Duration) error {
+	rawTimestamp := header.Get(HeaderWebhookTimestamp)
+	if rawTimestamp == "" {
+		return fmt.Errorf("missing %s header", HeaderWebhookTimestamp)
+	}
+	timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s header", HeaderWebhookTimestamp)
+	}
+	skew := now.Sub(time.Unix(timestamp, 0))
+	if skew > tolerance || skew < -tolerance {
+		return fmt.Errorf("timestamp is outside the %s tolerance", tolerance)
+	}
+
+	signatures := strings.Split(header.Get(HeaderWebhookSignature), ",")
+	for _, secret := range secrets {
+		expected := []byte(Sign(secret, timestamp, body))
+		for _, signature := range signatures {
+			if hmac.Equal([]byte(strings.TrimSpace(signature)), expected) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("signature mismatch")
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"PR_service/internal/httpclient"
+	"PR_service/internal/models"
+)
+
+// KeySource - действующие ключи подписи вебхуков (см. /admin/webhookKeys)
+type KeySource interface {
+	ActiveWebhookSigningKeys(ctx context.Context) ([]models.WebhookSigningKey, error)
+}
+
+// webhookPublisher отправляет каждое событие отдельным POST с подписью (см. Sign).
+// Ключи читаются перед каждой пачкой, поэтому ротация не требует перезапуска.
+type webhookPublisher struct {
+	endpoint string
+	keys     KeySource
+	client   *httpclient.Client
+	now      func() time.Time
+}
+
+func newWebhookPublisher(cfg Config) (*webhookPublisher, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("events URL must be an http(s) URL for webhooks")
+	}
+
+	// Повтор безопасен: потребитель отбрасывает повторы по HeaderWebhookDelivery
+	httpCfg := cfg.HTTP
+	httpCfg.Name = BrokerWebhook
+	httpCfg.Timeout = cfg.Timeout
+	httpCfg.RetryUnsafe = true
+	client, err := httpclient.New(httpCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhookPublisher{endpoint: cfg.URL, keys: cfg.Keys, client: client, now: time.Now}, nil
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, events []models.BusinessEvent) error {
+	if p.keys == nil {
+		return fmt.Errorf("webhook signing keys are not configured")
+	}
+	keys, err := p.keys.ActiveWebhookSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	// Неподписанные события не отправляются: они дождутся ключа в outbox
+	if len(keys) == 0 {
+		return fmt.Errorf("no active webhook signing keys, rotate one via /admin/webhookKeys/rotate")
+	}
+	secrets := make([]string, len(keys))
+	for i, key := range keys {
+		secrets[i] = key.Secret
+	}
+
+	for _, e := range events {
+		if err := p.send(ctx, e, secrets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *webhookPublisher) send(ctx context.Context, e models.BusinessEvent, secrets []string) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := p.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookEvent, e.Type)
+	req.Header.Set(HeaderWebhookDelivery, strconv.FormatInt(e.ID, 10))
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderWebhookSignature, signatureHeader(secrets, timestamp, body))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook %s: %s: %s", e.Type, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Check проверяет, что есть действующий ключ подписи и приемник доступен. Событие
+// при этом не отправляется: приемник получает HEAD без тела, и отказ 4xx (например,
+// 405 на HEAD) допустим, а сетевая ошибка или 5xx - нет.
+func (p *webhookPublisher) Check(ctx context.Context) error {
+	if p.keys == nil {
+		return fmt.Errorf("webhook signing keys are not configured")
+	}
+	keys, err := p.keys.ActiveWebhookSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no active webhook signing keys, rotate one via /admin/webhookKeys/rotate")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, p.endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 5 {
+		return fmt.Errorf("webhook receiver: %s", resp.Status)
+	}
+	return nil
+}
+
+func (p *webhookPublisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,214 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+)
+
+// node - узел разобранного выражения. Типы операндов проверены при разборе,
+// поэтому приведения в eval не паникуют, если значения переменных соответствуют Env.
+type node interface {
+	typ() Type
+	eval(vars Vars) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+	t     Type
+}
+
+func (n *literalNode) typ() Type { return n.t }
+
+func (n *literalNode) eval(Vars) (interface{}, error) { return n.value, nil }
+
+type varNode struct {
+	name string
+	t    Type
+}
+
+func (n *varNode) typ() Type { return n.t }
+
+func (n *varNode) eval(vars Vars) (interface{}, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("variable %q is not set", n.name)
+	}
+	var valid bool
+	switch n.t {
+	case Number:
+		_, valid = v.(float64)
+	case Bool:
+		_, valid = v.(bool)
+	case String:
+		_, valid = v.(string)
+	case List:
+		_, valid = v.([]string)
+	}
+	if !valid {
+		return nil, fmt.Errorf("variable %q must be %s, got %T", n.name, n.t, v)
+	}
+	return v, nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) typ() Type { return n.operand.typ() }
+
+func (n *unaryNode) eval(vars Vars) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !v.(bool), nil
+	}
+	return -v.(float64), nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) typ() Type { return Bool }
+
+func (n *logicalNode) eval(vars Vars) (interface{}, error) {
+	l, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" && !l.(bool) {
+		return false, nil
+	}
+	if n.op == "||" && l.(bool) {
+		return true, nil
+	}
+	return n.right.eval(vars)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) typ() Type { return Bool }
+
+func (n *compareNode) eval(vars Vars) (interface{}, error) {
+	l, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "in":
+		for _, item := range r.([]string) {
+			if item == l.(string) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "==":
+		return l == r, nil
+	case "!=":
+		return l != r, nil
+	}
+
+	a, b := l.(float64), r.(float64)
+	switch n.op {
+	case "<":
+		return a < b, nil
+	case "<=":
+		return a <= b, nil
+	case ">":
+		return a > b, nil
+	default:
+		return a >= b, nil
+	}
+}
+
+type arithNode struct {
+	op          string
+	left, right node
+}
+
+func (n *arithNode) typ() Type { return Number }
+
+func (n *arithNode) eval(vars Vars) (interface{}, error) {
+	l, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	a, b := l.(float64), r.(float64)
+	var res float64
+	switch n.op {
+	case "+":
+		res = a + b
+	case "-":
+		res = a - b
+	case "*":
+		res = a * b
+	default:
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		res = a / b
+	}
+	// Бесконечность и NaN не сериализуются в JSON и ломают сравнение весов
+	if math.IsInf(res, 0) || math.IsNaN(res) {
+		return nil, fmt.Errorf("numeric overflow")
+	}
+	return res, nil
+}
+
+type condNode struct {
+	cond, then, otherwise node
+}
+
+func (n *condNode) typ() Type { return n.then.typ() }
+
+func (n *condNode) eval(vars Vars) (interface{}, error) {
+	c, err := n.cond.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if c.(bool) {
+		return n.then.eval(vars)
+	}
+	return n.otherwise.eval(vars)
+}
+
+type callNode struct {
+	fn   string
+	args []node
+}
+
+func (n *callNode) typ() Type { return Number }
+
+func (n *callNode) eval(vars Vars) (interface{}, error) {
+	values := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	switch n.fn {
+	case "size":
+		return float64(len(values[0].([]string))), nil
+	case "min":
+		return math.Min(values[0].(float64), values[1].(float64)), nil
+	default:
+		return math.Max(values[0].(float64), values[1].(float64)), nil
+	}
+}
@@ -0,0 +1,125 @@
+// Package expr - небольшой язык выражений для пользовательских фильтров и ранжирования
+// кандидатов в ревьюеры. Выражение компилируется с проверкой типов при сохранении настроек
+// и вычисляется над фиксированным набором переменных: в языке нет циклов, присваиваний
+// и доступа к чему-либо кроме переданных значений, а размер выражения ограничен, поэтому
+// вычисление всегда завершается за время, линейное по длине выражения.
+//
+// Типы: number (float64), bool, string и list (список строк).
+// Операторы по убыванию приоритета:
+//
+//	! -x            логическое отрицание, унарный минус
+//	* /             числа
+//	+ -             числа
+//	< <= > >= == != in
+//	&&
+//	||
+//	c ? a : b       условное выражение
+//
+// Функции: size(list), min(a, b), max(a, b). Литералы: 1.5, "str", 'str', true, false, ["a", "b"].
+package expr
+
+import (
+	"fmt"
+)
+
+// Type - статический тип выражения
+type Type int
+
+// Типы значений
+const (
+	Number Type = iota + 1
+	Bool
+	String
+	List
+)
+
+func (t Type) String() string {
+	switch t {
+	case Number:
+		return "number"
+	case Bool:
+		return "bool"
+	case String:
+		return "string"
+	case List:
+		return "list"
+	default:
+		return "unknown"
+	}
+}
+
+// Ограничения выражений
+const (
+	// MaxLength - предел длины исходного текста выражения
+	MaxLength = 512
+	// MaxNodes - предел числа узлов разобранного выражения
+	MaxNodes = 128
+	// MaxDepth - предел вложенности выражения
+	MaxDepth = 32
+)
+
+// Env описывает переменные, доступные выражению, и их типы
+type Env map[string]Type
+
+// Vars - значения переменных: float64, bool, string или []string по типу из Env
+type Vars map[string]interface{}
+
+// Program - скомпилированное выражение
+type Program struct {
+	root node
+	typ  Type
+}
+
+// Compile разбирает выражение src и проверяет типы относительно env
+func Compile(src string, env Env) (*Program, error) {
+	if len(src) > MaxLength {
+		return nil, fmt.Errorf("expression must be at most %d characters", MaxLength)
+	}
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, env: env}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+	return &Program{root: root, typ: root.typ()}, nil
+}
+
+// Type возвращает тип результата выражения
+func (p *Program) Type() Type {
+	return p.typ
+}
+
+// Eval вычисляет выражение. Значения всех переменных из Env должны быть в vars.
+func (p *Program) Eval(vars Vars) (interface{}, error) {
+	return p.root.eval(vars)
+}
+
+// EvalBool вычисляет выражение типа bool
+func (p *Program) EvalBool(vars Vars) (bool, error) {
+	if p.typ != Bool {
+		return false, fmt.Errorf("expression is %s, not bool", p.typ)
+	}
+	v, err := p.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// EvalNumber вычисляет выражение типа number
+func (p *Program) EvalNumber(vars Vars) (float64, error) {
+	if p.typ != Number {
+		return 0, fmt.Errorf("expression is %s, not number", p.typ)
+	}
+	v, err := p.root.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	return v.(float64), nil
+}
@@ -0,0 +1,134 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEnv = Env{
+	"load":        Number,
+	"tenure_days": Number,
+	"in_focus":    Bool,
+	"tags":        List,
+	"team":        String,
+}
+
+var testVars = Vars{
+	"load":        3.0,
+	"tenure_days": 120.0,
+	"in_focus":    false,
+	"tags":        []string{"backend", "db"},
+	"team":        "payments",
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{"load < 5", true},
+		{"load >= 5 || tenure_days > 90", true},
+		{"!in_focus && load <= 3", true},
+		{`"db" in tags`, true},
+		{`"frontend" in tags`, false},
+		{`team == "payments" && team != 'core'`, true},
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"-load + 10", 7.0},
+		{"10 / (1 + load)", 2.5},
+		{"size(tags)", 2.0},
+		{"min(load, 2) + max(load, 2)", 5.0},
+		{`"x" in []`, false},
+		{`"a" in ["a", "b"]`, true},
+		{"tenure_days < 30 ? 0.5 : 1", 1.0},
+		{"load > 10 ? 0 : tenure_days > 90 ? 2 : 1", 2.0},
+		{"false && 1 / 0 > 1", false}, // Правый операнд не вычисляется
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			p, err := Compile(tt.src, testEnv)
+			if !assert.NoError(t, err) {
+				return
+			}
+			got, err := p.Eval(testVars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{"", "unexpected end of expression"},
+		{"load <", "unexpected end of expression"},
+		{"load < 5 extra", `unexpected "extra"`},
+		{"seniority > 3", `unknown variable "seniority"`},
+		{"exec(load)", `unknown function "exec"`},
+		{"load && in_focus", "needs bool operands"},
+		{"!load", "needs bool"},
+		{"-in_focus", "needs number"},
+		{`load == "3"`, "cannot compare number and string"},
+		{"tags == tags", "cannot compare list and list"},
+		{"load in tags", "needs string and list"},
+		{"1 < 2 < 3", `unexpected "<"`},
+		{"in_focus ? 1 : false", "different types"},
+		{"load ? 1 : 2", "must be bool"},
+		{"size(load)", "takes one list argument"},
+		{"min(1)", "takes two number arguments"},
+		{`[1, 2]`, "may contain only strings"},
+		{`"open`, "unterminated string"},
+		{"load # 1", "unexpected character"},
+		{"1.2.3", "invalid number"},
+		{strings.Repeat("9", 310), "invalid number"},
+		{"load * (1" + strings.Repeat("0", 200) + " * 1" + strings.Repeat("0", 200) + ")", "numeric overflow"},
+		{"(load", `expected ")"`},
+		{strings.Repeat("a", MaxLength+1), "at most"},
+		{strings.Repeat("1 + ", 70) + "1", "too complex"},
+		{strings.Repeat("(", 40) + "1" + strings.Repeat(")", 40), "nested deeper"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src, testEnv)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvalTyped(t *testing.T) {
+	filter, err := Compile("load < 5", testEnv)
+	assert.NoError(t, err)
+	assert.Equal(t, Bool, filter.Type())
+	ok, err := filter.EvalBool(testVars)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	_, err = filter.EvalNumber(testVars)
+	assert.Error(t, err)
+
+	rank, err := Compile("10 / load", testEnv)
+	assert.NoError(t, err)
+	assert.Equal(t, Number, rank.Type())
+	_, err = rank.EvalNumber(Vars{"load": 0.0})
+	assert.EqualError(t, err, "division by zero")
+
+	// Переполнение при вычислении - ошибка, а не бесконечность в результате
+	huge := "1" + strings.Repeat("0", 200)
+	rank, err = Compile("load * "+huge+" * "+huge, testEnv)
+	assert.NoError(t, err)
+	_, err = rank.EvalNumber(testVars)
+	assert.EqualError(t, err, "numeric overflow")
+
+	// Значение неверного типа не приводит к панике
+	_, err = filter.EvalBool(Vars{"load": 3})
+	assert.EqualError(t, err, `variable "load" must be number, got int`)
+	_, err = filter.EvalBool(Vars{})
+	assert.EqualError(t, err, `variable "load" is not set`)
+}
@@ -0,0 +1,116 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp // Операторы и знаки препинания
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// Операторы из двух символов проверяются раньше односимвольных
+var twoCharOps = []string{"&&", "||", "==", "!=", "<=", ">="}
+
+const oneCharOps = "!<>+-*/()[],?:"
+
+// tokenize разбивает выражение на токены
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", src[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: num, pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			closed := false
+			for i < len(src) {
+				if src[i] == '\\' && i+1 < len(src) {
+					sb.WriteByte(src[i+1])
+					i += 2
+					continue
+				}
+				if src[i] == c {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+		default:
+			op := ""
+			for _, candidate := range twoCharOps {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" && strings.IndexByte(oneCharOps, c) >= 0 {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
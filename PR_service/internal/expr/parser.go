@@ -0,0 +1,339 @@
+package expr
+
+import (
+	"fmt"
+)
+
+// parser - разбор рекурсивным спуском с проверкой типов каждого узла
+type parser struct {
+	tokens []token
+	pos    int
+	env    Env
+	nodes  int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// acceptOp пропускает оператор op, если он следующий
+func (p *parser) acceptOp(op string) bool {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q, got %s at position %d", op, tok, tok.pos)
+	}
+	return nil
+}
+
+// newNode учитывает узел в пределе MaxNodes
+func (p *parser) newNode(n node) (node, error) {
+	p.nodes++
+	if p.nodes > MaxNodes {
+		return nil, fmt.Errorf("expression is too complex: more than %d nodes", MaxNodes)
+	}
+	return n, nil
+}
+
+// parseExpr: cond ? a : b
+func (p *parser) parseExpr() (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > MaxDepth {
+		return nil, fmt.Errorf("expression is nested deeper than %d levels", MaxDepth)
+	}
+
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.acceptOp("?") {
+		return cond, nil
+	}
+	pos := p.peek().pos
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectOp(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if cond.typ() != Bool {
+		return nil, fmt.Errorf("condition before ? must be bool, got %s", cond.typ())
+	}
+	if then.typ() != otherwise.typ() {
+		return nil, fmt.Errorf("branches of ? at position %d have different types: %s and %s", pos, then.typ(), otherwise.typ())
+	}
+	return p.newNode(&condNode{cond: cond, then: then, otherwise: otherwise})
+}
+
+// parseOr и parseAnd - логические операторы с ленивым вычислением
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical("||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical("&&", p.parseComparison)
+}
+
+func (p *parser) parseLogical(op string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		pos := p.peek().pos
+		if !p.acceptOp(op) {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ() != Bool || right.typ() != Bool {
+			return nil, fmt.Errorf("operator %s at position %d needs bool operands, got %s and %s", op, pos, left.typ(), right.typ())
+		}
+		if left, err = p.newNode(&logicalNode{op: op, left: left, right: right}); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseComparison: сравнения не ассоциативны, a < b < c - синтаксическая ошибка
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	op := ""
+	switch {
+	case tok.kind == tokOp && (tok.text == "==" || tok.text == "!=" || tok.text == "<" ||
+		tok.text == "<=" || tok.text == ">" || tok.text == ">="):
+		op = tok.text
+	case tok.kind == tokIdent && tok.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "in":
+		if left.typ() != String || right.typ() != List {
+			return nil, fmt.Errorf("operator in at position %d needs string and list, got %s and %s", tok.pos, left.typ(), right.typ())
+		}
+	case "==", "!=":
+		if left.typ() != right.typ() || left.typ() == List {
+			return nil, fmt.Errorf("operator %s at position %d cannot compare %s and %s", op, tok.pos, left.typ(), right.typ())
+		}
+	default:
+		if left.typ() != Number || right.typ() != Number {
+			return nil, fmt.Errorf("operator %s at position %d needs numbers, got %s and %s", op, tok.pos, left.typ(), right.typ())
+		}
+	}
+	return p.newNode(&compareNode{op: op, left: left, right: right})
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	return p.parseArithmetic("+", "-", p.parseMultiplicative)
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	return p.parseArithmetic("*", "/", p.parseUnary)
+}
+
+func (p *parser) parseArithmetic(op1, op2 string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokOp || (tok.text != op1 && tok.text != op2) {
+			return left, nil
+		}
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ() != Number || right.typ() != Number {
+			return nil, fmt.Errorf("operator %s at position %d needs numbers, got %s and %s", tok.text, tok.pos, left.typ(), right.typ())
+		}
+		if left, err = p.newNode(&arithNode{op: tok.text, left: left, right: right}); err != nil {
+			return nil, err
+		}
+		if left, err = foldConstant(left); err != nil {
+			return nil, fmt.Errorf("operator %s at position %d: %w", tok.text, tok.pos, err)
+		}
+	}
+}
+
+// foldConstant вычисляет операцию над двумя литералами при разборе, чтобы переполнение
+// в константной части отклонялось при сохранении выражения. Деление на ноль остается
+// ошибкой вычисления: ветка с ним может не вычисляться (false && 1 / 0 > 1).
+func foldConstant(n node) (node, error) {
+	arith, ok := n.(*arithNode)
+	if !ok {
+		return n, nil
+	}
+	if _, ok := arith.left.(*literalNode); !ok {
+		return n, nil
+	}
+	right, ok := arith.right.(*literalNode)
+	if !ok || (arith.op == "/" && right.value.(float64) == 0) {
+		return n, nil
+	}
+	v, err := arith.eval(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &literalNode{value: v, t: Number}, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	tok := p.peek()
+	if tok.kind != tokOp || (tok.text != "!" && tok.text != "-") {
+		return p.parsePrimary()
+	}
+	p.next()
+
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > MaxDepth {
+		return nil, fmt.Errorf("expression is nested deeper than %d levels", MaxDepth)
+	}
+
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if tok.text == "!" && operand.typ() != Bool {
+		return nil, fmt.Errorf("operator ! at position %d needs bool, got %s", tok.pos, operand.typ())
+	}
+	if tok.text == "-" && operand.typ() != Number {
+		return nil, fmt.Errorf("operator - at position %d needs number, got %s", tok.pos, operand.typ())
+	}
+	return p.newNode(&unaryNode{op: tok.text, operand: operand})
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return p.newNode(&literalNode{value: tok.num, t: Number})
+	case tokString:
+		return p.newNode(&literalNode{value: tok.text, t: String})
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return p.newNode(&literalNode{value: tok.text == "true", t: Bool})
+		case "in":
+			return nil, fmt.Errorf("unexpected \"in\" at position %d", tok.pos)
+		}
+		if p.acceptOp("(") {
+			return p.parseCall(tok)
+		}
+		t, ok := p.env[tok.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %q at position %d", tok.text, tok.pos)
+		}
+		return p.newNode(&varNode{name: tok.text, t: t})
+	case tokOp:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			return p.parseList(tok)
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+}
+
+// parseList разбирает литерал списка строк ["a", "b"]
+func (p *parser) parseList(open token) (node, error) {
+	items := []string{}
+	if !p.acceptOp("]") {
+		for {
+			tok := p.next()
+			if tok.kind != tokString {
+				return nil, fmt.Errorf("list at position %d may contain only strings, got %s", open.pos, tok)
+			}
+			items = append(items, tok.text)
+			if p.acceptOp("]") {
+				break
+			}
+			if err := p.expectOp(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.nodes += len(items)
+	return p.newNode(&literalNode{value: items, t: List})
+}
+
+// parseCall разбирает вызов встроенной функции
+func (p *parser) parseCall(name token) (node, error) {
+	var args []node
+	if !p.acceptOp(")") {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.acceptOp(")") {
+				break
+			}
+			if err := p.expectOp(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch name.text {
+	case "size":
+		if len(args) != 1 || args[0].typ() != List {
+			return nil, fmt.Errorf("size at position %d takes one list argument", name.pos)
+		}
+	case "min", "max":
+		if len(args) != 2 || args[0].typ() != Number || args[1].typ() != Number {
+			return nil, fmt.Errorf("%s at position %d takes two number arguments", name.text, name.pos)
+		}
+	default:
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+	}
+	return p.newNode(&callNode{fn: name.text, args: args})
+}
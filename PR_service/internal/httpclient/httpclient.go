@@ -0,0 +1,317 @@
+// Package httpclient - общий HTTP-клиент исходящих интеграций: таймауты, повторы
+// с джиттером, автомат отключения (circuit breaker), метрики и прокси. Интеграции
+// (брокеры событий, вебхуки, VCS, мессенджеры) получают одинаковую устойчивость
+// вместо собственных реализаций.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen возвращается без запроса, пока автомат отключения открыт
+var ErrCircuitOpen = errors.New("httpclient: circuit open")
+
+// Observer принимает метрики исходящих запросов
+type Observer interface {
+	// ObserveOutboundRequest - одна попытка запроса; status - код ответа или "error"
+	ObserveOutboundRequest(client, method, status string, duration time.Duration)
+	// SetCircuitOpen сообщает об открытии и закрытии автомата
+	SetCircuitOpen(client string, open bool)
+}
+
+// Config - настройки клиента
+type Config struct {
+	Name        string        // Имя интеграции в метриках и ошибках
+	Timeout     time.Duration // Таймаут одной попытки
+	MaxRetries  int           // Повторы после первой попытки
+	BaseBackoff time.Duration // Начальная пауза между повторами, растет вдвое
+	MaxBackoff  time.Duration // Верхняя граница паузы, в том числе из Retry-After
+
+	// RetryUnsafe разрешает повторять POST/PATCH. Включать, только если получатель
+	// идемпотентен или дубли допустимы (доставка "как минимум один раз").
+	RetryUnsafe bool
+
+	BreakerThreshold int           // Подряд неудачных попыток до открытия автомата; 0 - без автомата
+	BreakerCooldown  time.Duration // Сколько автомат открыт до пробного запроса
+
+	ProxyURL string   // Пусто - HTTP_PROXY/HTTPS_PROXY/NO_PROXY из окружения
+	Observer Observer // Может быть nil
+}
+
+// Значения по умолчанию
+const (
+	DefaultTimeout         = 10 * time.Second
+	DefaultBaseBackoff     = 200 * time.Millisecond
+	DefaultMaxBackoff      = 5 * time.Second
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// Client выполняет запросы с повторами и автоматом отключения. Безопасен для конкурентного использования.
+type Client struct {
+	cfg     Config
+	http    *http.Client
+	breaker *breaker
+	sleep   func(ctx context.Context, d time.Duration) error
+	jitter  func(n int64) int64
+}
+
+// New создает клиент; незаданные параметры получают значения по умолчанию
+func New(cfg Config) (*Client, error) {
+	if cfg.Name == "" {
+		cfg.Name = "default"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxRetries < 0 {
+		return nil, fmt.Errorf("httpclient %s: max retries must not be negative", cfg.Name)
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = DefaultBaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = DefaultBreakerCooldown
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		u, err := url.Parse(cfg.ProxyURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			return nil, fmt.Errorf("httpclient %s: proxy must be an http(s) or socks5 URL", cfg.Name)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+
+	c := &Client{
+		cfg:    cfg,
+		http:   &http.Client{Transport: transport},
+		sleep:  sleepContext,
+		jitter: rand.Int63n,
+	}
+	if cfg.BreakerThreshold > 0 {
+		c.breaker = &breaker{threshold: cfg.BreakerThreshold, cooldown: cfg.BreakerCooldown, now: time.Now}
+	}
+	return c, nil
+}
+
+// Do выполняет запрос. Сетевые ошибки, 429 и 5xx (кроме 501) повторяются, если
+// запрос можно повторить: метод безопасен (или RetryUnsafe) и тело можно перечитать.
+// Последний ответ возвращается как есть, даже если он неуспешный.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	retryable := c.canRetry(req)
+
+	for attempt := 0; ; attempt++ {
+		if c.breaker != nil && !c.breaker.allow() {
+			return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, c.cfg.Name)
+		}
+
+		attemptReq, err := c.prepare(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.attempt(attemptReq)
+		failed := err != nil || retryableStatus(resp.StatusCode)
+		c.recordOutcome(!failed)
+
+		if !failed || !retryable || attempt >= c.cfg.MaxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait := c.backoff(attempt, resp)
+		if resp != nil {
+			// Тело дочитывается, чтобы соединение вернулось в пул
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		if err := c.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// CloseIdleConnections закрывает простаивающие соединения
+func (c *Client) CloseIdleConnections() {
+	c.http.CloseIdleConnections()
+}
+
+func (c *Client) canRetry(req *http.Request) bool {
+	if c.cfg.MaxRetries == 0 {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return c.cfg.RetryUnsafe
+	}
+}
+
+// prepare возвращает запрос попытки со свежим телом
+func (c *Client) prepare(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+// attempt выполняет одну попытку со своим таймаутом. Таймаут действует до конца
+// чтения тела, поэтому отменяется при закрытии тела ответа.
+func (c *Client) attempt(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), c.cfg.Timeout)
+	start := time.Now()
+	resp, err := c.http.Do(req.WithContext(ctx))
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	if c.cfg.Observer != nil {
+		c.cfg.Observer.ObserveOutboundRequest(c.cfg.Name, req.Method, status, time.Since(start))
+	}
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (c *Client) recordOutcome(success bool) {
+	if c.breaker == nil {
+		return
+	}
+	if changed, open := c.breaker.record(success); changed && c.cfg.Observer != nil {
+		c.cfg.Observer.SetCircuitOpen(c.cfg.Name, open)
+	}
+}
+
+// backoff возвращает паузу перед следующей попыткой: Retry-After, если сервер его прислал,
+// иначе случайную величину до BaseBackoff*2^attempt ("full jitter")
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			if d > c.cfg.MaxBackoff {
+				d = c.cfg.MaxBackoff
+			}
+			return d
+		}
+	}
+
+	ceiling := c.cfg.BaseBackoff << uint(attempt)
+	if ceiling <= 0 || ceiling > c.cfg.MaxBackoff {
+		ceiling = c.cfg.MaxBackoff
+	}
+	return time.Duration(c.jitter(int64(ceiling)) + 1)
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || (code >= 500 && code != http.StatusNotImplemented)
+}
+
+// retryAfter разбирает Retry-After в секундах; дату HTTP клиенты интеграций не присылают
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	secs, err := strconv.Atoi(value)
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	return time.Duration(secs) * time.Second, true
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// cancelBody отменяет контекст попытки при закрытии тела ответа
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// breaker - автомат отключения: после threshold неудач подряд открывается на cooldown,
+// затем пропускает один пробный запрос, успех которого закрывает автомат
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record учитывает исход попытки и сообщает, изменилось ли состояние автомата
+func (b *breaker) record(success bool) (changed, open bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasOpen := b.failures >= b.threshold
+	b.probing = false
+	if success {
+		b.failures = 0
+		return wasOpen, false
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+	isOpen := b.failures >= b.threshold
+	return isOpen != wasOpen, isOpen
+}
@@ -0,0 +1,176 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	statuses []string
+	circuit  []bool
+}
+
+func (o *recordingObserver) ObserveOutboundRequest(client, method, status string, duration time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.statuses = append(o.statuses, status)
+}
+
+func (o *recordingObserver) SetCircuitOpen(client string, open bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.circuit = append(o.circuit, open)
+}
+
+// newTestClient создает клиент без реальных пауз между повторами
+func newTestClient(t *testing.T, cfg Config) (*Client, *[]time.Duration) {
+	t.Helper()
+	c, err := New(cfg)
+	require.NoError(t, err)
+	var waits []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return c, &waits
+}
+
+func TestRetriesUntilSuccess(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "payload", string(body), "тело должно повторяться в каждой попытке")
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	obs := &recordingObserver{}
+	c, waits := newTestClient(t, Config{Name: "test", MaxRetries: 3, RetryUnsafe: true, Observer: obs})
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, bytes.NewBufferString("payload"))
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Len(t, *waits, 2)
+	assert.Equal(t, []string{"503", "503", "200"}, obs.statuses)
+}
+
+func TestPostIsNotRetriedByDefault(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient(t, Config{MaxRetries: 3})
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, bytes.NewBufferString("{}"))
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient(t, Config{MaxRetries: 3})
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestBackoff(t *testing.T) {
+	c, err := New(Config{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	require.NoError(t, err)
+	c.jitter = func(n int64) int64 { return n - 1 } // Верхняя граница джиттера
+
+	assert.Equal(t, 100*time.Millisecond, c.backoff(0, nil))
+	assert.Equal(t, 400*time.Millisecond, c.backoff(2, nil))
+	assert.Equal(t, time.Second, c.backoff(10, nil))
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	assert.Equal(t, time.Second, c.backoff(0, resp), "Retry-After ограничен MaxBackoff")
+	resp.Header.Set("Retry-After", "0")
+	assert.Equal(t, time.Duration(0), c.backoff(0, resp))
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if healthy.Load() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	obs := &recordingObserver{}
+	c, _ := newTestClient(t, Config{BreakerThreshold: 2, BreakerCooldown: time.Minute, Observer: obs})
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	c.breaker.now = func() time.Time { return now }
+
+	get := func() (*http.Response, error) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := c.Do(req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := get()
+		require.NoError(t, err)
+	}
+	_, err := get()
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), calls.Load(), "открытый автомат не должен слать запросы")
+
+	// После паузы проходит пробный запрос, успех закрывает автомат
+	now = now.Add(time.Minute)
+	healthy.Store(true)
+	resp, err := get()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []bool{true, false}, obs.circuit)
+}
+
+func TestInvalidProxy(t *testing.T) {
+	_, err := New(Config{ProxyURL: "ftp://proxy:21"})
+	assert.Error(t, err)
+
+	_, err = New(Config{ProxyURL: "http://proxy.internal:3128"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,204 @@
+// Package jsondiff сравнивает два JSON-снимка сущности и возвращает изменения
+// по путям полей. Схема сущности подсказывает, какие массивы сравнивать как
+// множества и какие поля не показывать (производные и служебные).
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Виды изменений
+const (
+	OpAdded   = "added"
+	OpRemoved = "removed"
+	OpChanged = "changed"
+)
+
+// Change - изменение значения по пути вида "approval_stages[1].approvals".
+// Для массивов-множеств путь указывает на массив, а значение - добавленный или убранный элемент.
+type Change struct {
+	Path   string          `json:"path"`
+	Op     string          `json:"op"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Schema описывает сущность для сравнения. Пути задаются без индексов массивов:
+// "approval_stages.kind" относится к полю kind каждого этапа.
+type Schema struct {
+	Sets   []string // Массивы, порядок элементов которых не важен
+	Ignore []string // Поля, изменения которых не показываются
+}
+
+func (s Schema) has(list []string, path string) bool {
+	for _, p := range list {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff возвращает изменения от before к after. Пустой снимок (nil или null)
+// означает, что сущности не было: все поля after считаются добавленными.
+func Diff(before, after json.RawMessage, schema Schema) ([]Change, error) {
+	b, err := decode(before)
+	if err != nil {
+		return nil, fmt.Errorf("before: %w", err)
+	}
+	a, err := decode(after)
+	if err != nil {
+		return nil, fmt.Errorf("after: %w", err)
+	}
+
+	// Отсутствующий снимок объекта сравнивается как пустой объект, чтобы изменения шли по полям
+	if _, ok := a.(map[string]interface{}); ok && b == nil {
+		b = map[string]interface{}{}
+	}
+	if _, ok := b.(map[string]interface{}); ok && a == nil {
+		a = map[string]interface{}{}
+	}
+
+	d := differ{schema: schema, changes: []Change{}}
+	d.diff("", "", b, a)
+	return d.changes, nil
+}
+
+func decode(raw json.RawMessage) (interface{}, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+type differ struct {
+	schema  Schema
+	changes []Change
+}
+
+// diff сравнивает значения по пути path; schemaPath - тот же путь без индексов массивов
+func (d *differ) diff(path, schemaPath string, before, after interface{}) {
+	if d.schema.has(d.schema.Ignore, schemaPath) {
+		return
+	}
+
+	switch {
+	case before == nil && after == nil:
+		return
+	case before == nil:
+		d.add(path, OpAdded, nil, after)
+		return
+	case after == nil:
+		d.add(path, OpRemoved, before, nil)
+		return
+	}
+
+	bObj, bIsObj := before.(map[string]interface{})
+	aObj, aIsObj := after.(map[string]interface{})
+	if bIsObj && aIsObj {
+		keys := make(map[string]bool, len(bObj)+len(aObj))
+		for k := range bObj {
+			keys[k] = true
+		}
+		for k := range aObj {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			d.diff(join(path, k), join(schemaPath, k), bObj[k], aObj[k])
+		}
+		return
+	}
+
+	bArr, bIsArr := before.([]interface{})
+	aArr, aIsArr := after.([]interface{})
+	if bIsArr && aIsArr {
+		if d.schema.has(d.schema.Sets, schemaPath) {
+			d.diffSet(path, bArr, aArr)
+			return
+		}
+		for i := 0; i < len(bArr) || i < len(aArr); i++ {
+			var bv, av interface{}
+			if i < len(bArr) {
+				bv = bArr[i]
+			}
+			if i < len(aArr) {
+				av = aArr[i]
+			}
+			d.diff(fmt.Sprintf("%s[%d]", path, i), schemaPath, bv, av)
+		}
+		return
+	}
+
+	if !equal(before, after) {
+		d.add(path, OpChanged, before, after)
+	}
+}
+
+// diffSet сравнивает массивы как множества: убранные элементы, затем добавленные
+func (d *differ) diffSet(path string, before, after []interface{}) {
+	unmatched := make(map[string]int, len(after))
+	for _, v := range after {
+		unmatched[canonical(v)]++
+	}
+	for _, v := range before {
+		if key := canonical(v); unmatched[key] > 0 {
+			unmatched[key]--
+			continue
+		}
+		d.add(path, OpRemoved, v, nil)
+	}
+	for _, v := range after {
+		if key := canonical(v); unmatched[key] > 0 {
+			unmatched[key]--
+			d.add(path, OpAdded, nil, v)
+		}
+	}
+}
+
+func (d *differ) add(path, op string, before, after interface{}) {
+	c := Change{Path: path, Op: op}
+	if before != nil {
+		c.Before = json.RawMessage(canonical(before))
+	}
+	if after != nil {
+		c.After = json.RawMessage(canonical(after))
+	}
+	d.changes = append(d.changes, c)
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func equal(a, b interface{}) bool {
+	return canonical(a) == canonical(b)
+}
+
+// canonical кодирует значение в JSON с отсортированными ключами объектов.
+// Выражения и шаблоны в снимках остаются читаемыми: "<" и "&" не экранируются.
+func canonical(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
@@ -0,0 +1,48 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := json.RawMessage(`{"reviewer_count":2,"strategy":"random","updated_at":"t1",
+		"approval_stages":[{"kind":"peer","approvals":1}],"leads":["u1","u2"]}`)
+	after := json.RawMessage(`{"reviewer_count":3,"strategy":"random","updated_at":"t2",
+		"approval_stages":[{"kind":"peer","approvals":2},{"kind":"lead","approvals":1}],
+		"leads":["u3","u1"],"candidate_filter":"load < 5"}`)
+
+	changes, err := Diff(before, after, Schema{Sets: []string{"leads"}, Ignore: []string{"updated_at"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Path: "approval_stages[0].approvals", Op: OpChanged, Before: json.RawMessage(`1`), After: json.RawMessage(`2`)},
+		{Path: "approval_stages[1]", Op: OpAdded, After: json.RawMessage(`{"approvals":1,"kind":"lead"}`)},
+		{Path: "candidate_filter", Op: OpAdded, After: json.RawMessage(`"load < 5"`)},
+		{Path: "leads", Op: OpRemoved, Before: json.RawMessage(`"u2"`)},
+		{Path: "leads", Op: OpAdded, After: json.RawMessage(`"u3"`)},
+		{Path: "reviewer_count", Op: OpChanged, Before: json.RawMessage(`2`), After: json.RawMessage(`3`)},
+	}, changes)
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	// Без снимка до изменения все поля считаются добавленными
+	changes, err := Diff(nil, json.RawMessage(`{"period":"day"}`), Schema{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{Path: "period", Op: OpAdded, After: json.RawMessage(`"day"`)}}, changes)
+
+	// Удаление сущности
+	changes, err = Diff(json.RawMessage(`{"max_assignments":5}`), json.RawMessage(`null`), Schema{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{Path: "max_assignments", Op: OpRemoved, Before: json.RawMessage(`5`)}}, changes)
+
+	// Порядок массивов-множеств и большие числа не дают ложных изменений
+	changes, err = Diff(json.RawMessage(`{"ids":[1,2,2],"n":12345678901234567890}`),
+		json.RawMessage(`{"ids":[2,1,2],"n":12345678901234567890}`), Schema{Sets: []string{"ids"}})
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = Diff(json.RawMessage(`{`), nil, Schema{})
+	assert.Error(t, err)
+}
@@ -1,12 +1,16 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	TeamName string `json:"team_name"` // Добавлено из спецификации
 	IsActive bool   `json:"is_active"`
+	IsBot    bool   `json:"is_bot"` // Бот может быть автором PR, но никогда не назначается ревьюером
 }
 
 type Team struct {
@@ -29,28 +33,76 @@ type PullRequest struct {
 	PullRequestID   string    `json:"pull_request_id"`
 	PullRequestName string    `json:"pull_request_name"`
 	AuthorID        string    `json:"author_id"`
-	Status          string    `json:"status"` // OPEN|MERGED
+	Status          string    `json:"status"` // OPEN|MERGED|DECLINED
 	Reviewers       []string  `json:"assigned_reviewers"`
-	CreatedAt       time.Time `json:"createdAt,omitempty"` // Добавлено из спецификации
-	MergedAt        *string   `json:"mergedAt,omitempty"`  // Может быть null
+	CreatedAt       time.Time `json:"createdAt,omitempty"`      // Добавлено из спецификации
+	MergedAt        *string   `json:"mergedAt,omitempty"`       // Может быть null
+	QueuePosition   int       `json:"queue_position,omitempty"` // Позиция в очереди команды, если все ревьюеры заняты
+	Tags            []string  `json:"tags,omitempty"`           // Технологии и области кода, которых касается PR
+	Priority        string    `json:"priority,omitempty"`       // low|normal|high|urgent
+	TeamName        string    `json:"-"`                        // Команда автора; заполняется только при создании PR
 }
 
 type PullRequestShort struct { // Добавлено из спецификации
 	PullRequestID   string `json:"pull_request_id"`
 	PullRequestName string `json:"pull_request_name"`
 	AuthorID        string `json:"author_id"`
-	Status          string `json:"status"` // OPEN|MERGED
+	Status          string `json:"status"` // OPEN|MERGED|DECLINED
+}
+
+// ReviewAssignment - PR в списке ревью пользователя с полями срочности
+type ReviewAssignment struct {
+	PullRequestShort
+	Priority   string     `json:"priority"`
+	AssignedAt time.Time  `json:"assigned_at"`
+	DueAt      *time.Time `json:"due_at,omitempty"` // Срок ревью по SLA команды автора; нет SLA или ревью завершено - не задан
 }
 
 type CreatePRRequest struct {
-	PullRequestID   string `json:"pull_request_id"`
-	PullRequestName string `json:"pull_request_name"`
-	AuthorID        string `json:"author_id"`
+	PullRequestID     string   `json:"pull_request_id"`
+	PullRequestName   string   `json:"pull_request_name"`
+	AuthorID          string   `json:"author_id"`
+	ExcludedReviewers []string `json:"excluded_reviewers,omitempty"` // Например, напарник по парному программированию
+	AuthorIsBot       bool     `json:"author_is_bot,omitempty"`      // Интеграция создает PR от имени бота
+	Tags              []string `json:"tags,omitempty"`               // Например, go, postgres, frontend
+	Priority          string   `json:"priority,omitempty"`           // low|normal|high|urgent, по умолчанию normal
+	SkipPolicies      bool     `json:"-"`                            // PR уже открыт во внешней системе: политики команды не проверяются
+}
+
+// AddReviewerRequest - запрос на дополнительного ревьюера ("второе мнение").
+// Если user_id пуст, ревьюер выбирается по стратегии команды.
+type AddReviewerRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id,omitempty"`
 }
 
 type ReassignRequest struct {
 	PullRequestID string `json:"pull_request_id"`
 	OldUserID     string `json:"old_user_id"`
+	NewUserID     string `json:"new_user_id,omitempty"` // Принудительная замена, только для лидов команды
+}
+
+// TransferAuthorRequest - передача открытых PR ушедшего автора преемнику.
+// Если successor_id пуст, преемником становится лид команды автора.
+type TransferAuthorRequest struct {
+	AuthorID    string `json:"author_id"`
+	SuccessorID string `json:"successor_id,omitempty"`
+}
+
+// QueuedPR - PR, ждущий освобождения ревьюеров в очереди команды
+type QueuedPR struct {
+	Position        int       `json:"position"` // 1 - следующий на назначение
+	PullRequestID   string    `json:"pull_request_id"`
+	PullRequestName string    `json:"pull_request_name"`
+	AuthorID        string    `json:"author_id"`
+	EnqueuedAt      time.Time `json:"enqueued_at"`
+	WaitSeconds     int64     `json:"wait_seconds"`
+}
+
+// TeamLeadRequest - запрос на назначение или снятие лида команды
+type TeamLeadRequest struct {
+	TeamName string `json:"team_name"`
+	UserID   string `json:"user_id"`
 }
 
 type ErrorResponse struct { // Добавлено из спецификации
@@ -58,4 +110,559 @@ type ErrorResponse struct { // Добавлено из спецификации
 		Code    string `json:"code"`
 		Message string `json:"message"`
 	} `json:"error"`
+	Data interface{} `json:"data,omitempty"` // Текущее состояние ресурса при конфликте, чтобы клиенту не нужен был GET
+}
+
+type FocusWindow struct {
+	Weekday   int    `json:"weekday"`    // 0 - воскресенье ... 6 - суббота
+	StartTime string `json:"start_time"` // HH:MM, UTC
+	EndTime   string `json:"end_time"`   // HH:MM, UTC
+}
+
+type SetFocusWindowsRequest struct {
+	UserID  string        `json:"user_id"`
+	Windows []FocusWindow `json:"windows"`
+}
+
+type AssignmentPRMetadata struct {
+	PullRequestID     string   `json:"pull_request_id"`
+	PullRequestName   string   `json:"pull_request_name"`
+	AuthorID          string   `json:"author_id"`
+	TeamName          string   `json:"team_name"`
+	ReplacedUserID    string   `json:"replaced_user_id,omitempty"` // Только для reassign
+	OnDutyReviewer    string   `json:"on_duty_reviewer,omitempty"` // Дежурный, которому отдан первый слот
+	ExcludedReviewers []string `json:"excluded_reviewers,omitempty"`
+	FilteredOut       []string `json:"filtered_out,omitempty"`      // Кандидаты, отсеянные фильтром команды
+	ExpressionErrors  []string `json:"expression_errors,omitempty"` // Ошибки вычисления фильтра и ранга команды
+	ForcedReviewers   []string `json:"forced_reviewers,omitempty"`  // Заголовок X-Force-Reviewers тестового стенда
+	QuotaDeferred     []string `json:"quota_deferred,omitempty"`    // Кандидаты, исчерпавшие квоту назначений
+	RequestedBy       string   `json:"requested_by,omitempty"`
+	RequestID         string   `json:"request_id,omitempty"`
+}
+
+// AssignmentDecision - входные данные и результат одного решения о назначении ревьюеров
+type AssignmentDecision struct {
+	ID            int64                `json:"decision_id"`
+	PullRequestID string               `json:"pull_request_id"`
+	Operation     string               `json:"operation"` // create|reassign
+	Strategy      string               `json:"strategy"`
+	Seed          int64                `json:"seed"`
+	Requested     int                  `json:"requested"`
+	Candidates    []string             `json:"candidates"`
+	Weights       map[string]float64   `json:"weights"`
+	Focused       []string             `json:"focused"`
+	Selected      []string             `json:"selected"`
+	PRMetadata    AssignmentPRMetadata `json:"pr_metadata"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
+
+type AssignmentReplay struct {
+	Decision AssignmentDecision `json:"decision"`
+	Replayed []string           `json:"replayed"`
+	Matches  bool               `json:"matches"`
+}
+
+type BorrowLender struct {
+	TeamName       string `json:"team_name"`
+	MaxNetBorrowed int    `json:"max_net_borrowed"` // 0 - без ограничения
+}
+
+type SetBorrowPoolRequest struct {
+	TeamName string         `json:"team_name"`
+	Lenders  []BorrowLender `json:"lenders"`
+}
+
+// BorrowingBalance - баланс заимствований ревьюеров команды TeamName у команды OtherTeam
+type BorrowingBalance struct {
+	TeamName       string `json:"team_name"`
+	OtherTeam      string `json:"other_team"`
+	Borrowed       int    `json:"borrowed"`
+	Lent           int    `json:"lent"`
+	Net            int    `json:"net"`
+	MaxNetBorrowed int    `json:"max_net_borrowed"`
+}
+
+type MaintenanceState struct {
+	Enabled   bool       `json:"enabled"`
+	Message   string     `json:"message"`
+	UpdatedBy string     `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type SetMaintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+}
+
+type TeamSettings struct {
+	TeamName          string `json:"team_name"`
+	ReviewerCount     int    `json:"reviewer_count"`
+	Strategy          string `json:"strategy"`           // random|least_loaded|knowledge_spread
+	MergePolicy       string `json:"merge_policy"`       // none|all|quorum
+	RequiredApprovals int    `json:"required_approvals"` // N для политики quorum
+	MaxOpenReviews    int    `json:"max_open_reviews"`   // Предел неодобренных ревью на человека, 0 - без предела
+
+	// Адаптивное число ревьюеров: пока бэклог ревью команды больше порога,
+	// новым PR назначается adaptive_reviewer_count ревьюеров вместо reviewer_count
+	AdaptiveBacklogThreshold int  `json:"adaptive_backlog_threshold"` // 0 - политика выключена
+	AdaptiveReviewerCount    int  `json:"adaptive_reviewer_count"`
+	ReviewerCountReduced     bool `json:"reviewer_count_reduced"` // Только чтение: число ревьюеров сейчас снижено
+
+	// Новые участники команды не назначаются ревьюерами автоматически первые N дней
+	NewMemberHoldbackDays int `json:"new_member_holdback_days"` // 0 - без периода адаптации
+
+	// Политики оформления PR: проверяются при создании PR, нарушения возвращаются с кодом 422
+	PRNamePattern          string `json:"pr_name_pattern"`          // Регулярное выражение для имени PR, пусто - без проверки
+	RequireTicketReference bool   `json:"require_ticket_reference"` // Имя PR должно ссылаться на задачу (ABC-123 или #123)
+	MinTags                int    `json:"min_tags"`                 // Минимум тегов PR, 0 - теги необязательны
+
+	// Первым ревьюером нового PR по возможности назначается дежурный недели (см. /team/rotation)
+	PreferOnDuty bool `json:"prefer_on_duty"`
+
+	// Цепочка одобрений: ревьюеры следующего этапа назначаются, когда завершен предыдущий,
+	// мердж требует завершения всех этапов. Первый этап - ревьюеры, назначенные при создании PR.
+	ApprovalStages []ApprovalStage `json:"approval_stages"` // Пусто - без цепочки
+
+	// Выражения над переменными кандидата (нагрузка, теги, стаж, фокус): фильтр отбирает
+	// кандидатов для автоматического назначения, ранг умножает их вес в стратегиях с весами
+	CandidateFilter string `json:"candidate_filter"` // Выражение типа bool, пусто - без фильтра
+	CandidateRank   string `json:"candidate_rank"`   // Выражение типа number, пусто - без ранжирования
+
+	// Срок ревью от назначения ревьюера; по нему сортируется /users/getReview и считается due_at
+	ReviewSLAHours int `json:"review_sla_hours"` // 0 - без срока
+
+	// Представление времени и текста для команды: уведомления, due_at и отчеты
+	// выводятся в поясе команды, в БД и метриках время остается в UTC
+	Locale   string `json:"locale"`   // Локаль уведомлений участников без своей локали, пусто - локаль пользователя
+	Timezone string `json:"timezone"` // Пояс IANA, например Europe/Moscow; пусто - UTC
+}
+
+// ApprovalStage - этап цепочки одобрений команды
+type ApprovalStage struct {
+	Kind      string `json:"kind"`      // peer - любые ревьюеры|lead - лиды команды
+	Approvals int    `json:"approvals"` // Сколько одобрений нужно на этапе
+}
+
+// ApprovalStageProgress - прогресс PR по этапу цепочки одобрений
+type ApprovalStageProgress struct {
+	Stage     int      `json:"stage"`
+	Kind      string   `json:"kind"`
+	Required  int      `json:"required_approvals"`
+	Approved  int      `json:"approvals"`
+	Reviewers []string `json:"reviewers"` // Назначенные на этап
+	Pending   []string `json:"pending"`   // Ревьюеры этапа, чье одобрение еще засчитается
+	Completed bool     `json:"completed"`
+}
+
+// PolicyViolation - нарушение политики оформления PR команды
+type PolicyViolation struct {
+	Field   string `json:"field"`
+	Policy  string `json:"policy"` // pr_name_pattern|require_ticket_reference|min_tags
+	Message string `json:"message"`
+}
+
+// CandidatePreview - может ли участник команды сейчас автоматически назначаться ревьюером.
+// Reason объясняет отказ или ограничение, например "in holdback until 2024-03-11".
+type CandidatePreview struct {
+	UserID        string     `json:"user_id"`
+	Username      string     `json:"username"`
+	Eligible      bool       `json:"eligible"`
+	Reason        string     `json:"reason,omitempty"`
+	HoldbackUntil *time.Time `json:"holdback_until,omitempty"`
+}
+
+// AdaptiveReviewerState - состояние адаптивного числа ревьюеров команды
+type AdaptiveReviewerState struct {
+	TeamName      string `json:"team_name"`
+	Backlog       int    `json:"backlog"`
+	ReviewerCount int    `json:"reviewer_count"` // Действующее число ревьюеров новых PR
+	Reduced       bool   `json:"reduced"`
+}
+
+// PRSettingsImpact - как изменение настроек команды затронет открытый PR
+type PRSettingsImpact struct {
+	PullRequestID    string   `json:"pull_request_id"`
+	PullRequestName  string   `json:"pull_request_name"`
+	AuthorID         string   `json:"author_id"`
+	CurrentReviewers []string `json:"current_reviewers"`
+	Required         int      `json:"required"`
+	Missing          int      `json:"missing"` // Сколько ревьюеров будет доназначено
+	Excess           int      `json:"excess"`  // Лишние ревьюеры не снимаются
+}
+
+type TeamSettingsImpact struct {
+	TeamName        string             `json:"team_name"`
+	Current         TeamSettings       `json:"current"`
+	Proposed        TeamSettings       `json:"proposed"`
+	StrategyChanged bool               `json:"strategy_changed"`
+	AffectedPRs     []PRSettingsImpact `json:"affected_prs"`
+}
+
+type PRTopUp struct {
+	PullRequestID string   `json:"pull_request_id"`
+	Added         []string `json:"added"`
+	StillMissing  int      `json:"still_missing"`
+}
+
+type TeamSettingsApplyResult struct {
+	Settings TeamSettings `json:"settings"`
+	Updated  []PRTopUp    `json:"updated"`
+}
+
+type ChecklistItem struct {
+	ItemID   int64  `json:"item_id"`
+	TeamName string `json:"team_name"`
+	Text     string `json:"text"`
+	Position int    `json:"position"`
+}
+
+type ChecklistProgress struct {
+	ChecklistItem
+	CheckedBy []string `json:"checked_by"`
+}
+
+// PullRequestDetail - PR с одобрениями и прогрессом по чек-листу команды
+type PullRequestDetail struct {
+	PullRequest
+	ApprovedBy     []string                `json:"approved_by"`
+	Checklist      []ChecklistProgress     `json:"checklist"`
+	ApprovalStage  int                     `json:"approval_stage,omitempty"`  // Текущий этап цепочки одобрений
+	ApprovalStages []ApprovalStageProgress `json:"approval_stages,omitempty"` // Только при цепочке у команды
+}
+
+type ApprovePRRequest struct {
+	PullRequestID string  `json:"pull_request_id"`
+	UserID        string  `json:"user_id"`
+	CheckedItems  []int64 `json:"checked_items"`
+}
+
+type ChecklistItemStats struct {
+	ChecklistItem
+	Checked        int     `json:"checked"`
+	CompletionRate float64 `json:"completion_rate"` // % одобрений с отмеченным пунктом
+}
+
+type ChecklistReport struct {
+	TeamName  string               `json:"team_name"`
+	Approvals int                  `json:"approvals"`
+	Items     []ChecklistItemStats `json:"items"`
+}
+
+// MergeBlockedResponse - ответ 409, когда политика одобрений команды не выполнена
+type MergeBlockedResponse struct {
+	ErrorResponse
+	MergePolicy      string   `json:"merge_policy"`
+	Required         int      `json:"required_approvals"`
+	Approved         int      `json:"approvals"`
+	MissingApprovals []string `json:"missing_approvals"`
+	ApprovalStage    int      `json:"approval_stage,omitempty"` // Незавершенный этап цепочки одобрений
+}
+
+// AuditEvent - запись журнала аудита об изменении
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	RequestID  string          `json:"request_id,omitempty"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Action     string          `json:"action"`
+	Details    json.RawMessage `json:"details"`
+	Before     json.RawMessage `json:"before,omitempty"` // Снимок сущности до изменения
+	After      json.RawMessage `json:"after,omitempty"`  // Снимок сущности после изменения
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditChange - изменение поля сущности между снимками записи аудита
+type AuditChange struct {
+	Path   string          `json:"path"`
+	Op     string          `json:"op"` // added|removed|changed
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditDiff - запись аудита и изменения сущности в ней
+type AuditDiff struct {
+	Event   AuditEvent    `json:"event"`
+	Changes []AuditChange `json:"changes"`
+}
+
+// AuditFilter - параметры поиска по журналу аудита
+type AuditFilter struct {
+	Actor      string
+	EntityType string
+	Action     string
+	From       *time.Time // Включительно
+	To         *time.Time // Не включительно
+	Limit      int
+}
+
+// PullRequestExportFilter - параметры выгрузки PR
+type PullRequestExportFilter struct {
+	Status   string     // OPEN|MERGED|DECLINED, пусто - все
+	TeamName string     // Команда автора, пусто - все
+	From     *time.Time // По created_at, включительно
+	To       *time.Time // По created_at, не включительно
+}
+
+// SetUserLocaleRequest - запрос на смену локали уведомлений пользователя
+type SetUserLocaleRequest struct {
+	UserID string `json:"user_id"`
+	Locale string `json:"locale"` // Пустая строка - локаль по умолчанию
+}
+
+// BulkLabelRequest - запрос на добавление или снятие метки (тега) у нескольких PR
+type BulkLabelRequest struct {
+	Action         string   `json:"action"` // add|remove
+	Label          string   `json:"label"`
+	PullRequestIDs []string `json:"pull_request_ids"`
+}
+
+// BulkLabelResult - результат массового изменения метки по PR
+type BulkLabelResult struct {
+	Action    string   `json:"action"`
+	Label     string   `json:"label"`
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"` // Метка уже была (add) или ее не было (remove)
+	NotFound  []string `json:"not_found"`
+	AtLimit   []string `json:"at_limit"` // У PR уже максимум тегов, метка не добавлена
+}
+
+// NotificationTemplate - шаблон уведомления команды для вида и локали
+type NotificationTemplate struct {
+	TeamName  string     `json:"team_name"`
+	Kind      string     `json:"kind"`
+	Locale    string     `json:"locale"`
+	Body      string     `json:"body"` // Пустое тело при сохранении удаляет шаблон
+	UpdatedBy string     `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// NotificationRecipient - получатель уведомления с локалью и шаблонами его команды
+type NotificationRecipient struct {
+	UserID    string
+	Username  string
+	TeamName  string
+	Locale    string            // Локаль пользователя, иначе локаль команды
+	Location  *time.Location    // Пояс команды для времени в тексте
+	DueAt     *time.Time        // Срок ревью PR получателем, если он ждет его ревью
+	Overrides map[string]string // locale -> тело шаблона команды
+}
+
+// NotificationPreviewRequest - запрос на предпросмотр уведомления
+type NotificationPreviewRequest struct {
+	Kind           string `json:"kind"`
+	UserID         string `json:"user_id"`
+	PullRequestID  string `json:"pull_request_id"`
+	ReplacedUserID string `json:"replaced_user_id,omitempty"`
+}
+
+// NotificationPreview - уведомление в том виде, в каком его получит пользователь
+type NotificationPreview struct {
+	Kind   string `json:"kind"`
+	UserID string `json:"user_id"`
+	Locale string `json:"locale"`
+	Source string `json:"source"` // team|default
+	Text   string `json:"text"`
+
+	Channels    []string `json:"channels"`               // Каналы маршрутов команды, сработавших для PR
+	RouteErrors []string `json:"route_errors,omitempty"` // Маршруты, условия которых не вычислились
+}
+
+// NotificationRoute - маршрут уведомлений команды: уведомления о PR, для которых
+// выражение condition истинно (например, "security" in labels), уходят и в канал channel
+type NotificationRoute struct {
+	TeamName  string     `json:"team_name"`
+	Name      string     `json:"name"`
+	Condition string     `json:"condition"`
+	Channel   string     `json:"channel"` // Пустой канал при сохранении удаляет маршрут
+	UpdatedBy string     `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// BusinessEvent - бизнес-событие из event_outbox для внешних потребителей
+type BusinessEvent struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	Actor       string          `json:"actor"`
+	RequestID   string          `json:"request_id,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+// WebhookSigningKey - ключ подписи исходящих вебхуков. Secret возвращается только
+// при ротации; после ротации старый ключ подписывает запросы до expires_at.
+type WebhookSigningKey struct {
+	KeyID     string     `json:"key_id"`
+	Secret    string     `json:"secret,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil - действует до следующей ротации
+	Active    bool       `json:"active"`
+}
+
+// RotateWebhookKeyRequest - запрос на выпуск нового ключа подписи
+type RotateWebhookKeyRequest struct {
+	GraceHours *int `json:"grace_hours,omitempty"` // Сколько действуют прежние ключи, по умолчанию 24
+}
+
+// RevokeWebhookKeyRequest - запрос на немедленный отзыв ключа подписи
+type RevokeWebhookKeyRequest struct {
+	KeyID string `json:"key_id"`
+}
+
+// TagReviewMonth - число ревью PR с тегом за календарный месяц
+type TagReviewMonth struct {
+	Month   string `json:"month"` // YYYY-MM в поясе команды
+	Reviews int    `json:"reviews"`
+}
+
+// TagGrowth - опыт ревьюера с одним тегом
+type TagGrowth struct {
+	Tag             string           `json:"tag"`
+	Reviews         int              `json:"reviews"`
+	FirstReviewedAt time.Time        `json:"first_reviewed_at"`
+	LastReviewedAt  time.Time        `json:"last_reviewed_at"`
+	Months          []TagReviewMonth `json:"months"`
+}
+
+// ReviewerGrowth - отчет о том, какие теги ревьюер смотрел за период.
+// Unexplored - теги PR команды за тот же период, которых ревьюер не видел.
+type ReviewerGrowth struct {
+	UserID     string      `json:"user_id"`
+	TeamName   string      `json:"team_name,omitempty"`
+	Timezone   string      `json:"timezone"` // Пояс команды, в котором считаются месяцы
+	From       *time.Time  `json:"from,omitempty"`
+	To         *time.Time  `json:"to,omitempty"`
+	Tags       []TagGrowth `json:"tags"`
+	Unexplored []string    `json:"unexplored"`
+}
+
+// MigrationInfo - миграция схемы из сборки и ее состояние в базе
+type MigrationInfo struct {
+	Version    int        `json:"version"`
+	Name       string     `json:"name"`
+	State      string     `json:"state"` // applied|pending|dirty
+	AppliedAt  *time.Time `json:"applied_at,omitempty"`
+	DurationMs int64      `json:"duration_ms,omitempty"`
+	Reversible bool       `json:"reversible"` // Есть down-миграция
+}
+
+// MigrationStatus - состояние миграций схемы базы данных
+type MigrationStatus struct {
+	Version       int             `json:"version"`        // Записанная версия схемы
+	LatestVersion int             `json:"latest_version"` // Последняя миграция этой сборки
+	Dirty         bool            `json:"dirty"`          // Применение миграции version не завершилось
+	CanRollBack   bool            `json:"can_roll_back"`
+	Migrations    []MigrationInfo `json:"migrations"`
+}
+
+// VetoRequest - ревьюер отказывается от назначения на PR с указанием причины
+type VetoRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+	Reason        string `json:"reason"` // conflict_of_interest|unfamiliar_area|overloaded
+	Comment       string `json:"comment,omitempty"`
+}
+
+// ReviewerVetoes - отказы ревьюера от назначений за период по причинам
+type ReviewerVetoes struct {
+	UserID   string         `json:"user_id"`
+	Total    int            `json:"total"`
+	ByReason map[string]int `json:"by_reason"`
+}
+
+// VetoReport - отказы от назначений ревьюеров команды за период
+type VetoReport struct {
+	TeamName  string           `json:"team_name"`
+	From      *time.Time       `json:"from,omitempty"`
+	To        *time.Time       `json:"to,omitempty"`
+	Total     int              `json:"total"`
+	ByReason  map[string]int   `json:"by_reason"`
+	Reviewers []ReviewerVetoes `json:"reviewers"`
+}
+
+// RotationWeek - дежурный ревьюер команды на неделю, начинающуюся в понедельник WeekStart
+type RotationWeek struct {
+	WeekStart  string `json:"week_start"` // YYYY-MM-DD
+	UserID     string `json:"user_id,omitempty"`
+	Overridden bool   `json:"overridden"` // Назначен вручную, а не по очереди
+	SetBy      string `json:"set_by,omitempty"`
+}
+
+// TeamRotation - расписание дежурств ревьюеров команды
+type TeamRotation struct {
+	TeamName     string         `json:"team_name"`
+	PreferOnDuty bool           `json:"prefer_on_duty"`
+	Weeks        []RotationWeek `json:"weeks"`
+}
+
+// SetRotationRequest - ручное назначение дежурного на неделю; пустой user_id возвращает очередь
+type SetRotationRequest struct {
+	TeamName  string `json:"team_name"`
+	WeekStart string `json:"week_start"` // Любой день недели, YYYY-MM-DD
+	UserID    string `json:"user_id"`
+}
+
+// ExternalIdentity - сопоставление учетной записи внешней системы (Bitbucket) пользователю
+// сервиса; пустой user_id в запросе удаляет сопоставление
+type ExternalIdentity struct {
+	Provider   string `json:"provider"`
+	ExternalID string `json:"external_id"`
+	UserID     string `json:"user_id"`
+}
+
+// AssignmentQuota - предел новых назначений ревьюером за скользящее окно, отдельный
+// от предела открытых ревью команды; max_assignments = 0 в запросе снимает квоту
+type AssignmentQuota struct {
+	UserID         string `json:"user_id"`
+	MaxAssignments int    `json:"max_assignments"`
+	Period         string `json:"period"` // day - последние 24 часа|week - последние 7 дней
+}
+
+// AssignmentQuotaStatus - квота пользователя и ее использование в текущем окне
+type AssignmentQuotaStatus struct {
+	AssignmentQuota
+	Used      int        `json:"used"`               // Назначений в окне
+	Remaining *int       `json:"remaining"`          // null - квоты нет
+	FreesAt   *time.Time `json:"frees_at,omitempty"` // Когда квота исчерпана: освобождение ближайшего места
+}
+
+// ServiceStatus - сводка состояния реплики для страницы /status
+type ServiceStatus struct {
+	Status        string           `json:"status"`             // ok|degraded|unavailable
+	Problems      []string         `json:"problems,omitempty"` // Причины degraded и unavailable
+	Version       string           `json:"version"`
+	Timestamp     time.Time        `json:"timestamp"`
+	StartedAt     time.Time        `json:"started_at"`
+	UptimeSeconds float64          `json:"uptime_seconds"`
+	Database      string           `json:"database"` // ok|unavailable|unknown (нет свежей проверки)
+	WarmingUp     bool             `json:"warming_up"`
+	Maintenance   MaintenanceState `json:"maintenance"`
+	Requests      *RecentRequests  `json:"recent_requests,omitempty"` // Нет, если метрики выключены
+	QueueDepths   map[string]int   `json:"queue_depths"`              // Команда -> PR в очереди на ревьюеров
+	Scheduler     *SchedulerStatus `json:"scheduler,omitempty"`       // Нет, если планировщик не подключен
+}
+
+// RecentRequests - запросы и ответы 5xx реплики за последние минуты
+type RecentRequests struct {
+	WindowSeconds int     `json:"window_seconds"`
+	Total         int     `json:"total"`
+	ServerErrors  int     `json:"server_errors"`
+	ErrorRate     float64 `json:"error_rate"` // Доля ответов 5xx, 0 - запросов не было
+}
+
+// SchedulerStatus - фоновые задачи реплики; выполняются только на лидере
+type SchedulerStatus struct {
+	Leader bool     `json:"leader"`
+	Jobs   []JobRun `json:"jobs"`
+}
+
+// JobRun - последний запуск фоновой задачи
+type JobRun struct {
+	Name            string     `json:"name"`
+	IntervalSeconds float64    `json:"interval_seconds"`
+	LastRunAt       *time.Time `json:"last_run_at,omitempty"` // Нет - еще не запускалась на этой реплике
+	LastDurationMs  float64    `json:"last_duration_ms"`
+	LastError       string     `json:"last_error,omitempty"`
 }
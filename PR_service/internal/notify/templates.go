@@ -0,0 +1,231 @@
+// Package notify формирует тексты уведомлений ревьюерам с учетом локали
+// получателя. Встроенные шаблоны лежат в templates/<locale>/<kind>.tmpl,
+// команды могут переопределять их своими шаблонами из БД.
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Виды уведомлений
+const (
+	KindReviewRequested    = "review_requested"
+	KindReviewerReassigned = "reviewer_reassigned"
+	KindPRMerged           = "pr_merged"
+	KindAuthorTransferred  = "author_transferred"
+)
+
+// DefaultLocale - локаль, если у получателя она не задана или не поддерживается
+const DefaultLocale = "en"
+
+// Источники шаблона в результате рендеринга
+const (
+	SourceTeam    = "team"
+	SourceDefault = "default"
+)
+
+//go:embed templates/*/*.tmpl
+var defaultTemplates embed.FS
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// IsKnownKind проверяет, поддерживается ли вид уведомления
+func IsKnownKind(kind string) bool {
+	switch kind {
+	case KindReviewRequested, KindReviewerReassigned, KindPRMerged, KindAuthorTransferred:
+		return true
+	}
+	return false
+}
+
+// NormalizeLocale приводит локаль к виду "ru" или "pt-br".
+// Возвращает ошибку для строк, не похожих на языковой тег.
+func NormalizeLocale(locale string) (string, error) {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if normalized == "" {
+		return "", nil
+	}
+	if !localePattern.MatchString(normalized) {
+		return "", fmt.Errorf("invalid locale %q", locale)
+	}
+	return normalized, nil
+}
+
+// localeFallbacks возвращает локали в порядке предпочтения: точная, базовый язык, DefaultLocale
+func localeFallbacks(locale string) []string {
+	var chain []string
+	add := func(l string) {
+		for _, c := range chain {
+			if c == l {
+				return
+			}
+		}
+		chain = append(chain, l)
+	}
+
+	if normalized, err := NormalizeLocale(locale); err == nil && normalized != "" {
+		add(normalized)
+		if i := strings.Index(normalized, "-"); i > 0 {
+			add(normalized[:i])
+		}
+	}
+	add(DefaultLocale)
+	return chain
+}
+
+// timeLayouts - формат даты и времени в тексте уведомлений по локали
+var timeLayouts = map[string]string{
+	"en": "Jan 2, 2006 15:04 MST",
+	"ru": "02.01.2006 15:04 MST",
+}
+
+// FormatTime форматирует время для текста уведомления в поясе loc (nil - UTC)
+// и формате локали с тем же порядком предпочтения, что и у шаблонов
+func FormatTime(t time.Time, locale string, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	for _, l := range localeFallbacks(locale) {
+		if layout, ok := timeLayouts[l]; ok {
+			return t.Format(layout)
+		}
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Data - данные, доступные в шаблонах уведомлений
+type Data struct {
+	RecipientID     string
+	RecipientName   string
+	TeamName        string
+	PullRequestID   string
+	PullRequestName string
+	AuthorID        string
+	ReplacedUserID  string
+	DueAt           string // Срок ревью, отформатированный FormatTime; пусто - без срока
+}
+
+// sampleData используется для проверки шаблонов при сохранении
+var sampleData = Data{
+	RecipientID:     "u2",
+	RecipientName:   "Bob",
+	TeamName:        "backend",
+	PullRequestID:   "pr-1001",
+	PullRequestName: "Add search",
+	AuthorID:        "u1",
+	ReplacedUserID:  "u3",
+	DueAt:           "Mar 4, 2025 18:00 MSK",
+}
+
+// Rendered - результат рендеринга уведомления
+type Rendered struct {
+	Locale string // Локаль, шаблон которой был использован
+	Source string // team|default
+	Text   string
+}
+
+// Renderer рендерит уведомления по встроенным шаблонам и переопределениям команд
+type Renderer struct {
+	defaults map[string]map[string]*template.Template // locale -> kind -> шаблон
+}
+
+// NewRenderer загружает встроенные шаблоны
+func NewRenderer() *Renderer {
+	r := &Renderer{defaults: make(map[string]map[string]*template.Template)}
+
+	files, err := fs.Glob(defaultTemplates, "templates/*/*.tmpl")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		locale := path.Base(path.Dir(file))
+		kind := strings.TrimSuffix(path.Base(file), ".tmpl")
+
+		body, err := defaultTemplates.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+		if r.defaults[locale] == nil {
+			r.defaults[locale] = make(map[string]*template.Template)
+		}
+		r.defaults[locale][kind] = template.Must(parse(kind, string(body)))
+	}
+	return r
+}
+
+// Locales возвращает локали встроенных шаблонов
+func (r *Renderer) Locales() []string {
+	locales := make([]string, 0, len(r.defaults))
+	for l := range r.defaults {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Render выбирает шаблон для локали получателя и заполняет его данными.
+// overrides - шаблоны команды для вида kind по локалям; для каждой локали
+// из цепочки предпочтения шаблон команды важнее встроенного.
+func (r *Renderer) Render(kind, locale string, overrides map[string]string, data Data) (*Rendered, error) {
+	if !IsKnownKind(kind) {
+		return nil, fmt.Errorf("unknown notification kind %q", kind)
+	}
+
+	for _, l := range localeFallbacks(locale) {
+		if body, ok := overrides[l]; ok {
+			tmpl, err := parse(kind, body)
+			if err != nil {
+				return nil, err
+			}
+			text, err := execute(tmpl, data)
+			if err != nil {
+				return nil, err
+			}
+			return &Rendered{Locale: l, Source: SourceTeam, Text: text}, nil
+		}
+		if tmpl, ok := r.defaults[l][kind]; ok {
+			text, err := execute(tmpl, data)
+			if err != nil {
+				return nil, err
+			}
+			return &Rendered{Locale: l, Source: SourceDefault, Text: text}, nil
+		}
+	}
+	return nil, fmt.Errorf("no template for %q", kind)
+}
+
+// Validate проверяет, что шаблон разбирается и выполняется на примерных данных
+func Validate(kind, body string) error {
+	tmpl, err := parse(kind, body)
+	if err != nil {
+		return err
+	}
+	_, err = execute(tmpl, sampleData)
+	return err
+}
+
+func parse(kind, body string) (*template.Template, error) {
+	tmpl, err := template.New(kind).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %v", err)
+	}
+	return tmpl, nil
+}
+
+func execute(tmpl *template.Template, data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid template: %v", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	tests := []struct {
+		in          string
+		want        string
+		shouldError bool
+	}{
+		{in: "", want: ""},
+		{in: "ru", want: "ru"},
+		{in: "pt_BR", want: "pt-br"},
+		{in: " EN-us ", want: "en-us"},
+		{in: "russian", shouldError: true},
+		{in: "ru-", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := NormalizeLocale(tt.in)
+			if tt.shouldError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLocaleFallbacks(t *testing.T) {
+	assert.Equal(t, []string{"ru-ru", "ru", "en"}, localeFallbacks("ru_RU"))
+	assert.Equal(t, []string{"en"}, localeFallbacks(""))
+	assert.Equal(t, []string{"en-gb", "en"}, localeFallbacks("en-GB"))
+	assert.Equal(t, []string{"en"}, localeFallbacks("not a locale"))
+}
+
+func TestRender(t *testing.T) {
+	r := NewRenderer()
+	assert.Equal(t, []string{"en", "ru"}, r.Locales())
+
+	data := Data{RecipientName: "Bob", PullRequestID: "pr-1", PullRequestName: "Fix", AuthorID: "u1"}
+
+	// Встроенный шаблон базового языка
+	rendered, err := r.Render(KindReviewRequested, "ru-RU", nil, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "ru", rendered.Locale)
+	assert.Equal(t, SourceDefault, rendered.Source)
+	assert.Contains(t, rendered.Text, "Привет, Bob")
+
+	// Неизвестная локаль падает на DefaultLocale
+	rendered, err = r.Render(KindPRMerged, "de", nil, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "en", rendered.Locale)
+
+	// Шаблон команды для точной локали важнее встроенного
+	overrides := map[string]string{"de": "Hallo {{.RecipientName}}: {{.PullRequestName}}"}
+	rendered, err = r.Render(KindReviewRequested, "de", overrides, data)
+	assert.NoError(t, err)
+	assert.Equal(t, SourceTeam, rendered.Source)
+	assert.Equal(t, "Hallo Bob: Fix", rendered.Text)
+
+	// Но встроенный шаблон точной локали важнее шаблона команды для базового языка
+	overrides = map[string]string{"en": "Team {{.PullRequestID}}"}
+	rendered, err = r.Render(KindReviewRequested, "ru", overrides, data)
+	assert.NoError(t, err)
+	assert.Equal(t, SourceDefault, rendered.Source)
+
+	_, err = r.Render("unknown", "en", nil, data)
+	assert.Error(t, err)
+}
+
+func TestFormatTime(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+	due := time.Date(2025, 3, 4, 15, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "04.03.2025 18:00 MSK", FormatTime(due, "ru-RU", moscow))
+	assert.Equal(t, "Mar 4, 2025 18:00 MSK", FormatTime(due, "de", moscow))
+	assert.Equal(t, "Mar 4, 2025 15:00 UTC", FormatTime(due, "", nil))
+}
+
+func TestRenderDueAt(t *testing.T) {
+	r := NewRenderer()
+	data := Data{RecipientName: "Bob", PullRequestID: "pr-1", PullRequestName: "Fix", AuthorID: "u1"}
+
+	rendered, err := r.Render(KindReviewRequested, "en", nil, data)
+	assert.NoError(t, err)
+	assert.NotContains(t, rendered.Text, "review by")
+
+	data.DueAt = "Mar 4, 2025 18:00 MSK"
+	rendered, err = r.Render(KindReviewRequested, "en", nil, data)
+	assert.NoError(t, err)
+	assert.Contains(t, rendered.Text, "Please review by Mar 4, 2025 18:00 MSK.")
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(KindPRMerged, "{{.PullRequestName}} merged"))
+	assert.Error(t, Validate(KindPRMerged, "{{.PullRequestName"))
+	assert.Error(t, Validate(KindPRMerged, "{{.Unknown}}"))
+}
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+)
+
+// Elector определяет, является ли текущая реплика лидером
+type Elector interface {
+	TryAcquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+// AdvisoryLockElector выбирает лидера через сессионный advisory lock PostgreSQL.
+// Блокировка живет пока открыто выделенное соединение, поэтому при падении
+// реплики лидерство автоматически переходит к другой.
+type AdvisoryLockElector struct {
+	db   *sql.DB
+	key  int64
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func NewAdvisoryLockElector(db *sql.DB, key int64) *AdvisoryLockElector {
+	return &AdvisoryLockElector{db: db, key: key}
+}
+
+// TryAcquire захватывает блокировку или подтверждает, что она все еще удерживается
+func (e *AdvisoryLockElector) TryAcquire(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Уже лидер - проверяем, что соединение с блокировкой живо
+	if e.conn != nil {
+		if err := e.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		discardConn(e.conn)
+		e.conn = nil
+	}
+
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, e.key).Scan(&acquired); err != nil {
+		discardConn(conn)
+		return false, err
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+
+	e.conn = conn
+	return true, nil
+}
+
+// Release освобождает блокировку, если она удерживается
+func (e *AdvisoryLockElector) Release(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return nil
+	}
+	conn := e.conn
+	e.conn = nil
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, e.key); err != nil {
+		discardConn(conn)
+		return err
+	}
+	return conn.Close()
+}
+
+// discardConn закрывает физическое соединение, не возвращая его в пул. Сессия,
+// на которой не удалось проверить или снять блокировку, может все еще удерживать ее:
+// вернувшись в пул, она оставила бы лидерство простаивающему соединению навсегда.
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	conn.Close()
+}
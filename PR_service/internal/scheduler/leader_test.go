@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lockDriver - драйвер БД, в котором advisory lock всегда свободен, а проверка
+// соединения и снятие блокировки могут завершаться ошибкой
+type lockDriver struct {
+	mu        sync.Mutex
+	opened    int
+	closed    int
+	pingErr   error
+	unlockErr error
+}
+
+func (d *lockDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opened++
+	return &lockConn{d: d}, nil
+}
+
+func (d *lockDriver) Driver() driver.Driver { return nil }
+
+func (d *lockDriver) counts() (int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.opened, d.closed
+}
+
+type lockConn struct {
+	d *lockDriver
+}
+
+func (c *lockConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *lockConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *lockConn) Close() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.closed++
+	return nil
+}
+
+func (c *lockConn) Ping(ctx context.Context) error { return c.d.pingErr }
+
+func (c *lockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &lockRows{}, nil
+}
+
+func (c *lockConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.d.unlockErr != nil {
+		return nil, c.d.unlockErr
+	}
+	return driver.RowsAffected(0), nil
+}
+
+// lockRows - результат pg_try_advisory_lock: блокировка захвачена
+type lockRows struct {
+	done bool
+}
+
+func (r *lockRows) Columns() []string { return []string{"pg_try_advisory_lock"} }
+
+func (r *lockRows) Close() error { return nil }
+
+func (r *lockRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = true
+	return nil
+}
+
+func TestAdvisoryLockElectorDiscardsBrokenSession(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Released lock returns connection to pool", func(t *testing.T) {
+		d := &lockDriver{}
+		db := sql.OpenDB(d)
+		defer db.Close()
+		e := NewAdvisoryLockElector(db, 1)
+
+		ok, err := e.TryAcquire(ctx)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, e.Release(ctx))
+
+		_, closed := d.counts()
+		assert.Equal(t, 0, closed)
+	})
+
+	t.Run("Failed unlock closes session", func(t *testing.T) {
+		d := &lockDriver{unlockErr: errors.New("unlock failed")}
+		db := sql.OpenDB(d)
+		defer db.Close()
+		e := NewAdvisoryLockElector(db, 1)
+
+		ok, err := e.TryAcquire(ctx)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Error(t, e.Release(ctx))
+
+		// Сессия, возможно удерживающая блокировку, не возвращается в пул
+		_, closed := d.counts()
+		assert.Equal(t, 1, closed)
+		assert.Equal(t, 0, db.Stats().Idle)
+	})
+
+	t.Run("Failed ping closes session before reacquiring", func(t *testing.T) {
+		d := &lockDriver{}
+		db := sql.OpenDB(d)
+		defer db.Close()
+		e := NewAdvisoryLockElector(db, 1)
+
+		ok, err := e.TryAcquire(ctx)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		d.mu.Lock()
+		d.pingErr = errors.New("connection reset")
+		d.mu.Unlock()
+		ok, err = e.TryAcquire(ctx)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		opened, closed := d.counts()
+		assert.Equal(t, 2, opened)
+		assert.Equal(t, 1, closed)
+	})
+}
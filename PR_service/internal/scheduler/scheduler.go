@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"PR_service/internal/clock"
+)
+
+// LockKey - ключ advisory lock, общий для всех реплик сервиса
+const LockKey int64 = 0x50525f73636864 // "PR_schd"
+
+// Job - периодическая фоновая задача
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// JobStatus - последний запуск задачи на этой реплике
+type JobStatus struct {
+	Name     string
+	Interval time.Duration
+	LastRun  time.Time     // Нулевое - задача еще не запускалась
+	Duration time.Duration // Длительность последнего запуска
+	Err      string        // Ошибка последнего запуска, пусто - успешно
+}
+
+// Scheduler запускает фоновые задачи только на реплике-лидере
+type Scheduler struct {
+	elector Elector
+	clock   clock.Clock
+	tick    time.Duration
+	jobs    []Job
+	lastRun map[string]time.Time
+
+	mu     sync.RWMutex
+	leader bool
+	runs   map[string]JobStatus
+}
+
+func New(elector Elector, tick time.Duration) *Scheduler {
+	return &Scheduler{
+		elector: elector,
+		clock:   clock.System,
+		tick:    tick,
+		lastRun: make(map[string]time.Time),
+		runs:    make(map[string]JobStatus),
+	}
+}
+
+// SetClock подменяет источник времени для сроков задач (вызывать до Run)
+func (s *Scheduler) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Register добавляет задачу (вызывать до Run)
+func (s *Scheduler) Register(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// IsLeader сообщает, выполняет ли эта реплика фоновые задачи
+func (s *Scheduler) IsLeader() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.leader
+}
+
+// JobStatuses возвращает последние запуски задач в порядке регистрации.
+// Задачи выполняет только лидер, на остальных репликах запусков нет.
+func (s *Scheduler) JobStatuses() []JobStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status, ok := s.runs[job.Name]
+		if !ok {
+			status = JobStatus{Name: job.Name, Interval: job.Interval}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Run выполняет цикл планировщика до отмены контекста
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, s.clock.Now())
+
+		select {
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := s.elector.Release(releaseCtx); err != nil {
+				log.Printf("Scheduler: failed to release leadership: %v", err)
+			}
+			cancel()
+			s.setLeader(false)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce подтверждает лидерство и запускает задачи, у которых подошел срок
+func (s *Scheduler) runOnce(ctx context.Context, now time.Time) {
+	leader, err := s.elector.TryAcquire(ctx)
+	if err != nil {
+		log.Printf("Scheduler: leader election failed: %v", err)
+		leader = false
+	}
+
+	if leader != s.IsLeader() {
+		if leader {
+			log.Println("Scheduler: acquired leadership")
+		} else {
+			log.Println("Scheduler: lost leadership")
+		}
+		s.setLeader(leader)
+	}
+	if !leader {
+		return
+	}
+
+	for _, job := range s.jobs {
+		if last, ok := s.lastRun[job.Name]; ok && now.Sub(last) < job.Interval {
+			continue
+		}
+		s.lastRun[job.Name] = now
+
+		started := time.Now()
+		err := job.Run(ctx)
+		status := JobStatus{Name: job.Name, Interval: job.Interval, LastRun: now, Duration: time.Since(started)}
+		if err != nil {
+			log.Printf("Scheduler: job %s failed: %v", job.Name, err)
+			status.Err = err.Error()
+		}
+		s.recordRun(status)
+	}
+}
+
+func (s *Scheduler) recordRun(status JobStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[status.Name] = status
+}
+
+func (s *Scheduler) setLeader(leader bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leader = leader
+}
@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeElector управляет лидерством в тестах
+type fakeElector struct {
+	leader   bool
+	err      error
+	released bool
+}
+
+func (f *fakeElector) TryAcquire(ctx context.Context) (bool, error) {
+	return f.leader, f.err
+}
+
+func (f *fakeElector) Release(ctx context.Context) error {
+	f.released = true
+	return nil
+}
+
+func TestSchedulerRunsJobsOnlyOnLeader(t *testing.T) {
+	elector := &fakeElector{}
+	s := New(elector, time.Second)
+
+	runs := 0
+	s.Register(Job{
+		Name:     "retention",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			runs++
+			return nil
+		},
+	})
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	t.Run("Follower does not run jobs", func(t *testing.T) {
+		s.runOnce(ctx, now)
+		assert.False(t, s.IsLeader())
+		assert.Equal(t, 0, runs)
+	})
+
+	t.Run("Leader runs due jobs", func(t *testing.T) {
+		elector.leader = true
+		s.runOnce(ctx, now)
+		assert.True(t, s.IsLeader())
+		assert.Equal(t, 1, runs)
+	})
+
+	t.Run("Job is not repeated before interval", func(t *testing.T) {
+		s.runOnce(ctx, now.Add(30*time.Second))
+		assert.Equal(t, 1, runs)
+
+		s.runOnce(ctx, now.Add(time.Minute))
+		assert.Equal(t, 2, runs)
+	})
+
+	t.Run("Election error drops leadership", func(t *testing.T) {
+		elector.err = errors.New("connection refused")
+		s.runOnce(ctx, now.Add(time.Hour))
+		assert.False(t, s.IsLeader())
+		assert.Equal(t, 2, runs)
+	})
+}
+
+func TestSchedulerReleasesOnShutdown(t *testing.T) {
+	elector := &fakeElector{leader: true}
+	s := New(elector, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.Run(ctx)
+
+	assert.True(t, elector.released)
+	assert.False(t, s.IsLeader())
+}
+
+func TestSchedulerJobStatuses(t *testing.T) {
+	s := New(&fakeElector{leader: true}, time.Second)
+	s.Register(Job{Name: "queue_drain", Interval: time.Minute, Run: func(ctx context.Context) error { return nil }})
+	s.Register(Job{Name: "retention", Interval: time.Hour, Run: func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}})
+
+	statuses := s.JobStatuses()
+	assert.Len(t, statuses, 2)
+	assert.True(t, statuses[0].LastRun.IsZero())
+	assert.Equal(t, time.Minute, statuses[0].Interval)
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.runOnce(context.Background(), now)
+
+	statuses = s.JobStatuses()
+	assert.Equal(t, "queue_drain", statuses[0].Name)
+	assert.Equal(t, now, statuses[0].LastRun)
+	assert.Empty(t, statuses[0].Err)
+	assert.Equal(t, "retention", statuses[1].Name)
+	assert.Equal(t, "connection refused", statuses[1].Err)
+}
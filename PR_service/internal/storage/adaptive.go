@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// adaptReviewerCount пересчитывает состояние адаптивной политики команды по ее бэклогу
+// (без PR skipPR) и возвращает число ревьюеров для назначения. Каждое переключение
+// сохраняется в team_settings и записывается в журнал аудита и outbox.
+func (s *StorageData) adaptReviewerCount(ctx context.Context, tx *sql.Tx, settings models.TeamSettings, skipPR string) (int, error) {
+	if settings.AdaptiveBacklogThreshold <= 0 {
+		return settings.ReviewerCount, nil
+	}
+
+	backlog, err := s.reviewBacklog(ctx, tx, settings.TeamName, skipPR)
+	if err != nil {
+		return 0, err
+	}
+
+	reduced := domain.ReviewerCountReduced(settings, backlog)
+	if reduced == settings.ReviewerCountReduced {
+		return domain.EffectiveReviewerCount(settings, reduced), nil
+	}
+
+	// Параллельный CreatePR мог уже переключить состояние - тогда записывать нечего
+	res, err := s.txExecWithMetrics(tx, ctx, "update", "team_settings",
+		`UPDATE team_settings SET reviewer_count_reduced = $2
+		 WHERE team_name = $1 AND reviewer_count_reduced = $3`,
+		settings.TeamName, reduced, settings.ReviewerCountReduced)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return domain.EffectiveReviewerCount(settings, reduced), nil
+	}
+
+	action := "reduce_reviewer_count"
+	if !reduced {
+		action = "restore_reviewer_count"
+	}
+	count := domain.EffectiveReviewerCount(settings, reduced)
+	details := map[string]interface{}{
+		"backlog":                    backlog,
+		"adaptive_backlog_threshold": settings.AdaptiveBacklogThreshold,
+		"from":                       domain.EffectiveReviewerCount(settings, !reduced),
+		"to":                         count,
+	}
+	before := settings
+	after := settings
+	after.ReviewerCountReduced = reduced
+	if err := s.recordAuditChange(ctx, tx, AuditEntityTeamSettings, settings.TeamName, action, details, before, after); err != nil {
+		return 0, err
+	}
+	if err := s.recordEvent(ctx, tx, EventTeamAdapted, settings.TeamName, models.AdaptiveReviewerState{
+		TeamName:      settings.TeamName,
+		Backlog:       backlog,
+		ReviewerCount: count,
+		Reduced:       reduced,
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// reviewBacklog возвращает число открытых PR авторов команды, ждущих ревью:
+// без ревьюеров или с хотя бы одним неодобренным ревью
+func (s *StorageData) reviewBacklog(ctx context.Context, tx *sql.Tx, teamName, skipPR string) (int, error) {
+	var backlog int
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT COUNT(*) FROM pull_requests p
+		 WHERE p.status = 'OPEN' AND p.pull_request_id <> $2
+		   AND p.author_id IN (SELECT user_id FROM team_members WHERE team_name = $1)
+		   AND (NOT EXISTS (SELECT 1 FROM pr_reviewers r WHERE r.pull_request_id = p.pull_request_id)
+		        OR EXISTS (SELECT 1 FROM pr_reviewers r
+		                   WHERE r.pull_request_id = p.pull_request_id AND r.approved_at IS NULL))`,
+		teamName, skipPR).Scan(&backlog)
+	return backlog, err
+}
+
+// AdaptiveReviewerStates возвращает бэклог и действующее число ревьюеров команд
+// с включенной адаптивной политикой
+func (s *StorageData) AdaptiveReviewerStates(ctx context.Context) ([]models.AdaptiveReviewerState, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "team_settings",
+		`SELECT team_name FROM team_settings WHERE adaptive_backlog_threshold > 0 ORDER BY team_name`)
+	if err != nil {
+		return nil, err
+	}
+	var teams []string
+	for rows.Next() {
+		var team string
+		if err := rows.Scan(&team); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	states := make([]models.AdaptiveReviewerState, 0, len(teams))
+	for _, team := range teams {
+		settings, err := s.getTeamSettings(ctx, tx, team)
+		if err != nil {
+			return nil, err
+		}
+		backlog, err := s.reviewBacklog(ctx, tx, team, "")
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, models.AdaptiveReviewerState{
+			TeamName:      team,
+			Backlog:       backlog,
+			ReviewerCount: domain.EffectiveReviewerCount(settings, settings.ReviewerCountReduced),
+			Reduced:       settings.ReviewerCountReduced,
+		})
+	}
+	return states, tx.Commit()
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/jsondiff"
+	"PR_service/internal/models"
+)
+
+// Типы сущностей журнала аудита
+const (
+	AuditEntityTeam         = "team"
+	AuditEntityUser         = "user"
+	AuditEntityPullRequest  = "pull_request"
+	AuditEntityChecklist    = "checklist_item"
+	AuditEntityMaintenance  = "maintenance"
+	AuditEntityTeamSettings = "team_settings"
+	AuditEntityWebhookKey   = "webhook_signing_key"
+)
+
+// DefaultAuditSearchLimit - число записей в ответе поиска, если limit не задан
+const DefaultAuditSearchLimit = 100
+
+// MaxAuditSearchLimit - верхняя граница limit для поиска и выгрузки
+const MaxAuditSearchLimit = 10000
+
+// auditSchemas - схемы сущностей для сравнения снимков записей аудита
+var auditSchemas = map[string]jsondiff.Schema{
+	AuditEntityTeam: {Sets: []string{"leads"}},
+}
+
+// recordAudit добавляет запись в журнал аудита в рамках транзакции изменения.
+// Автор и request id берутся из контекста запроса.
+func (s *StorageData) recordAudit(ctx context.Context, tx *sql.Tx, entityType, entityID, action string, details interface{}) error {
+	return s.recordAuditChange(ctx, tx, entityType, entityID, action, details, nil, nil)
+}
+
+// recordAuditChange добавляет запись аудита со снимками сущности до и после изменения,
+// по которым /audit/diff показывает, что именно изменилось. nil - сущности не было
+// (создание) или не стало (удаление).
+func (s *StorageData) recordAuditChange(ctx context.Context, tx *sql.Tx, entityType, entityID, action string,
+	details, before, after interface{}) error {
+	detailsJSON := []byte("{}")
+	if details != nil {
+		var err error
+		if detailsJSON, err = json.Marshal(details); err != nil {
+			return err
+		}
+	}
+	beforeJSON, err := marshalSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.txExecWithMetrics(tx, ctx, "insert", "audit_log",
+		`INSERT INTO audit_log(actor, request_id, entity_type, entity_id, action, details, before_state, after_state, created_at)
+		 VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+		authctx.ActorID(ctx), authctx.RequestIDFrom(ctx), entityType, entityID, action, string(detailsJSON),
+		beforeJSON, afterJSON, s.now())
+	return err
+}
+
+// marshalSnapshot кодирует снимок сущности; nil - NULL в БД
+func marshalSnapshot(snapshot interface{}) (sql.NullString, error) {
+	if snapshot == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// GetAuditDiff возвращает запись аудита и изменения сущности между ее снимками
+func (s *StorageData) GetAuditDiff(ctx context.Context, id int64) (*models.AuditDiff, error) {
+	var e models.AuditEvent
+	var details, before, after []byte
+	err := s.queryRowWithMetrics(ctx, "select", "audit_log",
+		`SELECT id, actor, request_id, entity_type, entity_id, action, details, before_state, after_state, created_at
+		 FROM audit_log WHERE id = $1`, id).
+		Scan(&e.ID, &e.Actor, &e.RequestID, &e.EntityType, &e.EntityID, &e.Action, &details, &before, &after, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("audit entry not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if before == nil && after == nil {
+		return nil, fmt.Errorf("audit entry has no snapshots")
+	}
+	e.Details = json.RawMessage(details)
+	e.Before = json.RawMessage(before)
+	e.After = json.RawMessage(after)
+
+	changes, err := jsondiff.Diff(e.Before, e.After, auditSchemas[e.EntityType])
+	if err != nil {
+		return nil, fmt.Errorf("decode audit event %d: %w", e.ID, err)
+	}
+
+	diff := &models.AuditDiff{Event: e, Changes: make([]models.AuditChange, 0, len(changes))}
+	for _, c := range changes {
+		diff.Changes = append(diff.Changes, models.AuditChange{Path: c.Path, Op: c.Op, Before: c.Before, After: c.After})
+	}
+	return diff, nil
+}
+
+// SearchAuditLog возвращает записи журнала аудита по фильтру, новые первыми
+func (s *StorageData) SearchAuditLog(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAuditSearchLimit
+	}
+	if filter.Limit > MaxAuditSearchLimit {
+		filter.Limit = MaxAuditSearchLimit
+	}
+
+	events := []models.AuditEvent{}
+	err := s.StreamAuditLog(ctx, filter, func(e models.AuditEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// StreamAuditLog передает записи журнала аудита по фильтру в fn по одной, новые первыми,
+// не накапливая их в памяти. Limit 0 - без ограничения. Ошибка fn прерывает чтение.
+func (s *StorageData) StreamAuditLog(ctx context.Context, filter models.AuditFilter, fn func(models.AuditEvent) error) error {
+	var from, to sql.NullTime
+	if filter.From != nil {
+		from = sql.NullTime{Time: *filter.From, Valid: true}
+	}
+	if filter.To != nil {
+		to = sql.NullTime{Time: *filter.To, Valid: true}
+	}
+	var limit sql.NullInt64
+	if filter.Limit > 0 {
+		limit = sql.NullInt64{Int64: int64(filter.Limit), Valid: true}
+	}
+
+	rows, err := s.queryWithMetrics(ctx, "select", "audit_log",
+		`SELECT id, actor, request_id, entity_type, entity_id, action, details, before_state, after_state, created_at
+		 FROM audit_log
+		 WHERE ($1::text = '' OR actor = $1)
+		   AND ($2::text = '' OR entity_type = $2)
+		   AND ($3::text = '' OR action = $3)
+		   AND ($4::timestamptz IS NULL OR created_at >= $4)
+		   AND ($5::timestamptz IS NULL OR created_at < $5)
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $6`,
+		filter.Actor, filter.EntityType, filter.Action, from, to, limit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AuditEvent
+		var details, before, after []byte
+		if err := rows.Scan(&e.ID, &e.Actor, &e.RequestID, &e.EntityType, &e.EntityID,
+			&e.Action, &details, &before, &after, &e.CreatedAt); err != nil {
+			return err
+		}
+		if !json.Valid(details) {
+			return fmt.Errorf("decode audit event %d: invalid details", e.ID)
+		}
+		e.Details = json.RawMessage(details)
+		if before != nil {
+			e.Before = json.RawMessage(before)
+		}
+		if after != nil {
+			e.After = json.RawMessage(after)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// PurgeAuditLog удаляет записи журнала аудита старше before
+func (s *StorageData) PurgeAuditLog(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.execWithMetrics(ctx, "delete", "audit_log",
+		`DELETE FROM audit_log WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
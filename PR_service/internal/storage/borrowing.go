@@ -0,0 +1,334 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// SetBorrowPool заменяет список команд, у которых команда может занимать ревьюеров
+func (s *StorageData) SetBorrowPool(ctx context.Context, teamName string, lenders []models.BorrowLender) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, name := range append([]string{teamName}, lenderNames(lenders)...) {
+		if err := s.ensureTeamExists(ctx, tx, name); err != nil {
+			return err
+		}
+	}
+
+	before, err := s.getBorrowPool(ctx, tx, teamName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "delete", "team_borrow_pools",
+		`DELETE FROM team_borrow_pools WHERE team_name = $1`, teamName); err != nil {
+		return err
+	}
+
+	for i, l := range lenders {
+		if _, err := s.txExecWithMetrics(tx, ctx, "insert", "team_borrow_pools",
+			`INSERT INTO team_borrow_pools(team_name, lender_team, priority, max_net_borrowed) VALUES($1,$2,$3,$4)
+			 ON CONFLICT (team_name, lender_team) DO UPDATE SET max_net_borrowed = EXCLUDED.max_net_borrowed`,
+			teamName, l.TeamName, i, l.MaxNetBorrowed); err != nil {
+			return err
+		}
+	}
+
+	after, err := s.getBorrowPool(ctx, tx, teamName)
+	if err != nil {
+		return err
+	}
+	if err := s.recordAuditChange(ctx, tx, AuditEntityTeam, teamName, "set_borrow_pool", map[string]interface{}{
+		"lenders": lenders,
+	}, map[string]interface{}{"lenders": before}, map[string]interface{}{"lenders": after}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetBorrowPool возвращает команды-доноры в порядке приоритета
+func (s *StorageData) GetBorrowPool(ctx context.Context, teamName string) ([]models.BorrowLender, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	lenders, err := s.getBorrowPool(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	return lenders, tx.Commit()
+}
+
+func (s *StorageData) getBorrowPool(ctx context.Context, tx *sql.Tx, teamName string) ([]models.BorrowLender, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "team_borrow_pools",
+		`SELECT lender_team, max_net_borrowed FROM team_borrow_pools
+		 WHERE team_name = $1 ORDER BY priority, lender_team`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lenders := []models.BorrowLender{}
+	for rows.Next() {
+		var l models.BorrowLender
+		if err := rows.Scan(&l.TeamName, &l.MaxNetBorrowed); err != nil {
+			return nil, err
+		}
+		lenders = append(lenders, l)
+	}
+	return lenders, rows.Err()
+}
+
+// borrowReviewers добирает недостающих ревьюеров из команд-доноров с учетом лимитов.
+// Каждое заимствование фиксируется в review_borrows для отчета о балансе. Ревьюеры
+// доноров выбираются по стратегии и выражениям кандидатов команды-заемщика среди тех,
+// кто не превысил max_open_reviews своей команды.
+func (s *StorageData) borrowReviewers(ctx context.Context, tx *sql.Tx, settings models.TeamSettings,
+	meta models.AssignmentPRMetadata, exclude []string, need int) ([]string, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "team_borrow_pools",
+		`SELECT p.lender_team, p.max_net_borrowed,
+		   (SELECT COUNT(*) FROM review_borrows b WHERE b.borrower_team = p.team_name AND b.lender_team = p.lender_team)
+		 - (SELECT COUNT(*) FROM review_borrows b WHERE b.borrower_team = p.lender_team AND b.lender_team = p.team_name)
+		 FROM team_borrow_pools p
+		 WHERE p.team_name = $1
+		 ORDER BY p.priority, p.lender_team`, meta.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	type lender struct {
+		team string
+		max  int
+		net  int
+	}
+	var lenders []lender
+	for rows.Next() {
+		var l lender
+		if err := rows.Scan(&l.team, &l.max, &l.net); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lenders = append(lenders, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[string]bool, len(exclude)+1)
+	excluded[meta.AuthorID] = true
+	for _, uid := range exclude {
+		excluded[uid] = true
+	}
+
+	var borrowed []string
+	for _, l := range lenders {
+		if len(borrowed) >= need {
+			break
+		}
+
+		quota := need - len(borrowed)
+		if l.max > 0 {
+			if l.net >= l.max {
+				continue
+			}
+			if remaining := l.max - l.net; remaining < quota {
+				quota = remaining
+			}
+		}
+
+		candidates, err := s.getActiveTeamMembers(ctx, tx, l.team, excluded)
+		if err != nil {
+			return nil, err
+		}
+		// Предел открытых ревью задает команда-донор: ее перегруженных участников не занимают
+		lenderSettings, err := s.getTeamSettings(ctx, tx, l.team)
+		if err != nil {
+			return nil, err
+		}
+		if candidates, _, err = s.filterByCapacity(ctx, tx, candidates, lenderSettings.MaxOpenReviews); err != nil {
+			return nil, err
+		}
+
+		input, err := s.buildAssignmentInput(ctx, tx, settings, meta.PullRequestID, candidates, quota)
+		if err != nil {
+			return nil, err
+		}
+		selected := domain.SelectReviewers(input)
+
+		lenderMeta := meta
+		lenderMeta.TeamName = l.team
+		if err := s.recordAssignmentDecision(ctx, tx, "borrow", lenderMeta, input, selected); err != nil {
+			return nil, err
+		}
+
+		for _, uid := range selected {
+			if _, err := s.txExecWithMetrics(tx, ctx, "insert", "review_borrows",
+				`INSERT INTO review_borrows(pull_request_id, user_id, borrower_team, lender_team, created_at) VALUES($1,$2,$3,$4,$5)`,
+				meta.PullRequestID, uid, meta.TeamName, l.team, s.now()); err != nil {
+				return nil, err
+			}
+			excluded[uid] = true
+			borrowed = append(borrowed, uid)
+		}
+	}
+
+	return borrowed, nil
+}
+
+// getActiveTeamMembers возвращает активных участников команды, кроме excluded
+func (s *StorageData) getActiveTeamMembers(ctx context.Context, tx *sql.Tx, teamName string, excluded map[string]bool) ([]string, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "users", activeTeamMembersQuery, teamName, s.now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		if !excluded[uid] {
+			members = append(members, uid)
+		}
+	}
+	return members, rows.Err()
+}
+
+// GetBorrowingReport возвращает баланс заимствований ревьюеров между парами команд.
+// Если teamName не пуст, отчет строится только для этой команды.
+func (s *StorageData) GetBorrowingReport(ctx context.Context, teamName string) ([]models.BorrowingBalance, error) {
+	rows, err := s.queryWithMetrics(ctx, "select", "review_borrows",
+		`SELECT borrower_team, lender_team, COUNT(*) FROM review_borrows
+		 WHERE $1::text = '' OR borrower_team = $1 OR lender_team = $1
+		 GROUP BY borrower_team, lender_team`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flows []borrowFlow
+	for rows.Next() {
+		var f borrowFlow
+		if err := rows.Scan(&f.borrower, &f.lender, &f.count); err != nil {
+			return nil, err
+		}
+		flows = append(flows, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	capRows, err := s.queryWithMetrics(ctx, "select", "team_borrow_pools",
+		`SELECT team_name, lender_team, max_net_borrowed FROM team_borrow_pools
+		 WHERE $1::text = '' OR team_name = $1 OR lender_team = $1`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer capRows.Close()
+
+	caps := make(map[[2]string]int)
+	for capRows.Next() {
+		var borrower, lender string
+		var limit int
+		if err := capRows.Scan(&borrower, &lender, &limit); err != nil {
+			return nil, err
+		}
+		caps[[2]string{borrower, lender}] = limit
+	}
+	if err := capRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buildBorrowingBalances(flows, caps, teamName), nil
+}
+
+type borrowFlow struct {
+	borrower string
+	lender   string
+	count    int
+}
+
+// buildBorrowingBalances сводит потоки заимствований в балансы с точки зрения каждой команды
+func buildBorrowingBalances(flows []borrowFlow, caps map[[2]string]int, teamName string) []models.BorrowingBalance {
+	balances := make(map[[2]string]*models.BorrowingBalance)
+	get := func(team, other string) *models.BorrowingBalance {
+		key := [2]string{team, other}
+		if balances[key] == nil {
+			balances[key] = &models.BorrowingBalance{
+				TeamName:       team,
+				OtherTeam:      other,
+				MaxNetBorrowed: caps[key],
+			}
+		}
+		return balances[key]
+	}
+
+	for _, f := range flows {
+		get(f.borrower, f.lender).Borrowed += f.count
+		get(f.lender, f.borrower).Lent += f.count
+	}
+	// Пулы без заимствований тоже попадают в отчет
+	for key := range caps {
+		get(key[0], key[1])
+	}
+
+	result := make([]models.BorrowingBalance, 0, len(balances))
+	for _, b := range balances {
+		if teamName != "" && b.TeamName != teamName {
+			continue
+		}
+		b.Net = b.Borrowed - b.Lent
+		result = append(result, *b)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TeamName != result[j].TeamName {
+			return result[i].TeamName < result[j].TeamName
+		}
+		return result[i].OtherTeam < result[j].OtherTeam
+	})
+	return result
+}
+
+func lenderNames(lenders []models.BorrowLender) []string {
+	names := make([]string, 0, len(lenders))
+	for _, l := range lenders {
+		names = append(names, l.TeamName)
+	}
+	return names
+}
+
+// getPRCandidates возвращает активных кандидатов в ревьюеры PR автора authorID из команды,
+// кроме excluded
+func (s *StorageData) getPRCandidates(ctx context.Context, tx *sql.Tx, teamName, authorID string, excluded map[string]bool) ([]string, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "users", prCandidatesQuery, teamName, authorID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		if !excluded[uid] {
+			candidates = append(candidates, uid)
+		}
+	}
+	return candidates, rows.Err()
+}
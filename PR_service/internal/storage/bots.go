@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"PR_service/internal/models"
+)
+
+// EnableBotAuthors включает автосоздание ботов-авторов: PR с author_is_bot от неизвестного
+// автора создает бота в команде teamName вместо ошибки "author not found".
+// Так вебхук-интеграции не требуют заранее заводить каждого бота через /team/add.
+func (s *StorageData) EnableBotAuthors(teamName string) {
+	s.botAuthorsTeam = teamName
+}
+
+// createBotAuthor заводит активного бота в команде ботов-авторов
+func (s *StorageData) createBotAuthor(ctx context.Context, tx *sql.Tx, userID string) error {
+	if err := s.ensureTeamExists(ctx, tx, s.botAuthorsTeam); err != nil {
+		if err.Error() == "team not found" {
+			return fmt.Errorf("bot authors team not found")
+		}
+		return err
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "insert", "users",
+		`INSERT INTO users(user_id, username, team_name, is_active, is_bot) VALUES($1,$1,$2,true,true)`,
+		userID, s.botAuthorsTeam); err != nil {
+		return err
+	}
+	if _, err := s.txExecWithMetrics(tx, ctx, "insert", "team_members",
+		`INSERT INTO team_members(team_name, user_id, joined_at) VALUES($1,$2,$3)`, s.botAuthorsTeam, userID, s.now()); err != nil {
+		return err
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityUser, userID, "auto_create_bot", map[string]interface{}{
+		"team_name": s.botAuthorsTeam,
+	}); err != nil {
+		return err
+	}
+	return s.recordEvent(ctx, tx, EventUserUpserted, userID, models.User{
+		UserID:   userID,
+		Username: userID,
+		TeamName: s.botAuthorsTeam,
+		IsActive: true,
+		IsBot:    true,
+	})
+}
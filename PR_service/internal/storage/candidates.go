@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"PR_service/internal/expr"
+)
+
+// getCandidateVars собирает значения переменных выражений для каждого кандидата на PR prID
+func (s *StorageData) getCandidateVars(ctx context.Context, tx *sql.Tx, prID string, candidates []string, focused map[string]bool) (map[string]expr.Vars, error) {
+	vars := make(map[string]expr.Vars, len(candidates))
+	if len(candidates) == 0 {
+		return vars, nil
+	}
+
+	loads, err := s.getOpenReviewLoads(ctx, tx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	prTags, err := s.getPRTags(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if prTags == nil {
+		prTags = []string{}
+	}
+	reviewedTags, err := s.getReviewedTags(ctx, tx, prID, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	tenure := make(map[string]float64, len(candidates))
+	leads := make(map[string]bool)
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "team_members",
+		`SELECT tm.user_id, MIN(tm.joined_at),
+		        EXISTS(SELECT 1 FROM team_leads l WHERE l.user_id = tm.user_id)
+		 FROM team_members tm
+		 WHERE tm.user_id = ANY($1)
+		 GROUP BY tm.user_id`, candidates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var uid string
+		var joinedAt sql.NullTime
+		var lead bool
+		if err := rows.Scan(&uid, &joinedAt, &lead); err != nil {
+			return nil, err
+		}
+		if joinedAt.Valid {
+			tenure[uid] = float64(int(now.Sub(joinedAt.Time).Hours() / 24))
+		} else {
+			tenure[uid] = -1
+		}
+		leads[uid] = lead
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, uid := range candidates {
+		tags := reviewedTags[uid]
+		if tags == nil {
+			tags = []string{}
+		}
+		shared := 0
+		for _, tag := range prTags {
+			if containsString(tags, tag) {
+				shared++
+			}
+		}
+		t, ok := tenure[uid]
+		if !ok {
+			t = -1
+		}
+		vars[uid] = expr.Vars{
+			"load":        float64(loads[uid]),
+			"tenure_days": t,
+			"is_lead":     leads[uid],
+			"in_focus":    focused[uid],
+			"tags":        tags,
+			"pr_tags":     prTags,
+			"shared_tags": float64(shared),
+		}
+	}
+	return vars, nil
+}
+
+// getReviewedTags возвращает теги PR (кроме prID), которые каждый кандидат отревьюил
+// за KnowledgeSpreadWindow, по алфавиту
+func (s *StorageData) getReviewedTags(ctx context.Context, tx *sql.Tx, prID string, candidates []string) (map[string][]string, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_tags",
+		`SELECT DISTINCT r.user_id, t.tag
+		 FROM pr_reviewers r
+		 JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		 JOIN pr_tags t ON t.pull_request_id = r.pull_request_id
+		 WHERE r.user_id = ANY($1)
+		   AND r.pull_request_id <> $2
+		   AND COALESCE(r.approved_at, p.merged_at) >= $3
+		 ORDER BY r.user_id, t.tag`, candidates, prID, s.now().Add(-KnowledgeSpreadWindow))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string, len(candidates))
+	for rows.Next() {
+		var uid, tag string
+		if err := rows.Scan(&uid, &tag); err != nil {
+			return nil, err
+		}
+		tags[uid] = append(tags[uid], tag)
+	}
+	return tags, rows.Err()
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
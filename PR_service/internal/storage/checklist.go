@@ -0,0 +1,426 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+// GetChecklist возвращает активные пункты чек-листа ревью команды
+func (s *StorageData) GetChecklist(ctx context.Context, teamName string) ([]models.ChecklistItem, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	items, err := s.getChecklistItems(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	return items, tx.Commit()
+}
+
+// AddChecklistItem добавляет пункт в конец чек-листа команды
+func (s *StorageData) AddChecklistItem(ctx context.Context, teamName, text string) (*models.ChecklistItem, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	item := models.ChecklistItem{TeamName: teamName, Text: text}
+	err = s.txQueryRowWithMetrics(tx, ctx, "insert", "checklist_items",
+		`INSERT INTO checklist_items(team_name, text, position)
+		 VALUES($1, $2, (SELECT COALESCE(MAX(position), 0) + 1 FROM checklist_items WHERE team_name = $1))
+		 RETURNING item_id, position`, teamName, text).Scan(&item.ItemID, &item.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordAuditChange(ctx, tx, AuditEntityChecklist, fmt.Sprint(item.ItemID), "add", item, nil, item); err != nil {
+		return nil, err
+	}
+	return &item, tx.Commit()
+}
+
+// UpdateChecklistItem меняет текст пункта чек-листа
+func (s *StorageData) UpdateChecklistItem(ctx context.Context, itemID int64, text string) (*models.ChecklistItem, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := s.lockChecklistItem(ctx, tx, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	item := *before
+	item.Text = text
+	if _, err := s.txExecWithMetrics(tx, ctx, "update", "checklist_items",
+		`UPDATE checklist_items SET text = $2 WHERE item_id = $1`, itemID, text); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordAuditChange(ctx, tx, AuditEntityChecklist, fmt.Sprint(itemID), "update", item, before, item); err != nil {
+		return nil, err
+	}
+	return &item, tx.Commit()
+}
+
+// DeleteChecklistItem убирает пункт из шаблона. Пункт архивируется, чтобы
+// отметки в уже проведенных ревью сохранились для отчетов.
+func (s *StorageData) DeleteChecklistItem(ctx context.Context, itemID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := s.lockChecklistItem(ctx, tx, itemID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "update", "checklist_items",
+		`UPDATE checklist_items SET archived_at = $2 WHERE item_id = $1`, itemID, s.now()); err != nil {
+		return err
+	}
+
+	if err := s.recordAuditChange(ctx, tx, AuditEntityChecklist, fmt.Sprint(itemID), "delete", nil, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockChecklistItem читает действующий пункт чек-листа с блокировкой строки
+func (s *StorageData) lockChecklistItem(ctx context.Context, tx *sql.Tx, itemID int64) (*models.ChecklistItem, error) {
+	item := models.ChecklistItem{ItemID: itemID}
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "checklist_items",
+		`SELECT team_name, text, position FROM checklist_items
+		 WHERE item_id = $1 AND archived_at IS NULL FOR UPDATE`, itemID).
+		Scan(&item.TeamName, &item.Text, &item.Position)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("checklist item not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ApprovePR фиксирует одобрение PR ревьюером и отмеченные им пункты чек-листа
+func (s *StorageData) ApprovePR(ctx context.Context, prID, userID string, checkedItems []int64) (*models.PullRequestDetail, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status, authorID, prName string
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT status, author_id, pull_request_name FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE`,
+		prID).Scan(&status, &authorID, &prName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("pr not found")
+		}
+		return nil, err
+	}
+	if status == "MERGED" {
+		return nil, fmt.Errorf("pr already merged")
+	}
+
+	res, err := s.txExecWithMetrics(tx, ctx, "update", "pr_reviewers",
+		`UPDATE pr_reviewers SET approved_at = COALESCE(approved_at, $3)
+		 WHERE pull_request_id = $1 AND user_id = $2`, prID, userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, fmt.Errorf("reviewer is not assigned to this PR")
+	}
+
+	// Одобрение освобождает ревьюера для PR из очереди
+	if err := s.assignQueuedPRsForReviewers(ctx, tx, []string{userID}); err != nil {
+		return nil, err
+	}
+
+	teamName, err := s.getUserTeam(ctx, tx, authorID)
+	if err != nil && err.Error() != "user not in any team" {
+		return nil, err
+	}
+
+	// Одобрение может завершить этап цепочки: назначаем ревьюеров следующего
+	if teamName != "" {
+		settings, err := s.getTeamSettings(ctx, tx, teamName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.advanceApprovalStages(ctx, tx, models.AssignmentPRMetadata{
+			PullRequestID:   prID,
+			PullRequestName: prName,
+			AuthorID:        authorID,
+			TeamName:        teamName,
+		}, settings); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(checkedItems) > 0 {
+		if teamName == "" {
+			return nil, fmt.Errorf("user not in any team")
+		}
+
+		active := make(map[int64]bool)
+		items, err := s.getChecklistItems(ctx, tx, teamName)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			active[item.ItemID] = true
+		}
+
+		for _, itemID := range checkedItems {
+			if !active[itemID] {
+				return nil, fmt.Errorf("checklist item not found")
+			}
+			if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_checklist_marks",
+				`INSERT INTO pr_checklist_marks(pull_request_id, user_id, item_id) VALUES($1,$2,$3)
+				 ON CONFLICT DO NOTHING`, prID, userID, itemID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	detail, err := s.getPRDetail(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, prID, "approve", map[string]interface{}{
+		"reviewer_id":   userID,
+		"checked_items": checkedItems,
+	}); err != nil {
+		return nil, err
+	}
+	return detail, tx.Commit()
+}
+
+// GetPRDetail возвращает PR вместе с одобрениями и прогрессом по чек-листу
+func (s *StorageData) GetPRDetail(ctx context.Context, prID string) (*models.PullRequestDetail, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	detail, err := s.getPRDetail(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	return detail, tx.Commit()
+}
+
+func (s *StorageData) getPRDetail(ctx context.Context, tx *sql.Tx, prID string) (*models.PullRequestDetail, error) {
+	var detail models.PullRequestDetail
+	var mergedAt sql.NullTime
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
+		 FROM pull_requests WHERE pull_request_id = $1`, prID).
+		Scan(&detail.PullRequestID, &detail.PullRequestName, &detail.AuthorID, &detail.Status,
+			&detail.CreatedAt, &mergedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("pr not found")
+		}
+		return nil, err
+	}
+	if mergedAt.Valid {
+		mergedAtStr := mergedAt.Time.Format(time.RFC3339)
+		detail.MergedAt = &mergedAtStr
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_reviewers",
+		`SELECT user_id, approved_at IS NOT NULL FROM pr_reviewers
+		 WHERE pull_request_id = $1 ORDER BY user_id`, prID)
+	if err != nil {
+		return nil, err
+	}
+	detail.Reviewers = []string{}
+	detail.ApprovedBy = []string{}
+	for rows.Next() {
+		var uid string
+		var approved bool
+		if err := rows.Scan(&uid, &approved); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		detail.Reviewers = append(detail.Reviewers, uid)
+		if approved {
+			detail.ApprovedBy = append(detail.ApprovedBy, uid)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if detail.Tags, err = s.getPRTags(ctx, tx, prID); err != nil {
+		return nil, err
+	}
+
+	detail.Checklist = []models.ChecklistProgress{}
+	teamName, err := s.getUserTeam(ctx, tx, detail.AuthorID)
+	if err != nil {
+		if err.Error() == "user not in any team" {
+			return &detail, nil
+		}
+		return nil, err
+	}
+
+	settings, err := s.getTeamSettings(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	if len(settings.ApprovalStages) > 0 {
+		if detail.ApprovalStage, detail.ApprovalStages, err = s.getApprovalProgress(ctx, tx, prID, teamName, settings.ApprovalStages); err != nil {
+			return nil, err
+		}
+	}
+
+	items, err := s.getChecklistItems(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	checkedBy := make(map[int64][]string)
+	markRows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_checklist_marks",
+		`SELECT item_id, user_id FROM pr_checklist_marks WHERE pull_request_id = $1 ORDER BY user_id`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer markRows.Close()
+	for markRows.Next() {
+		var itemID int64
+		var uid string
+		if err := markRows.Scan(&itemID, &uid); err != nil {
+			return nil, err
+		}
+		checkedBy[itemID] = append(checkedBy[itemID], uid)
+	}
+	if err := markRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		progress := models.ChecklistProgress{ChecklistItem: item, CheckedBy: checkedBy[item.ItemID]}
+		if progress.CheckedBy == nil {
+			progress.CheckedBy = []string{}
+		}
+		detail.Checklist = append(detail.Checklist, progress)
+	}
+	return &detail, nil
+}
+
+// GetChecklistReport возвращает долю одобрений, в которых был отмечен каждый пункт чек-листа
+func (s *StorageData) GetChecklistReport(ctx context.Context, teamName string) (*models.ChecklistReport, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	report := &models.ChecklistReport{TeamName: teamName, Items: []models.ChecklistItemStats{}}
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "pr_reviewers",
+		`SELECT COUNT(*) FROM pr_reviewers r
+		 JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		 WHERE r.approved_at IS NOT NULL
+		   AND p.author_id IN (SELECT user_id FROM team_members WHERE team_name = $1)`, teamName).
+		Scan(&report.Approvals)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_checklist_marks",
+		`SELECT i.item_id, i.text, i.position, COUNT(m.item_id)
+		 FROM checklist_items i
+		 LEFT JOIN pr_checklist_marks m ON m.item_id = i.item_id
+		 WHERE i.team_name = $1 AND i.archived_at IS NULL
+		 GROUP BY i.item_id, i.text, i.position
+		 ORDER BY i.position`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var st models.ChecklistItemStats
+		if err := rows.Scan(&st.ItemID, &st.Text, &st.Position, &st.Checked); err != nil {
+			return nil, err
+		}
+		st.TeamName = teamName
+		st.CompletionRate = completionRate(st.Checked, report.Approvals)
+		report.Items = append(report.Items, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return report, tx.Commit()
+}
+
+// completionRate возвращает процент отмеченных пунктов среди одобрений
+func completionRate(checked, approvals int) float64 {
+	if approvals == 0 {
+		return 0
+	}
+	return float64(checked) / float64(approvals) * 100
+}
+
+func (s *StorageData) getChecklistItems(ctx context.Context, tx *sql.Tx, teamName string) ([]models.ChecklistItem, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "checklist_items",
+		`SELECT item_id, text, position FROM checklist_items
+		 WHERE team_name = $1 AND archived_at IS NULL ORDER BY position`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ChecklistItem{}
+	for rows.Next() {
+		item := models.ChecklistItem{TeamName: teamName}
+		if err := rows.Scan(&item.ItemID, &item.Text, &item.Position); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// getUserTeam возвращает команду пользователя (первую, как и при создании PR)
+func (s *StorageData) getUserTeam(ctx context.Context, tx *sql.Tx, userID string) (string, error) {
+	var teamName string
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "team_members", userTeamQuery, userID).Scan(&teamName)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("user not in any team")
+	}
+	return teamName, err
+}
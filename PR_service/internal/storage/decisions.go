@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// buildAssignmentInput собирает из БД все входные данные для выбора ревьюеров на PR prID
+// по стратегии и выражениям кандидатов из настроек команды
+func (s *StorageData) buildAssignmentInput(ctx context.Context, tx *sql.Tx, settings models.TeamSettings, prID string, candidates []string, count int) (domain.AssignmentInput, error) {
+	strategy := settings.Strategy
+	stats := domain.NewSelectionStats(len(candidates), time.Now())
+
+	// Исчерпавшие квоту назначений не выбираются, пока их окно не сдвинется
+	candidates, quotaDeferred, err := s.filterByQuota(ctx, tx, candidates)
+	if err != nil {
+		return domain.AssignmentInput{}, err
+	}
+	if slots := quotaDeferredSlots(count, len(candidates), len(quotaDeferred)); slots > 0 && s.metrics != nil {
+		s.metrics.IncAssignmentQuotaDeferred(settings.TeamName, slots)
+	}
+	stats.Filter(domain.SelectionRuleQuota, len(quotaDeferred))
+
+	// Принудительный выбор заменяет стратегию, фокус, отказы и выражения команды
+	if forced := ForcedReviewersFrom(ctx); len(forced) > 0 {
+		in := domain.NewAssignmentInput(strategy, time.Now().UnixNano(), candidates, map[string]bool{}, nil, count)
+		in.Forced = forced
+		in.QuotaDeferred = quotaDeferred
+		in.Stats = stats
+		return in, nil
+	}
+
+	// Кандидаты в окне фокуса назначаются только если без них не набрать ревьюеров
+	focused, err := s.getFocusedCandidates(ctx, tx, candidates, s.now())
+	if err != nil {
+		return domain.AssignmentInput{}, err
+	}
+
+	var loads map[string]int
+	switch strategy {
+	case domain.StrategyLeastLoaded:
+		loads, err = s.getOpenReviewLoads(ctx, tx, candidates)
+	case domain.StrategyKnowledgeSpread:
+		loads, err = s.getRecentTagReviews(ctx, tx, prID, candidates)
+	}
+	if err != nil {
+		return domain.AssignmentInput{}, err
+	}
+
+	// Прошлые отказы кандидатов исключают их или снижают их вес
+	vetoes, err := s.getVetoAdjustments(ctx, tx, prID, candidates)
+	if err != nil {
+		return domain.AssignmentInput{}, err
+	}
+
+	in := domain.NewAssignmentInput(strategy, time.Now().UnixNano(), candidates, focused, loads, count)
+	in.Stats = stats
+	in = vetoes.apply(in)
+	in.QuotaDeferred = quotaDeferred
+
+	// Фильтр и ранг команды вычисляются после отказов; решение хранит уже итоговые
+	// кандидатов и веса, поэтому повтор решения не вычисляет выражения заново
+	exprs, err := domain.CompileCandidateExpressions(settings)
+	if err != nil {
+		return domain.AssignmentInput{}, err
+	}
+	if exprs.Empty() {
+		return in, nil
+	}
+	vars, err := s.getCandidateVars(ctx, tx, prID, in.Candidates, focused)
+	if err != nil {
+		return domain.AssignmentInput{}, err
+	}
+	return exprs.Apply(in, vars), nil
+}
+
+// getOpenReviewLoads возвращает число открытых PR на ревью у каждого кандидата
+func (s *StorageData) getOpenReviewLoads(ctx context.Context, tx *sql.Tx, candidates []string) (map[string]int, error) {
+	loads := make(map[string]int, len(candidates))
+	if len(candidates) == 0 {
+		return loads, nil
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_reviewers",
+		`SELECT r.user_id, COUNT(*)
+		 FROM pr_reviewers r
+		 JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		 WHERE p.status = 'OPEN' AND r.user_id = ANY($1)
+		 GROUP BY r.user_id`, candidates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uid string
+		var load int
+		if err := rows.Scan(&uid, &load); err != nil {
+			return nil, err
+		}
+		loads[uid] = load
+	}
+	return loads, rows.Err()
+}
+
+// recordAssignmentDecision сохраняет входные данные и результат решения о назначении
+func (s *StorageData) recordAssignmentDecision(ctx context.Context, tx *sql.Tx, operation string,
+	meta models.AssignmentPRMetadata, in domain.AssignmentInput, selected []string) error {
+	meta.RequestedBy = authctx.ActorID(ctx)
+	meta.OnDutyReviewer = in.OnDuty
+	meta.FilteredOut = in.FilteredOut
+	meta.ExpressionErrors = in.ExpressionErrors
+	meta.ForcedReviewers = in.Forced
+	meta.QuotaDeferred = in.QuotaDeferred
+	meta.RequestID = authctx.RequestIDFrom(ctx)
+	s.observeSelection(operation, in, selected)
+
+	candidates, err := json.Marshal(in.Candidates)
+	if err != nil {
+		return err
+	}
+	weights, err := json.Marshal(in.Weights)
+	if err != nil {
+		return err
+	}
+	focused, err := json.Marshal(sortedKeys(in.Focused))
+	if err != nil {
+		return err
+	}
+	if selected == nil {
+		selected = []string{}
+	}
+	selectedJSON, err := json.Marshal(selected)
+	if err != nil {
+		return err
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.txExecWithMetrics(tx, ctx, "insert", "assignment_decisions",
+		`INSERT INTO assignment_decisions(pull_request_id, operation, strategy, seed, requested,
+		 candidates, weights, focused, selected, pr_metadata, created_at)
+		 VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`,
+		meta.PullRequestID, operation, in.Strategy, in.Seed, in.Count,
+		string(candidates), string(weights), string(focused), string(selectedJSON), string(metaJSON), s.now())
+	return err
+}
+
+// GetAssignmentDecisions возвращает журнал решений о назначении по PR в хронологическом порядке
+func (s *StorageData) GetAssignmentDecisions(ctx context.Context, prID string) ([]models.AssignmentDecision, error) {
+	rows, err := s.queryWithMetrics(ctx, "select", "assignment_decisions",
+		`SELECT id, pull_request_id, operation, strategy, seed, requested,
+		 candidates, weights, focused, selected, pr_metadata, created_at
+		 FROM assignment_decisions WHERE pull_request_id = $1 ORDER BY id`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	decisions := []models.AssignmentDecision{}
+	for rows.Next() {
+		d, err := scanAssignmentDecision(rows)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, *d)
+	}
+	return decisions, rows.Err()
+}
+
+// ReplayAssignmentDecision повторно выполняет выбор ревьюеров по сохраненным входным данным
+func (s *StorageData) ReplayAssignmentDecision(ctx context.Context, decisionID int64) (*models.AssignmentReplay, error) {
+	row := s.queryRowWithMetrics(ctx, "select", "assignment_decisions",
+		`SELECT id, pull_request_id, operation, strategy, seed, requested,
+		 candidates, weights, focused, selected, pr_metadata, created_at
+		 FROM assignment_decisions WHERE id = $1`, decisionID)
+
+	d, err := scanAssignmentDecision(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("decision not found")
+		}
+		return nil, err
+	}
+
+	if !domain.IsKnownStrategy(d.Strategy) {
+		return nil, fmt.Errorf("unknown strategy %q", d.Strategy)
+	}
+
+	focused := make(map[string]bool, len(d.Focused))
+	for _, uid := range d.Focused {
+		focused[uid] = true
+	}
+
+	replayed := domain.SelectReviewers(domain.AssignmentInput{
+		Strategy:   d.Strategy,
+		Seed:       d.Seed,
+		Candidates: d.Candidates,
+		Weights:    d.Weights,
+		Focused:    focused,
+		Count:      d.Requested,
+		OnDuty:     d.PRMetadata.OnDutyReviewer,
+		Forced:     d.PRMetadata.ForcedReviewers,
+	})
+	if replayed == nil {
+		replayed = []string{}
+	}
+
+	return &models.AssignmentReplay{
+		Decision: *d,
+		Replayed: replayed,
+		Matches:  equalStrings(d.Selected, replayed),
+	}, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAssignmentDecision(row rowScanner) (*models.AssignmentDecision, error) {
+	var d models.AssignmentDecision
+	var candidates, weights, focused, selected, meta []byte
+	if err := row.Scan(&d.ID, &d.PullRequestID, &d.Operation, &d.Strategy, &d.Seed, &d.Requested,
+		&candidates, &weights, &focused, &selected, &meta, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	for _, f := range []struct {
+		raw  []byte
+		dest interface{}
+	}{
+		{candidates, &d.Candidates},
+		{weights, &d.Weights},
+		{focused, &d.Focused},
+		{selected, &d.Selected},
+		{meta, &d.PRMetadata},
+	} {
+		if err := json.Unmarshal(f.raw, f.dest); err != nil {
+			return nil, fmt.Errorf("decode assignment decision %d: %w", d.ID, err)
+		}
+	}
+	return &d, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k, v := range set {
+		if v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PurgeAssignmentDecisions удаляет записи журнала решений старше before
+func (s *StorageData) PurgeAssignmentDecisions(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.execWithMetrics(ctx, "delete", "assignment_decisions",
+		`DELETE FROM assignment_decisions WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+// StreamPullRequests передает PR по фильтру в fn по одной в порядке создания.
+// Строки читаются из соединения по мере обработки, поэтому память не растет
+// с размером выгрузки. Ошибка fn или отмена ctx прерывает чтение.
+func (s *StorageData) StreamPullRequests(ctx context.Context, filter models.PullRequestExportFilter, fn func(models.PullRequest) error) error {
+	var from, to sql.NullTime
+	if filter.From != nil {
+		from = sql.NullTime{Time: *filter.From, Valid: true}
+	}
+	if filter.To != nil {
+		to = sql.NullTime{Time: *filter.To, Valid: true}
+	}
+
+	rows, err := s.queryWithMetrics(ctx, "select", "pull_requests",
+		`SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.status, p.created_at, p.merged_at,
+		        COALESCE((SELECT json_agg(r.user_id ORDER BY r.user_id)
+		                  FROM pr_reviewers r WHERE r.pull_request_id = p.pull_request_id), '[]')
+		 FROM pull_requests p
+		 WHERE ($1::text = '' OR p.status = $1)
+		   AND ($2::text = '' OR p.author_id IN (SELECT user_id FROM team_members WHERE team_name = $2))
+		   AND ($3::timestamptz IS NULL OR p.created_at >= $3)
+		   AND ($4::timestamptz IS NULL OR p.created_at < $4)
+		 ORDER BY p.created_at, p.pull_request_id`,
+		filter.Status, filter.TeamName, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pr models.PullRequest
+		var mergedAt sql.NullTime
+		var reviewers []byte
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status,
+			&pr.CreatedAt, &mergedAt, &reviewers); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(reviewers, &pr.Reviewers); err != nil {
+			return fmt.Errorf("decode reviewers of pr %s: %v", pr.PullRequestID, err)
+		}
+		if mergedAt.Valid {
+			mergedAtStr := mergedAt.Time.Format(time.RFC3339)
+			pr.MergedAt = &mergedAtStr
+		}
+		if err := fn(pr); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
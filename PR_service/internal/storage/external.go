@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/models"
+)
+
+// ProviderBitbucket - внешняя система Bitbucket Server; external_id - slug пользователя
+const ProviderBitbucket = "bitbucket"
+
+// MaxExternalIDLength - предел длины идентификатора пользователя внешней системы
+const MaxExternalIDLength = 255
+
+// SetExternalIdentity сопоставляет учетную запись внешней системы пользователю сервиса.
+// Пустой UserID удаляет сопоставление.
+func (s *StorageData) SetExternalIdentity(ctx context.Context, identity models.ExternalIdentity) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	action := "map_external_identity"
+	entityID := identity.UserID
+	if identity.UserID == "" {
+		action = "unmap_external_identity"
+		err := s.txQueryRowWithMetrics(tx, ctx, "delete", "external_identities",
+			`DELETE FROM external_identities WHERE provider = $1 AND external_id = $2 RETURNING user_id`,
+			identity.Provider, identity.ExternalID).Scan(&entityID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("external identity not found")
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		var exists bool
+		if err := s.txQueryRowWithMetrics(tx, ctx, "select", "users",
+			`SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)`, identity.UserID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("user not found")
+		}
+
+		if _, err := s.txExecWithMetrics(tx, ctx, "upsert", "external_identities",
+			`INSERT INTO external_identities(provider, external_id, user_id, updated_by, updated_at)
+			 VALUES($1,$2,$3,$4,$5)
+			 ON CONFLICT (provider, external_id) DO UPDATE SET user_id = EXCLUDED.user_id,
+			 updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+			identity.Provider, identity.ExternalID, identity.UserID, authctx.ActorID(ctx), s.now()); err != nil {
+			return err
+		}
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityUser, entityID, action, map[string]interface{}{
+		"provider":    identity.Provider,
+		"external_id": identity.ExternalID,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListExternalIdentities возвращает сопоставления учетных записей внешней системы
+func (s *StorageData) ListExternalIdentities(ctx context.Context, provider string) ([]models.ExternalIdentity, error) {
+	rows, err := s.queryWithMetrics(ctx, "select", "external_identities",
+		`SELECT provider, external_id, user_id FROM external_identities
+		 WHERE provider = $1 ORDER BY external_id`, provider)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.ExternalIdentity{}
+	for rows.Next() {
+		var identity models.ExternalIdentity
+		if err := rows.Scan(&identity.Provider, &identity.ExternalID, &identity.UserID); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// ResolveExternalIdentities возвращает пользователей сервиса по идентификаторам внешней
+// системы. Несопоставленных идентификаторов в результате нет.
+func (s *StorageData) ResolveExternalIdentities(ctx context.Context, provider string, externalIDs []string) (map[string]string, error) {
+	resolved := make(map[string]string, len(externalIDs))
+	if len(externalIDs) == 0 {
+		return resolved, nil
+	}
+
+	rows, err := s.queryWithMetrics(ctx, "select", "external_identities",
+		`SELECT external_id, user_id FROM external_identities
+		 WHERE provider = $1 AND external_id = ANY($2)`, provider, externalIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var externalID, userID string
+		if err := rows.Scan(&externalID, &userID); err != nil {
+			return nil, err
+		}
+		resolved[externalID] = userID
+	}
+	return resolved, rows.Err()
+}
+
+// DeclinePR переводит PR, отклоненный во внешней системе, в DECLINED. Ревьюеры остаются
+// в истории PR, но больше не нагружены им и получают PR из очереди команды.
+func (s *StorageData) DeclinePR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pr, err := s.lockPR(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Status == "MERGED" {
+		return nil, s.prConflict(ctx, tx, pr, "pr already merged")
+	}
+
+	reviewers, err := s.getReviewersForPR(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	pr.Reviewers = reviewers
+	if pr.Status == "DECLINED" {
+		return pr, tx.Commit()
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "update", "pull_requests",
+		`UPDATE pull_requests SET status = 'DECLINED' WHERE pull_request_id = $1`, prID); err != nil {
+		return nil, err
+	}
+	pr.Status = "DECLINED"
+
+	if err := s.dequeuePR(ctx, tx, prID); err != nil {
+		return nil, err
+	}
+	if err := s.assignQueuedPRsForReviewers(ctx, tx, reviewers); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, prID, "decline", nil); err != nil {
+		return nil, err
+	}
+	if err := s.recordEvent(ctx, tx, EventPRDeclined, prID, pr); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// RemoveReviewer снимает ревьюера с PR без замены: ревьюер уже убран во внешней системе.
+// Освободившийся ревьюер получает PR из очереди своей команды.
+func (s *StorageData) RemoveReviewer(ctx context.Context, prID, userID string) (*models.PullRequest, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pr, err := s.lockPR(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Status == "MERGED" {
+		return nil, s.prConflict(ctx, tx, pr, "cannot modify reviewers after merge")
+	}
+
+	result, err := s.txExecWithMetrics(tx, ctx, "delete", "pr_reviewers",
+		`DELETE FROM pr_reviewers WHERE pull_request_id = $1 AND user_id = $2`, prID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if removed, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if removed == 0 {
+		return nil, s.prConflict(ctx, tx, pr, "reviewer is not assigned to this PR")
+	}
+
+	reviewers, err := s.getReviewersForPR(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	pr.Reviewers = reviewers
+
+	if pr.Status == "OPEN" {
+		if err := s.assignQueuedPRsForReviewers(ctx, tx, []string{userID}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, prID, "remove_reviewer", map[string]interface{}{
+		"user_id": userID,
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.recordEvent(ctx, tx, EventPRReviewerRemoved, prID, map[string]interface{}{
+		"pr":          pr,
+		"reviewer_id": userID,
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// lockPR читает PR с блокировкой строки до конца транзакции
+func (s *StorageData) lockPR(ctx context.Context, tx *sql.Tx, prID string) (*models.PullRequest, error) {
+	var pr models.PullRequest
+	var mergedAt sql.NullTime
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
+		 FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE`, prID).
+		Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &mergedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("pr not found")
+		}
+		return nil, err
+	}
+	if mergedAt.Valid {
+		mergedAtStr := mergedAt.Time.Format(time.RFC3339)
+		pr.MergedAt = &mergedAtStr
+	}
+	return &pr, nil
+}
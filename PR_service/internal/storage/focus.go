@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+// SetFocusWindows заменяет расписание окон фокуса пользователя целиком
+func (s *StorageData) SetFocusWindows(ctx context.Context, userID string, windows []models.FocusWindow) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userExists bool
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "users",
+		`SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)`, userID).Scan(&userExists)
+	if err != nil {
+		return err
+	}
+	if !userExists {
+		return fmt.Errorf("user not found")
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "delete", "user_focus_windows",
+		`DELETE FROM user_focus_windows WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, fw := range windows {
+		start, err := ParseMinuteOfDay(fw.StartTime)
+		if err != nil {
+			return err
+		}
+		end, err := ParseMinuteOfDay(fw.EndTime)
+		if err != nil {
+			return err
+		}
+		if _, err := s.txExecWithMetrics(tx, ctx, "insert", "user_focus_windows",
+			`INSERT INTO user_focus_windows(user_id, weekday, start_minute, end_minute) VALUES($1,$2,$3,$4)
+			 ON CONFLICT (user_id, weekday, start_minute) DO UPDATE SET end_minute = EXCLUDED.end_minute`,
+			userID, fw.Weekday, start, end); err != nil {
+			return err
+		}
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityUser, userID, "set_focus_windows", map[string]interface{}{
+		"focus_windows": windows,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetFocusWindows возвращает расписание окон фокуса пользователя
+func (s *StorageData) GetFocusWindows(ctx context.Context, userID string) ([]models.FocusWindow, error) {
+	rows, err := s.queryWithMetrics(ctx, "select", "user_focus_windows",
+		`SELECT weekday, start_minute, end_minute FROM user_focus_windows
+		 WHERE user_id = $1 ORDER BY weekday, start_minute`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := []models.FocusWindow{}
+	for rows.Next() {
+		var weekday, start, end int
+		if err := rows.Scan(&weekday, &start, &end); err != nil {
+			return nil, err
+		}
+		windows = append(windows, models.FocusWindow{
+			Weekday:   weekday,
+			StartTime: formatMinuteOfDay(start),
+			EndTime:   formatMinuteOfDay(end),
+		})
+	}
+	return windows, rows.Err()
+}
+
+// getFocusedCandidates возвращает кандидатов, у которых в момент now идет окно фокуса
+func (s *StorageData) getFocusedCandidates(ctx context.Context, tx *sql.Tx, candidates []string, now time.Time) (map[string]bool, error) {
+	focused := make(map[string]bool)
+	if len(candidates) == 0 {
+		return focused, nil
+	}
+
+	now = now.UTC()
+	minute := now.Hour()*60 + now.Minute()
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "user_focus_windows",
+		`SELECT DISTINCT user_id FROM user_focus_windows
+		 WHERE user_id = ANY($1) AND weekday = $2 AND start_minute <= $3 AND end_minute > $3`,
+		candidates, int(now.Weekday()), minute)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		focused[uid] = true
+	}
+	return focused, rows.Err()
+}
+
+// ParseMinuteOfDay переводит время в формате HH:MM в минуты от начала суток.
+// 24:00 допустимо как конец суток.
+func ParseMinuteOfDay(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatMinuteOfDay(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
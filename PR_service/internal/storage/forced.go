@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+)
+
+type forcedReviewersKey struct{}
+
+// WithForcedReviewers возвращает контекст, в котором автоматические назначения запроса
+// выбирают ревьюеров из userIDs вместо стратегии команды. Только для тестовых стендов:
+// API кладет список в контекст, лишь когда режим явно включен конфигурацией.
+func WithForcedReviewers(ctx context.Context, userIDs []string) context.Context {
+	if len(userIDs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, forcedReviewersKey{}, userIDs)
+}
+
+// ForcedReviewersFrom возвращает принудительно выбранных ревьюеров запроса
+func ForcedReviewersFrom(ctx context.Context) []string {
+	ids, _ := ctx.Value(forcedReviewersKey{}).([]string)
+	return ids
+}
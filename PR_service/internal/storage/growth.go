@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+// Ограничения на теги PR
+const (
+	MaxPRTags    = 20
+	MaxTagLength = 64
+)
+
+// KnowledgeSpreadWindow - за какой период стратегия knowledge_spread учитывает ревью с тегами PR
+const KnowledgeSpreadWindow = 90 * 24 * time.Hour
+
+// NormalizeTag приводит тег к виду, в котором он хранится: без пробелов по краям, в нижнем регистре
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// insertPRTags сохраняет теги PR и возвращает их в нормализованном виде без повторов
+func (s *StorageData) insertPRTags(ctx context.Context, tx *sql.Tx, prID string, tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	var stored []string
+	for _, raw := range tags {
+		tag := NormalizeTag(raw)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+
+		if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_tags",
+			`INSERT INTO pr_tags(pull_request_id, tag) VALUES($1,$2)`, prID, tag); err != nil {
+			return nil, err
+		}
+		stored = append(stored, tag)
+	}
+	sort.Strings(stored)
+	return stored, nil
+}
+
+// getPRTags возвращает теги PR по алфавиту
+func (s *StorageData) getPRTags(ctx context.Context, tx *sql.Tx, prID string) ([]string, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_tags",
+		`SELECT tag FROM pr_tags WHERE pull_request_id = $1 ORDER BY tag`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// getRecentTagReviews возвращает, сколько PR с тегами PR prID каждый кандидат отревьюил
+// за KnowledgeSpreadWindow. PR считается отревьюенным, когда ревьюер его одобрил или PR смержен.
+func (s *StorageData) getRecentTagReviews(ctx context.Context, tx *sql.Tx, prID string, candidates []string) (map[string]int, error) {
+	reviews := make(map[string]int, len(candidates))
+	if len(candidates) == 0 || prID == "" {
+		return reviews, nil
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_tags",
+		`SELECT r.user_id, COUNT(DISTINCT r.pull_request_id)
+		 FROM pr_reviewers r
+		 JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		 JOIN pr_tags t ON t.pull_request_id = r.pull_request_id
+		 WHERE r.user_id = ANY($1)
+		   AND r.pull_request_id <> $2
+		   AND t.tag IN (SELECT tag FROM pr_tags WHERE pull_request_id = $2)
+		   AND COALESCE(r.approved_at, p.merged_at) >= $3
+		 GROUP BY r.user_id`, candidates, prID, s.now().Add(-KnowledgeSpreadWindow))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uid string
+		var n int
+		if err := rows.Scan(&uid, &n); err != nil {
+			return nil, err
+		}
+		reviews[uid] = n
+	}
+	return reviews, rows.Err()
+}
+
+// GetReviewerGrowth возвращает отчет о тегах, которые пользователь ревьюил за период
+// [from, to), с разбивкой по месяцам, и о тегах PR его команды, которых он не видел.
+// Границы периода необязательны.
+func (s *StorageData) GetReviewerGrowth(ctx context.Context, userID string, from, to *time.Time) (*models.ReviewerGrowth, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := s.txQueryRowWithMetrics(tx, ctx, "select", "users",
+		`SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)`, userID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	report := &models.ReviewerGrowth{UserID: userID, From: from, To: to, Tags: []models.TagGrowth{}, Unexplored: []string{}}
+
+	teamName, err := s.getUserTeam(ctx, tx, userID)
+	if err != nil && err.Error() != "user not in any team" {
+		return nil, err
+	}
+	report.TeamName = teamName
+
+	// Месяцы и время ревью - в поясе команды: ревью вечером 31-го по Москве относится к этому месяцу
+	loc := time.UTC
+	if teamName != "" {
+		var timezone string
+		err := s.txQueryRowWithMetrics(tx, ctx, "select", "team_settings",
+			`SELECT timezone FROM team_settings WHERE team_name = $1`, teamName).Scan(&timezone)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		loc = teamLocation(timezone)
+	}
+	report.Timezone = loc.String()
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_tags",
+		`SELECT t.tag, to_char(COALESCE(r.approved_at, p.merged_at) AT TIME ZONE $4, 'YYYY-MM'),
+		        COUNT(*), MIN(COALESCE(r.approved_at, p.merged_at)), MAX(COALESCE(r.approved_at, p.merged_at))
+		 FROM pr_reviewers r
+		 JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		 JOIN pr_tags t ON t.pull_request_id = r.pull_request_id
+		 WHERE r.user_id = $1
+		   AND COALESCE(r.approved_at, p.merged_at) IS NOT NULL
+		   AND ($2::timestamptz IS NULL OR COALESCE(r.approved_at, p.merged_at) >= $2)
+		   AND ($3::timestamptz IS NULL OR COALESCE(r.approved_at, p.merged_at) < $3)
+		 GROUP BY 1, 2
+		 ORDER BY 1, 2`, userID, from, to, loc.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag string
+		var month models.TagReviewMonth
+		var first, last time.Time
+		if err := rows.Scan(&tag, &month.Month, &month.Reviews, &first, &last); err != nil {
+			return nil, err
+		}
+		n := len(report.Tags)
+		if n == 0 || report.Tags[n-1].Tag != tag {
+			report.Tags = append(report.Tags, models.TagGrowth{Tag: tag, FirstReviewedAt: first.In(loc)})
+			n++
+		}
+		g := &report.Tags[n-1]
+		g.Reviews += month.Reviews
+		g.LastReviewedAt = last.In(loc)
+		g.Months = append(g.Months, month)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if teamName == "" {
+		return report, tx.Commit()
+	}
+
+	// Теги, которые команда встречала за период, а пользователь - нет
+	unexplored, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_tags",
+		`SELECT DISTINCT t.tag
+		 FROM pr_tags t
+		 JOIN pull_requests p ON p.pull_request_id = t.pull_request_id
+		 JOIN team_members tm ON tm.user_id = p.author_id AND tm.team_name = $1
+		 WHERE ($2::timestamptz IS NULL OR p.created_at >= $2)
+		   AND ($3::timestamptz IS NULL OR p.created_at < $3)
+		 ORDER BY t.tag`, teamName, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer unexplored.Close()
+
+	seen := make(map[string]bool, len(report.Tags))
+	for _, g := range report.Tags {
+		seen[g.Tag] = true
+	}
+	for unexplored.Next() {
+		var tag string
+		if err := unexplored.Scan(&tag); err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			report.Unexplored = append(report.Unexplored, tag)
+		}
+	}
+	if err := unexplored.Err(); err != nil {
+		return nil, err
+	}
+	return report, tx.Commit()
+}
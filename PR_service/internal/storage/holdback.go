@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"PR_service/internal/models"
+)
+
+// MaxHoldbackDays - верхняя граница периода адаптации новых участников команды
+const MaxHoldbackDays = 90
+
+// notInHoldback возвращает SQL-условие: участник tm прошел период адаптации своей команды
+// к моменту nowParam. У участников, добавленных до появления периода, joined_at пуст.
+func notInHoldback(nowParam string) string {
+	return `(tm.joined_at IS NULL OR tm.joined_at <= ` + nowParam + `::timestamptz - make_interval(days => COALESCE(
+		 (SELECT ts.new_member_holdback_days FROM team_settings ts WHERE ts.team_name = tm.team_name), 0)))`
+}
+
+// holdbackUntil возвращает момент окончания периода адаптации участника, добавленного в joinedAt.
+// Второе значение false, если участник уже может назначаться ревьюером.
+func holdbackUntil(joinedAt sql.NullTime, days int, now time.Time) (time.Time, bool) {
+	if !joinedAt.Valid || days <= 0 {
+		return time.Time{}, false
+	}
+	until := joinedAt.Time.AddDate(0, 0, days).UTC()
+	return until, now.Before(until)
+}
+
+// PreviewCandidates показывает, кого из участников команды сейчас можно назначить ревьюером
+// автоматически, а кого нет и почему
+func (s *StorageData) PreviewCandidates(ctx context.Context, teamName string) ([]models.CandidatePreview, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.getTeamSettings(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "team_members",
+		`SELECT u.user_id, u.username, u.is_active, u.is_bot, tm.joined_at
+		 FROM team_members tm
+		 JOIN users u ON u.user_id = tm.user_id
+		 WHERE tm.team_name = $1
+		 ORDER BY u.user_id`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type member struct {
+		preview  models.CandidatePreview
+		active   bool
+		bot      bool
+		joinedAt sql.NullTime
+	}
+	var members []member
+	var ids []string
+	for rows.Next() {
+		var m member
+		if err := rows.Scan(&m.preview.UserID, &m.preview.Username, &m.active, &m.bot, &m.joinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+		ids = append(ids, m.preview.UserID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	now := s.now()
+	focused, err := s.getFocusedCandidates(ctx, tx, ids, now)
+	if err != nil {
+		return nil, err
+	}
+	var loads map[string]int
+	if settings.MaxOpenReviews > 0 {
+		if loads, err = s.getPendingReviewLoads(ctx, tx, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	previews := make([]models.CandidatePreview, 0, len(members))
+	for _, m := range members {
+		p := m.preview
+		until, held := holdbackUntil(m.joinedAt, settings.NewMemberHoldbackDays, now)
+		switch {
+		case !m.active:
+			p.Reason = "inactive"
+		case m.bot:
+			p.Reason = "bot"
+		case held:
+			p.HoldbackUntil = &until
+			p.Reason = fmt.Sprintf("in holdback until %s", until.Format("2006-01-02"))
+		case settings.MaxOpenReviews > 0 && loads[p.UserID] >= settings.MaxOpenReviews:
+			p.Reason = fmt.Sprintf("at max_open_reviews (%d)", settings.MaxOpenReviews)
+		case focused[p.UserID]:
+			// Кандидат в окне фокуса назначается, только если без него не набрать ревьюеров
+			p.Eligible = true
+			p.Reason = "in focus window"
+		default:
+			p.Eligible = true
+		}
+		previews = append(previews, p)
+	}
+	return previews, tx.Commit()
+}
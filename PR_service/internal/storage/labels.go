@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"PR_service/internal/models"
+)
+
+// Действия массового изменения меток
+const (
+	LabelActionAdd    = "add"
+	LabelActionRemove = "remove"
+)
+
+// MaxBulkLabelPRs - предел числа PR в одном запросе массового изменения метки
+const MaxBulkLabelPRs = 500
+
+// IsKnownLabelAction проверяет действие массового изменения меток
+func IsKnownLabelAction(action string) bool {
+	return action == LabelActionAdd || action == LabelActionRemove
+}
+
+// BulkLabelPRs добавляет метку (тег) PR prIDs или снимает ее одной транзакцией.
+// Отсутствующие PR и PR, которым метку не добавить из-за MaxPRTags, не прерывают
+// операцию, а возвращаются в результате отдельно.
+func (s *StorageData) BulkLabelPRs(ctx context.Context, action, label string, prIDs []string) (*models.BulkLabelResult, error) {
+	if !IsKnownLabelAction(action) {
+		return nil, fmt.Errorf("unknown label action %q", action)
+	}
+	label = NormalizeTag(label)
+	result := &models.BulkLabelResult{
+		Action:    action,
+		Label:     label,
+		Updated:   []string{},
+		Unchanged: []string{},
+		NotFound:  []string{},
+		AtLimit:   []string{},
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Блокировки берутся в порядке id, чтобы встречные массовые операции не взаимоблокировались
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT p.pull_request_id,
+		        (SELECT COUNT(*) FROM pr_tags t WHERE t.pull_request_id = p.pull_request_id),
+		        EXISTS(SELECT 1 FROM pr_tags t WHERE t.pull_request_id = p.pull_request_id AND t.tag = $2)
+		 FROM pull_requests p
+		 WHERE p.pull_request_id = ANY($1)
+		 ORDER BY p.pull_request_id
+		 FOR UPDATE OF p`, prIDs, label)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type prLabels struct {
+		tags     int
+		hasLabel bool
+	}
+	found := make(map[string]prLabels, len(prIDs))
+	for rows.Next() {
+		var id string
+		var state prLabels
+		if err := rows.Scan(&id, &state.tags, &state.hasLabel); err != nil {
+			return nil, err
+		}
+		found[id] = state
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	seen := make(map[string]bool, len(prIDs))
+	for _, id := range prIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		state, ok := found[id]
+		switch {
+		case !ok:
+			result.NotFound = append(result.NotFound, id)
+			continue
+		case action == LabelActionAdd && state.hasLabel, action == LabelActionRemove && !state.hasLabel:
+			result.Unchanged = append(result.Unchanged, id)
+			continue
+		case action == LabelActionAdd && state.tags >= MaxPRTags:
+			result.AtLimit = append(result.AtLimit, id)
+			continue
+		}
+
+		auditAction := "add_label"
+		if action == LabelActionAdd {
+			if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_tags",
+				`INSERT INTO pr_tags(pull_request_id, tag) VALUES($1,$2)`, id, label); err != nil {
+				return nil, err
+			}
+		} else {
+			auditAction = "remove_label"
+			if _, err := s.txExecWithMetrics(tx, ctx, "delete", "pr_tags",
+				`DELETE FROM pr_tags WHERE pull_request_id = $1 AND tag = $2`, id, label); err != nil {
+				return nil, err
+			}
+		}
+
+		details := map[string]interface{}{"label": label}
+		if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, id, auditAction, details); err != nil {
+			return nil, err
+		}
+		if err := s.recordEvent(ctx, tx, EventPRLabelsChanged, id, map[string]interface{}{
+			"pull_request_id": id,
+			"action":          action,
+			"label":           label,
+		}); err != nil {
+			return nil, err
+		}
+		result.Updated = append(result.Updated, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"PR_service/internal/authctx"
+)
+
+// ErrTeamLeadRequired - операция доступна только лидам команды и администраторам
+var ErrTeamLeadRequired = errors.New("team lead required")
+
+// GetTeamLeads возвращает лидов команды
+func (s *StorageData) GetTeamLeads(ctx context.Context, teamName string) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	leads, err := s.getTeamLeads(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	return leads, tx.Commit()
+}
+
+// AddTeamLead назначает участника команды лидом и возвращает всех лидов команды
+func (s *StorageData) AddTeamLead(ctx context.Context, teamName, userID string) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+	if err := s.requireTeamLead(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	var isMember bool
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "team_members",
+		`SELECT EXISTS(SELECT 1 FROM team_members WHERE team_name = $1 AND user_id = $2)`,
+		teamName, userID).Scan(&isMember)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, fmt.Errorf("user is not a team member")
+	}
+
+	before, err := s.getTeamLeads(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "insert", "team_leads",
+		`INSERT INTO team_leads(team_name, user_id) VALUES($1,$2) ON CONFLICT DO NOTHING`,
+		teamName, userID); err != nil {
+		return nil, err
+	}
+
+	leads, err := s.getTeamLeads(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordAuditChange(ctx, tx, AuditEntityTeam, teamName, "add_lead", map[string]interface{}{
+		"user_id": userID,
+	}, map[string]interface{}{"leads": before}, map[string]interface{}{"leads": leads}); err != nil {
+		return nil, err
+	}
+	return leads, tx.Commit()
+}
+
+// RemoveTeamLead снимает с пользователя роль лида команды и возвращает оставшихся лидов
+func (s *StorageData) RemoveTeamLead(ctx context.Context, teamName, userID string) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+	if err := s.requireTeamLead(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	before, err := s.getTeamLeads(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.txExecWithMetrics(tx, ctx, "delete", "team_leads",
+		`DELETE FROM team_leads WHERE team_name = $1 AND user_id = $2`, teamName, userID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, fmt.Errorf("user is not a team lead")
+	}
+
+	leads, err := s.getTeamLeads(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordAuditChange(ctx, tx, AuditEntityTeam, teamName, "remove_lead", map[string]interface{}{
+		"user_id": userID,
+	}, map[string]interface{}{"leads": before}, map[string]interface{}{"leads": leads}); err != nil {
+		return nil, err
+	}
+	return leads, tx.Commit()
+}
+
+// requireTeamLead проверяет, что автор запроса - лид команды или администратор.
+// Пока у команды нет лидов, операции доступны всем, как и до появления лидов.
+func (s *StorageData) requireTeamLead(ctx context.Context, tx *sql.Tx, teamName string) error {
+	principal, _ := authctx.PrincipalFrom(ctx)
+	if principal.HasRole(authctx.RoleAdmin) {
+		return nil
+	}
+
+	leads, err := s.getTeamLeads(ctx, tx, teamName)
+	if err != nil {
+		return err
+	}
+	if len(leads) == 0 {
+		return nil
+	}
+	for _, uid := range leads {
+		if uid == principal.ID {
+			return nil
+		}
+	}
+	return ErrTeamLeadRequired
+}
+
+func (s *StorageData) getTeamLeads(ctx context.Context, tx *sql.Tx, teamName string) ([]string, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "team_leads",
+		`SELECT user_id FROM team_leads WHERE team_name = $1 ORDER BY user_id`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leads := []string{}
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		leads = append(leads, uid)
+	}
+	return leads, rows.Err()
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/models"
+)
+
+// GetMaintenance возвращает сохраненное состояние режима обслуживания
+func (s *StorageData) GetMaintenance(ctx context.Context) (models.MaintenanceState, error) {
+	var state models.MaintenanceState
+	var updatedAt sql.NullTime
+	err := s.queryRowWithMetrics(ctx, "select", "service_maintenance",
+		`SELECT enabled, message, updated_by, updated_at FROM service_maintenance WHERE id = 1`).
+		Scan(&state.Enabled, &state.Message, &state.UpdatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if updatedAt.Valid {
+		state.UpdatedAt = &updatedAt.Time
+	}
+	return state, nil
+}
+
+// SetMaintenance включает или выключает режим обслуживания для всех реплик
+func (s *StorageData) SetMaintenance(ctx context.Context, enabled bool, message string) (models.MaintenanceState, error) {
+	now := s.now()
+	state := models.MaintenanceState{
+		Enabled:   enabled,
+		Message:   message,
+		UpdatedBy: authctx.ActorID(ctx),
+		UpdatedAt: &now,
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return state, err
+	}
+	defer tx.Rollback()
+
+	before := map[string]interface{}{"enabled": false, "message": ""}
+	var wasEnabled bool
+	var wasMessage string
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "service_maintenance",
+		`SELECT enabled, message FROM service_maintenance WHERE id = 1 FOR UPDATE`).Scan(&wasEnabled, &wasMessage)
+	if err != nil && err != sql.ErrNoRows {
+		return state, err
+	}
+	if err == nil {
+		before = map[string]interface{}{"enabled": wasEnabled, "message": wasMessage}
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "upsert", "service_maintenance",
+		`INSERT INTO service_maintenance(id, enabled, message, updated_by, updated_at) VALUES(1,$1,$2,$3,$4)
+		 ON CONFLICT (id) DO UPDATE SET enabled = EXCLUDED.enabled, message = EXCLUDED.message,
+		 updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		state.Enabled, state.Message, state.UpdatedBy, now); err != nil {
+		return state, err
+	}
+
+	after := map[string]interface{}{"enabled": enabled, "message": message}
+	if err := s.recordAuditChange(ctx, tx, AuditEntityMaintenance, "service", "set", after, before, after); err != nil {
+		return state, err
+	}
+	return state, tx.Commit()
+}
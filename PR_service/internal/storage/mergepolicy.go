@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// checkMergePolicy проверяет политику мерджа и цепочку одобрений команды автора для PR
+func (s *StorageData) checkMergePolicy(ctx context.Context, tx *sql.Tx, prID, authorID string) error {
+	teamName, err := s.getUserTeam(ctx, tx, authorID)
+	if err != nil {
+		if err.Error() == "user not in any team" {
+			return nil
+		}
+		return err
+	}
+
+	settings, err := s.getTeamSettings(ctx, tx, teamName)
+	if err != nil {
+		return err
+	}
+	if settings.MergePolicy != domain.MergePolicyNone {
+		if err := s.checkApprovals(ctx, tx, prID, settings); err != nil {
+			return err
+		}
+	}
+
+	if len(settings.ApprovalStages) > 0 {
+		_, progress, err := s.getApprovalProgress(ctx, tx, prID, teamName, settings.ApprovalStages)
+		if err != nil {
+			return err
+		}
+		if missing := domain.StagesMissing(progress); missing != nil {
+			return missing
+		}
+	}
+	return nil
+}
+
+// checkApprovals проверяет одобрения назначенных ревьюеров по политике мерджа команды
+func (s *StorageData) checkApprovals(ctx context.Context, tx *sql.Tx, prID string, settings models.TeamSettings) error {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_reviewers",
+		`SELECT user_id, approved_at IS NOT NULL FROM pr_reviewers
+		 WHERE pull_request_id = $1 ORDER BY user_id`, prID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var reviewers []string
+	approved := make(map[string]bool)
+	for rows.Next() {
+		var uid string
+		var ok bool
+		if err := rows.Scan(&uid, &ok); err != nil {
+			return err
+		}
+		reviewers = append(reviewers, uid)
+		approved[uid] = ok
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if missing := domain.EvaluateMergePolicy(settings.MergePolicy, settings.RequiredApprovals, reviewers, approved); missing != nil {
+		return missing
+	}
+	return nil
+}
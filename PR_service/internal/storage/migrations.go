@@ -0,0 +1,487 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"PR_service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// schemaVersionDDL - таблица версии схемы (миграция 0014)
+const schemaVersionDDL = `CREATE TABLE IF NOT EXISTS schema_version (
+  id SMALLINT PRIMARY KEY CHECK (id = 1),
+  version INT NOT NULL,
+  applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+`
+
+// migrationStateDDL - флаг dirty и история миграций (миграция 0020).
+// Вместе с schemaVersionDDL выполняется и перед чтением состояния: без этих
+// таблиц раннер не может определить, с какой версии продолжать.
+const migrationStateDDL = `ALTER TABLE schema_version ADD COLUMN IF NOT EXISTS dirty BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INT PRIMARY KEY,
+  name TEXT NOT NULL,
+  applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  duration_ms BIGINT NOT NULL DEFAULT 0
+);
+`
+
+// downMigrations - откат миграций по версиям. Откатывается только последняя
+// примененная версия; новая миграция добавляет сюда свой откат.
+var downMigrations = map[int]string{
+	34: `DROP TABLE IF EXISTS notification_routes;`,
+	33: `DROP TABLE IF EXISTS webhook_signing_keys;`,
+	32: `ALTER TABLE team_settings DROP COLUMN IF EXISTS timezone;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS locale;`,
+	31: `DROP INDEX IF EXISTS idx_pull_requests_author_status;
+DROP INDEX IF EXISTS idx_pr_reviewers_pending;`,
+	30: `DROP TABLE IF EXISTS webhook_deliveries;`,
+	29: `ALTER TABLE team_settings DROP COLUMN IF EXISTS review_sla_hours;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS priority;`,
+	28: `ALTER TABLE audit_log DROP COLUMN IF EXISTS after_state;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS before_state;`,
+	27: `DROP TABLE IF EXISTS user_assignment_quotas;
+DROP INDEX IF EXISTS idx_pr_reviewers_user_assigned;
+ALTER TABLE pr_reviewers DROP COLUMN IF EXISTS assigned_at;`,
+	26: `ALTER TABLE team_settings DROP COLUMN IF EXISTS candidate_rank;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS candidate_filter;`,
+	25: `ALTER TABLE pr_reviewers DROP COLUMN IF EXISTS stage;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS approval_stage;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS approval_stages;`,
+	24: `DROP TABLE IF EXISTS external_identities;`,
+	23: `DROP TABLE IF EXISTS team_rotation_overrides;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS prefer_on_duty;`,
+	22: `ALTER TABLE team_settings DROP COLUMN IF EXISTS min_tags;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS require_ticket_reference;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS pr_name_pattern;`,
+	21: `DROP TABLE IF EXISTS pr_vetoes;`,
+	// Состояние раннера нужно ему самому и пересоздается при следующем запуске,
+	// поэтому откат 0020 только снимает версию
+	20: ``,
+	19: `ALTER TABLE team_settings DROP COLUMN IF EXISTS new_member_holdback_days;
+ALTER TABLE team_members DROP COLUMN IF EXISTS joined_at;`,
+	18: `DROP TABLE IF EXISTS pr_tags;`,
+}
+
+// DirtySchemaError - прошлое применение миграции version не завершилось
+// (процесс упал посреди миграции). Новые миграции не применяются, пока оператор
+// не откатит ее (-migrate-down) или не подтвердит версию схемы (-migrate-force).
+type DirtySchemaError struct {
+	Version int
+}
+
+func (e *DirtySchemaError) Error() string {
+	return fmt.Sprintf("schema is dirty at version %d: roll it back with -migrate-down or set the version with -migrate-force", e.Version)
+}
+
+// migration - пронумерованная секция migrationsDDL
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+
+	// NoTransaction - секция помечена noTransactionDirective: инструкции выполняются
+	// по одной вне транзакции, как требует CREATE INDEX CONCURRENTLY
+	NoTransaction bool
+}
+
+// noTransactionDirective - строка секции миграции, которая строит индексы без блокировки
+// записи в таблицу. Такая миграция содержит только идемпотентные инструкции
+// (CREATE INDEX CONCURRENTLY IF NOT EXISTS): после сбоя она выполняется заново целиком.
+const noTransactionDirective = "-- no-transaction"
+
+var (
+	noTransactionRe = regexp.MustCompile(`(?m)^` + noTransactionDirective + `\s*$`)
+	// concurrentIndexRe выделяет имя индекса, создаваемого без блокировки
+	concurrentIndexRe = regexp.MustCompile(`(?i)^\s*CREATE\s+(?:UNIQUE\s+)?INDEX\s+CONCURRENTLY\s+IF\s+NOT\s+EXISTS\s+(\w+)`)
+	concurrentlyRe    = regexp.MustCompile(`(?i)\s+CONCURRENTLY\b`)
+)
+
+// statements разбивает секцию на инструкции без комментариев. Используется для секций
+// вне транзакции: в них нет ";" внутри строк и тел функций.
+func (m migration) statements() []string {
+	var stmts []string
+	for _, part := range strings.Split(m.Up, ";") {
+		var lines []string
+		for _, line := range strings.Split(part, "\n") {
+			if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			stmts = append(stmts, strings.Join(lines, "\n"))
+		}
+	}
+	return stmts
+}
+
+// transactional возвращает DDL секции, пригодный для выполнения в транзакции: при проверке
+// миграций во временной схеме индексы над пустыми таблицами строятся обычным способом
+func (m migration) transactional() string {
+	if !m.NoTransaction {
+		return m.Up
+	}
+	return concurrentlyRe.ReplaceAllString(m.Up, "")
+}
+
+// migrations - секции migrationsDDL по возрастанию версии
+var migrations = splitMigrations(migrationsDDL)
+
+// splitMigrations разбивает DDL на секции по заголовкам "-- NNNN name"
+func splitMigrations(ddl string) []migration {
+	locs := migrationHeaderRe.FindAllStringSubmatchIndex(ddl, -1)
+	result := make([]migration, 0, len(locs))
+	for i, loc := range locs {
+		end := len(ddl)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		version, _ := strconv.Atoi(ddl[loc[2]:loc[3]])
+		name := ddl[loc[1]:]
+		if nl := strings.IndexByte(name, '\n'); nl >= 0 {
+			name = name[:nl]
+		}
+		up := ddl[loc[0]:end]
+		result = append(result, migration{
+			Version:       version,
+			Name:          strings.TrimSpace(name),
+			Up:            up,
+			NoTransaction: noTransactionRe.MatchString(up),
+		})
+	}
+	return result
+}
+
+// previousVersion возвращает версию миграции, предшествующей version (0 - ни одной)
+func previousVersion(version int) int {
+	prev := 0
+	for _, m := range migrations {
+		if m.Version < version && m.Version > prev {
+			prev = m.Version
+		}
+	}
+	return prev
+}
+
+// lockMigrations выполняет fn на отдельном соединении под advisory lock миграций:
+// реплики, запущенные одновременно, применяют и проверяют миграции по очереди
+func lockMigrations(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationsLockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationsLockKey)
+
+	return fn(conn)
+}
+
+// withMigrationLock выполняет fn под advisory lock миграций, предварительно создав
+// таблицы состояния миграций
+func withMigrationLock(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn) error) error {
+	return lockMigrations(ctx, db, func(conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, schemaVersionDDL+migrationStateDDL); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// readMigrationState возвращает записанную версию схемы и флаг незавершенной миграции
+func readMigrationState(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}) (int, bool, error) {
+	var version int
+	var dirty bool
+	err := q.QueryRowContext(ctx, `SELECT version, dirty FROM schema_version WHERE id = 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// setMigrationState записывает версию схемы и флаг незавершенной миграции
+func setMigrationState(ctx context.Context, q interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}, version int, dirty bool) error {
+	_, err := q.ExecContext(ctx,
+		`INSERT INTO schema_version(id, version, dirty, applied_at) VALUES(1, $1, $2, now())
+		 ON CONFLICT (id) DO UPDATE SET version = EXCLUDED.version, dirty = EXCLUDED.dirty,
+		 applied_at = EXCLUDED.applied_at`, version, dirty)
+	return err
+}
+
+// ApplyMigrations применяет миграции новее записанной версии схемы, каждую в своей транзакции
+// (секции с noTransactionDirective - по одной инструкции вне транзакции).
+// Требует роль с правом DDL. Перед миграцией версия помечается dirty; если процесс упадет,
+// не дойдя до фиксации, следующий запуск вернет DirtySchemaError вместо применения поверх.
+func ApplyMigrations(db *sql.DB) error {
+	ctx := context.Background()
+	return withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		current, dirty, err := readMigrationState(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return &DirtySchemaError{Version: current}
+		}
+
+		for _, m := range migrations {
+			if m.Version <= current {
+				continue
+			}
+			if err := applyMigration(ctx, conn, m, current); err != nil {
+				return err
+			}
+			current = m.Version
+		}
+		return nil
+	})
+}
+
+// applyMigration применяет одну миграцию. DDL в Postgres транзакционен, поэтому при ошибке
+// миграция откатывается целиком и версия возвращается к prev без флага dirty. Миграция вне
+// транзакции при ошибке остается применена частично, но ее инструкции идемпотентны,
+// и следующий запуск выполнит ее заново.
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration, prev int) error {
+	if err := setMigrationState(ctx, conn, m.Version, true); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := func() error {
+		if m.NoTransaction {
+			if err := applyOnline(ctx, conn, m); err != nil {
+				return err
+			}
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if !m.NoTransaction {
+			if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations(version, name, applied_at, duration_ms) VALUES($1,$2,now(),$3)
+			 ON CONFLICT (version) DO UPDATE SET name = EXCLUDED.name, applied_at = EXCLUDED.applied_at,
+			 duration_ms = EXCLUDED.duration_ms`,
+			m.Version, m.Name, time.Since(start).Milliseconds()); err != nil {
+			return err
+		}
+		if err := setMigrationState(ctx, tx, m.Version, false); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		if resetErr := setMigrationState(ctx, conn, prev, false); resetErr != nil {
+			return fmt.Errorf("migration %04d %s: %w (reset state: %v)", m.Version, m.Name, err, resetErr)
+		}
+		return fmt.Errorf("migration %04d %s: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// applyOnline выполняет инструкции миграции по одной вне транзакции. Прерванный
+// CREATE INDEX CONCURRENTLY оставляет невалидный индекс, который IF NOT EXISTS пропустил бы,
+// поэтому такой индекс перед повтором удаляется.
+func applyOnline(ctx context.Context, conn *sql.Conn, m migration) error {
+	for _, stmt := range m.statements() {
+		if match := concurrentIndexRe.FindStringSubmatch(stmt); match != nil {
+			if err := dropInvalidIndex(ctx, conn, match[1]); err != nil {
+				return err
+			}
+		}
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropInvalidIndex удаляет индекс name текущей схемы, если его построение не завершилось
+func dropInvalidIndex(ctx context.Context, conn *sql.Conn, name string) error {
+	var invalid bool
+	err := conn.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM pg_index i
+		 JOIN pg_class c ON c.oid = i.indexrelid
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE c.relname = $1 AND n.nspname = current_schema() AND NOT i.indisvalid)`, name).Scan(&invalid)
+	if err != nil || !invalid {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `DROP INDEX CONCURRENTLY IF EXISTS `+name)
+	return err
+}
+
+// RollbackMigration откатывает последнюю примененную миграцию, в том числе незавершенную,
+// и возвращает новую версию схемы
+func RollbackMigration(db *sql.DB) (int, error) {
+	ctx := context.Background()
+	var version int
+	err := withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		current, _, err := readMigrationState(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if current == 0 {
+			return fmt.Errorf("no migrations to roll back")
+		}
+		down, ok := downMigrations[current]
+		if !ok {
+			return fmt.Errorf("migration %04d has no down migration", current)
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if down != "" {
+			if _, err := tx.ExecContext(ctx, down); err != nil {
+				return fmt.Errorf("roll back migration %04d: %w", current, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, current); err != nil {
+			return err
+		}
+		version = previousVersion(current)
+		if err := setMigrationState(ctx, tx, version, false); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	return version, err
+}
+
+// ForceMigrationVersion записывает версию схемы и снимает флаг dirty без выполнения DDL.
+// Нужна, когда оператор вручную довел или откатил незавершенную миграцию.
+func ForceMigrationVersion(db *sql.DB, version int) error {
+	if version < 0 || version > SchemaVersion {
+		return fmt.Errorf("version must be between 0 and %d", SchemaVersion)
+	}
+	ctx := context.Background()
+	return withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		return setMigrationState(ctx, conn, version, false)
+	})
+}
+
+// migrationCheckSchema - временная схема, в которой CheckMigrations пробно применяет миграции
+const migrationCheckSchema = "pr_service_migration_check"
+
+// CheckMigrations проверяет миграции под тем же advisory lock, что и ApplyMigrations,
+// не трогая таблицы сервиса: схема не должна быть dirty, а все миграции применяются
+// в одной транзакции к пустой временной схеме, которая исчезает при откате. ALTER TABLE
+// не берет блокировок живых таблиц, а индексы секций вне транзакции строятся обычным
+// способом только над пустыми таблицами временной схемы.
+func CheckMigrations(db *sql.DB) error {
+	ctx := context.Background()
+	return lockMigrations(ctx, db, func(conn *sql.Conn) error {
+		var tracked bool
+		if err := conn.QueryRowContext(ctx, `SELECT to_regclass('schema_version') IS NOT NULL`).Scan(&tracked); err != nil {
+			return err
+		}
+		if tracked {
+			current, dirty, err := readMigrationState(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if dirty {
+				return &DirtySchemaError{Version: current}
+			}
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		schema := pgx.Identifier{migrationCheckSchema}.Sanitize()
+		if _, err := tx.ExecContext(ctx, `CREATE SCHEMA `+schema); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `SET LOCAL search_path TO `+schema); err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, err := tx.ExecContext(ctx, m.transactional()); err != nil {
+				return fmt.Errorf("migration %04d %s: %w", m.Version, m.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetMigrationStatus возвращает версию схемы, флаг dirty и состояние каждой миграции сборки
+func (s *StorageData) GetMigrationStatus(ctx context.Context) (*models.MigrationStatus, error) {
+	current, dirty, err := readMigrationState(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(map[int]models.MigrationInfo)
+	rows, err := s.queryWithMetrics(ctx, "select", "schema_migrations",
+		`SELECT version, applied_at, duration_ms FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var info models.MigrationInfo
+		var appliedAt time.Time
+		if err := rows.Scan(&info.Version, &appliedAt, &info.DurationMs); err != nil {
+			return nil, err
+		}
+		appliedAt = appliedAt.UTC()
+		info.AppliedAt = &appliedAt
+		history[info.Version] = info
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := &models.MigrationStatus{
+		Version:       current,
+		LatestVersion: SchemaVersion,
+		Dirty:         dirty,
+		Migrations:    make([]models.MigrationInfo, 0, len(migrations)),
+	}
+	for _, m := range migrations {
+		info := history[m.Version]
+		info.Version = m.Version
+		info.Name = m.Name
+		_, info.Reversible = downMigrations[m.Version]
+		switch {
+		case m.Version == current && dirty:
+			info.State = "dirty"
+		case m.Version <= current:
+			info.State = "applied"
+		default:
+			info.State = "pending"
+		}
+		status.Migrations = append(status.Migrations, info)
+	}
+	_, status.CanRollBack = downMigrations[current]
+	return status, nil
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// SetUserLocale задает локаль уведомлений пользователя
+func (s *StorageData) SetUserLocale(ctx context.Context, userID, locale string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := s.txExecWithMetrics(tx, ctx, "update", "users",
+		`UPDATE users SET locale = $2 WHERE user_id = $1`, userID, locale)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityUser, userID, "set_locale", map[string]interface{}{
+		"locale": locale,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetNotificationTemplate сохраняет шаблон уведомления команды.
+// Пустое тело удаляет шаблон, и команда возвращается к встроенному.
+func (s *StorageData) SetNotificationTemplate(ctx context.Context, t models.NotificationTemplate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, t.TeamName); err != nil {
+		return err
+	}
+
+	action := "set_template"
+	if t.Body == "" {
+		action = "delete_template"
+		if _, err := s.txExecWithMetrics(tx, ctx, "delete", "notification_templates",
+			`DELETE FROM notification_templates WHERE team_name = $1 AND kind = $2 AND locale = $3`,
+			t.TeamName, t.Kind, t.Locale); err != nil {
+			return err
+		}
+	} else {
+		if _, err := s.txExecWithMetrics(tx, ctx, "upsert", "notification_templates",
+			`INSERT INTO notification_templates(team_name, kind, locale, body, updated_by, updated_at)
+			 VALUES($1,$2,$3,$4,$5,$6)
+			 ON CONFLICT (team_name, kind, locale) DO UPDATE SET body = EXCLUDED.body,
+			 updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+			t.TeamName, t.Kind, t.Locale, t.Body, authctx.ActorID(ctx), s.now()); err != nil {
+			return err
+		}
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityTeam, t.TeamName, action, map[string]interface{}{
+		"kind":   t.Kind,
+		"locale": t.Locale,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetNotificationTemplates возвращает шаблоны уведомлений команды
+func (s *StorageData) GetNotificationTemplates(ctx context.Context, teamName string) ([]models.NotificationTemplate, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "notification_templates",
+		`SELECT kind, locale, body, updated_by, updated_at FROM notification_templates
+		 WHERE team_name = $1 ORDER BY kind, locale`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.NotificationTemplate{}
+	for rows.Next() {
+		t := models.NotificationTemplate{TeamName: teamName}
+		var updatedAt time.Time
+		if err := rows.Scan(&t.Kind, &t.Locale, &t.Body, &t.UpdatedBy, &updatedAt); err != nil {
+			return nil, err
+		}
+		t.UpdatedAt = &updatedAt
+		templates = append(templates, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return templates, tx.Commit()
+}
+
+// GetNotificationRecipient возвращает локаль и пояс получателя, шаблоны его команды для вида kind
+// и срок ревью PR prID, если получатель - ревьюер, чье ревью еще ждут
+func (s *StorageData) GetNotificationRecipient(ctx context.Context, userID, prID, kind string) (*models.NotificationRecipient, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	r := models.NotificationRecipient{UserID: userID, Overrides: map[string]string{}}
+	var teamName sql.NullString
+	var teamLocale, timezone string
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "users",
+		`SELECT u.username, u.locale, tm.team_name, COALESCE(ts.locale, ''), COALESCE(ts.timezone, '')
+		 FROM users u
+		 LEFT JOIN LATERAL (SELECT team_name FROM team_members
+		                    WHERE user_id = u.user_id ORDER BY team_name LIMIT 1) tm ON true
+		 LEFT JOIN team_settings ts ON ts.team_name = tm.team_name
+		 WHERE u.user_id = $1`, userID).Scan(&r.Username, &r.Locale, &teamName, &teamLocale, &timezone)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, err
+	}
+	r.TeamName = teamName.String
+	if r.Locale == "" {
+		r.Locale = teamLocale
+	}
+	r.Location = teamLocation(timezone)
+
+	// Срок считается по SLA команды автора, как в /users/getReview
+	var assignedAt time.Time
+	var slaHours int
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "pr_reviewers",
+		`SELECT COALESCE(r.assigned_at, p.created_at),
+		        COALESCE((SELECT ts.review_sla_hours FROM team_members tm
+		                  JOIN team_settings ts ON ts.team_name = tm.team_name
+		                  WHERE tm.user_id = p.author_id LIMIT 1), 0)
+		 FROM pr_reviewers r
+		 JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		 WHERE r.pull_request_id = $1 AND r.user_id = $2 AND p.status = 'OPEN' AND r.approved_at IS NULL`,
+		prID, userID).Scan(&assignedAt, &slaHours)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil {
+		r.DueAt = inLocation(domain.ReviewDueAt(assignedAt, slaHours), r.Location)
+	}
+
+	if teamName.Valid {
+		rows, err := s.txQueryWithMetrics(tx, ctx, "select", "notification_templates",
+			`SELECT locale, body FROM notification_templates WHERE team_name = $1 AND kind = $2`,
+			teamName.String, kind)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var locale, body string
+			if err := rows.Scan(&locale, &body); err != nil {
+				return nil, err
+			}
+			r.Overrides[locale] = body
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &r, tx.Commit()
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/models"
+)
+
+// Типы бизнес-событий
+const (
+	EventPRCreated           = "pr.created"
+	EventPRMerged            = "pr.merged"
+	EventPRDeclined          = "pr.declined"
+	EventPRReassigned        = "pr.reassigned"
+	EventPRReviewerAdded     = "pr.reviewer_added"
+	EventPRReviewerRemoved   = "pr.reviewer_removed"
+	EventPRStageAdvanced     = "pr.approval_stage_advanced"
+	EventPRVetoed            = "pr.reviewer_vetoed"
+	EventPRQueued            = "pr.queued"
+	EventPRQueueAssigned     = "pr.queue_assigned"
+	EventPRAuthorTransferred = "pr.author_transferred"
+	EventPRLabelsChanged     = "pr.labels_changed"
+	EventTeamAdapted         = "team.reviewer_count_adapted"
+	EventUserUpserted        = "user.upserted"
+	EventUserActivated       = "user.activated"
+	EventUserDeactivated     = "user.deactivated"
+)
+
+// EnableEventOutbox включает запись бизнес-событий в event_outbox.
+// Без публикатора события не записываются, чтобы таблица не росла.
+func (s *StorageData) EnableEventOutbox() {
+	s.events = true
+}
+
+// recordEvent добавляет бизнес-событие в event_outbox в транзакции изменения,
+// поэтому событие публикуется тогда и только тогда, когда изменение закоммичено
+func (s *StorageData) recordEvent(ctx context.Context, tx *sql.Tx, eventType, aggregateID string, payload interface{}) error {
+	if !s.events {
+		return nil
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.txExecWithMetrics(tx, ctx, "insert", "event_outbox",
+		`INSERT INTO event_outbox(event_type, aggregate_id, actor, request_id, payload, created_at) VALUES($1,$2,$3,$4,$5,$6)`,
+		eventType, aggregateID, authctx.ActorID(ctx), authctx.RequestIDFrom(ctx), string(payloadJSON), s.now())
+	return err
+}
+
+// PendingEvents возвращает до limit неопубликованных событий в порядке записи
+func (s *StorageData) PendingEvents(ctx context.Context, limit int) ([]models.BusinessEvent, error) {
+	rows, err := s.queryWithMetrics(ctx, "select", "event_outbox",
+		`SELECT id, event_type, aggregate_id, actor, request_id, payload, created_at
+		 FROM event_outbox WHERE published_at IS NULL ORDER BY id LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.BusinessEvent{}
+	for rows.Next() {
+		var e models.BusinessEvent
+		var payload []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.AggregateID, &e.Actor, &e.RequestID, &payload, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		e.Payload = json.RawMessage(payload)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// MarkEventsPublished отмечает события опубликованными
+func (s *StorageData) MarkEventsPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := s.execWithMetrics(ctx, "update", "event_outbox",
+		`UPDATE event_outbox SET published_at = $2 WHERE id = ANY($1)`, ids, s.now())
+	return err
+}
+
+// PurgePublishedEvents удаляет опубликованные события старше before
+func (s *StorageData) PurgePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.execWithMetrics(ctx, "delete", "event_outbox",
+		`DELETE FROM event_outbox WHERE published_at IS NOT NULL AND published_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// MaxOpenReviewsLimit - верхняя граница max_open_reviews в настройках команды
+const MaxOpenReviewsLimit = 100
+
+// filterByCapacity убирает кандидатов, у которых уже maxOpen неодобренных ревью открытых PR.
+// Второе значение сообщает, был ли кто-то отсеян. maxOpen 0 - без предела.
+func (s *StorageData) filterByCapacity(ctx context.Context, tx *sql.Tx, candidates []string, maxOpen int) ([]string, bool, error) {
+	if maxOpen <= 0 || len(candidates) == 0 {
+		return candidates, false, nil
+	}
+
+	loads, err := s.getPendingReviewLoads(ctx, tx, candidates)
+	if err != nil {
+		return nil, false, err
+	}
+	free, filtered := domain.FilterByCapacity(candidates, loads, maxOpen)
+	return free, filtered, nil
+}
+
+// getPendingReviewLoads возвращает число неодобренных ревью открытых PR у каждого кандидата
+func (s *StorageData) getPendingReviewLoads(ctx context.Context, tx *sql.Tx, candidates []string) (map[string]int, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_reviewers",
+		`SELECT r.user_id, COUNT(*)
+		 FROM pr_reviewers r
+		 JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		 WHERE p.status = 'OPEN' AND r.approved_at IS NULL AND r.user_id = ANY($1)
+		 GROUP BY r.user_id`, candidates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loads := make(map[string]int, len(candidates))
+	for rows.Next() {
+		var uid string
+		var load int
+		if err := rows.Scan(&uid, &load); err != nil {
+			return nil, err
+		}
+		loads[uid] = load
+	}
+	return loads, rows.Err()
+}
+
+// enqueuePR ставит PR в конец очереди команды и возвращает его позицию (1 - первый)
+func (s *StorageData) enqueuePR(ctx context.Context, tx *sql.Tx, prID, teamName string) (int, error) {
+	if _, err := s.txExecWithMetrics(tx, ctx, "insert", "review_queue",
+		`INSERT INTO review_queue(pull_request_id, team_name, enqueued_at) VALUES($1,$2,$3)`, prID, teamName, s.now()); err != nil {
+		return 0, err
+	}
+
+	var position int
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "review_queue",
+		`SELECT COUNT(*) FROM review_queue
+		 WHERE team_name = $1
+		   AND position <= (SELECT position FROM review_queue WHERE pull_request_id = $2)`,
+		teamName, prID).Scan(&position)
+	return position, err
+}
+
+// dequeuePR убирает PR из очереди: ревьюер назначен вручную или PR смержен
+func (s *StorageData) dequeuePR(ctx context.Context, tx *sql.Tx, prID string) error {
+	_, err := s.txExecWithMetrics(tx, ctx, "delete", "review_queue",
+		`DELETE FROM review_queue WHERE pull_request_id = $1`, prID)
+	return err
+}
+
+// queueDepth возвращает число PR, ждущих ревьюеров в очереди команды
+func (s *StorageData) queueDepth(ctx context.Context, tx *sql.Tx, teamName string) (int, error) {
+	var depth int
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "review_queue",
+		`SELECT COUNT(*) FROM review_queue WHERE team_name = $1`, teamName).Scan(&depth)
+	return depth, err
+}
+
+// assignQueuedPRsForReviewers разбирает очереди команд, в которых у ревьюеров освободилась емкость
+func (s *StorageData) assignQueuedPRsForReviewers(ctx context.Context, tx *sql.Tx, reviewers []string) error {
+	if len(reviewers) == 0 {
+		return nil
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "team_members",
+		`SELECT DISTINCT team_name FROM team_members WHERE user_id = ANY($1) ORDER BY team_name`, reviewers)
+	if err != nil {
+		return err
+	}
+	var teams []string
+	for rows.Next() {
+		var team string
+		if err := rows.Scan(&team); err != nil {
+			rows.Close()
+			return err
+		}
+		teams = append(teams, team)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, team := range teams {
+		if _, err := s.assignQueuedPRs(ctx, tx, team); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignQueuedPRs назначает ревьюеров PR из очереди команды в порядке постановки, пока
+// для первого в очереди PR находятся ревьюеры со свободной емкостью. Очередь строго FIFO:
+// PR не обгоняет стоящий перед ним. Возвращает PR, получившие ревьюеров.
+func (s *StorageData) assignQueuedPRs(ctx context.Context, tx *sql.Tx, teamName string) ([]string, error) {
+	// Очередь команды разбирает одна транзакция за раз
+	if _, err := s.txExecWithMetrics(tx, ctx, "lock", "review_queue",
+		`SELECT pg_advisory_xact_lock(hashtext('review_queue'), hashtext($1))`, teamName); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.getTeamSettings(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	reviewerCount, err := s.adaptReviewerCount(ctx, tx, settings, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var assigned []string
+	for {
+		var prID, prName, authorID string
+		err := s.txQueryRowWithMetrics(tx, ctx, "select", "review_queue",
+			`SELECT q.pull_request_id, p.pull_request_name, p.author_id
+			 FROM review_queue q
+			 JOIN pull_requests p ON p.pull_request_id = q.pull_request_id
+			 WHERE q.team_name = $1
+			 ORDER BY q.position
+			 LIMIT 1
+			 FOR UPDATE OF p`, teamName).Scan(&prID, &prName, &authorID)
+		if err == sql.ErrNoRows {
+			return assigned, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		candidates, err := s.queuedPRCandidates(ctx, tx, prID, authorID, teamName, settings.MaxOpenReviews)
+		if err != nil {
+			return nil, err
+		}
+		input, err := s.buildAssignmentInput(ctx, tx, settings, prID, candidates, reviewerCount)
+		if err != nil {
+			return nil, err
+		}
+		if input.OnDuty, err = s.preferredOnDuty(ctx, tx, settings); err != nil {
+			return nil, err
+		}
+		selected := domain.SelectReviewers(input)
+		if len(selected) == 0 {
+			return assigned, nil
+		}
+
+		// PR мог покинуть очередь, пока мы ждали блокировку (ручное назначение, merge)
+		res, err := s.txExecWithMetrics(tx, ctx, "delete", "review_queue",
+			`DELETE FROM review_queue WHERE pull_request_id = $1`, prID)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 0 {
+			continue
+		}
+
+		if err := s.recordAssignmentDecision(ctx, tx, "queue_assign", models.AssignmentPRMetadata{
+			PullRequestID:   prID,
+			PullRequestName: prName,
+			AuthorID:        authorID,
+			TeamName:        teamName,
+		}, input, selected); err != nil {
+			return nil, err
+		}
+
+		for _, uid := range selected {
+			if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_reviewers",
+				`INSERT INTO pr_reviewers(pull_request_id, user_id, assigned_at) VALUES($1,$2,$3)`, prID, uid, s.now()); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, prID, "queue_assign", map[string]interface{}{
+			"reviewers": selected,
+		}); err != nil {
+			return nil, err
+		}
+		if err := s.recordEvent(ctx, tx, EventPRQueueAssigned, prID, map[string]interface{}{
+			"pull_request_id": prID,
+			"team_name":       teamName,
+			"reviewers":       selected,
+		}); err != nil {
+			return nil, err
+		}
+		assigned = append(assigned, prID)
+	}
+}
+
+// queuedPRCandidates возвращает активных участников команды со свободной емкостью,
+// которых можно назначить на PR из очереди
+func (s *StorageData) queuedPRCandidates(ctx context.Context, tx *sql.Tx, prID, authorID, teamName string, maxOpen int) ([]string, error) {
+	excluded, err := s.getExcludedReviewers(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	skip := make(map[string]bool, len(excluded))
+	for _, uid := range excluded {
+		skip[uid] = true
+	}
+
+	candidates, err := s.getPRCandidates(ctx, tx, teamName, authorID, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, _, err = s.filterByCapacity(ctx, tx, candidates, maxOpen)
+	return candidates, err
+}
+
+// GetReviewQueue возвращает PR, ждущие ревьюеров в очереди команды, в порядке очереди
+func (s *StorageData) GetReviewQueue(ctx context.Context, teamName string) ([]models.QueuedPR, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "review_queue",
+		`SELECT q.pull_request_id, p.pull_request_name, p.author_id, q.enqueued_at
+		 FROM review_queue q
+		 JOIN pull_requests p ON p.pull_request_id = q.pull_request_id
+		 WHERE q.team_name = $1
+		 ORDER BY q.position`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queue := []models.QueuedPR{}
+	now := s.now()
+	for rows.Next() {
+		q := models.QueuedPR{Position: len(queue) + 1}
+		if err := rows.Scan(&q.PullRequestID, &q.PullRequestName, &q.AuthorID, &q.EnqueuedAt); err != nil {
+			return nil, err
+		}
+		q.WaitSeconds = int64(now.Sub(q.EnqueuedAt).Seconds())
+		queue = append(queue, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return queue, tx.Commit()
+}
+
+// ReviewQueueDepths возвращает глубину очереди ревью по командам с непустой очередью
+func (s *StorageData) ReviewQueueDepths(ctx context.Context) (map[string]int, error) {
+	rows, err := s.queryWithMetrics(ctx, "select", "review_queue",
+		`SELECT team_name, COUNT(*) FROM review_queue GROUP BY team_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	depths := make(map[string]int)
+	for rows.Next() {
+		var team string
+		var depth int
+		if err := rows.Scan(&team, &depth); err != nil {
+			return nil, err
+		}
+		depths[team] = depth
+	}
+	return depths, rows.Err()
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/models"
+)
+
+// Периоды квоты назначений: скользящие окна, а не календарные сутки и недели
+const (
+	QuotaPeriodDay  = "day"
+	QuotaPeriodWeek = "week"
+)
+
+// MaxAssignmentQuota - предел max_assignments квоты назначений
+const MaxAssignmentQuota = 100
+
+// IsKnownQuotaPeriod проверяет, поддерживается ли период квоты
+func IsKnownQuotaPeriod(period string) bool {
+	return period == QuotaPeriodDay || period == QuotaPeriodWeek
+}
+
+// quotaWindow возвращает длину скользящего окна периода квоты
+func quotaWindow(period string) time.Duration {
+	if period == QuotaPeriodWeek {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// quotaState - квота пользователя и назначения в ее окне
+type quotaState struct {
+	Max    int
+	Period string
+	Used   int
+}
+
+// exhausted проверяет, исчерпана ли квота
+func (q quotaState) exhausted() bool {
+	return q.Used >= q.Max
+}
+
+// SetAssignmentQuota задает квоту назначений пользователя; MaxAssignments = 0 снимает квоту
+func (s *StorageData) SetAssignmentQuota(ctx context.Context, quota models.AssignmentQuota) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := s.txQueryRowWithMetrics(tx, ctx, "select", "users",
+		`SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)`, quota.UserID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("user not found")
+	}
+
+	var before, after interface{}
+	var prev models.AssignmentQuota
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "user_assignment_quotas",
+		`SELECT max_assignments, period FROM user_assignment_quotas WHERE user_id = $1 FOR UPDATE`, quota.UserID).
+		Scan(&prev.MaxAssignments, &prev.Period)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		before = map[string]interface{}{"max_assignments": prev.MaxAssignments, "period": prev.Period}
+	}
+
+	action := "set_assignment_quota"
+	var details interface{} = map[string]interface{}{
+		"max_assignments": quota.MaxAssignments,
+		"period":          quota.Period,
+	}
+	after = details
+	if quota.MaxAssignments == 0 {
+		action = "remove_assignment_quota"
+		details = nil
+		after = nil
+		if _, err := s.txExecWithMetrics(tx, ctx, "delete", "user_assignment_quotas",
+			`DELETE FROM user_assignment_quotas WHERE user_id = $1`, quota.UserID); err != nil {
+			return err
+		}
+	} else if _, err := s.txExecWithMetrics(tx, ctx, "upsert", "user_assignment_quotas",
+		`INSERT INTO user_assignment_quotas(user_id, max_assignments, period, updated_by, updated_at)
+		 VALUES($1,$2,$3,$4,$5)
+		 ON CONFLICT (user_id) DO UPDATE SET max_assignments = EXCLUDED.max_assignments,
+		 period = EXCLUDED.period, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		quota.UserID, quota.MaxAssignments, quota.Period, authctx.ActorID(ctx), s.now()); err != nil {
+		return err
+	}
+
+	if err := s.recordAuditChange(ctx, tx, AuditEntityUser, quota.UserID, action, details,
+		map[string]interface{}{"assignment_quota": before}, map[string]interface{}{"assignment_quota": after}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAssignmentQuota возвращает квоту назначений пользователя и ее использование.
+// Без квоты used считается за последние сутки.
+func (s *StorageData) GetAssignmentQuota(ctx context.Context, userID string) (*models.AssignmentQuotaStatus, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := s.txQueryRowWithMetrics(tx, ctx, "select", "users",
+		`SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)`, userID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	status := &models.AssignmentQuotaStatus{
+		AssignmentQuota: models.AssignmentQuota{UserID: userID, Period: QuotaPeriodDay},
+	}
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "user_assignment_quotas",
+		`SELECT max_assignments, period FROM user_assignment_quotas WHERE user_id = $1`, userID).
+		Scan(&status.MaxAssignments, &status.Period)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	var oldest sql.NullTime
+	if err := s.txQueryRowWithMetrics(tx, ctx, "select", "pr_reviewers",
+		`SELECT COUNT(*), MIN(assigned_at) FROM pr_reviewers WHERE user_id = $1 AND assigned_at > $2`,
+		userID, s.now().Add(-quotaWindow(status.Period))).Scan(&status.Used, &oldest); err != nil {
+		return nil, err
+	}
+
+	if status.MaxAssignments > 0 {
+		remaining := status.MaxAssignments - status.Used
+		if remaining < 0 {
+			remaining = 0
+		}
+		status.Remaining = &remaining
+		if remaining == 0 && oldest.Valid {
+			freesAt := oldest.Time.Add(quotaWindow(status.Period)).UTC()
+			status.FreesAt = &freesAt
+		}
+	}
+	return status, tx.Commit()
+}
+
+// getQuotaStates возвращает квоты кандидатов, у которых она задана, с использованием в окне.
+// Учитываются все назначения пользователя в окне, в том числе ручные; снятые с PR - нет.
+func (s *StorageData) getQuotaStates(ctx context.Context, tx *sql.Tx, candidates []string) (map[string]quotaState, error) {
+	states := make(map[string]quotaState)
+	if len(candidates) == 0 {
+		return states, nil
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "user_assignment_quotas",
+		`SELECT q.user_id, q.max_assignments, q.period, COUNT(r.user_id)
+		 FROM user_assignment_quotas q
+		 LEFT JOIN pr_reviewers r ON r.user_id = q.user_id
+		   AND r.assigned_at > CASE q.period WHEN $3 THEN $2::timestamptz - interval '7 days'
+		                                     ELSE $2::timestamptz - interval '1 day' END
+		 WHERE q.user_id = ANY($1)
+		 GROUP BY q.user_id, q.max_assignments, q.period`, candidates, s.now(), QuotaPeriodWeek)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uid string
+		var st quotaState
+		if err := rows.Scan(&uid, &st.Max, &st.Period, &st.Used); err != nil {
+			return nil, err
+		}
+		states[uid] = st
+	}
+	return states, rows.Err()
+}
+
+// filterByQuota убирает из кандидатов исчерпавших квоту назначений. Назначение не
+// теряется: PR без ревьюеров встает в очередь команды и получает их, когда окно сдвинется.
+func (s *StorageData) filterByQuota(ctx context.Context, tx *sql.Tx, candidates []string) ([]string, []string, error) {
+	states, err := s.getQuotaStates(ctx, tx, candidates)
+	if err != nil || len(states) == 0 {
+		return candidates, nil, err
+	}
+	allowed, deferred := splitByQuota(candidates, states)
+	return allowed, deferred, nil
+}
+
+// splitByQuota делит кандидатов на доступных и исчерпавших квоту, сохраняя порядок
+func splitByQuota(candidates []string, states map[string]quotaState) ([]string, []string) {
+	allowed := make([]string, 0, len(candidates))
+	var deferred []string
+	for _, uid := range candidates {
+		if st, ok := states[uid]; ok && st.exhausted() {
+			deferred = append(deferred, uid)
+			continue
+		}
+		allowed = append(allowed, uid)
+	}
+	return allowed, deferred
+}
+
+// quotaDeferredSlots возвращает, сколько из count мест не заполнить из-за квот:
+// мест не хватает доступным кандидатам, а исчерпавшие квоту могли бы их занять
+func quotaDeferredSlots(count, allowed, deferred int) int {
+	missing := count - allowed
+	if missing <= 0 || deferred == 0 {
+		return 0
+	}
+	if deferred < missing {
+		return deferred
+	}
+	return missing
+}
+
+// DrainReviewQueues назначает ревьюеров PR из очередей всех команд. Освобождение
+// емкости при одобрении и мердже разбирает очередь сразу, а место в квоте назначений
+// освобождается со временем, поэтому очереди периодически разбираются и по расписанию.
+func (s *StorageData) DrainReviewQueues(ctx context.Context) (int, error) {
+	rows, err := s.queryWithMetrics(ctx, "select", "review_queue",
+		`SELECT DISTINCT team_name FROM review_queue ORDER BY team_name`)
+	if err != nil {
+		return 0, err
+	}
+	var teams []string
+	for rows.Next() {
+		var team string
+		if err := rows.Scan(&team); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		teams = append(teams, team)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	assigned := 0
+	for _, team := range teams {
+		n, err := s.drainTeamQueue(ctx, team)
+		if err != nil {
+			return assigned, err
+		}
+		assigned += n
+	}
+	return assigned, nil
+}
+
+func (s *StorageData) drainTeamQueue(ctx context.Context, teamName string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	prs, err := s.assignQueuedPRs(ctx, tx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	return len(prs), tx.Commit()
+}
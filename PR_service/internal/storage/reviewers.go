@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// Источники назначения ревьюера
+const (
+	ReviewerSourceAuto   = "auto"
+	ReviewerSourceManual = "manual"
+)
+
+// PRConflictError - операция с PR отклонена из-за его текущего состояния.
+// Несет PR на момент отказа, чтобы клиент мог синхронизироваться без повторного запроса.
+type PRConflictError struct {
+	Reason string
+	PR     *models.PullRequest
+}
+
+func (e *PRConflictError) Error() string {
+	return e.Reason
+}
+
+// prConflict дополняет PR ревьюерами и возвращает ошибку конфликта с ним
+func (s *StorageData) prConflict(ctx context.Context, tx *sql.Tx, pr *models.PullRequest, reason string) error {
+	reviewers, err := s.getReviewersForPR(ctx, tx, pr.PullRequestID)
+	if err != nil {
+		return err
+	}
+	pr.Reviewers = reviewers
+	return &PRConflictError{Reason: reason, PR: pr}
+}
+
+// AddReviewer назначает PR дополнительного ревьюера сверх обычного числа, никого не снимая.
+// Если userID пуст, ревьюер выбирается по стратегии команды автора (с заимствованием
+// из пула, если в команде никого не осталось). Возвращает PR и добавленного ревьюера.
+func (s *StorageData) AddReviewer(ctx context.Context, prID, userID string) (*models.PullRequest, string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	var pr models.PullRequest
+	var mergedAt sql.NullTime
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
+		 FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE`, prID).
+		Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &mergedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", fmt.Errorf("pr not found")
+		}
+		return nil, "", err
+	}
+	if pr.Status == "MERGED" {
+		return nil, "", fmt.Errorf("cannot modify reviewers after merge")
+	}
+
+	assigned, err := s.getReviewersForPR(ctx, tx, prID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(assigned) >= MaxReviewerCount {
+		return nil, "", fmt.Errorf("reviewer limit reached")
+	}
+
+	excluded, err := s.getExcludedReviewers(ctx, tx, prID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	teamName, err := s.getUserTeam(ctx, tx, pr.AuthorID)
+	if err != nil {
+		if err.Error() == "user not in any team" {
+			return nil, "", fmt.Errorf("author is not in any team")
+		}
+		return nil, "", err
+	}
+
+	skip := map[string]bool{pr.AuthorID: true}
+	for _, uid := range assigned {
+		skip[uid] = true
+	}
+	for _, uid := range excluded {
+		skip[uid] = true
+	}
+
+	meta := models.AssignmentPRMetadata{
+		PullRequestID:     prID,
+		PullRequestName:   pr.PullRequestName,
+		AuthorID:          pr.AuthorID,
+		TeamName:          teamName,
+		ExcludedReviewers: excluded,
+	}
+
+	var added string
+	if userID != "" {
+		if err := s.checkManualReviewer(ctx, tx, pr.AuthorID, userID, assigned, excluded); err != nil {
+			return nil, "", err
+		}
+
+		// Ручной выбор тоже попадает в журнал решений: единственный кандидат воспроизводится
+		input := domain.NewAssignmentInput(domain.StrategyRandom, time.Now().UnixNano(), []string{userID}, nil, nil, 1)
+		if err := s.recordAssignmentDecision(ctx, tx, "add_reviewer", meta, input, []string{userID}); err != nil {
+			return nil, "", err
+		}
+		added = userID
+	} else {
+		settings, err := s.getTeamSettings(ctx, tx, teamName)
+		if err != nil {
+			return nil, "", err
+		}
+
+		candidates, err := s.getActiveTeamMembers(ctx, tx, teamName, skip)
+		if err != nil {
+			return nil, "", err
+		}
+
+		input, err := s.buildAssignmentInput(ctx, tx, settings, prID, candidates, 1)
+		if err != nil {
+			return nil, "", err
+		}
+		selected := domain.SelectReviewers(input)
+		if err := s.recordAssignmentDecision(ctx, tx, "add_reviewer", meta, input, selected); err != nil {
+			return nil, "", err
+		}
+
+		if len(selected) == 0 {
+			exclude := append(append([]string{}, assigned...), excluded...)
+			selected, err = s.borrowReviewers(ctx, tx, settings, meta, exclude, 1)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+		if len(selected) == 0 {
+			return nil, "", fmt.Errorf("no active candidate available")
+		}
+		added = selected[0]
+	}
+
+	// Дополнительный ревьюер попадает на текущий этап цепочки одобрений PR
+	if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_reviewers",
+		`INSERT INTO pr_reviewers(pull_request_id, user_id, source, stage, assigned_at)
+		 SELECT $1, $2, $3, approval_stage, $4 FROM pull_requests WHERE pull_request_id = $1`,
+		prID, added, ReviewerSourceManual, s.now()); err != nil {
+		return nil, "", err
+	}
+
+	// PR из очереди, получивший ревьюера вручную, больше не ждет
+	if err := s.dequeuePR(ctx, tx, prID); err != nil {
+		return nil, "", err
+	}
+
+	pr.Reviewers = append(assigned, added)
+	if mergedAt.Valid {
+		mergedAtStr := mergedAt.Time.Format(time.RFC3339)
+		pr.MergedAt = &mergedAtStr
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, prID, "add_reviewer", map[string]interface{}{
+		"reviewer_id": added,
+		"source":      ReviewerSourceManual,
+		"auto_picked": userID == "",
+	}); err != nil {
+		return nil, "", err
+	}
+
+	if err := s.recordEvent(ctx, tx, EventPRReviewerAdded, prID, map[string]interface{}{
+		"pr":          pr,
+		"reviewer_id": added,
+		"source":      ReviewerSourceManual,
+	}); err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+	return &pr, added, nil
+}
+
+// checkManualReviewer проверяет, что указанного вручную пользователя можно назначить на PR
+func (s *StorageData) checkManualReviewer(ctx context.Context, tx *sql.Tx, authorID, userID string, assigned, excluded []string) error {
+	if userID == authorID {
+		return fmt.Errorf("author cannot review own PR")
+	}
+	for _, uid := range assigned {
+		if uid == userID {
+			return fmt.Errorf("reviewer already assigned")
+		}
+	}
+	for _, uid := range excluded {
+		if uid == userID {
+			return fmt.Errorf("reviewer excluded by author")
+		}
+	}
+
+	var active, bot bool
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "users",
+		`SELECT is_active, is_bot FROM users WHERE user_id = $1`, userID).Scan(&active, &bot)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user not found")
+		}
+		return err
+	}
+	if !active {
+		return fmt.Errorf("reviewer is inactive")
+	}
+	if bot {
+		return fmt.Errorf("reviewer is a bot")
+	}
+	return nil
+}
+
+// getExcludedReviewers возвращает ревьюеров, исключенных автором при создании PR
+func (s *StorageData) getExcludedReviewers(ctx context.Context, tx *sql.Tx, prID string) ([]string, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_excluded_reviewers",
+		`SELECT user_id FROM pr_excluded_reviewers WHERE pull_request_id = $1 ORDER BY user_id`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var excluded []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		excluded = append(excluded, uid)
+	}
+	return excluded, rows.Err()
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// migrationsLockKey - ключ advisory lock на время применения миграций
+const migrationsLockKey int64 = 0x50525f6d696772 // "PR_migr"
+
+// SchemaVersion - номер последней миграции в migrationsDDL
+var SchemaVersion = latestMigration(migrationsDDL)
+
+var migrationHeaderRe = regexp.MustCompile(`(?m)^-- (\d{4}) `)
+
+func latestMigration(ddl string) int {
+	latest := 0
+	for _, m := range migrationHeaderRe.FindAllStringSubmatch(ddl, -1) {
+		if n, _ := strconv.Atoi(m[1]); n > latest {
+			latest = n
+		}
+	}
+	return latest
+}
+
+// VerifySchema проверяет без DDL, что схема мигрирована до версии этой сборки,
+// а у текущей роли есть права на чтение и изменение всех таблиц.
+// Используется, когда сервис работает под ролью без права DDL, а миграции
+// применяются отдельным шагом под ролью миграций.
+func VerifySchema(ctx context.Context, db *sql.DB) error {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT version FROM schema_version WHERE id = 1`).Scan(&version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("schema version is not recorded; run migrations")
+		}
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if version < SchemaVersion {
+		return fmt.Errorf("schema version %d is older than required %d; run migrations", version, SchemaVersion)
+	}
+
+	// Колонка dirty гарантированно есть: она появилась в миграции не новее требуемой
+	var dirty bool
+	if err := db.QueryRowContext(ctx, `SELECT dirty FROM schema_version WHERE id = 1`).Scan(&dirty); err != nil {
+		return fmt.Errorf("read schema state: %w", err)
+	}
+	if dirty {
+		return &DirtySchemaError{Version: version}
+	}
+
+	for _, table := range DataTables {
+		var allowed bool
+		err := db.QueryRowContext(ctx,
+			`SELECT has_table_privilege($1, 'SELECT, INSERT, UPDATE, DELETE')`, table).Scan(&allowed)
+		if err != nil {
+			return fmt.Errorf("check privileges on %s: %w", table, err)
+		}
+		if !allowed {
+			return fmt.Errorf("current role lacks SELECT, INSERT, UPDATE, DELETE on %s", table)
+		}
+	}
+	return nil
+}
+
+// GrantRuntimeAccess выдает роли приложения только права на данные: чтение и изменение
+// таблиц и использование последовательностей, без DDL и владения таблицами.
+// Права на таблицы, созданные будущими миграциями, выдаются по умолчанию.
+func GrantRuntimeAccess(db *sql.DB, role string) error {
+	if role == "" {
+		return fmt.Errorf("runtime role is required")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var schemaName string
+	if err := tx.QueryRow(`SELECT current_schema()`).Scan(&schemaName); err != nil {
+		return err
+	}
+	schema := pgx.Identifier{schemaName}.Sanitize()
+	grantee := pgx.Identifier{role}.Sanitize()
+
+	for _, stmt := range []string{
+		"GRANT USAGE ON SCHEMA " + schema + " TO " + grantee,
+		"GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA " + schema + " TO " + grantee,
+		"GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA " + schema + " TO " + grantee,
+		"ALTER DEFAULT PRIVILEGES IN SCHEMA " + schema + " GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO " + grantee,
+		"ALTER DEFAULT PRIVILEGES IN SCHEMA " + schema + " GRANT USAGE, SELECT ON SEQUENCES TO " + grantee,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("grant runtime access to %s: %w", role, err)
+		}
+	}
+	return tx.Commit()
+}
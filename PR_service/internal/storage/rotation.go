@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// MaxRotationWeeks - на сколько недель вперед можно посмотреть расписание дежурств
+const MaxRotationWeeks = 26
+
+// preferredOnDuty возвращает дежурного текущей недели, если команда отдает ему первый слот
+func (s *StorageData) preferredOnDuty(ctx context.Context, tx *sql.Tx, settings models.TeamSettings) (string, error) {
+	if !settings.PreferOnDuty {
+		return "", nil
+	}
+	week := domain.WeekStart(s.now())
+	overrides, err := s.getRotationOverrides(ctx, tx, settings.TeamName, week, 1)
+	if err != nil {
+		return "", err
+	}
+	if o, ok := overrides[week.Format("2006-01-02")]; ok {
+		return o.UserID, nil
+	}
+	members, err := s.getActiveTeamMembers(ctx, tx, settings.TeamName, nil)
+	if err != nil {
+		return "", err
+	}
+	return domain.RotationPick(members, week), nil
+}
+
+// getRotationOverrides возвращает ручные назначения дежурных на weeks недель с from по дате недели
+func (s *StorageData) getRotationOverrides(ctx context.Context, tx *sql.Tx, teamName string, from time.Time, weeks int) (map[string]models.RotationWeek, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "team_rotation_overrides",
+		`SELECT to_char(week_start, 'YYYY-MM-DD'), user_id, set_by
+		 FROM team_rotation_overrides
+		 WHERE team_name = $1 AND week_start >= $2::date AND week_start < $3::date`,
+		teamName, from, from.AddDate(0, 0, 7*weeks))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]models.RotationWeek)
+	for rows.Next() {
+		var w models.RotationWeek
+		if err := rows.Scan(&w.WeekStart, &w.UserID, &w.SetBy); err != nil {
+			return nil, err
+		}
+		w.Overridden = true
+		overrides[w.WeekStart] = w
+	}
+	return overrides, rows.Err()
+}
+
+// GetTeamRotation возвращает дежурных команды на weeks недель, начиная с текущей
+func (s *StorageData) GetTeamRotation(ctx context.Context, teamName string, weeks int) (*models.TeamRotation, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rotation, err := s.buildTeamRotation(ctx, tx, teamName, weeks)
+	if err != nil {
+		return nil, err
+	}
+	return rotation, tx.Commit()
+}
+
+func (s *StorageData) buildTeamRotation(ctx context.Context, tx *sql.Tx, teamName string, weeks int) (*models.TeamRotation, error) {
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+	settings, err := s.getTeamSettings(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.getActiveTeamMembers(ctx, tx, teamName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	from := domain.WeekStart(s.now())
+	overrides, err := s.getRotationOverrides(ctx, tx, teamName, from, weeks)
+	if err != nil {
+		return nil, err
+	}
+
+	rotation := &models.TeamRotation{
+		TeamName:     teamName,
+		PreferOnDuty: settings.PreferOnDuty,
+		Weeks:        make([]models.RotationWeek, 0, weeks),
+	}
+	for i := 0; i < weeks; i++ {
+		week := from.AddDate(0, 0, 7*i)
+		key := week.Format("2006-01-02")
+		if o, ok := overrides[key]; ok {
+			rotation.Weeks = append(rotation.Weeks, o)
+			continue
+		}
+		rotation.Weeks = append(rotation.Weeks, models.RotationWeek{WeekStart: key, UserID: domain.RotationPick(members, week)})
+	}
+	return rotation, nil
+}
+
+// SetRotationOverride назначает дежурного на неделю вместо очереди; пустой userID
+// возвращает неделю очереди. Менять расписание могут только лиды команды.
+func (s *StorageData) SetRotationOverride(ctx context.Context, teamName string, week time.Time, userID string) (*models.TeamRotation, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+	if err := s.requireTeamLead(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	week = domain.WeekStart(week)
+	current := domain.WeekStart(s.now())
+	if week.Before(current) {
+		return nil, fmt.Errorf("cannot change rotation for past weeks")
+	}
+	weeks := int(week.Sub(current).Hours()/24)/7 + 1
+	if weeks > MaxRotationWeeks {
+		return nil, fmt.Errorf("week_start must be within %d weeks", MaxRotationWeeks)
+	}
+
+	if userID == "" {
+		if _, err := s.txExecWithMetrics(tx, ctx, "delete", "team_rotation_overrides",
+			`DELETE FROM team_rotation_overrides WHERE team_name = $1 AND week_start = $2::date`, teamName, week); err != nil {
+			return nil, err
+		}
+	} else {
+		var member bool
+		if err := s.txQueryRowWithMetrics(tx, ctx, "select", "team_members",
+			`SELECT EXISTS(SELECT 1 FROM team_members WHERE team_name = $1 AND user_id = $2)`,
+			teamName, userID).Scan(&member); err != nil {
+			return nil, err
+		}
+		if !member {
+			return nil, fmt.Errorf("user is not a member of the team")
+		}
+
+		if _, err := s.txExecWithMetrics(tx, ctx, "upsert", "team_rotation_overrides",
+			`INSERT INTO team_rotation_overrides(team_name, week_start, user_id, set_by, updated_at)
+			 VALUES($1,$2::date,$3,$4,$5)
+			 ON CONFLICT (team_name, week_start) DO UPDATE SET user_id = EXCLUDED.user_id,
+			 set_by = EXCLUDED.set_by, updated_at = EXCLUDED.updated_at`,
+			teamName, week, userID, authctx.ActorID(ctx), s.now()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityTeamSettings, teamName, "rotation_override", map[string]interface{}{
+		"week_start": week.Format("2006-01-02"),
+		"user_id":    userID,
+	}); err != nil {
+		return nil, err
+	}
+
+	rotation, err := s.buildTeamRotation(ctx, tx, teamName, weeks)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return rotation, nil
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/domain"
+	"PR_service/internal/expr"
+	"PR_service/internal/models"
+)
+
+// Ограничения маршрутов уведомлений
+const (
+	MaxNotificationRouteNameLength    = 64
+	MaxNotificationRouteChannelLength = 200
+)
+
+// SetNotificationRoute сохраняет маршрут уведомлений команды. Пустой канал удаляет маршрут.
+func (s *StorageData) SetNotificationRoute(ctx context.Context, route models.NotificationRoute) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, route.TeamName); err != nil {
+		return err
+	}
+
+	action := "set_notification_route"
+	if route.Channel == "" {
+		action = "delete_notification_route"
+		res, err := s.txExecWithMetrics(tx, ctx, "delete", "notification_routes",
+			`DELETE FROM notification_routes WHERE team_name = $1 AND name = $2`, route.TeamName, route.Name)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return fmt.Errorf("notification route not found")
+		}
+	} else {
+		if _, err := s.txExecWithMetrics(tx, ctx, "upsert", "notification_routes",
+			`INSERT INTO notification_routes(team_name, name, condition, channel, updated_by, updated_at)
+			 VALUES($1,$2,$3,$4,$5,$6)
+			 ON CONFLICT (team_name, name) DO UPDATE SET condition = EXCLUDED.condition,
+			 channel = EXCLUDED.channel, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+			route.TeamName, route.Name, route.Condition, route.Channel, authctx.ActorID(ctx), s.now()); err != nil {
+			return err
+		}
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityTeam, route.TeamName, action, map[string]interface{}{
+		"name":      route.Name,
+		"condition": route.Condition,
+		"channel":   route.Channel,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetNotificationRoutes возвращает маршруты уведомлений команды по имени
+func (s *StorageData) GetNotificationRoutes(ctx context.Context, teamName string) ([]models.NotificationRoute, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "notification_routes",
+		`SELECT name, condition, channel, updated_by, updated_at FROM notification_routes
+		 WHERE team_name = $1 ORDER BY name`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	routes := []models.NotificationRoute{}
+	for rows.Next() {
+		route := models.NotificationRoute{TeamName: teamName}
+		var updatedAt time.Time
+		if err := rows.Scan(&route.Name, &route.Condition, &route.Channel, &route.UpdatedBy, &updatedAt); err != nil {
+			return nil, err
+		}
+		route.UpdatedAt = &updatedAt
+		routes = append(routes, route)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return routes, tx.Commit()
+}
+
+// MatchNotificationRoutes возвращает каналы маршрутов команды автора PR, условия которых
+// истинны для уведомления kind о PR, и ошибки вычисления условий (см. domain.MatchNotificationRoutes)
+func (s *StorageData) MatchNotificationRoutes(ctx context.Context, prID, kind string) ([]string, []string, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var authorID, status, priority string
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT author_id, status, priority FROM pull_requests WHERE pull_request_id = $1`, prID).
+		Scan(&authorID, &status, &priority)
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("pr not found")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	teamName, err := s.getUserTeam(ctx, tx, authorID)
+	if err != nil {
+		if err.Error() == "user not in any team" {
+			return []string{}, nil, tx.Commit()
+		}
+		return nil, nil, err
+	}
+
+	labels, err := s.getPRTags(ctx, tx, prID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if labels == nil {
+		labels = []string{}
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "notification_routes",
+		`SELECT name, condition, channel FROM notification_routes WHERE team_name = $1 ORDER BY name`, teamName)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var routes []models.NotificationRoute
+	for rows.Next() {
+		route := models.NotificationRoute{TeamName: teamName}
+		if err := rows.Scan(&route.Name, &route.Condition, &route.Channel); err != nil {
+			return nil, nil, err
+		}
+		routes = append(routes, route)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	channels, routeErrors := domain.MatchNotificationRoutes(routes, expr.Vars{
+		"labels": labels, "kind": kind, "priority": priority, "status": status,
+	})
+	return channels, routeErrors, tx.Commit()
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"time"
+
+	"PR_service/internal/domain"
+)
+
+// selectionStrategyForced - метка стратегии для принудительного выбора тестового стенда
+const selectionStrategyForced = "forced"
+
+// AssignmentSelection - один автоматический выбор ревьюеров для метрик стратегий
+type AssignmentSelection struct {
+	Strategy   string
+	Duration   time.Duration  // Сбор входных данных и выбор
+	Considered int            // Кандидаты до правил отбора
+	Requested  int            // Сколько ревьюеров нужно было выбрать
+	Selected   int            // Сколько выбрано
+	Filtered   map[string]int // Кандидаты, отсеянные каждым правилом
+	Fallbacks  []string       // Использованные запасные варианты
+}
+
+// observeSelection передает в метрики выбор ревьюеров. Входные данные, собранные
+// не buildAssignmentInput (ручное назначение, повтор решения), не учитываются.
+func (s *StorageData) observeSelection(operation string, in domain.AssignmentInput, selected []string) {
+	if s.metrics == nil || in.Stats.Started.IsZero() {
+		return
+	}
+
+	strategy := in.Strategy
+	if len(in.Forced) > 0 {
+		strategy = selectionStrategyForced
+	}
+	fallbacks := append([]string(nil), in.Stats.Fallbacks...)
+	if operation == "borrow" {
+		fallbacks = append(fallbacks, domain.SelectionFallbackBorrow)
+	}
+	for _, uid := range selected {
+		if in.Focused[uid] {
+			fallbacks = append(fallbacks, domain.SelectionFallbackFocused)
+			break
+		}
+	}
+
+	s.metrics.ObserveAssignmentSelection(AssignmentSelection{
+		Strategy:   strategy,
+		Duration:   time.Since(in.Stats.Started),
+		Considered: in.Stats.Considered,
+		Requested:  in.Count,
+		Selected:   len(selected),
+		Filtered:   in.Stats.Filtered,
+		Fallbacks:  fallbacks,
+	})
+}
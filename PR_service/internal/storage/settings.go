@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// DefaultReviewerCount - число ревьюеров на PR, если команда не задала свое
+const DefaultReviewerCount = 2
+
+// MaxReviewerCount - верхняя граница числа ревьюеров в настройках команды
+const MaxReviewerCount = 10
+
+// defaultTeamSettings возвращает настройки команды по умолчанию
+func defaultTeamSettings(teamName string) models.TeamSettings {
+	return models.TeamSettings{
+		TeamName:      teamName,
+		ReviewerCount: DefaultReviewerCount,
+		Strategy:      domain.StrategyRandom,
+		MergePolicy:   domain.MergePolicyNone,
+
+		ApprovalStages: []models.ApprovalStage{},
+	}
+}
+
+// getTeamSettings возвращает настройки команды или значения по умолчанию
+func (s *StorageData) getTeamSettings(ctx context.Context, tx *sql.Tx, teamName string) (models.TeamSettings, error) {
+	settings := defaultTeamSettings(teamName)
+	var stages []byte
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "team_settings", teamSettingsQuery, teamName).
+		Scan(&settings.ReviewerCount, &settings.Strategy, &settings.MergePolicy, &settings.RequiredApprovals,
+			&settings.MaxOpenReviews, &settings.AdaptiveBacklogThreshold, &settings.AdaptiveReviewerCount,
+			&settings.ReviewerCountReduced, &settings.NewMemberHoldbackDays,
+			&settings.PRNamePattern, &settings.RequireTicketReference, &settings.MinTags, &settings.PreferOnDuty, &stages,
+			&settings.CandidateFilter, &settings.CandidateRank, &settings.ReviewSLAHours,
+			&settings.Locale, &settings.Timezone)
+	if err != nil && err != sql.ErrNoRows {
+		return settings, err
+	}
+	if len(stages) > 0 {
+		if err := json.Unmarshal(stages, &settings.ApprovalStages); err != nil {
+			return settings, err
+		}
+	}
+	return settings, nil
+}
+
+// GetTeamSettings возвращает настройки назначения ревьюеров команды
+func (s *StorageData) GetTeamSettings(ctx context.Context, teamName string) (*models.TeamSettings, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.getTeamSettings(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, tx.Commit()
+}
+
+// PreviewTeamSettings показывает, как новые настройки повлияют на открытые PR команды
+func (s *StorageData) PreviewTeamSettings(ctx context.Context, proposed models.TeamSettings) (*models.TeamSettingsImpact, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	impact, err := s.buildSettingsImpact(ctx, tx, proposed)
+	if err != nil {
+		return nil, err
+	}
+	return impact, tx.Commit()
+}
+
+// ApplyTeamSettings сохраняет настройки и доназначает ревьюеров открытым PR,
+// которым их теперь не хватает
+func (s *StorageData) ApplyTeamSettings(ctx context.Context, proposed models.TeamSettings) (*models.TeamSettingsApplyResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	impact, err := s.buildSettingsImpact(ctx, tx, proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Настройки команды меняют только ее лиды
+	if err := s.requireTeamLead(ctx, tx, proposed.TeamName); err != nil {
+		return nil, err
+	}
+
+	if proposed.ApprovalStages == nil {
+		proposed.ApprovalStages = []models.ApprovalStage{}
+	}
+	stages, err := json.Marshal(proposed.ApprovalStages)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "upsert", "team_settings",
+		`INSERT INTO team_settings(team_name, reviewer_count, strategy, merge_policy, required_approvals,
+		 max_open_reviews, adaptive_backlog_threshold, adaptive_reviewer_count, new_member_holdback_days,
+		 pr_name_pattern, require_ticket_reference, min_tags, prefer_on_duty, approval_stages,
+		 candidate_filter, candidate_rank, review_sla_hours, locale, timezone, updated_at)
+		 VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)
+		 ON CONFLICT (team_name) DO UPDATE SET reviewer_count = EXCLUDED.reviewer_count,
+		 strategy = EXCLUDED.strategy, merge_policy = EXCLUDED.merge_policy,
+		 required_approvals = EXCLUDED.required_approvals, max_open_reviews = EXCLUDED.max_open_reviews,
+		 adaptive_backlog_threshold = EXCLUDED.adaptive_backlog_threshold,
+		 adaptive_reviewer_count = EXCLUDED.adaptive_reviewer_count,
+		 reviewer_count_reduced = team_settings.reviewer_count_reduced AND EXCLUDED.adaptive_backlog_threshold > 0,
+		 new_member_holdback_days = EXCLUDED.new_member_holdback_days,
+		 pr_name_pattern = EXCLUDED.pr_name_pattern, require_ticket_reference = EXCLUDED.require_ticket_reference,
+		 min_tags = EXCLUDED.min_tags, prefer_on_duty = EXCLUDED.prefer_on_duty,
+		 approval_stages = EXCLUDED.approval_stages, candidate_filter = EXCLUDED.candidate_filter,
+		 candidate_rank = EXCLUDED.candidate_rank, review_sla_hours = EXCLUDED.review_sla_hours,
+		 locale = EXCLUDED.locale, timezone = EXCLUDED.timezone, updated_at = EXCLUDED.updated_at`,
+		proposed.TeamName, proposed.ReviewerCount, proposed.Strategy, proposed.MergePolicy,
+		proposed.RequiredApprovals, proposed.MaxOpenReviews, proposed.AdaptiveBacklogThreshold,
+		proposed.AdaptiveReviewerCount, proposed.NewMemberHoldbackDays,
+		proposed.PRNamePattern, proposed.RequireTicketReference, proposed.MinTags, proposed.PreferOnDuty,
+		string(stages), proposed.CandidateFilter, proposed.CandidateRank, proposed.ReviewSLAHours,
+		proposed.Locale, proposed.Timezone, s.now()); err != nil {
+		return nil, err
+	}
+
+	// Снимок после изменения хранит то же состояние снижения, что записал upsert
+	after := proposed
+	after.ReviewerCountReduced = impact.Current.ReviewerCountReduced && proposed.AdaptiveBacklogThreshold > 0
+	if err := s.recordAuditChange(ctx, tx, AuditEntityTeamSettings, proposed.TeamName, "apply", proposed,
+		impact.Current, after); err != nil {
+		return nil, err
+	}
+
+	result := &models.TeamSettingsApplyResult{
+		Settings: proposed,
+		Updated:  []models.PRTopUp{},
+	}
+
+	for _, pr := range impact.AffectedPRs {
+		if pr.Missing == 0 {
+			continue
+		}
+
+		added, err := s.topUpReviewers(ctx, tx, proposed, pr)
+		if err != nil {
+			return nil, err
+		}
+		result.Updated = append(result.Updated, models.PRTopUp{
+			PullRequestID: pr.PullRequestID,
+			Added:         added,
+			StillMissing:  pr.Missing - len(added),
+		})
+	}
+
+	// Открытые PR, уже прошедшие текущий этап новой цепочки, переходят к следующему
+	for _, pr := range impact.AffectedPRs {
+		if _, err := s.advanceApprovalStages(ctx, tx, models.AssignmentPRMetadata{
+			PullRequestID:   pr.PullRequestID,
+			PullRequestName: pr.PullRequestName,
+			AuthorID:        pr.AuthorID,
+			TeamName:        proposed.TeamName,
+		}, proposed); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// buildSettingsImpact сравнивает число ревьюеров открытых PR команды с новыми настройками
+func (s *StorageData) buildSettingsImpact(ctx context.Context, tx *sql.Tx, proposed models.TeamSettings) (*models.TeamSettingsImpact, error) {
+	if err := s.ensureTeamExists(ctx, tx, proposed.TeamName); err != nil {
+		return nil, err
+	}
+
+	current, err := s.getTeamSettings(ctx, tx, proposed.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT p.pull_request_id, p.pull_request_name, p.author_id, r.user_id
+		 FROM pull_requests p
+		 LEFT JOIN pr_reviewers r ON r.pull_request_id = p.pull_request_id
+		 WHERE p.status = 'OPEN'
+		   AND p.author_id IN (SELECT user_id FROM team_members WHERE team_name = $1)
+		 ORDER BY p.created_at, p.pull_request_id, r.user_id`, proposed.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prs []models.PRSettingsImpact
+	for rows.Next() {
+		var prID, prName, authorID string
+		var reviewer sql.NullString
+		if err := rows.Scan(&prID, &prName, &authorID, &reviewer); err != nil {
+			return nil, err
+		}
+		if len(prs) == 0 || prs[len(prs)-1].PullRequestID != prID {
+			prs = append(prs, models.PRSettingsImpact{
+				PullRequestID:    prID,
+				PullRequestName:  prName,
+				AuthorID:         authorID,
+				CurrentReviewers: []string{},
+			})
+		}
+		if reviewer.Valid {
+			last := &prs[len(prs)-1]
+			last.CurrentReviewers = append(last.CurrentReviewers, reviewer.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	impact := &models.TeamSettingsImpact{
+		TeamName:        proposed.TeamName,
+		Current:         current,
+		Proposed:        proposed,
+		StrategyChanged: current.Strategy != proposed.Strategy,
+		AffectedPRs:     []models.PRSettingsImpact{},
+	}
+	for _, pr := range prs {
+		pr.Required = proposed.ReviewerCount
+		if diff := proposed.ReviewerCount - len(pr.CurrentReviewers); diff > 0 {
+			pr.Missing = diff
+		} else {
+			pr.Excess = -diff
+		}
+		if pr.Missing > 0 || pr.Excess > 0 {
+			impact.AffectedPRs = append(impact.AffectedPRs, pr)
+		}
+	}
+	return impact, nil
+}
+
+// topUpReviewers доназначает PR недостающих ревьюеров из команды автора
+func (s *StorageData) topUpReviewers(ctx context.Context, tx *sql.Tx, settings models.TeamSettings, pr models.PRSettingsImpact) ([]string, error) {
+	excluded := map[string]bool{pr.AuthorID: true}
+	for _, uid := range pr.CurrentReviewers {
+		excluded[uid] = true
+	}
+
+	candidates, err := s.getActiveTeamMembers(ctx, tx, settings.TeamName, excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := s.buildAssignmentInput(ctx, tx, settings, pr.PullRequestID, candidates, pr.Missing)
+	if err != nil {
+		return nil, err
+	}
+	selected := domain.SelectReviewers(input)
+
+	meta := models.AssignmentPRMetadata{
+		PullRequestID:   pr.PullRequestID,
+		PullRequestName: pr.PullRequestName,
+		AuthorID:        pr.AuthorID,
+		TeamName:        settings.TeamName,
+	}
+	if err := s.recordAssignmentDecision(ctx, tx, "top_up", meta, input, selected); err != nil {
+		return nil, err
+	}
+
+	if len(selected) < pr.Missing {
+		borrowed, err := s.borrowReviewers(ctx, tx, settings, meta,
+			append(pr.CurrentReviewers, selected...), pr.Missing-len(selected))
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, borrowed...)
+	}
+
+	for _, uid := range selected {
+		if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_reviewers",
+			`INSERT INTO pr_reviewers(pull_request_id, user_id, assigned_at) VALUES($1,$2,$3)`,
+			pr.PullRequestID, uid, s.now()); err != nil {
+			return nil, err
+		}
+	}
+
+	if selected == nil {
+		selected = []string{}
+	}
+	return selected, nil
+}
+
+// ensureTeamExists возвращает ошибку "team not found", если команды нет
+func (s *StorageData) ensureTeamExists(ctx context.Context, tx *sql.Tx, teamName string) error {
+	var exists bool
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "teams",
+		`SELECT EXISTS(SELECT 1 FROM teams WHERE team_name = $1)`, teamName).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("team not found")
+	}
+	return nil
+}
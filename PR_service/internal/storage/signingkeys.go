@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/models"
+)
+
+// DefaultWebhookKeyGrace - сколько прежние ключи подписывают вебхуки после ротации,
+// чтобы потребители успели перейти на новый секрет
+const DefaultWebhookKeyGrace = 24 * time.Hour
+
+// MaxWebhookKeyGrace - верхняя граница периода перехода на новый ключ
+const MaxWebhookKeyGrace = 7 * 24 * time.Hour
+
+// randomHex возвращает n случайных байт в hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// RotateWebhookSigningKey выпускает новый ключ подписи исходящих вебхуков. Действующие
+// ключи истекают через grace: до этого запросы подписываются и старым, и новым ключом.
+// Возвращает новый ключ вместе с секретом - больше он нигде не показывается.
+func (s *StorageData) RotateWebhookSigningKey(ctx context.Context, grace time.Duration) (*models.WebhookSigningKey, error) {
+	suffix, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	key := models.WebhookSigningKey{
+		KeyID:     "whk_" + suffix,
+		Secret:    secret,
+		CreatedBy: authctx.ActorID(ctx),
+		CreatedAt: now,
+		Active:    true,
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Срок уже истекающих ключей не продлевается
+	expires := now.Add(grace)
+	retired, err := s.txExecWithMetrics(tx, ctx, "update", "webhook_signing_keys",
+		`UPDATE webhook_signing_keys SET expires_at = $1
+		 WHERE expires_at IS NULL OR expires_at > $1`, expires)
+	if err != nil {
+		return nil, err
+	}
+	retiredCount, err := retired.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "insert", "webhook_signing_keys",
+		`INSERT INTO webhook_signing_keys(key_id, secret, created_by, created_at) VALUES($1,$2,$3,$4)`,
+		key.KeyID, key.Secret, key.CreatedBy, key.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityWebhookKey, key.KeyID, "rotate_webhook_signing_key", map[string]interface{}{
+		"grace_seconds": int64(grace / time.Second),
+		"retired_keys":  retiredCount,
+	}); err != nil {
+		return nil, err
+	}
+	return &key, tx.Commit()
+}
+
+// RevokeWebhookSigningKey немедленно прекращает подпись вебхуков ключом keyID
+func (s *StorageData) RevokeWebhookSigningKey(ctx context.Context, keyID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := s.now()
+	var exists bool
+	if err := s.txQueryRowWithMetrics(tx, ctx, "select", "webhook_signing_keys",
+		`SELECT EXISTS(SELECT 1 FROM webhook_signing_keys WHERE key_id = $1)`, keyID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("signing key not found")
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "update", "webhook_signing_keys",
+		`UPDATE webhook_signing_keys SET expires_at = $2
+		 WHERE key_id = $1 AND (expires_at IS NULL OR expires_at > $2)`, keyID, now); err != nil {
+		return err
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityWebhookKey, keyID, "revoke_webhook_signing_key", nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListWebhookSigningKeys возвращает ключи подписи без секретов, новые первыми
+func (s *StorageData) ListWebhookSigningKeys(ctx context.Context) ([]models.WebhookSigningKey, error) {
+	return s.webhookSigningKeys(ctx, false)
+}
+
+// ActiveWebhookSigningKeys возвращает действующие ключи подписи с секретами, новые первыми
+func (s *StorageData) ActiveWebhookSigningKeys(ctx context.Context) ([]models.WebhookSigningKey, error) {
+	return s.webhookSigningKeys(ctx, true)
+}
+
+func (s *StorageData) webhookSigningKeys(ctx context.Context, activeOnly bool) ([]models.WebhookSigningKey, error) {
+	now := s.now()
+	rows, err := s.queryWithMetrics(ctx, "select", "webhook_signing_keys",
+		`SELECT key_id, secret, created_by, created_at, expires_at
+		 FROM webhook_signing_keys
+		 WHERE NOT $1 OR expires_at IS NULL OR expires_at > $2
+		 ORDER BY created_at DESC, key_id`, activeOnly, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.WebhookSigningKey{}
+	for rows.Next() {
+		var key models.WebhookSigningKey
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&key.KeyID, &key.Secret, &key.CreatedBy, &key.CreatedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			key.ExpiresAt = &expiresAt.Time
+		}
+		key.Active = key.ExpiresAt == nil || key.ExpiresAt.After(now)
+		if !activeOnly {
+			key.Secret = ""
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
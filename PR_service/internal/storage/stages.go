@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// getStageReviewers возвращает ревьюеров PR с этапами и отметкой лида команды
+func (s *StorageData) getStageReviewers(ctx context.Context, tx *sql.Tx, prID, teamName string) ([]domain.StageReviewer, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_reviewers",
+		`SELECT r.user_id, r.stage, r.approved_at IS NOT NULL,
+		        EXISTS(SELECT 1 FROM team_leads l WHERE l.team_name = $2 AND l.user_id = r.user_id)
+		 FROM pr_reviewers r
+		 WHERE r.pull_request_id = $1
+		 ORDER BY r.stage, r.user_id`, prID, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviewers []domain.StageReviewer
+	for rows.Next() {
+		var r domain.StageReviewer
+		if err := rows.Scan(&r.UserID, &r.Stage, &r.Approved, &r.Lead); err != nil {
+			return nil, err
+		}
+		reviewers = append(reviewers, r)
+	}
+	return reviewers, rows.Err()
+}
+
+// getApprovalProgress возвращает текущий этап PR и прогресс по всем этапам команды
+func (s *StorageData) getApprovalProgress(ctx context.Context, tx *sql.Tx, prID, teamName string, stages []models.ApprovalStage) (int, []models.ApprovalStageProgress, error) {
+	var current int
+	if err := s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT approval_stage FROM pull_requests WHERE pull_request_id = $1`, prID).Scan(&current); err != nil {
+		return 0, nil, err
+	}
+	reviewers, err := s.getStageReviewers(ctx, tx, prID, teamName)
+	if err != nil {
+		return 0, nil, err
+	}
+	return current, domain.EvaluateApprovalStages(stages, current, reviewers), nil
+}
+
+// advanceApprovalStages переводит PR на следующие этапы, пока текущий завершен, и назначает
+// ревьюеров нового этапа. PR в очереди команды не продвигается: ревьюеров первого этапа
+// у него еще нет. Возвращает назначенных ревьюеров.
+func (s *StorageData) advanceApprovalStages(ctx context.Context, tx *sql.Tx, meta models.AssignmentPRMetadata, settings models.TeamSettings) ([]string, error) {
+	stages := settings.ApprovalStages
+	if len(stages) < 2 {
+		return nil, nil
+	}
+
+	var queued bool
+	if err := s.txQueryRowWithMetrics(tx, ctx, "select", "review_queue",
+		`SELECT EXISTS(SELECT 1 FROM review_queue WHERE pull_request_id = $1)`, meta.PullRequestID).Scan(&queued); err != nil {
+		return nil, err
+	}
+	if queued {
+		return nil, nil
+	}
+
+	var added []string
+	for {
+		current, progress, err := s.getApprovalProgress(ctx, tx, meta.PullRequestID, meta.TeamName, stages)
+		if err != nil {
+			return nil, err
+		}
+		if current >= len(stages) || !progress[current-1].Completed {
+			return added, nil
+		}
+
+		next := current + 1
+		selected, err := s.assignStageReviewers(ctx, tx, meta, settings, next)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.txExecWithMetrics(tx, ctx, "update", "pull_requests",
+			`UPDATE pull_requests SET approval_stage = $2 WHERE pull_request_id = $1`, meta.PullRequestID, next); err != nil {
+			return nil, err
+		}
+
+		if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, meta.PullRequestID, "advance_approval_stage", map[string]interface{}{
+			"stage":     next,
+			"kind":      stages[next-1].Kind,
+			"reviewers": selected,
+		}); err != nil {
+			return nil, err
+		}
+		if err := s.recordEvent(ctx, tx, EventPRStageAdvanced, meta.PullRequestID, map[string]interface{}{
+			"pull_request_id": meta.PullRequestID,
+			"stage":           next,
+			"kind":            stages[next-1].Kind,
+			"reviewers":       selected,
+		}); err != nil {
+			return nil, err
+		}
+		added = append(added, selected...)
+	}
+}
+
+// assignStageReviewers назначает ревьюеров этапа stage по стратегии команды: лидов
+// для этапа lead или участников команды для этапа peer. Автор, уже назначенные
+// и исключенные ревьюеры не выбираются.
+func (s *StorageData) assignStageReviewers(ctx context.Context, tx *sql.Tx, meta models.AssignmentPRMetadata, settings models.TeamSettings, stage int) ([]string, error) {
+	assigned, err := s.getReviewersForPR(ctx, tx, meta.PullRequestID)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := s.getExcludedReviewers(ctx, tx, meta.PullRequestID)
+	if err != nil {
+		return nil, err
+	}
+	skip := map[string]bool{meta.AuthorID: true}
+	for _, uid := range assigned {
+		skip[uid] = true
+	}
+	for _, uid := range excluded {
+		skip[uid] = true
+	}
+
+	candidates, err := s.getActiveTeamMembers(ctx, tx, meta.TeamName, skip)
+	if err != nil {
+		return nil, err
+	}
+	if domain.IsLeadStage(settings, stage) {
+		if candidates, err = s.filterTeamLeads(ctx, tx, meta.TeamName, candidates); err != nil {
+			return nil, err
+		}
+	}
+
+	input, err := s.buildAssignmentInput(ctx, tx, settings, meta.PullRequestID, candidates, settings.ApprovalStages[stage-1].Approvals)
+	if err != nil {
+		return nil, err
+	}
+	selected := domain.SelectReviewers(input)
+	meta.ExcludedReviewers = excluded
+	if err := s.recordAssignmentDecision(ctx, tx, "approval_stage", meta, input, selected); err != nil {
+		return nil, err
+	}
+
+	for _, uid := range selected {
+		if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_reviewers",
+			`INSERT INTO pr_reviewers(pull_request_id, user_id, stage, assigned_at) VALUES($1,$2,$3,$4)`,
+			meta.PullRequestID, uid, stage, s.now()); err != nil {
+			return nil, err
+		}
+	}
+	return selected, nil
+}
+
+// filterTeamLeads оставляет среди кандидатов только лидов команды
+func (s *StorageData) filterTeamLeads(ctx context.Context, tx *sql.Tx, teamName string, candidates []string) ([]string, error) {
+	leads, err := s.getTeamLeads(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	isLead := make(map[string]bool, len(leads))
+	for _, uid := range leads {
+		isLead[uid] = true
+	}
+	filtered := make([]string, 0, len(candidates))
+	for _, uid := range candidates {
+		if isLead[uid] {
+			filtered = append(filtered, uid)
+		}
+	}
+	return filtered, nil
+}
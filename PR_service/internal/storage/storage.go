@@ -8,20 +8,28 @@ import (
 	"math/rand"
 	"time"
 
+	"PR_service/internal/clock"
+	"PR_service/internal/domain"
 	"PR_service/internal/models"
 )
 
 type StorageData struct {
 	db      *sql.DB
 	metrics MetricsInterface // Интерфейс для метрик
+	events  bool             // Записывать бизнес-события в event_outbox
+	clock   clock.Clock      // Источник времени для записываемых меток и окон фокуса
+
+	botAuthorsTeam string // Команда для автосоздаваемых ботов-авторов; пусто - автосоздание выключено
 }
 
 type MetricsInterface interface {
 	ObserveDBQuery(operation, table string, duration time.Duration)
+	IncAssignmentQuotaDeferred(team string, slots int)
+	ObserveAssignmentSelection(sel AssignmentSelection)
 }
 
 func NewStorage(db *sql.DB) *StorageData {
-	return &StorageData{db: db}
+	return &StorageData{db: db, clock: clock.System}
 }
 
 // SetMetrics устанавливает метрики (можно вызвать после инициализации)
@@ -29,9 +37,18 @@ func (s *StorageData) SetMetrics(metrics MetricsInterface) {
 	s.metrics = metrics
 }
 
-// ApplyMigrations применяет миграции базы данных
-func ApplyMigrations(db *sql.DB) error {
-	ddl := `-- 0001 init
+// SetClock подменяет источник времени (в тестах - clock.Fake)
+func (s *StorageData) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// now возвращает текущее время по часам storage в UTC
+func (s *StorageData) now() time.Time {
+	return s.clock.Now().UTC()
+}
+
+// migrationsDDL - схема базы данных; все шаги идемпотентны
+const migrationsDDL = `-- 0001 init
 CREATE TABLE IF NOT EXISTS teams (
   team_name TEXT PRIMARY KEY
 );
@@ -67,9 +84,331 @@ CREATE TABLE IF NOT EXISTS pr_reviewers (
 CREATE INDEX IF NOT EXISTS idx_team_members_team ON team_members(team_name);
 CREATE INDEX IF NOT EXISTS idx_users_active ON users(is_active);
 CREATE INDEX IF NOT EXISTS idx_pr_created_at ON pull_requests(created_at); -- Добавлен индекс
+
+-- 0002 focus windows
+CREATE TABLE IF NOT EXISTS user_focus_windows (
+  user_id TEXT REFERENCES users(user_id) ON DELETE CASCADE,
+  weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+  start_minute SMALLINT NOT NULL CHECK (start_minute BETWEEN 0 AND 1439),
+  end_minute SMALLINT NOT NULL CHECK (end_minute BETWEEN 1 AND 1440),
+  PRIMARY KEY (user_id, weekday, start_minute)
+);
+
+-- 0003 assignment decisions
+CREATE TABLE IF NOT EXISTS assignment_decisions (
+  id BIGSERIAL PRIMARY KEY,
+  pull_request_id TEXT REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+  operation TEXT NOT NULL,
+  strategy TEXT NOT NULL,
+  seed BIGINT NOT NULL,
+  requested INT NOT NULL,
+  candidates JSONB NOT NULL,
+  weights JSONB NOT NULL,
+  focused JSONB NOT NULL,
+  selected JSONB NOT NULL,
+  pr_metadata JSONB NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignment_decisions_pr ON assignment_decisions(pull_request_id);
+
+-- 0004 reviewer borrowing between teams
+CREATE TABLE IF NOT EXISTS team_borrow_pools (
+  team_name TEXT REFERENCES teams(team_name) ON DELETE CASCADE,
+  lender_team TEXT REFERENCES teams(team_name) ON DELETE CASCADE,
+  priority INT NOT NULL DEFAULT 0,
+  max_net_borrowed INT NOT NULL DEFAULT 0, -- 0 - без ограничения
+  PRIMARY KEY (team_name, lender_team)
+);
+
+CREATE TABLE IF NOT EXISTS review_borrows (
+  pull_request_id TEXT REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+  user_id TEXT REFERENCES users(user_id) ON DELETE CASCADE,
+  borrower_team TEXT NOT NULL,
+  lender_team TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (pull_request_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_borrows_teams ON review_borrows(borrower_team, lender_team);
+
+-- 0005 maintenance mode
+CREATE TABLE IF NOT EXISTS service_maintenance (
+  id SMALLINT PRIMARY KEY CHECK (id = 1),
+  enabled BOOLEAN NOT NULL DEFAULT false,
+  message TEXT NOT NULL DEFAULT '',
+  updated_by TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMP WITH TIME ZONE
+);
+
+-- 0006 team assignment settings
+CREATE TABLE IF NOT EXISTS team_settings (
+  team_name TEXT PRIMARY KEY REFERENCES teams(team_name) ON DELETE CASCADE,
+  reviewer_count INT NOT NULL DEFAULT 2 CHECK (reviewer_count BETWEEN 0 AND 10),
+  strategy TEXT NOT NULL DEFAULT 'random',
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 0007 approvals and review checklists
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS approved_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS checklist_items (
+  item_id BIGSERIAL PRIMARY KEY,
+  team_name TEXT NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+  text TEXT NOT NULL,
+  position INT NOT NULL,
+  archived_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_checklist_items_team ON checklist_items(team_name);
+
+CREATE TABLE IF NOT EXISTS pr_checklist_marks (
+  pull_request_id TEXT REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+  user_id TEXT REFERENCES users(user_id) ON DELETE CASCADE,
+  item_id BIGINT REFERENCES checklist_items(item_id) ON DELETE CASCADE,
+  checked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (pull_request_id, user_id, item_id)
+);
+
+-- 0008 merge policies
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS merge_policy TEXT NOT NULL DEFAULT 'none';
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS required_approvals INT NOT NULL DEFAULT 0;
+
+-- 0009 audit_log
+CREATE TABLE IF NOT EXISTS audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  actor TEXT NOT NULL,
+  request_id TEXT NOT NULL DEFAULT '',
+  entity_type TEXT NOT NULL,
+  entity_id TEXT NOT NULL,
+  action TEXT NOT NULL,
+  details JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+
+-- 0010 notification templates
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS notification_templates (
+  team_name TEXT NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  locale TEXT NOT NULL,
+  body TEXT NOT NULL,
+  updated_by TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY (team_name, kind, locale)
+);
+
+-- 0011 event_outbox
+CREATE TABLE IF NOT EXISTS event_outbox (
+  id BIGSERIAL PRIMARY KEY,
+  event_type TEXT NOT NULL,
+  aggregate_id TEXT NOT NULL,
+  actor TEXT NOT NULL,
+  request_id TEXT NOT NULL DEFAULT '',
+  payload JSONB NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  published_at TIMESTAMP WITH TIME ZONE NULL
+);
+CREATE INDEX IF NOT EXISTS idx_event_outbox_unpublished ON event_outbox(id) WHERE published_at IS NULL;
+
+-- 0012 reviewer sources and author exclusions
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'auto';
+CREATE TABLE IF NOT EXISTS pr_excluded_reviewers (
+  pull_request_id TEXT REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL,
+  PRIMARY KEY (pull_request_id, user_id)
+);
+
+-- 0013 team leads
+CREATE TABLE IF NOT EXISTS team_leads (
+  team_name TEXT REFERENCES teams(team_name) ON DELETE CASCADE,
+  user_id TEXT REFERENCES users(user_id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (team_name, user_id)
+);
+
+-- 0014 schema version
+` + schemaVersionDDL + `
+-- 0015 review queue for PRs waiting on reviewer capacity
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS max_open_reviews INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS review_queue (
+  pull_request_id TEXT PRIMARY KEY REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+  team_name TEXT NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+  position BIGSERIAL,
+  enqueued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_queue_team ON review_queue(team_name, position);
+
+-- 0016 bot users: authors only, never reviewers
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT false;
+
+-- 0017 adaptive reviewer count under review backlog
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS adaptive_backlog_threshold INT NOT NULL DEFAULT 0;
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS adaptive_reviewer_count INT NOT NULL DEFAULT 0;
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS reviewer_count_reduced BOOLEAN NOT NULL DEFAULT false;
+
+-- 0018 PR tags for reviewer growth and knowledge spreading
+CREATE TABLE IF NOT EXISTS pr_tags (
+  pull_request_id TEXT REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+  tag TEXT NOT NULL,
+  PRIMARY KEY (pull_request_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_tags_tag ON pr_tags(tag);
+
+-- 0019 holdback for new team members; members added before it have no joined_at
+ALTER TABLE team_members ADD COLUMN IF NOT EXISTS joined_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS new_member_holdback_days INT NOT NULL DEFAULT 0;
+
+-- 0020 migration runner state: dirty flag and history
+` + migrationStateDDL + `
+-- 0021 assignment vetoes
+CREATE TABLE IF NOT EXISTS pr_vetoes (
+  id BIGSERIAL PRIMARY KEY,
+  pull_request_id TEXT REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL,
+  reason TEXT NOT NULL,
+  comment TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_vetoes_user_created ON pr_vetoes(user_id, created_at);
+
+-- 0022 PR template policies
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS pr_name_pattern TEXT NOT NULL DEFAULT '';
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS require_ticket_reference BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS min_tags INT NOT NULL DEFAULT 0;
+
+-- 0023 on-duty reviewer rotation
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS prefer_on_duty BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS team_rotation_overrides (
+  team_name TEXT REFERENCES teams(team_name) ON DELETE CASCADE,
+  week_start DATE NOT NULL,
+  user_id TEXT NOT NULL,
+  set_by TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY (team_name, week_start)
+);
+
+-- 0024 external identities
+CREATE TABLE IF NOT EXISTS external_identities (
+  provider TEXT NOT NULL,
+  external_id TEXT NOT NULL,
+  user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  updated_by TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY (provider, external_id)
+);
+CREATE INDEX IF NOT EXISTS idx_external_identities_user ON external_identities(user_id);
+
+-- 0025 approval stages
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS approval_stages JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS approval_stage INT NOT NULL DEFAULT 1;
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS stage INT NOT NULL DEFAULT 1;
+
+-- 0026 candidate filter and rank expressions
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS candidate_filter TEXT NOT NULL DEFAULT '';
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS candidate_rank TEXT NOT NULL DEFAULT '';
+
+-- 0027 assignment quotas; reviewers assigned before it have no assigned_at
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE pr_reviewers ALTER COLUMN assigned_at SET DEFAULT CURRENT_TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_pr_reviewers_user_assigned ON pr_reviewers(user_id, assigned_at);
+
+CREATE TABLE IF NOT EXISTS user_assignment_quotas (
+  user_id TEXT PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+  max_assignments INT NOT NULL,
+  period TEXT NOT NULL,
+  updated_by TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 0028 audit snapshots; NULL - no snapshot (earlier entries, actions without state)
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS before_state JSONB;
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS after_state JSONB;
+
+-- 0029 review urgency: PR priority and team review SLA; 0 - no deadline
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT 'normal';
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS review_sla_hours INT NOT NULL DEFAULT 0;
+
+-- 0030 webhook delivery dedupe; rows older than the dedupe TTL are reclaimed and purged
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  provider TEXT NOT NULL,
+  delivery_id TEXT NOT NULL,
+  event TEXT NOT NULL DEFAULT '',
+  received_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  PRIMARY KEY (provider, delivery_id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_received ON webhook_deliveries(received_at);
+
+-- 0031 online indexes for pending reviews and open PRs by author
+-- no-transaction
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_pr_reviewers_pending ON pr_reviewers(user_id) WHERE approved_at IS NULL;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_pull_requests_author_status ON pull_requests(author_id, status);
+
+-- 0032 team locale and timezone for notifications and reports; '' - user locale / UTC
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '';
+
+-- 0033 outgoing webhook signing keys; expires_at NULL - active until the next rotation
+CREATE TABLE IF NOT EXISTS webhook_signing_keys (
+  key_id TEXT PRIMARY KEY,
+  secret TEXT NOT NULL,
+  created_by TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE
+);
+
+-- 0034 label-driven notification routes; condition is an expression over PR labels
+CREATE TABLE IF NOT EXISTS notification_routes (
+  team_name TEXT NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  condition TEXT NOT NULL,
+  channel TEXT NOT NULL,
+  updated_by TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY (team_name, name)
+);
 `
-	_, err := db.Exec(ddl)
-	return err
+
+// DataTables - таблицы с данными сервиса в порядке зависимостей внешних ключей:
+// каждая таблица ссылается только на таблицы перед ней
+var DataTables = []string{
+	"teams",
+	"users",
+	"team_members",
+	"team_leads",
+	"pull_requests",
+	"pr_reviewers",
+	"pr_excluded_reviewers",
+	"user_focus_windows",
+	"assignment_decisions",
+	"team_borrow_pools",
+	"review_borrows",
+	"service_maintenance",
+	"team_settings",
+	"checklist_items",
+	"pr_checklist_marks",
+	"audit_log",
+	"notification_templates",
+	"event_outbox",
+	"schema_version",
+	"review_queue",
+	"pr_tags",
+	"schema_migrations",
+	"pr_vetoes",
+	"team_rotation_overrides",
+	"external_identities",
+	"user_assignment_quotas",
+	"webhook_deliveries",
+	"webhook_signing_keys",
+	"notification_routes",
 }
 
 // Обертки для методов БД с метриками
@@ -161,25 +500,77 @@ func (s *StorageData) UpsertTeam(ctx context.Context, t models.Team) error {
 	for _, u := range t.Members {
 		// Создает/обновляет пользователя с team_name
 		if _, err := s.txExecWithMetrics(tx, ctx, "upsert", "users",
-			`INSERT INTO users(user_id, username, team_name, is_active) VALUES($1,$2,$3,$4) 
-			 ON CONFLICT (user_id) DO UPDATE SET username=EXCLUDED.username, team_name=EXCLUDED.team_name`,
-			u.UserID, u.Username, t.TeamName, u.IsActive); err != nil {
+			`INSERT INTO users(user_id, username, team_name, is_active, is_bot) VALUES($1,$2,$3,$4,$5) 
+			 ON CONFLICT (user_id) DO UPDATE SET username=EXCLUDED.username, team_name=EXCLUDED.team_name, is_bot=EXCLUDED.is_bot`,
+			u.UserID, u.Username, t.TeamName, u.IsActive, u.IsBot); err != nil {
 			return err
 		}
 		// Добавляет в команду (если не состоит)
 		if _, err := s.txExecWithMetrics(tx, ctx, "insert", "team_members",
-			`INSERT INTO team_members(team_name,user_id) VALUES($1,$2) ON CONFLICT DO NOTHING`,
-			t.TeamName, u.UserID); err != nil {
+			`INSERT INTO team_members(team_name,user_id,joined_at) VALUES($1,$2,$3) ON CONFLICT DO NOTHING`,
+			t.TeamName, u.UserID, s.now()); err != nil {
+			return err
+		}
+		if err := s.recordEvent(ctx, tx, EventUserUpserted, u.UserID, models.User{
+			UserID:   u.UserID,
+			Username: u.Username,
+			TeamName: t.TeamName,
+			IsActive: u.IsActive,
+			IsBot:    u.IsBot,
+		}); err != nil {
 			return err
 		}
 	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityTeam, t.TeamName, "upsert", map[string]interface{}{
+		"members": t.Members,
+	}); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
 func (s *StorageData) SetUserActive(ctx context.Context, userID string, active bool) error {
-	_, err := s.execWithMetrics(ctx, "update", "users",
-		`UPDATE users SET is_active=$1 WHERE user_id=$2`, active, userID)
-	return err
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Прежнее значение нужно для снимка аудита; неизвестный пользователь, как и раньше, не ошибка
+	var wasActive sql.NullBool
+	if err := s.txQueryRowWithMetrics(tx, ctx, "select", "users",
+		`SELECT is_active FROM users WHERE user_id = $1 FOR UPDATE`, userID).Scan(&wasActive); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "update", "users",
+		`UPDATE users SET is_active=$1 WHERE user_id=$2`, active, userID); err != nil {
+		return err
+	}
+
+	var before, after interface{}
+	if wasActive.Valid {
+		before = map[string]interface{}{"is_active": wasActive.Bool}
+		after = map[string]interface{}{"is_active": active}
+	}
+	if err := s.recordAuditChange(ctx, tx, AuditEntityUser, userID, "set_is_active", map[string]interface{}{
+		"is_active": active,
+	}, before, after); err != nil {
+		return err
+	}
+
+	eventType := EventUserDeactivated
+	if active {
+		eventType = EventUserActivated
+	}
+	if err := s.recordEvent(ctx, tx, eventType, userID, map[string]interface{}{
+		"user_id":   userID,
+		"is_active": active,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *StorageData) CreatePR(ctx context.Context, pr models.CreatePRRequest) (*models.PullRequest, error) {
@@ -189,84 +580,67 @@ func (s *StorageData) CreatePR(ctx context.Context, pr models.CreatePRRequest) (
 	}
 	defer tx.Rollback()
 
-	// Проверяем существование автора
-	var authorExists bool
-	err = s.txQueryRowWithMetrics(tx, ctx, "select", "users",
-		`SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)`, pr.AuthorID).Scan(&authorExists)
+	// Автор, его команда и занятость идентификатора PR проверяются одним запросом
+	var authorExists, prExists bool
+	var authorTeam sql.NullString
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests", createPRChecksQuery,
+		pr.AuthorID, pr.PullRequestID).Scan(&authorExists, &authorTeam, &prExists)
 	if err != nil {
 		return nil, err
 	}
+	teamName := authorTeam.String
 	if !authorExists {
-		return nil, fmt.Errorf("author not found")
-	}
-
-	// Проверяем что автор состоит хотя бы в одной команде
-	var teamName string
-	err = s.txQueryRowWithMetrics(tx, ctx, "select", "team_members",
-		`SELECT team_name FROM team_members WHERE user_id = $1 LIMIT 1`, pr.AuthorID).Scan(&teamName)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("author is not in any team")
+		if !pr.AuthorIsBot || s.botAuthorsTeam == "" {
+			return nil, fmt.Errorf("author not found")
 		}
-		return nil, err
+		if err := s.createBotAuthor(ctx, tx, pr.AuthorID); err != nil {
+			return nil, err
+		}
+		teamName = s.botAuthorsTeam
+	} else if !authorTeam.Valid {
+		return nil, fmt.Errorf("author is not in any team")
+	}
+	if prExists {
+		return nil, fmt.Errorf("pr already exists")
 	}
 
-	// Проверяем существование PR
-	var prExists bool
-	err = s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests",
-		`SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)`, pr.PullRequestID).Scan(&prExists)
+	// Настройки читаются в той же транзакции, что и назначение: политики и стратегия
+	// видят одну и ту же версию настроек команды
+	settings, err := s.getTeamSettings(ctx, tx, teamName)
 	if err != nil {
 		return nil, err
 	}
-	if prExists {
-		return nil, fmt.Errorf("pr already exists")
+	if !pr.SkipPolicies {
+		if violations := domain.CheckPRPolicies(settings, pr); len(violations) > 0 {
+			return nil, &domain.PolicyViolationError{TeamName: teamName, Violations: violations}
+		}
+	}
+
+	priority := pr.Priority
+	if priority == "" {
+		priority = domain.PriorityNormal
 	}
 
 	// Создаем PR с created_at
 	if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pull_requests",
-		`INSERT INTO pull_requests(pull_request_id, pull_request_name, author_id, status, created_at) 
-		 VALUES($1,$2,$3,'OPEN', CURRENT_TIMESTAMP)`,
-		pr.PullRequestID, pr.PullRequestName, pr.AuthorID); err != nil {
+		`INSERT INTO pull_requests(pull_request_id, pull_request_name, author_id, status, created_at, priority) 
+		 VALUES($1,$2,$3,'OPEN',$4,$5)`,
+		pr.PullRequestID, pr.PullRequestName, pr.AuthorID, s.now(), priority); err != nil {
 		return nil, err
 	}
 
-	// Собираем активных кандидатов исключая автора
-	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "users",
-		`SELECT u.user_id 
-        FROM users u 
-        JOIN team_members tm ON u.user_id = tm.user_id 
-        WHERE tm.team_name = $1 AND u.is_active = true AND u.user_id <> $2`,
-		teamName, pr.AuthorID)
+	// Теги сохраняются до выбора ревьюеров: по ним работает стратегия knowledge_spread
+	tags, err := s.insertPRTags(ctx, tx, pr.PullRequestID, pr.Tags)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var candidates []string
-	for rows.Next() {
-		var uid string
-		if err := rows.Scan(&uid); err != nil {
-			return nil, err
-		}
-		candidates = append(candidates, uid)
-	}
-	if err := rows.Err(); err != nil {
+	// Назначаем ревьюеров или ставим PR в очередь команды
+	reviewers, queuePosition, err := s.assignNewPR(ctx, tx, pr, settings)
+	if err != nil {
 		return nil, err
 	}
 
-	// Выбираем до 2 случайных ревьюеров
-	selected := pickRandomDistinct(candidates, 2)
-	var reviewers []string
-
-	for _, r := range selected {
-		if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_reviewers",
-			`INSERT INTO pr_reviewers(pull_request_id, user_id) VALUES($1,$2)`,
-			pr.PullRequestID, r); err != nil {
-			return nil, err
-		}
-		reviewers = append(reviewers, r)
-	}
-
 	// Получаем созданный PR с датами
 	var createdAt time.Time
 	var mergedAt sql.NullTime
@@ -277,6 +651,30 @@ func (s *StorageData) CreatePR(ctx context.Context, pr models.CreatePRRequest) (
 		return nil, err
 	}
 
+	if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, pr.PullRequestID, "create", map[string]interface{}{
+		"pull_request_name": pr.PullRequestName,
+		"author_id":         pr.AuthorID,
+		"reviewers":         reviewers,
+		"queue_position":    queuePosition,
+		"tags":              tags,
+		"priority":          priority,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordEvent(ctx, tx, EventPRCreated, pr.PullRequestID, models.PullRequest{
+		PullRequestID:   pr.PullRequestID,
+		PullRequestName: pr.PullRequestName,
+		AuthorID:        pr.AuthorID,
+		Status:          "OPEN",
+		Reviewers:       reviewers,
+		CreatedAt:       createdAt,
+		Tags:            tags,
+		Priority:        priority,
+	}); err != nil {
+		return nil, err
+	}
+
 	// Коммитим транзакцию
 	if err := tx.Commit(); err != nil {
 		return nil, err
@@ -291,12 +689,139 @@ func (s *StorageData) CreatePR(ctx context.Context, pr models.CreatePRRequest) (
 		Reviewers:       reviewers,
 		CreatedAt:       createdAt,
 		MergedAt:        nil, // Будет nil пока PR не смержен
+		QueuePosition:   queuePosition,
+		Tags:            tags,
+		Priority:        priority,
+		TeamName:        teamName,
 	}
 
 	return createdPR, nil
 }
 
+// assignNewPR назначает ревьюеров новому PR по плану domain.PlanCreatePR: собирает данные
+// команды, сохраняет выбор и заимствованных ревьюеров, ставит PR в очередь или переходит
+// к следующему этапу одобрений. Возвращает ревьюеров и позицию в очереди (0 - не в очереди).
+func (s *StorageData) assignNewPR(ctx context.Context, tx *sql.Tx, pr models.CreatePRRequest, settings models.TeamSettings) ([]string, int, error) {
+	teamName := settings.TeamName
+
+	// Собираем активных кандидатов исключая автора
+	candidates, err := s.getPRCandidates(ctx, tx, teamName, pr.AuthorID, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// При большом бэклоге ревью команда может временно назначать меньше ревьюеров
+	reviewerCount, err := s.adaptReviewerCount(ctx, tx, settings, pr.PullRequestID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Решения о назначении принимает план, здесь только собираются данные и сохраняется итог
+	state := domain.CreatePRState{
+		Settings:      settings,
+		ReviewerCount: reviewerCount,
+		Candidates:    candidates,
+		Excluded:      pr.ExcludedReviewers,
+	}
+	if domain.ReviewQueueEnabled(settings) {
+		// Очередь разбирается до нового PR: ее PR получают освободившихся ревьюеров первыми
+		if _, err := s.assignQueuedPRs(ctx, tx, teamName); err != nil {
+			return nil, 0, err
+		}
+		if state.QueueDepth, err = s.queueDepth(ctx, tx, teamName); err != nil {
+			return nil, 0, err
+		}
+		if state.Loads, err = s.getPendingReviewLoads(ctx, tx, candidates); err != nil {
+			return nil, 0, err
+		}
+	}
+	plan, err := domain.PlanCreatePR(state)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, uid := range pr.ExcludedReviewers {
+		if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_excluded_reviewers",
+			`INSERT INTO pr_excluded_reviewers(pull_request_id, user_id) VALUES($1,$2)`,
+			pr.PullRequestID, uid); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// Выбираем ревьюеров по настройкам команды и сохраняем входные данные решения
+	input, err := s.buildAssignmentInput(ctx, tx, settings, pr.PullRequestID, plan.Candidates, plan.ReviewerCount)
+	if err != nil {
+		return nil, 0, err
+	}
+	if input.OnDuty, err = s.preferredOnDuty(ctx, tx, settings); err != nil {
+		return nil, 0, err
+	}
+	selected := domain.SelectReviewers(input)
+	meta := models.AssignmentPRMetadata{
+		PullRequestID:     pr.PullRequestID,
+		PullRequestName:   pr.PullRequestName,
+		AuthorID:          pr.AuthorID,
+		TeamName:          teamName,
+		ExcludedReviewers: pr.ExcludedReviewers,
+	}
+	if err := s.recordAssignmentDecision(ctx, tx, "create", meta, input, selected); err != nil {
+		return nil, 0, err
+	}
+
+	// Если в команде не хватило ревьюеров - занимаем у команд из пула
+	if slots := plan.BorrowSlots(len(selected)); slots > 0 {
+		exclude := append(append([]string{}, selected...), pr.ExcludedReviewers...)
+		borrowed, err := s.borrowReviewers(ctx, tx, settings, meta, exclude, slots)
+		if err != nil {
+			return nil, 0, err
+		}
+		selected = append(selected, borrowed...)
+	}
+	var reviewers []string
+	for _, r := range selected {
+		if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_reviewers",
+			`INSERT INTO pr_reviewers(pull_request_id, user_id, assigned_at) VALUES($1,$2,$3)`,
+			pr.PullRequestID, r, s.now()); err != nil {
+			return nil, 0, err
+		}
+		reviewers = append(reviewers, r)
+	}
+
+	queuePosition := 0
+	outcome := plan.Outcome(len(reviewers), len(input.QuotaDeferred) > 0)
+	if outcome.Enqueue {
+		if queuePosition, err = s.enqueuePR(ctx, tx, pr.PullRequestID, teamName); err != nil {
+			return nil, 0, err
+		}
+		if err := s.recordEvent(ctx, tx, EventPRQueued, pr.PullRequestID, map[string]interface{}{
+			"pull_request_id": pr.PullRequestID,
+			"team_name":       teamName,
+			"position":        queuePosition,
+		}); err != nil {
+			return nil, 0, err
+		}
+	}
+	if outcome.AdvanceStages {
+		added, err := s.advanceApprovalStages(ctx, tx, meta, settings)
+		if err != nil {
+			return nil, 0, err
+		}
+		reviewers = append(reviewers, added...)
+	}
+	return reviewers, queuePosition, nil
+}
+
+// MergePR переводит PR в MERGED, если выполнена политика одобрений команды
 func (s *StorageData) MergePR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	return s.mergePR(ctx, prID, true)
+}
+
+// SyncMergedPR отражает мердж, уже выполненный во внешней системе (вебхук Bitbucket):
+// политика одобрений не проверяется, потому что отменить мердж сервис не может
+func (s *StorageData) SyncMergedPR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	return s.mergePR(ctx, prID, false)
+}
+
+func (s *StorageData) mergePR(ctx context.Context, prID string, enforcePolicy bool) (*models.PullRequest, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -335,11 +860,31 @@ func (s *StorageData) MergePR(ctx context.Context, prID string) (*models.PullReq
 		return &pr, tx.Commit()
 	}
 
+	if pr.Status == "DECLINED" {
+		return nil, fmt.Errorf("pr is declined")
+	}
+
+	// Проверяем политику одобрений команды
+	if enforcePolicy {
+		if err := s.checkMergePolicy(ctx, tx, prID, pr.AuthorID); err != nil {
+			var missing *domain.ApprovalsMissingError
+			if errors.As(err, &missing) {
+				reviewers, rerr := s.getReviewersForPR(ctx, tx, prID)
+				if rerr != nil {
+					return nil, rerr
+				}
+				pr.Reviewers = reviewers
+				missing.PR = &pr
+			}
+			return nil, err
+		}
+	}
+
 	// Обновляем статус на MERGED и устанавливаем время мерджа
 	_, err = s.txExecWithMetrics(tx, ctx, "update", "pull_requests",
-		`UPDATE pull_requests SET status = 'MERGED', merged_at = CURRENT_TIMESTAMP 
+		`UPDATE pull_requests SET status = 'MERGED', merged_at = $2 
          WHERE pull_request_id = $1`,
-		prID)
+		prID, s.now())
 	if err != nil {
 		return nil, err
 	}
@@ -361,11 +906,31 @@ func (s *StorageData) MergePR(ctx context.Context, prID string) (*models.PullReq
 
 	pr.Reviewers = reviewers
 	pr.Status = "MERGED"
+
+	// Ревьюеры смерженного PR освободились - назначаем их на PR из очереди
+	if err := s.dequeuePR(ctx, tx, prID); err != nil {
+		return nil, err
+	}
+	if err := s.assignQueuedPRsForReviewers(ctx, tx, reviewers); err != nil {
+		return nil, err
+	}
 	if newMergedAt.Valid {
 		mergedAtStr := newMergedAt.Time.Format(time.RFC3339)
 		pr.MergedAt = &mergedAtStr
 	}
 
+	var details interface{}
+	if !enforcePolicy {
+		details = map[string]interface{}{"external": true}
+	}
+	if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, prID, "merge", details); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordEvent(ctx, tx, EventPRMerged, prID, pr); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -395,7 +960,8 @@ func (s *StorageData) getReviewersForPR(ctx context.Context, tx *sql.Tx, prID st
 }
 
 // Заменяет одного ревьюера на другого случайного активного пользователя из той же команды.
-func (s *StorageData) ReassignReviewer(ctx context.Context, prID string, oldReviewerID string) (*models.PullRequest, string, error) {
+// Если newReviewerID задан, замена выбрана вручную: это доступно только лидам команды старого ревьюера.
+func (s *StorageData) ReassignReviewer(ctx context.Context, prID, oldReviewerID, newReviewerID string) (*models.PullRequest, string, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, "", err
@@ -425,9 +991,11 @@ func (s *StorageData) ReassignReviewer(ctx context.Context, prID string, oldRevi
 		pr.MergedAt = &mergedAtStr
 	}
 
+	pr.AuthorID = authorID
+
 	// Проверяем что PR не мерджен
 	if pr.Status == "MERGED" {
-		return nil, "", fmt.Errorf("cannot modify reviewers after merge")
+		return nil, "", s.prConflict(ctx, tx, &pr, "cannot modify reviewers after merge")
 	}
 
 	// СНАЧАЛА проверяем существование пользователя
@@ -451,7 +1019,7 @@ func (s *StorageData) ReassignReviewer(ctx context.Context, prID string, oldRevi
 		return nil, "", err
 	}
 	if !isAssigned {
-		return nil, "", fmt.Errorf("reviewer is not assigned to this PR")
+		return nil, "", s.prConflict(ctx, tx, &pr, "reviewer is not assigned to this PR")
 	}
 
 	// Находим команду старого ревьюера
@@ -466,6 +1034,24 @@ func (s *StorageData) ReassignReviewer(ctx context.Context, prID string, oldRevi
 		return nil, "", err
 	}
 
+	// Принудительная замена: проверяем право и выбранного ревьюера
+	if newReviewerID != "" {
+		if err := s.requireTeamLead(ctx, tx, teamName); err != nil {
+			return nil, "", err
+		}
+		assigned, err := s.getReviewersForPR(ctx, tx, prID)
+		if err != nil {
+			return nil, "", err
+		}
+		excluded, err := s.getExcludedReviewers(ctx, tx, prID)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := s.checkManualReviewer(ctx, tx, authorID, newReviewerID, assigned, excluded); err != nil {
+			return nil, "", err
+		}
+	}
+
 	// Ищем кандидатов для замены
 	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "users", `
         SELECT u.user_id 
@@ -474,9 +1060,12 @@ func (s *StorageData) ReassignReviewer(ctx context.Context, prID string, oldRevi
         LEFT JOIN pr_reviewers pr ON u.user_id = pr.user_id AND pr.pull_request_id = $1
         WHERE tm.team_name = $2 
           AND u.is_active = true 
+          AND u.is_bot = false
           AND u.user_id <> $3
-          AND pr.user_id IS NULL`,
-		prID, teamName, authorID)
+          AND pr.user_id IS NULL
+          AND `+notInHoldback("$4")+`
+        ORDER BY u.user_id`,
+		prID, teamName, authorID, s.now())
 	if err != nil {
 		return nil, "", err
 	}
@@ -494,40 +1083,102 @@ func (s *StorageData) ReassignReviewer(ctx context.Context, prID string, oldRevi
 		return nil, "", err
 	}
 
-	// Удаляем старого ревьюера
-	_, err = s.txExecWithMetrics(tx, ctx, "delete", "pr_reviewers",
-		`DELETE FROM pr_reviewers WHERE pull_request_id = $1 AND user_id = $2`,
-		prID, oldReviewerID)
+	// Удаляем старого ревьюера; замена занимает его этап цепочки одобрений
+	var stage int
+	err = s.txQueryRowWithMetrics(tx, ctx, "delete", "pr_reviewers",
+		`DELETE FROM pr_reviewers WHERE pull_request_id = $1 AND user_id = $2 RETURNING stage`,
+		prID, oldReviewerID).Scan(&stage)
 	if err != nil {
 		return nil, "", err
 	}
 
 	var replacedBy string
+	meta := models.AssignmentPRMetadata{
+		PullRequestID:   prID,
+		PullRequestName: pr.PullRequestName,
+		AuthorID:        authorID,
+		TeamName:        teamName,
+		ReplacedUserID:  oldReviewerID,
+	}
 
-	// Выбираем нового ревьюера если есть кандидаты
-	if len(candidates) > 0 {
-		selected := pickRandomDistinct(candidates, 1)
-		newID := selected[0]
+	if newReviewerID != "" {
+		// Выбор лида попадает в журнал решений как единственный кандидат
+		input := domain.NewAssignmentInput(domain.StrategyRandom, time.Now().UnixNano(), []string{newReviewerID}, nil, nil, 1)
+		if err := s.recordAssignmentDecision(ctx, tx, "force_reassign", meta, input, []string{newReviewerID}); err != nil {
+			return nil, "", err
+		}
 
 		_, err = s.txExecWithMetrics(tx, ctx, "insert", "pr_reviewers",
-			`INSERT INTO pr_reviewers(pull_request_id, user_id) VALUES($1, $2)`,
-			prID, newID)
+			`INSERT INTO pr_reviewers(pull_request_id, user_id, source, stage, assigned_at) VALUES($1, $2, $3, $4, $5)`,
+			prID, newReviewerID, ReviewerSourceManual, stage, s.now())
 		if err != nil {
 			return nil, "", err
 		}
-		replacedBy = newID
+		replacedBy = newReviewerID
+	} else if len(candidates) > 0 {
+		// Выбираем нового ревьюера если есть кандидаты
+		settings, err := s.getTeamSettings(ctx, tx, teamName)
+		if err != nil {
+			return nil, "", err
+		}
+
+		// Лида на этапе lead заменяет только другой лид
+		if domain.IsLeadStage(settings, stage) {
+			if candidates, err = s.filterTeamLeads(ctx, tx, teamName, candidates); err != nil {
+				return nil, "", err
+			}
+		}
+
+		input, err := s.buildAssignmentInput(ctx, tx, settings, prID, candidates, 1)
+		if err != nil {
+			return nil, "", err
+		}
+		selected := domain.SelectReviewers(input)
+		if err := s.recordAssignmentDecision(ctx, tx, "reassign", meta, input, selected); err != nil {
+			return nil, "", err
+		}
+
+		if len(selected) > 0 {
+			_, err = s.txExecWithMetrics(tx, ctx, "insert", "pr_reviewers",
+				`INSERT INTO pr_reviewers(pull_request_id, user_id, stage, assigned_at) VALUES($1, $2, $3, $4)`,
+				prID, selected[0], stage, s.now())
+			if err != nil {
+				return nil, "", err
+			}
+			replacedBy = selected[0]
+		}
 	} else {
 		// Нет доступных кандидатов
 		replacedBy = ""
 	}
 
+	// Снятый ревьюер освободился для PR из очереди
+	if err := s.assignQueuedPRsForReviewers(ctx, tx, []string{oldReviewerID}); err != nil {
+		return nil, "", err
+	}
+
 	// Получаем обновленный список ревьюеров
 	reviewers, err := s.getReviewersForPR(ctx, tx, prID)
 	if err != nil {
 		return nil, "", err
 	}
 	pr.Reviewers = reviewers
-	pr.AuthorID = authorID
+
+	if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, prID, "reassign", map[string]interface{}{
+		"old_reviewer_id": oldReviewerID,
+		"replaced_by":     replacedBy,
+		"forced":          newReviewerID != "",
+	}); err != nil {
+		return nil, "", err
+	}
+
+	if err := s.recordEvent(ctx, tx, EventPRReassigned, prID, map[string]interface{}{
+		"pr":          pr,
+		"old_user_id": oldReviewerID,
+		"replaced_by": replacedBy,
+	}); err != nil {
+		return nil, "", err
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, "", err
@@ -536,10 +1187,20 @@ func (s *StorageData) ReassignReviewer(ctx context.Context, prID string, oldRevi
 	return &pr, replacedBy, nil
 }
 
-// Get PRs where user is reviewer - возвращает PullRequestShort
-func (s *StorageData) GetPRsForUser(ctx context.Context, userID string) ([]models.PullRequestShort, error) {
+// GetPRsForUser возвращает PR, где пользователь ревьюер, в порядке срочности (см. domain.SortReviewAssignments).
+// Срок ревью считается от назначения по SLA команды автора, время выводится в поясе команды ревьюера.
+func (s *StorageData) GetPRsForUser(ctx context.Context, userID string) ([]models.ReviewAssignment, error) {
+	loc, err := s.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := s.queryWithMetrics(ctx, "select", "pull_requests",
-		`SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status
+		`SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status, pr.priority, pr.created_at,
+		        COALESCE(r.assigned_at, pr.created_at), r.approved_at IS NOT NULL,
+		        COALESCE((SELECT ts.review_sla_hours FROM team_members tm
+		                  JOIN team_settings ts ON ts.team_name = tm.team_name
+		                  WHERE tm.user_id = pr.author_id LIMIT 1), 0)
         FROM pull_requests pr
         JOIN pr_reviewers r ON pr.pull_request_id = r.pull_request_id
         WHERE r.user_id = $1`, userID)
@@ -548,18 +1209,26 @@ func (s *StorageData) GetPRsForUser(ctx context.Context, userID string) ([]model
 	}
 	defer rows.Close()
 
-	var res []models.PullRequestShort
+	var items []domain.ReviewItem
 	for rows.Next() {
-		var pr models.PullRequestShort
-		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status); err != nil {
+		var item domain.ReviewItem
+		var approved bool
+		var slaHours int
+		if err := rows.Scan(&item.PullRequestID, &item.PullRequestName, &item.AuthorID, &item.Status,
+			&item.Priority, &item.CreatedAt, &item.AssignedAt, &approved, &slaHours); err != nil {
 			return nil, err
 		}
-		res = append(res, pr)
+		item.Pending = item.Status == "OPEN" && !approved
+		if item.Pending {
+			item.DueAt = inLocation(domain.ReviewDueAt(item.AssignedAt, slaHours), loc)
+		}
+		item.AssignedAt = item.AssignedAt.In(loc)
+		items = append(items, item)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return res, nil
+	return domain.SortReviewAssignments(items), nil
 }
 
 // GetTeam возвращает команду с участниками (с транзакцией)
@@ -583,7 +1252,7 @@ func (s *StorageData) GetTeam(ctx context.Context, teamName string) (*models.Tea
 
 	// Получаем участников команды как TeamMember (без team_name)
 	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "users", `
-        SELECT u.user_id, u.username, u.is_active 
+        SELECT u.user_id, u.username, u.is_active, u.is_bot 
         FROM users u
         JOIN team_members tm ON u.user_id = tm.user_id
         WHERE tm.team_name = $1
@@ -596,7 +1265,7 @@ func (s *StorageData) GetTeam(ctx context.Context, teamName string) (*models.Tea
 	var members []models.User
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.UserID, &user.Username, &user.IsActive); err != nil {
+		if err := rows.Scan(&user.UserID, &user.Username, &user.IsActive, &user.IsBot); err != nil {
 			return nil, err
 		}
 		user.TeamName = teamName // Устанавливаем team_name
@@ -619,6 +1288,16 @@ func (s *StorageData) GetTeam(ctx context.Context, teamName string) (*models.Tea
 	return team, nil
 }
 
+// GetUserTeamName возвращает имя команды пользователя без загрузки участников
+func (s *StorageData) GetUserTeamName(ctx context.Context, userID string) (string, error) {
+	var teamName string
+	err := s.queryRowWithMetrics(ctx, "select", "team_members", userTeamQuery, userID).Scan(&teamName)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("user not in any team")
+	}
+	return teamName, err
+}
+
 // GetTeamByUserID возвращает команду пользователя
 func (s *StorageData) GetTeamByUserID(ctx context.Context, userID string) (*models.Team, error) {
 	var teamName string
@@ -656,23 +1335,7 @@ func (s *StorageData) HealthCheck(ctx context.Context) error {
 
 // pickRandomDistinct выбирает случайные уникальные элементы из массива
 func pickRandomDistinct(arr []string, n int) []string {
-	if arr == nil || n <= 0 {
-		return []string{}
-	}
-
-	if len(arr) <= n {
-		res := make([]string, len(arr))
-		copy(res, arr)
-		return res
-	}
-
-	res := make([]string, len(arr))
-	copy(res, arr)
-	for i := len(res) - 1; i > 0; i-- {
-		j := rand.Intn(i + 1)
-		res[i], res[j] = res[j], res[i]
-	}
-	return res[:n]
+	return domain.PickDistinct(rand.Intn, arr, n)
 }
 
 // PickForTest экспортирует функцию для тестов
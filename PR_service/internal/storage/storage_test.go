@@ -1,9 +1,12 @@
 package storage
 
 import (
+	"database/sql"
+	"regexp"
 	"testing"
 	"time"
 
+	"PR_service/internal/domain"
 	"PR_service/internal/models"
 
 	"github.com/stretchr/testify/assert"
@@ -401,3 +404,258 @@ func uniqueStrings(arr []string) []string {
 	}
 	return result
 }
+
+func TestParseMinuteOfDay(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  int
+		wantError bool
+	}{
+		{input: "00:00", expected: 0},
+		{input: "09:30", expected: 570},
+		{input: "23:59", expected: 1439},
+		{input: "24:00", expected: 1440},
+		{input: "25:00", wantError: true},
+		{input: "9am", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseMinuteOfDay(tt.input)
+			if tt.wantError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.input, formatMinuteOfDay(result))
+		})
+	}
+}
+
+func TestBuildBorrowingBalances(t *testing.T) {
+	flows := []borrowFlow{
+		{borrower: "backend", lender: "frontend", count: 5},
+		{borrower: "frontend", lender: "backend", count: 2},
+	}
+	caps := map[[2]string]int{
+		{"backend", "frontend"}: 4,
+		{"backend", "mobile"}:   0,
+	}
+
+	t.Run("All teams", func(t *testing.T) {
+		result := buildBorrowingBalances(flows, caps, "")
+		assert.Equal(t, []models.BorrowingBalance{
+			{TeamName: "backend", OtherTeam: "frontend", Borrowed: 5, Lent: 2, Net: 3, MaxNetBorrowed: 4},
+			{TeamName: "backend", OtherTeam: "mobile"},
+			{TeamName: "frontend", OtherTeam: "backend", Borrowed: 2, Lent: 5, Net: -3},
+		}, result)
+	})
+
+	t.Run("Single team", func(t *testing.T) {
+		result := buildBorrowingBalances(flows, caps, "frontend")
+		assert.Len(t, result, 1)
+		assert.Equal(t, -3, result[0].Net)
+	})
+}
+
+func TestHoldbackUntil(t *testing.T) {
+	joined := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	joinedAt := sql.NullTime{Time: joined, Valid: true}
+
+	until, held := holdbackUntil(joinedAt, 7, joined.Add(24*time.Hour))
+	assert.True(t, held)
+	assert.Equal(t, time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC), until)
+
+	_, held = holdbackUntil(joinedAt, 7, joined.AddDate(0, 0, 7))
+	assert.False(t, held)
+
+	_, held = holdbackUntil(joinedAt, 0, joined)
+	assert.False(t, held)
+
+	// Участники, добавленные до появления периода адаптации, не задерживаются
+	_, held = holdbackUntil(sql.NullTime{}, 7, joined)
+	assert.False(t, held)
+}
+
+func TestNormalizeTag(t *testing.T) {
+	assert.Equal(t, "postgres", NormalizeTag("  Postgres "))
+	assert.Equal(t, "", NormalizeTag("   "))
+}
+
+func TestCompletionRate(t *testing.T) {
+	assert.Equal(t, 0.0, completionRate(0, 0))
+	assert.Equal(t, 0.0, completionRate(3, 0))
+	assert.Equal(t, 50.0, completionRate(2, 4))
+	assert.Equal(t, 100.0, completionRate(4, 4))
+}
+
+func TestDataTablesCoverSchema(t *testing.T) {
+	tableRe := regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+) \(`)
+	refRe := regexp.MustCompile(`REFERENCES (\w+)\(`)
+
+	position := make(map[string]int, len(DataTables))
+	for i, table := range DataTables {
+		position[table] = i
+	}
+
+	blocks := tableRe.FindAllStringSubmatchIndex(migrationsDDL, -1)
+	assert.Len(t, blocks, len(DataTables))
+	for i, m := range blocks {
+		table := migrationsDDL[m[2]:m[3]]
+		pos, ok := position[table]
+		if !assert.True(t, ok, "table %s is missing from DataTables", table) {
+			continue
+		}
+
+		end := len(migrationsDDL)
+		if i+1 < len(blocks) {
+			end = blocks[i+1][0]
+		}
+		for _, ref := range refRe.FindAllStringSubmatch(migrationsDDL[m[1]:end], -1) {
+			if ref[1] != table {
+				assert.Less(t, position[ref[1]], pos, "%s must come after %s", table, ref[1])
+			}
+		}
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	assert.Equal(t, 34, SchemaVersion)
+	assert.Equal(t, 3, latestMigration("-- 0001 init\n-- 0003 later\n-- 0002 earlier\n"))
+	assert.Equal(t, 0, latestMigration("SELECT 1; -- 0005 not a header"))
+}
+
+func TestSplitMigrations(t *testing.T) {
+	assert.Len(t, migrations, SchemaVersion)
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version)
+		assert.NotEmpty(t, m.Name)
+		assert.Contains(t, m.Up, m.Name)
+	}
+	assert.Equal(t, "init", migrations[0].Name)
+	assert.Contains(t, migrations[19].Up, "schema_migrations")
+
+	// Последняя миграция сборки всегда откатывается
+	_, ok := downMigrations[SchemaVersion]
+	assert.True(t, ok)
+	assert.Equal(t, 18, previousVersion(19))
+	assert.Equal(t, 0, previousVersion(1))
+}
+
+func TestOnlineMigrations(t *testing.T) {
+	online := migrations[30]
+	assert.True(t, online.NoTransaction)
+	assert.False(t, migrations[29].NoTransaction)
+
+	stmts := online.statements()
+	assert.Len(t, stmts, 2)
+	assert.Equal(t, "idx_pr_reviewers_pending", concurrentIndexRe.FindStringSubmatch(stmts[0])[1])
+	assert.NotContains(t, online.transactional(), "CONCURRENTLY")
+
+	// CONCURRENTLY нельзя выполнить в транзакции, а миграция вне транзакции
+	// после сбоя повторяется целиком - ее инструкции должны быть идемпотентны
+	for _, m := range migrations {
+		if !m.NoTransaction {
+			assert.NotRegexp(t, concurrentlyRe, m.Up, "migration %04d", m.Version)
+			continue
+		}
+		for _, stmt := range m.statements() {
+			assert.Regexp(t, `(?i)^\s*(CREATE\s+(UNIQUE\s+)?INDEX\s+CONCURRENTLY\s+IF\s+NOT\s+EXISTS|DROP\s+INDEX\s+CONCURRENTLY\s+IF\s+EXISTS)\s`,
+				stmt, "migration %04d", m.Version)
+		}
+	}
+}
+
+func TestVetoAdjustmentsApply(t *testing.T) {
+	in := domain.NewAssignmentInput(domain.StrategyLeastLoaded, time.Now().UnixNano(), []string{"u1", "u2", "u3"}, nil, nil, 1)
+	adj := vetoAdjustments{
+		Excluded:  map[string]bool{"u1": true},
+		Penalties: map[string]int{"u2": 1, "u9": 3},
+	}
+
+	out := adj.apply(in)
+	assert.Equal(t, []string{"u2", "u3"}, out.Candidates)
+	assert.Equal(t, map[string]float64{"u2": 0.5, "u3": 1}, out.Weights)
+	assert.Equal(t, []string{"u3"}, domain.SelectReviewers(out))
+
+	// Исключение не оставляет PR без нужного числа ревьюеров
+	in = domain.NewAssignmentInput(domain.StrategyRandom, time.Now().UnixNano(), []string{"u1", "u2"}, nil, nil, 2)
+	out = adj.apply(in)
+	assert.Equal(t, []string{"u1", "u2"}, out.Candidates)
+}
+
+func TestVetoPenaltyUnderRandomStrategy(t *testing.T) {
+	adj := vetoAdjustments{Penalties: map[string]int{"u1": 3}}
+	picks := map[string]int{}
+	for seed := int64(0); seed < 2000; seed++ {
+		in := domain.NewAssignmentInput(domain.StrategyRandom, seed, []string{"u1", "u2"}, nil, nil, 1)
+		picks[domain.SelectReviewers(adj.apply(in))[0]]++
+	}
+	// Штраф 3 делит вес на 4: u1 выбирается примерно в 20% случаев вместо 50%
+	assert.Less(t, picks["u1"], 600)
+	assert.Greater(t, picks["u2"], 1400)
+}
+
+func TestSplitByQuota(t *testing.T) {
+	states := map[string]quotaState{
+		"u1": {Max: 3, Period: QuotaPeriodDay, Used: 3},
+		"u2": {Max: 3, Period: QuotaPeriodDay, Used: 2},
+		"u4": {Max: 5, Period: QuotaPeriodWeek, Used: 7},
+	}
+	allowed, deferred := splitByQuota([]string{"u1", "u2", "u3", "u4"}, states)
+	assert.Equal(t, []string{"u2", "u3"}, allowed)
+	assert.Equal(t, []string{"u1", "u4"}, deferred)
+
+	assert.Equal(t, 24*time.Hour, quotaWindow(QuotaPeriodDay))
+	assert.Equal(t, 7*24*time.Hour, quotaWindow(QuotaPeriodWeek))
+}
+
+func TestQuotaDeferredSlots(t *testing.T) {
+	assert.Equal(t, 0, quotaDeferredSlots(2, 3, 1), "enough candidates without quota-limited ones")
+	assert.Equal(t, 0, quotaDeferredSlots(2, 1, 0), "shortage is not caused by quotas")
+	assert.Equal(t, 1, quotaDeferredSlots(2, 1, 3))
+	assert.Equal(t, 1, quotaDeferredSlots(3, 0, 1))
+}
+
+type selectionRecorder struct {
+	selections []AssignmentSelection
+}
+
+func (r *selectionRecorder) ObserveDBQuery(operation, table string, duration time.Duration) {}
+
+func (r *selectionRecorder) IncAssignmentQuotaDeferred(team string, slots int) {}
+
+func (r *selectionRecorder) ObserveAssignmentSelection(sel AssignmentSelection) {
+	r.selections = append(r.selections, sel)
+}
+
+func TestObserveSelection(t *testing.T) {
+	recorder := &selectionRecorder{}
+	s := &StorageData{}
+	s.SetMetrics(recorder)
+
+	in := domain.NewAssignmentInput(domain.StrategyLeastLoaded, time.Now().UnixNano(), []string{"u1", "u2", "u3", "u4"}, map[string]bool{"u4": true}, nil, 2)
+	in.Stats = domain.NewSelectionStats(5, time.Now())
+	in.Stats.Filter(domain.SelectionRuleQuota, 1)
+	in = vetoAdjustments{Excluded: map[string]bool{"u1": true}}.apply(in)
+	in = vetoAdjustments{Excluded: map[string]bool{"u2": true, "u3": true}}.apply(in)
+
+	s.observeSelection("borrow", in, []string{"u3", "u4"})
+	assert.Len(t, recorder.selections, 1)
+	sel := recorder.selections[0]
+	assert.Equal(t, domain.StrategyLeastLoaded, sel.Strategy)
+	assert.Equal(t, 5, sel.Considered)
+	assert.Equal(t, 2, sel.Selected)
+	assert.Equal(t, map[string]int{domain.SelectionRuleQuota: 1, domain.SelectionRuleVeto: 1}, sel.Filtered)
+	assert.Equal(t, []string{domain.SelectionFallbackVetoIgnored, domain.SelectionFallbackBorrow, domain.SelectionFallbackFocused}, sel.Fallbacks)
+
+	// Принудительный выбор учитывается отдельно от стратегии команды
+	in.Forced = []string{"u3"}
+	s.observeSelection("create", in, []string{"u3"})
+	assert.Equal(t, "forced", recorder.selections[1].Strategy)
+
+	// Ручные назначения не собираются buildAssignmentInput и в метрики стратегий не попадают
+	s.observeSelection("add_reviewer", domain.AssignmentInput{Strategy: domain.StrategyRandom}, []string{"u1"})
+	assert.Len(t, recorder.selections, 2)
+}
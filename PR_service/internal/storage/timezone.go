@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	_ "time/tzdata" // Пояса команд не должны зависеть от tzdata в образе
+)
+
+// LoadTimezone возвращает пояс IANA из настроек команды; пустое имя - UTC.
+// Local не принимается: пояс сервера не должен влиять на отчеты команды.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// teamLocation возвращает пояс команды. Пояс проверяется при сохранении настроек,
+// поэтому неизвестное имя (например, удаленное из tzdata) сводится к UTC.
+func teamLocation(name string) *time.Location {
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// userTimezoneQuery - пояс команды пользователя; команда выбирается так же, как для уведомлений
+const userTimezoneQuery = `SELECT COALESCE(ts.timezone, '')
+	 FROM team_members tm
+	 LEFT JOIN team_settings ts ON ts.team_name = tm.team_name
+	 WHERE tm.user_id = $1
+	 ORDER BY tm.team_name LIMIT 1`
+
+// userLocation возвращает пояс команды пользователя или UTC, если он не в команде
+func (s *StorageData) userLocation(ctx context.Context, userID string) (*time.Location, error) {
+	var name string
+	err := s.queryRowWithMetrics(ctx, "select", "team_settings", userTimezoneQuery, userID).Scan(&name)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return teamLocation(name), nil
+}
+
+// inLocation переводит необязательное время в пояс loc
+func inLocation(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.In(loc)
+	return &local
+}
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"PR_service/internal/models"
+)
+
+// TransferAuthor передает открытые PR ушедшего (деактивированного) автора преемнику.
+// Если successorID пуст, преемником становится первый активный лид команды автора.
+// История сохраняется: прежний автор остается в журнале решений, аудите и событиях.
+// Если преемник был ревьюером PR, он снимается с ревью, а место доназначается.
+// Возвращает преемника и переданные PR.
+func (s *StorageData) TransferAuthor(ctx context.Context, authorID, successorID string) (string, []models.PullRequest, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer tx.Rollback()
+
+	var active bool
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "users",
+		`SELECT is_active FROM users WHERE user_id = $1`, authorID).Scan(&active)
+	if err == sql.ErrNoRows {
+		return "", nil, fmt.Errorf("author not found")
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if active {
+		return "", nil, fmt.Errorf("author is still active")
+	}
+
+	teamName, err := s.getUserTeam(ctx, tx, authorID)
+	if err != nil {
+		if err.Error() == "user not in any team" {
+			return "", nil, fmt.Errorf("author is not in any team")
+		}
+		return "", nil, err
+	}
+	if err := s.requireTeamLead(ctx, tx, teamName); err != nil {
+		return "", nil, err
+	}
+
+	if successorID == "" {
+		if successorID, err = s.defaultSuccessor(ctx, tx, teamName, authorID); err != nil {
+			return "", nil, err
+		}
+	} else if err := s.checkSuccessor(ctx, tx, successorID); err != nil {
+		return "", nil, err
+	}
+
+	successorTeam, err := s.getUserTeam(ctx, tx, successorID)
+	if err != nil {
+		if err.Error() == "user not in any team" {
+			return "", nil, fmt.Errorf("successor is not in any team")
+		}
+		return "", nil, err
+	}
+	settings, err := s.getTeamSettings(ctx, tx, successorTeam)
+	if err != nil {
+		return "", nil, err
+	}
+
+	prs, err := s.lockOpenPRsByAuthor(ctx, tx, authorID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for i := range prs {
+		pr := &prs[i]
+		if _, err := s.txExecWithMetrics(tx, ctx, "update", "pull_requests",
+			`UPDATE pull_requests SET author_id = $2 WHERE pull_request_id = $1`,
+			pr.PullRequestID, successorID); err != nil {
+			return "", nil, err
+		}
+		pr.AuthorID = successorID
+
+		reviewers, err := s.getReviewersForPR(ctx, tx, pr.PullRequestID)
+		if err != nil {
+			return "", nil, err
+		}
+		replaced, err := s.replaceSuccessorReview(ctx, tx, settings, pr, reviewers)
+		if err != nil {
+			return "", nil, err
+		}
+
+		details := map[string]interface{}{
+			"previous_author_id": authorID,
+			"author_id":          successorID,
+		}
+		if replaced != nil {
+			details["removed_reviewer_id"] = successorID
+			details["added_reviewers"] = replaced
+		}
+		if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, pr.PullRequestID, "transfer_author", details); err != nil {
+			return "", nil, err
+		}
+		if err := s.recordEvent(ctx, tx, EventPRAuthorTransferred, pr.PullRequestID, map[string]interface{}{
+			"pr":                 pr,
+			"previous_author_id": authorID,
+		}); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if err := s.recordAudit(ctx, tx, AuditEntityUser, authorID, "transfer_authorship", map[string]interface{}{
+		"successor_id": successorID,
+		"prs":          len(prs),
+	}); err != nil {
+		return "", nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", nil, err
+	}
+	return successorID, prs, nil
+}
+
+// defaultSuccessor возвращает первого активного лида команды, кроме ушедшего автора
+func (s *StorageData) defaultSuccessor(ctx context.Context, tx *sql.Tx, teamName, authorID string) (string, error) {
+	var successorID string
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "team_leads",
+		`SELECT l.user_id FROM team_leads l
+		 JOIN users u ON u.user_id = l.user_id
+		 WHERE l.team_name = $1 AND l.user_id <> $2 AND u.is_active AND NOT u.is_bot
+		 ORDER BY l.user_id
+		 LIMIT 1`, teamName, authorID).Scan(&successorID)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("no successor available")
+	}
+	return successorID, err
+}
+
+// checkSuccessor проверяет, что явно указанный преемник может стать автором
+func (s *StorageData) checkSuccessor(ctx context.Context, tx *sql.Tx, successorID string) error {
+	var active, bot bool
+	err := s.txQueryRowWithMetrics(tx, ctx, "select", "users",
+		`SELECT is_active, is_bot FROM users WHERE user_id = $1`, successorID).Scan(&active, &bot)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("successor not found")
+	}
+	if err != nil {
+		return err
+	}
+	if !active {
+		return fmt.Errorf("successor is inactive")
+	}
+	if bot {
+		return fmt.Errorf("successor is a bot")
+	}
+	return nil
+}
+
+// lockOpenPRsByAuthor возвращает открытые PR автора с блокировкой строк
+func (s *StorageData) lockOpenPRsByAuthor(ctx context.Context, tx *sql.Tx, authorID string) ([]models.PullRequest, error) {
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT pull_request_id, pull_request_name, status, created_at
+		 FROM pull_requests
+		 WHERE author_id = $1 AND status = 'OPEN'
+		 ORDER BY pull_request_id
+		 FOR UPDATE`, authorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prs := []models.PullRequest{}
+	for rows.Next() {
+		pr := models.PullRequest{AuthorID: authorID}
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.Status, &pr.CreatedAt); err != nil {
+			return nil, err
+		}
+		prs = append(prs, pr)
+	}
+	return prs, rows.Err()
+}
+
+// replaceSuccessorReview снимает преемника с ревью его нового PR и доназначает
+// освободившееся место. Возвращает доназначенных ревьюеров или nil, если
+// преемник не был ревьюером. Итоговые ревьюеры записываются в pr.Reviewers.
+func (s *StorageData) replaceSuccessorReview(ctx context.Context, tx *sql.Tx, settings models.TeamSettings,
+	pr *models.PullRequest, reviewers []string) ([]string, error) {
+	remaining := make([]string, 0, len(reviewers))
+	for _, uid := range reviewers {
+		if uid != pr.AuthorID {
+			remaining = append(remaining, uid)
+		}
+	}
+	if len(remaining) == len(reviewers) {
+		pr.Reviewers = reviewers
+		return nil, nil
+	}
+
+	if _, err := s.txExecWithMetrics(tx, ctx, "delete", "pr_reviewers",
+		`DELETE FROM pr_reviewers WHERE pull_request_id = $1 AND user_id = $2`,
+		pr.PullRequestID, pr.AuthorID); err != nil {
+		return nil, err
+	}
+
+	added, err := s.topUpReviewers(ctx, tx, settings, models.PRSettingsImpact{
+		PullRequestID:    pr.PullRequestID,
+		PullRequestName:  pr.PullRequestName,
+		AuthorID:         pr.AuthorID,
+		CurrentReviewers: remaining,
+		Missing:          1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pr.Reviewers = append(remaining, added...)
+
+	if err := s.recordEvent(ctx, tx, EventPRReviewerRemoved, pr.PullRequestID, map[string]interface{}{
+		"pr":          pr,
+		"reviewer_id": pr.AuthorID,
+	}); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"PR_service/internal/authctx"
+	"PR_service/internal/domain"
+	"PR_service/internal/models"
+)
+
+// Причины отказа ревьюера от назначения
+const (
+	VetoConflictOfInterest = "conflict_of_interest"
+	VetoUnfamiliarArea     = "unfamiliar_area"
+	VetoOverloaded         = "overloaded"
+)
+
+// IsKnownVetoReason проверяет, поддерживается ли причина отказа
+func IsKnownVetoReason(reason string) bool {
+	return reason == VetoConflictOfInterest || reason == VetoUnfamiliarArea || reason == VetoOverloaded
+}
+
+// MaxVetoCommentLength - предел длины комментария к отказу
+const MaxVetoCommentLength = 500
+
+// Периоды, за которые отказы влияют на автоматическое назначение
+const (
+	// VetoWindow - отказы из-за конфликта интересов и незнакомой области
+	VetoWindow = 90 * 24 * time.Hour
+	// VetoOverloadWindow - отказы из-за перегрузки: перегрузка быстро проходит
+	VetoOverloadWindow = 7 * 24 * time.Hour
+)
+
+// ErrVetoNotAllowed - отказаться от назначения может только сам ревьюер или администратор
+var ErrVetoNotAllowed = errors.New("only the assigned reviewer can veto the assignment")
+
+// VetoAssignment снимает ревьюера с PR по его отказу и назначает замену по стратегии команды.
+// Ревьюер исключается из кандидатов этого PR, а причина сохраняется для отчетов и
+// для поправок при следующих назначениях (см. getVetoAdjustments).
+// Возвращает PR и нового ревьюера (пусто, если замены не нашлось).
+func (s *StorageData) VetoAssignment(ctx context.Context, req models.VetoRequest) (*models.PullRequest, string, error) {
+	if principal, ok := authctx.PrincipalFrom(ctx); ok && principal.ID != req.UserID && !principal.HasRole(authctx.RoleAdmin) {
+		return nil, "", ErrVetoNotAllowed
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	var pr models.PullRequest
+	var mergedAt sql.NullTime
+	err = s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests",
+		`SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
+		 FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE`, req.PullRequestID).
+		Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &mergedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", fmt.Errorf("pr not found")
+		}
+		return nil, "", err
+	}
+	if mergedAt.Valid {
+		mergedAtStr := mergedAt.Time.Format(time.RFC3339)
+		pr.MergedAt = &mergedAtStr
+	}
+	if pr.Status == "MERGED" {
+		return nil, "", s.prConflict(ctx, tx, &pr, "cannot modify reviewers after merge")
+	}
+
+	assigned, err := s.getReviewersForPR(ctx, tx, req.PullRequestID)
+	if err != nil {
+		return nil, "", err
+	}
+	isAssigned := false
+	for _, uid := range assigned {
+		if uid == req.UserID {
+			isAssigned = true
+			break
+		}
+	}
+	if !isAssigned {
+		return nil, "", s.prConflict(ctx, tx, &pr, "reviewer is not assigned to this PR")
+	}
+
+	teamName, err := s.getUserTeam(ctx, tx, pr.AuthorID)
+	if err != nil {
+		if err.Error() == "user not in any team" {
+			return nil, "", fmt.Errorf("author is not in any team")
+		}
+		return nil, "", err
+	}
+
+	// Замена занимает этап цепочки одобрений отказавшегося ревьюера
+	var stage int
+	if err := s.txQueryRowWithMetrics(tx, ctx, "delete", "pr_reviewers",
+		`DELETE FROM pr_reviewers WHERE pull_request_id = $1 AND user_id = $2 RETURNING stage`,
+		req.PullRequestID, req.UserID).Scan(&stage); err != nil {
+		return nil, "", err
+	}
+	// Отказавшийся ревьюер больше не предлагается на этот PR
+	if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_excluded_reviewers",
+		`INSERT INTO pr_excluded_reviewers(pull_request_id, user_id) VALUES($1,$2) ON CONFLICT DO NOTHING`,
+		req.PullRequestID, req.UserID); err != nil {
+		return nil, "", err
+	}
+	if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_vetoes",
+		`INSERT INTO pr_vetoes(pull_request_id, user_id, reason, comment, created_at) VALUES($1,$2,$3,$4,$5)`,
+		req.PullRequestID, req.UserID, req.Reason, req.Comment, s.now()); err != nil {
+		return nil, "", err
+	}
+
+	excluded, err := s.getExcludedReviewers(ctx, tx, req.PullRequestID)
+	if err != nil {
+		return nil, "", err
+	}
+	skip := map[string]bool{pr.AuthorID: true}
+	for _, uid := range assigned {
+		skip[uid] = true
+	}
+	for _, uid := range excluded {
+		skip[uid] = true
+	}
+
+	settings, err := s.getTeamSettings(ctx, tx, teamName)
+	if err != nil {
+		return nil, "", err
+	}
+	candidates, err := s.getActiveTeamMembers(ctx, tx, teamName, skip)
+	if err != nil {
+		return nil, "", err
+	}
+	candidates, _, err = s.filterByCapacity(ctx, tx, candidates, settings.MaxOpenReviews)
+	if err != nil {
+		return nil, "", err
+	}
+	if domain.IsLeadStage(settings, stage) {
+		if candidates, err = s.filterTeamLeads(ctx, tx, teamName, candidates); err != nil {
+			return nil, "", err
+		}
+	}
+
+	input, err := s.buildAssignmentInput(ctx, tx, settings, req.PullRequestID, candidates, 1)
+	if err != nil {
+		return nil, "", err
+	}
+	selected := domain.SelectReviewers(input)
+	if err := s.recordAssignmentDecision(ctx, tx, "veto", models.AssignmentPRMetadata{
+		PullRequestID:     req.PullRequestID,
+		PullRequestName:   pr.PullRequestName,
+		AuthorID:          pr.AuthorID,
+		TeamName:          teamName,
+		ExcludedReviewers: excluded,
+		ReplacedUserID:    req.UserID,
+	}, input, selected); err != nil {
+		return nil, "", err
+	}
+
+	var replacedBy string
+	if len(selected) > 0 {
+		replacedBy = selected[0]
+		if _, err := s.txExecWithMetrics(tx, ctx, "insert", "pr_reviewers",
+			`INSERT INTO pr_reviewers(pull_request_id, user_id, stage, assigned_at) VALUES($1,$2,$3,$4)`,
+			req.PullRequestID, replacedBy, stage, s.now()); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Отказавшийся ревьюер освободился для PR из очереди
+	if err := s.assignQueuedPRsForReviewers(ctx, tx, []string{req.UserID}); err != nil {
+		return nil, "", err
+	}
+
+	reviewers, err := s.getReviewersForPR(ctx, tx, req.PullRequestID)
+	if err != nil {
+		return nil, "", err
+	}
+	pr.Reviewers = reviewers
+
+	if err := s.recordAudit(ctx, tx, AuditEntityPullRequest, req.PullRequestID, "veto", map[string]interface{}{
+		"reviewer_id": req.UserID,
+		"reason":      req.Reason,
+		"comment":     req.Comment,
+		"replaced_by": replacedBy,
+	}); err != nil {
+		return nil, "", err
+	}
+	if err := s.recordEvent(ctx, tx, EventPRVetoed, req.PullRequestID, map[string]interface{}{
+		"pr":          pr,
+		"reviewer_id": req.UserID,
+		"reason":      req.Reason,
+		"replaced_by": replacedBy,
+	}); err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+	return &pr, replacedBy, nil
+}
+
+// vetoAdjustments - поправки к автоматическому назначению по прошлым отказам кандидатов
+type vetoAdjustments struct {
+	// Excluded - кандидаты, отказавшиеся от PR того же автора из-за конфликта интересов
+	Excluded map[string]bool
+	// Penalties - отказы из-за перегрузки и из-за незнакомой области в PR с теми же тегами
+	Penalties map[string]int
+}
+
+// getVetoAdjustments собирает поправки к назначению кандидатов на PR prID по их отказам:
+// конфликт интересов за VetoWindow исключает кандидата из PR того же автора, незнакомая
+// область за VetoWindow на PR с общими тегами и перегрузка за VetoOverloadWindow снижают вес.
+func (s *StorageData) getVetoAdjustments(ctx context.Context, tx *sql.Tx, prID string, candidates []string) (vetoAdjustments, error) {
+	adj := vetoAdjustments{Excluded: map[string]bool{}, Penalties: map[string]int{}}
+	if len(candidates) == 0 || prID == "" {
+		return adj, nil
+	}
+
+	now := s.now()
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_vetoes",
+		`SELECT v.user_id, v.reason, COUNT(*)
+		 FROM pr_vetoes v
+		 JOIN pull_requests p ON p.pull_request_id = v.pull_request_id
+		 WHERE v.user_id = ANY($1)
+		   AND v.pull_request_id <> $2
+		   AND (
+		     (v.reason = $3 AND v.created_at >= $6
+		      AND p.author_id = (SELECT author_id FROM pull_requests WHERE pull_request_id = $2))
+		     OR (v.reason = $4 AND v.created_at >= $6
+		      AND EXISTS (SELECT 1 FROM pr_tags t
+		                  WHERE t.pull_request_id = v.pull_request_id
+		                    AND t.tag IN (SELECT tag FROM pr_tags WHERE pull_request_id = $2)))
+		     OR (v.reason = $5 AND v.created_at >= $7)
+		   )
+		 GROUP BY v.user_id, v.reason`,
+		candidates, prID, VetoConflictOfInterest, VetoUnfamiliarArea, VetoOverloaded,
+		now.Add(-VetoWindow), now.Add(-VetoOverloadWindow))
+	if err != nil {
+		return adj, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uid, reason string
+		var n int
+		if err := rows.Scan(&uid, &reason, &n); err != nil {
+			return adj, err
+		}
+		if reason == VetoConflictOfInterest {
+			adj.Excluded[uid] = true
+		} else {
+			adj.Penalties[uid] += n
+		}
+	}
+	return adj, rows.Err()
+}
+
+// apply убирает исключенных кандидатов, пока оставшихся хватает на count ревьюеров,
+// и делит вес кандидата на 1+штраф. Вес учитывают все стратегии, включая случайную:
+// она выбирает кандидатов с вероятностью, пропорциональной весу.
+func (adj vetoAdjustments) apply(in domain.AssignmentInput) domain.AssignmentInput {
+	if len(adj.Excluded) > 0 {
+		remaining := make([]string, 0, len(in.Candidates))
+		for _, uid := range in.Candidates {
+			if !adj.Excluded[uid] {
+				remaining = append(remaining, uid)
+			}
+		}
+		if len(remaining) >= in.Count {
+			weights := make(map[string]float64, len(remaining))
+			for _, uid := range remaining {
+				weights[uid] = in.Weights[uid]
+			}
+			in.Stats.Filter(domain.SelectionRuleVeto, len(in.Candidates)-len(remaining))
+			in.Candidates = remaining
+			in.Weights = weights
+		} else if len(remaining) < len(in.Candidates) {
+			in.Stats.Fallback(domain.SelectionFallbackVetoIgnored)
+		}
+	}
+	for uid, penalty := range adj.Penalties {
+		if _, ok := in.Weights[uid]; ok {
+			in.Weights[uid] /= float64(1 + penalty)
+		}
+	}
+	return in
+}
+
+// GetVetoReport возвращает отказы от назначений ревьюеров команды за период [from, to)
+func (s *StorageData) GetVetoReport(ctx context.Context, teamName string, from, to *time.Time) (*models.VetoReport, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ensureTeamExists(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.txQueryWithMetrics(tx, ctx, "select", "pr_vetoes",
+		`SELECT v.user_id, v.reason, COUNT(*)
+		 FROM pr_vetoes v
+		 JOIN team_members tm ON tm.user_id = v.user_id AND tm.team_name = $1
+		 WHERE ($2::timestamptz IS NULL OR v.created_at >= $2)
+		   AND ($3::timestamptz IS NULL OR v.created_at < $3)
+		 GROUP BY v.user_id, v.reason
+		 ORDER BY v.user_id, v.reason`, teamName, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &models.VetoReport{TeamName: teamName, From: from, To: to, ByReason: map[string]int{}, Reviewers: []models.ReviewerVetoes{}}
+	for rows.Next() {
+		var uid, reason string
+		var n int
+		if err := rows.Scan(&uid, &reason, &n); err != nil {
+			return nil, err
+		}
+		last := len(report.Reviewers) - 1
+		if last < 0 || report.Reviewers[last].UserID != uid {
+			report.Reviewers = append(report.Reviewers, models.ReviewerVetoes{UserID: uid, ByReason: map[string]int{}})
+			last++
+		}
+		report.Reviewers[last].Total += n
+		report.Reviewers[last].ByReason[reason] += n
+		report.ByReason[reason] += n
+		report.Total += n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Сначала ревьюеры с наибольшим числом отказов
+	sort.SliceStable(report.Reviewers, func(i, j int) bool {
+		return report.Reviewers[i].Total > report.Reviewers[j].Total
+	})
+	return report, tx.Commit()
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Запросы горячего пути назначения ревьюеров. Вынесены в общие переменные, чтобы прогрев
+// выполнял ровно те же тексты: pgx кэширует подготовленные выражения по тексту
+// запроса отдельно для каждого соединения.
+const (
+	userTeamQuery = `SELECT team_name FROM team_members WHERE user_id = $1 LIMIT 1`
+
+	// createPRChecksQuery - проверки CreatePR одним запросом: автор существует,
+	// его команда (NULL - ни в одной) и занят ли идентификатор PR
+	createPRChecksQuery = `SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1),
+		 (SELECT team_name FROM team_members WHERE user_id = $1 LIMIT 1),
+		 EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $2)`
+
+	teamSettingsQuery = `SELECT reviewer_count, strategy, merge_policy, required_approvals, max_open_reviews,
+		 adaptive_backlog_threshold, adaptive_reviewer_count, reviewer_count_reduced, new_member_holdback_days,
+		 pr_name_pattern, require_ticket_reference, min_tags, prefer_on_duty, approval_stages,
+		 candidate_filter, candidate_rank, review_sla_hours, locale, timezone
+		 FROM team_settings WHERE team_name = $1`
+)
+
+var (
+	prCandidatesQuery = `SELECT u.user_id
+		 FROM users u
+		 JOIN team_members tm ON u.user_id = tm.user_id
+		 WHERE tm.team_name = $1 AND u.is_active = true AND u.is_bot = false AND u.user_id <> $2
+		   AND ` + notInHoldback("$3")
+
+	activeTeamMembersQuery = `SELECT u.user_id
+		 FROM users u
+		 JOIN team_members tm ON u.user_id = tm.user_id
+		 WHERE tm.team_name = $1 AND u.is_active = true AND u.is_bot = false
+		   AND ` + notInHoldback("$2") + `
+		 ORDER BY u.user_id`
+)
+
+// hotPathQueries - запросы CreatePR, которые прогрев явно готовит на каждом соединении
+var hotPathQueries = []string{
+	userTeamQuery,
+	createPRChecksQuery,
+	teamSettingsQuery,
+	prCandidatesQuery,
+	activeTeamMembersQuery,
+}
+
+// WarmUpStats - итог прогрева
+type WarmUpStats struct {
+	Connections int // Прогретые соединения пула
+	Teams       int // Команды с участниками
+	Candidates  int // Активные участники команд
+	Statements  int // Подготовленные выражения на всех прогретых соединениях
+}
+
+// WarmUp прогревает пул соединений до того, как реплика объявит готовность:
+// открывает conns соединений и на каждом загружает членство в командах, настройки
+// и активных кандидатов всех команд теми же функциями, что и CreatePR. Сервис не держит
+// членство в памяти процесса: реплики пишут в общую БД, и кэш одной реплики устаревал бы
+// от изменений на других. Поэтому прогреваются кэши, которые читает CreatePR, - кэш
+// подготовленных выражений каждого соединения и буферный кэш Postgres, - и первый
+// CreatePR после деплоя не платит за холодный старт.
+func (s *StorageData) WarmUp(ctx context.Context, conns int) (WarmUpStats, error) {
+	if conns < 1 {
+		conns = 1
+	}
+
+	var stats WarmUpStats
+	teams, err := s.warmUpTeams(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.Teams = len(teams)
+
+	// Соединения удерживаются одновременно, иначе пул каждый раз отдавал бы одно и то же
+	held := make([]*sql.Conn, 0, conns)
+	defer func() {
+		for _, conn := range held {
+			conn.Close()
+		}
+	}()
+
+	for i := 0; i < conns; i++ {
+		conn, err := s.db.Conn(ctx)
+		if err != nil {
+			return stats, err
+		}
+		held = append(held, conn)
+
+		prepared, err := prepareHotPath(ctx, conn)
+		if err != nil {
+			return stats, err
+		}
+		stats.Statements += prepared
+
+		candidates, err := s.warmUpConn(ctx, conn, teams)
+		if err != nil {
+			return stats, err
+		}
+		stats.Connections++
+		stats.Candidates = candidates
+	}
+	return stats, nil
+}
+
+// prepareHotPath готовит запросы горячего пути на соединении и возвращает их число.
+// Выражение готовится под именем, равным тексту запроса: так pgx находит его при обычном
+// QueryContext с тем же текстом, и первый CreatePR не разбирает запросы даже в пустой БД,
+// где прогреву нечего загружать. На соединениях, открытых пулом позже, те же выражения
+// готовит кэш pgx при первом использовании.
+func prepareHotPath(ctx context.Context, conn *sql.Conn) (int, error) {
+	prepared := 0
+	err := conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return nil
+		}
+		for _, query := range hotPathQueries {
+			if _, err := c.Conn().Prepare(ctx, query, query); err != nil {
+				return err
+			}
+			prepared++
+		}
+		return nil
+	})
+	return prepared, err
+}
+
+// warmUpTeam - команда и любой ее участник, от имени которого выполняются запросы
+type warmUpTeam struct {
+	name   string
+	member string
+}
+
+func (s *StorageData) warmUpTeams(ctx context.Context) ([]warmUpTeam, error) {
+	rows, err := s.queryWithMetrics(ctx, "select", "team_members",
+		`SELECT team_name, MIN(user_id) FROM team_members GROUP BY team_name ORDER BY team_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []warmUpTeam
+	for rows.Next() {
+		var t warmUpTeam
+		if err := rows.Scan(&t.name, &t.member); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+// warmUpConn загружает на соединении данные CreatePR для всех команд и возвращает
+// число активных участников команд
+func (s *StorageData) warmUpConn(ctx context.Context, conn *sql.Conn, teams []warmUpTeam) (int, error) {
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	candidates := 0
+	for _, t := range teams {
+		if _, err := s.getUserTeam(ctx, tx, t.member); err != nil && err.Error() != "user not in any team" {
+			return 0, err
+		}
+
+		var authorExists, prExists bool
+		var authorTeam sql.NullString
+		if err := s.txQueryRowWithMetrics(tx, ctx, "select", "pull_requests", createPRChecksQuery, t.member, "").
+			Scan(&authorExists, &authorTeam, &prExists); err != nil {
+			return 0, err
+		}
+
+		if _, err := s.getPRCandidates(ctx, tx, t.name, t.member, nil); err != nil {
+			return 0, err
+		}
+		members, err := s.getActiveTeamMembers(ctx, tx, t.name, nil)
+		if err != nil {
+			return 0, err
+		}
+		candidates += len(members)
+
+		if _, err := s.getTeamSettings(ctx, tx, t.name); err != nil {
+			return 0, err
+		}
+	}
+	return candidates, tx.Commit()
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultWebhookDedupeTTL - сколько хранится идентификатор доставки вебхука.
+// Внешние системы повторяют доставку в течение часов, а не дней.
+const DefaultWebhookDedupeTTL = 72 * time.Hour
+
+// MaxDeliveryIDLength - предел длины идентификатора доставки вебхука
+const MaxDeliveryIDLength = 255
+
+// ClaimWebhookDelivery отмечает доставку вебхука как принятую. Возвращает false, если
+// доставка с тем же идентификатором уже принята не раньше ttl назад: это повтор.
+// Запись старше ttl занимается заново, как если бы ее не было.
+func (s *StorageData) ClaimWebhookDelivery(ctx context.Context, provider, deliveryID, event string, ttl time.Duration) (bool, error) {
+	now := s.now()
+	res, err := s.execWithMetrics(ctx, "upsert", "webhook_deliveries",
+		`INSERT INTO webhook_deliveries(provider, delivery_id, event, received_at) VALUES($1,$2,$3,$4)
+		 ON CONFLICT (provider, delivery_id) DO UPDATE SET event = EXCLUDED.event, received_at = EXCLUDED.received_at
+		 WHERE webhook_deliveries.received_at < $5`,
+		provider, deliveryID, event, now, now.Add(-ttl))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// ReleaseWebhookDelivery снимает отметку доставки, обработка которой не удалась,
+// чтобы повтор внешней системы обработал событие
+func (s *StorageData) ReleaseWebhookDelivery(ctx context.Context, provider, deliveryID string) error {
+	_, err := s.execWithMetrics(ctx, "delete", "webhook_deliveries",
+		`DELETE FROM webhook_deliveries WHERE provider = $1 AND delivery_id = $2`, provider, deliveryID)
+	return err
+}
+
+// PurgeWebhookDeliveries удаляет отметки доставок, принятых раньше before
+func (s *StorageData) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.execWithMetrics(ctx, "delete", "webhook_deliveries",
+		`DELETE FROM webhook_deliveries WHERE received_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}